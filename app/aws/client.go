@@ -0,0 +1,248 @@
+// Package aws provides a minimal AWS EC2 API client for listing instances
+package aws
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	ec2APIVersion = "2016-11-15"
+	ec2Service    = "ec2"
+	maxResults    = 100
+)
+
+// DefaultRegions lists regions scanned for EC2 instances when none are configured
+var DefaultRegions = []string{
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+	"eu-central-1", "eu-west-1", "eu-west-2", "eu-west-3", "eu-north-1",
+	"ap-southeast-1", "ap-southeast-2", "ap-northeast-1", "ap-south-1",
+	"ca-central-1", "sa-east-1",
+}
+
+// errOptInRequired is the EC2 error code returned for regions not enabled for the account
+const errOptInRequired = "OptInRequired"
+
+// Client is an AWS EC2 API client
+type Client struct {
+	httpClient *http.Client
+	accessKey  string
+	secretKey  string
+	regions    []string
+	endpoint   func(region string) string
+	now        func() time.Time
+}
+
+// NewClient creates a new EC2 client scanning the given regions, DefaultRegions if empty
+func NewClient(accessKey, secretKey string, regions []string) *Client {
+	if len(regions) == 0 {
+		regions = DefaultRegions
+	}
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		accessKey: accessKey,
+		secretKey: secretKey,
+		regions:   regions,
+		endpoint: func(region string) string {
+			return fmt.Sprintf("https://ec2.%s.amazonaws.com/", region)
+		},
+		now: time.Now,
+	}
+}
+
+// Instance represents an EC2 instance
+type Instance struct {
+	InstanceID       string `xml:"instanceId"`
+	InstanceType     string `xml:"instanceType"`
+	PrivateIPAddress string `xml:"privateIpAddress"`
+	IPAddress        string `xml:"ipAddress"`
+	IPv6Address      string `xml:"ipv6Address"`
+	State            struct {
+		Code int    `xml:"code"`
+		Name string `xml:"name"`
+	} `xml:"instanceState"`
+	Placement struct {
+		AvailabilityZone string `xml:"availabilityZone"`
+	} `xml:"placement"`
+	Tags []Tag `xml:"tagSet>item"`
+
+	// Region is the region the instance was listed in, not part of the API response
+	Region string `xml:"-"`
+}
+
+// Tag is an EC2 resource tag
+type Tag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+// describeInstancesResponse is the API response for DescribeInstances
+type describeInstancesResponse struct {
+	Reservations []struct {
+		Instances []Instance `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// errorResponse is the EC2 error envelope
+type errorResponse struct {
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Errors>Error"`
+}
+
+// APIError is an error returned by EC2 API
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error returns the error message
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("API error: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API error: status %d, %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// ListInstances fetches instances from all configured regions.
+// Regions not enabled for the account are skipped.
+func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
+	var allInstances []Instance
+
+	for _, region := range c.regions {
+		instances, err := c.listRegionInstances(ctx, region)
+		if err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.Code == errOptInRequired {
+				continue
+			}
+			return nil, fmt.Errorf("list instances in %s: %w", region, err)
+		}
+		allInstances = append(allInstances, instances...)
+	}
+
+	return allInstances, nil
+}
+
+// listRegionInstances fetches all instances of a single region following pagination
+func (c *Client) listRegionInstances(ctx context.Context, region string) ([]Instance, error) {
+	var instances []Instance
+	nextToken := ""
+
+	for {
+		params := url.Values{}
+		params.Set("Action", "DescribeInstances")
+		params.Set("Version", ec2APIVersion)
+		params.Set("MaxResults", fmt.Sprintf("%d", maxResults))
+		if nextToken != "" {
+			params.Set("NextToken", nextToken)
+		}
+
+		var result describeInstancesResponse
+		if err := c.do(ctx, region, params, &result); err != nil {
+			return nil, err
+		}
+
+		for _, r := range result.Reservations {
+			for _, inst := range r.Instances {
+				inst.Region = region
+				instances = append(instances, inst)
+			}
+		}
+
+		if result.NextToken == "" {
+			break
+		}
+		nextToken = result.NextToken
+	}
+
+	return instances, nil
+}
+
+// do performs a signed GET request to the regional EC2 endpoint and decodes XML response into dst
+func (c *Client) do(ctx context.Context, region string, params url.Values, dst any) error {
+	u := c.endpoint(region) + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	signV4(req, c.accessKey, c.secretKey, region, ec2Service, c.now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var errResp errorResponse
+		if xml.Unmarshal(body, &errResp) == nil && len(errResp.Errors) > 0 {
+			apiErr.Code = errResp.Errors[0].Code
+			apiErr.Message = errResp.Errors[0].Message
+		}
+		return apiErr
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// GetName returns the Name tag, falling back to instance ID
+func (i *Instance) GetName() string {
+	for _, t := range i.Tags {
+		if t.Key == "Name" && t.Value != "" {
+			return t.Value
+		}
+	}
+	return i.InstanceID
+}
+
+// GetLabels returns tags of the instance except the Name tag, which is used as the server name
+func (i *Instance) GetLabels() map[string]string {
+	res := make(map[string]string, len(i.Tags))
+	for _, t := range i.Tags {
+		if t.Key != "Name" {
+			res[t.Key] = t.Value
+		}
+	}
+	return res
+}
+
+// GetServerIP returns the public IP, falling back to private IP
+func (i *Instance) GetServerIP() string {
+	if i.IPAddress != "" {
+		return i.IPAddress
+	}
+	return i.PrivateIPAddress
+}
+
+// GetDescription returns a description with instance type and private IP
+func (i *Instance) GetDescription() string {
+	desc := i.InstanceType
+	if i.PrivateIPAddress != "" && i.IPAddress != "" {
+		desc += " | Private IP: " + i.PrivateIPAddress
+	}
+	return desc
+}
+
+// GetMonthlyPrice returns approximate monthly on-demand price from the bundled price table,
+// false if the instance type is missing from it
+func (i *Instance) GetMonthlyPrice() (float64, bool) {
+	return MonthlyPrice(i.InstanceType, i.Region)
+}
@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListInstances(t *testing.T) {
+	var tokens []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{region}/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DescribeInstances", r.URL.Query().Get("Action"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		switch r.PathValue("region") {
+		case "ap-east-1":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `<Response><Errors><Error><Code>OptInRequired</Code>`+
+				`<Message>You are not subscribed to this service.</Message></Error></Errors></Response>`)
+		case "eu-west-1":
+			token := r.URL.Query().Get("NextToken")
+			tokens = append(tokens, token)
+			switch token {
+			case "":
+				_, _ = fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet>`+
+					`<item><instanceId>i-1</instanceId><instanceType>t3.micro</instanceType>`+
+					`<ipAddress>1.2.3.4</ipAddress><instanceState><code>16</code><name>running</name></instanceState>`+
+					`<tagSet><item><key>Name</key><value>web</value></item></tagSet></item>`+
+					`</instancesSet></item></reservationSet><nextToken>page-2</nextToken></DescribeInstancesResponse>`)
+			case "page-2":
+				_, _ = fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet>`+
+					`<item><instanceId>i-2</instanceId><instanceType>t3.large</instanceType>`+
+					`<privateIpAddress>10.0.0.2</privateIpAddress></item>`+
+					`</instancesSet></item></reservationSet></DescribeInstancesResponse>`)
+			default:
+				t.Errorf("unexpected token %q", token)
+			}
+		default:
+			_, _ = fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet/></DescribeInstancesResponse>`)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClient("key", "secret", []string{"ap-east-1", "eu-west-1", "us-east-1"})
+	client.endpoint = func(region string) string { return ts.URL + "/" + region + "/" }
+	client.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	instances, err := client.ListInstances(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "page-2"}, tokens, "pages followed by next token")
+	require.Len(t, instances, 2, "opt-in region skipped")
+	assert.Equal(t, "i-1", instances[0].InstanceID)
+	assert.Equal(t, "web", instances[0].GetName())
+	assert.Equal(t, "running", instances[0].State.Name)
+	assert.Equal(t, "eu-west-1", instances[0].Region)
+	assert.Equal(t, "i-2", instances[1].GetName())
+	assert.Equal(t, "10.0.0.2", instances[1].GetServerIP())
+}
+
+func TestClient_ListInstancesError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, `<Response><Errors><Error><Code>AuthFailure</Code>`+
+			`<Message>AWS was not able to validate the provided access credentials</Message></Error></Errors></Response>`)
+	}))
+	defer ts.Close()
+
+	client := NewClient("key", "bad", []string{"eu-west-1"})
+	client.endpoint = func(string) string { return ts.URL + "/" }
+
+	_, err := client.ListInstances(context.Background())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "AuthFailure", apiErr.Code)
+	assert.Contains(t, err.Error(), "list instances in eu-west-1")
+}
+
+func TestMonthlyPrice(t *testing.T) {
+	price, ok := MonthlyPrice("t3.micro", "us-east-1")
+	require.True(t, ok)
+	assert.Greater(t, price, 0.0)
+
+	_, ok = MonthlyPrice("x99.huge", "us-east-1")
+	assert.False(t, ok, "unknown type")
+	_, ok = MonthlyPrice("t3.micro", "moon-1")
+	assert.False(t, ok, "unknown region")
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	_ "embed" // embed price table
+	"encoding/json"
+	"sync"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// hoursPerMonth is the average number of hours in a month, used to estimate monthly price from hourly
+const hoursPerMonth = 730
+
+// pricesJSON is a bundled table of approximate Linux on-demand hourly prices in USD,
+// keyed by region and instance type
+//
+//go:embed prices.json
+var pricesJSON []byte
+
+var (
+	pricesOnce sync.Once
+	prices     map[string]map[string]float64
+
+	unknownPrices sync.Map // "region/type" keys of instance types already logged as missing from the table
+)
+
+// HourlyPrice returns approximate on-demand hourly price for instance type in region,
+// false if the type is missing from the bundled table. Missing types are logged once.
+func HourlyPrice(instanceType, region string) (float64, bool) {
+	pricesOnce.Do(func() {
+		if err := json.Unmarshal(pricesJSON, &prices); err != nil {
+			log.Printf("[WARN] failed to parse bundled EC2 price table: %v", err)
+		}
+	})
+	price, ok := prices[region][instanceType]
+	if !ok {
+		if _, logged := unknownPrices.LoadOrStore(region+"/"+instanceType, true); !logged {
+			log.Printf("[WARN] no bundled EC2 price for %s in %s, cost is not synced", instanceType, region)
+		}
+	}
+	return price, ok
+}
+
+// MonthlyPrice returns approximate on-demand monthly price for instance type in region,
+// false if the type is missing from the bundled table
+func MonthlyPrice(instanceType, region string) (float64, bool) {
+	price, ok := HourlyPrice(instanceType, region)
+	return price * hoursPerMonth, ok
+}
@@ -13,11 +13,12 @@ const (
 type logAction int
 
 const (
-	LogActionAdded   logAction = iota // enum:alias=added
-	LogActionPaused                   // enum:alias=paused
-	LogActionDeleted                  // enum:alias=deleted
-	LogActionSynced                   // enum:alias=synced
-	LogActionUpdated                  // enum:alias=updated
+	LogActionAdded    logAction = iota // enum:alias=added
+	LogActionPaused                    // enum:alias=paused
+	LogActionDeleted                   // enum:alias=deleted
+	LogActionSynced                    // enum:alias=synced
+	LogActionUpdated                   // enum:alias=updated
+	LogActionExecuted                  // enum:alias=executed
 )
 
 //go:generate go run github.com/go-pkgz/enum@latest -type theme -lower
@@ -38,3 +39,67 @@ const (
 	ViewModeTable viewMode = iota
 	ViewModeCards
 )
+
+//go:generate go run github.com/go-pkgz/enum@latest -type syncTrigger -lower
+type syncTrigger int
+
+const (
+	SyncTriggerManual    syncTrigger = iota // enum:alias=manual
+	SyncTriggerScheduled                    // enum:alias=scheduled
+)
+
+//go:generate go run github.com/go-pkgz/enum@latest -type serverField -lower
+type serverField int
+
+const (
+	ServerFieldName        serverField = iota // enum:alias=name
+	ServerFieldIP                             // enum:alias=ip
+	ServerFieldLocation                       // enum:alias=location
+	ServerFieldDescription                    // enum:alias=description
+	ServerFieldCost                           // enum:alias=cost
+	ServerFieldBackups                        // enum:alias=backups
+)
+
+//go:generate go run github.com/go-pkgz/enum@latest -type addressKind -lower
+type addressKind int
+
+const (
+	AddressKindPublic   addressKind = iota // enum:alias=public
+	AddressKindPrivate                     // enum:alias=private
+	AddressKindFloating                    // enum:alias=floating
+	AddressKindSubnet                      // enum:alias=subnet
+)
+
+//go:generate go run github.com/go-pkgz/enum@latest -type resourceKind -lower
+type resourceKind int
+
+const (
+	ResourceKindVolume       resourceKind = iota // enum:alias=volume
+	ResourceKindFloatingIP                       // enum:alias=floating_ip
+	ResourceKindPrimaryIP                        // enum:alias=primary_ip
+	ResourceKindLoadBalancer                     // enum:alias=load_balancer
+	ResourceKindSnapshot                         // enum:alias=snapshot
+	ResourceKindStorageBox                       // enum:alias=storage_box
+	ResourceKindVSwitch                          // enum:alias=vswitch
+)
+
+//go:generate go run github.com/go-pkgz/enum@latest -type serverAction -lower
+type serverAction int
+
+const (
+	ServerActionPowerOn        serverAction = iota // enum:alias=power_on
+	ServerActionShutdown                           // enum:alias=shutdown
+	ServerActionReboot                             // enum:alias=reboot
+	ServerActionReset                              // enum:alias=reset
+	ServerActionEnableBackups                      // enum:alias=enable_backups
+	ServerActionDisableBackups                     // enum:alias=disable_backups
+	ServerActionCreateSnapshot                     // enum:alias=create_snapshot
+)
+
+//go:generate go run github.com/go-pkgz/enum@latest -type tagSource -lower
+type tagSource int
+
+const (
+	TagSourceProvider tagSource = iota // enum:alias=provider
+	TagSourceUser                      // enum:alias=user
+)
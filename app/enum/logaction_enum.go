@@ -22,6 +22,8 @@ func (l LogAction) String() string {
 		return "synced"
 	case LogActionUpdated:
 		return "updated"
+	case LogActionExecuted:
+		return "executed"
 	}
 	return fmt.Sprintf("LogAction(%d)", l)
 }
@@ -39,11 +41,13 @@ func ParseLogAction(s string) (LogAction, error) {
 		return LogActionSynced, nil
 	case "updated":
 		return LogActionUpdated, nil
+	case "executed":
+		return LogActionExecuted, nil
 	}
 	return 0, fmt.Errorf("invalid LogAction: %q", s)
 }
 
 // AllLogActions returns all valid LogAction values
 func AllLogActions() []LogAction {
-	return []LogAction{LogActionAdded, LogActionPaused, LogActionDeleted, LogActionSynced, LogActionUpdated}
+	return []LogAction{LogActionAdded, LogActionPaused, LogActionDeleted, LogActionSynced, LogActionUpdated, LogActionExecuted}
 }
@@ -0,0 +1,133 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Cloud server action commands, used as the last path element of /servers/{id}/actions/{command}
+const (
+	ActionPowerOn       = "poweron"
+	ActionShutdown      = "shutdown" // graceful ACPI shutdown
+	ActionReboot        = "reboot"   // graceful ACPI reboot
+	ActionReset         = "reset"    // hard reset
+	ActionEnableBackup  = "enable_backup"
+	ActionDisableBackup = "disable_backup"
+)
+
+// Cloud action statuses
+const (
+	ActionStatusRunning = "running"
+	ActionStatusSuccess = "success"
+	ActionStatusError   = "error"
+)
+
+// Action is an asynchronous Hetzner Cloud action, e.g. a server reboot
+type Action struct {
+	ID       int64        `json:"id"`
+	Command  string       `json:"command"`
+	Status   string       `json:"status"`
+	Progress int          `json:"progress"`
+	Error    *ActionError `json:"error"`
+}
+
+// ActionError is the error an action finished with
+type ActionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the error description
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("action failed: %s: %s", e.Code, e.Message)
+}
+
+// actionResponse is the response of endpoints starting or returning an action
+type actionResponse struct {
+	Action Action `json:"action"`
+}
+
+// ServerAction starts the action command on the server and returns the running action
+func (c *Client) ServerAction(ctx context.Context, serverID int64, command string) (*Action, error) {
+	var resp actionResponse
+	path := "/servers/" + strconv.FormatInt(serverID, 10) + "/actions/" + command
+	if err := c.transport.postJSON(ctx, path, struct{}{}, &resp); err != nil {
+		return nil, fmt.Errorf("%s server %d: %w", command, serverID, err)
+	}
+	return &resp.Action, nil
+}
+
+// CreateSnapshot starts creating a snapshot image of the server with the description
+func (c *Client) CreateSnapshot(ctx context.Context, serverID int64, description string) (*Action, error) {
+	req := struct {
+		Type        string `json:"type"`
+		Description string `json:"description,omitempty"`
+	}{Type: "snapshot", Description: description}
+
+	var resp actionResponse
+	path := "/servers/" + strconv.FormatInt(serverID, 10) + "/actions/create_image"
+	if err := c.transport.postJSON(ctx, path, req, &resp); err != nil {
+		return nil, fmt.Errorf("create snapshot of server %d: %w", serverID, err)
+	}
+	return &resp.Action, nil
+}
+
+// GetAction fetches the current state of the action
+func (c *Client) GetAction(ctx context.Context, id int64) (*Action, error) {
+	var resp actionResponse
+	if err := c.transport.get(ctx, "/actions/"+strconv.FormatInt(id, 10), &resp); err != nil {
+		return nil, fmt.Errorf("get action %d: %w", id, err)
+	}
+	return &resp.Action, nil
+}
+
+// WaitAction polls the action every interval until it is finished. Returns the finished action,
+// and ActionError if it failed.
+func (c *Client) WaitAction(ctx context.Context, action *Action, interval time.Duration) (*Action, error) {
+	for action.Status == ActionStatusRunning {
+		if err := sleep(ctx, interval); err != nil {
+			return action, err
+		}
+		next, err := c.GetAction(ctx, action.ID)
+		if err != nil {
+			return action, err
+		}
+		action = next
+	}
+
+	if action.Status == ActionStatusError {
+		if action.Error != nil {
+			return action, action.Error
+		}
+		return action, &ActionError{Code: "unknown", Message: action.Command + " failed"}
+	}
+	return action, nil
+}
+
+// Robot reset types
+const (
+	RobotResetSoftware = "sw"    // send CTRL+ALT+DEL
+	RobotResetHardware = "hw"    // hardware reset
+	RobotResetPower    = "power" // press power button, shuts down a running server gracefully
+)
+
+// Reset resets the dedicated server with the reset type. Robot executes resets synchronously.
+func (c *RobotClient) Reset(ctx context.Context, serverNumber int64, resetType string) error {
+	path := "/reset/" + strconv.FormatInt(serverNumber, 10)
+	if err := c.transport.postForm(ctx, path, url.Values{"type": {resetType}}, nil); err != nil {
+		return fmt.Errorf("reset server %d: %w", serverNumber, err)
+	}
+	return nil
+}
+
+// WakeOnLAN sends a Wake-on-LAN packet to the dedicated server to power it on
+func (c *RobotClient) WakeOnLAN(ctx context.Context, serverNumber int64) error {
+	path := "/wol/" + strconv.FormatInt(serverNumber, 10)
+	if err := c.transport.postForm(ctx, path, url.Values{}, nil); err != nil {
+		return fmt.Errorf("wake on lan server %d: %w", serverNumber, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ServerActionWait(t *testing.T) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /servers/42/actions/reboot", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"action":{"id":7,"command":"reboot_server","status":"running","progress":0}}`))
+	})
+	mux.HandleFunc("GET /actions/7", func(w http.ResponseWriter, _ *http.Request) {
+		if polls.Add(1) < 2 {
+			_, _ = w.Write([]byte(`{"action":{"id":7,"command":"reboot_server","status":"running","progress":50}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"action":{"id":7,"command":"reboot_server","status":"success","progress":100}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL))
+	action, err := client.ServerAction(context.Background(), 42, ActionReboot)
+	require.NoError(t, err)
+	assert.Equal(t, ActionStatusRunning, action.Status)
+
+	action, err = client.WaitAction(context.Background(), action, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, ActionStatusSuccess, action.Status)
+	assert.Equal(t, int32(2), polls.Load())
+}
+
+func TestClient_CreateSnapshotFailed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /servers/42/actions/create_image", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "snapshot", req["type"])
+		assert.Equal(t, "before upgrade", req["description"])
+		_, _ = w.Write([]byte(`{"image":{"id":100},"action":{"id":8,"command":"create_image","status":"running"}}`))
+	})
+	mux.HandleFunc("GET /actions/8", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"action":{"id":8,"command":"create_image","status":"error",
+			"error":{"code":"action_failed","message":"Action failed"}}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL))
+	action, err := client.CreateSnapshot(context.Background(), 42, "before upgrade")
+	require.NoError(t, err)
+
+	_, err = client.WaitAction(context.Background(), action, time.Millisecond)
+	var actionErr *ActionError
+	require.ErrorAs(t, err, &actionErr)
+	assert.Equal(t, "action_failed", actionErr.Code)
+}
+
+func TestRobotClient_Reset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/reset/321", r.URL.Path)
+		assert.Equal(t, "hw", r.FormValue("type"))
+		_, _ = w.Write([]byte(`{"reset":{"server_number":321,"type":"hw"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	require.NoError(t, client.Reset(context.Background(), 321, RobotResetHardware))
+}
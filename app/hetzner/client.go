@@ -6,44 +6,49 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 )
 
 const baseURL = "https://api.hetzner.cloud/v1"
 
 // Client is a Hetzner Cloud API client
 type Client struct {
-	httpClient *http.Client
-	apiToken   string
+	transport *transport
 }
 
 // NewClient creates a new Hetzner API client
-func NewClient(apiToken string) *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		apiToken: apiToken,
-	}
+func NewClient(apiToken string, opts ...Option) *Client {
+	auth := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+apiToken) }
+	return &Client{transport: newTransport(baseURL, auth, opts)}
 }
 
 // Server represents a Hetzner server
 type Server struct {
-	ID           int64      `json:"id"`
-	Name         string     `json:"name"`
-	Status       string     `json:"status"`
-	BackupWindow *string    `json:"backup_window"`
-	PublicNet    PublicNet  `json:"public_net"`
-	ServerType   ServerType `json:"server_type"`
-	Datacenter   Datacenter `json:"datacenter"`
-	Location     Location   `json:"location"`
-	Image        *Image     `json:"image"`
+	ID           int64             `json:"id"`
+	Name         string            `json:"name"`
+	Status       string            `json:"status"`
+	BackupWindow *string           `json:"backup_window"`
+	PublicNet    PublicNet         `json:"public_net"`
+	PrivateNet   []PrivateNet      `json:"private_net"`
+	ServerType   ServerType        `json:"server_type"`
+	Datacenter   Datacenter        `json:"datacenter"`
+	Location     Location          `json:"location"`
+	Image        *Image            `json:"image"`
+	Labels       map[string]string `json:"labels"`
 }
 
 // PublicNet contains public network information
 type PublicNet struct {
-	IPv4 IPv4 `json:"ipv4"`
-	IPv6 IPv6 `json:"ipv6"`
+	IPv4        IPv4    `json:"ipv4"`
+	IPv6        IPv6    `json:"ipv6"`
+	FloatingIPs []int64 `json:"floating_ips"` // IDs of floating IPs assigned to the server
+}
+
+// PrivateNet contains server address in an attached private network
+type PrivateNet struct {
+	Network  int64    `json:"network"`
+	IP       string   `json:"ip"`
+	AliasIPs []string `json:"alias_ips"`
 }
 
 // IPv4 contains IPv4 address info
@@ -51,7 +56,7 @@ type IPv4 struct {
 	IP string `json:"ip"`
 }
 
-// IPv6 contains IPv6 address info
+// IPv6 contains IPv6 network info, IP is the network in CIDR notation, e.g. "2001:db8::/64"
 type IPv6 struct {
 	IP string `json:"ip"`
 }
@@ -107,12 +112,6 @@ type Image struct {
 	OSVersion   string `json:"os_version"`
 }
 
-// ListServersResponse is the API response for listing servers
-type ListServersResponse struct {
-	Servers []Server `json:"servers"`
-	Meta    Meta     `json:"meta"`
-}
-
 // Meta contains pagination info
 type Meta struct {
 	Pagination Pagination `json:"pagination"`
@@ -130,45 +129,51 @@ type Pagination struct {
 
 // ListServers fetches all servers from Hetzner Cloud
 func (c *Client) ListServers(ctx context.Context) ([]Server, error) {
-	var allServers []Server
+	servers, err := listAll[Server](ctx, c.transport, "/servers", "servers")
+	if err != nil {
+		return nil, fmt.Errorf("list servers: %w", err)
+	}
+	return servers, nil
+}
+
+// listAll fetches all pages of a Cloud API list endpoint, items are taken from the key of the response
+func listAll[T any](ctx context.Context, t *transport, path, key string) ([]T, error) {
+	var all []T
 	page := 1
 
 	for {
-		url := fmt.Sprintf("%s/servers?page=%d&per_page=50", baseURL, page)
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("create request: %w", err)
+		var result map[string]json.RawMessage
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
 		}
-
-		req.Header.Set("Authorization", "Bearer "+c.apiToken)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("do request: %w", err)
+		if err := t.get(ctx, fmt.Sprintf("%s%spage=%d&per_page=50", path, sep, page), &result); err != nil {
+			return nil, err
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+		var items []T
+		if raw, ok := result[key]; ok {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("decode %s: %w", key, err)
+			}
 		}
+		all = append(all, items...)
 
-		var result ListServersResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return nil, fmt.Errorf("decode response: %w", err)
+		var meta Meta
+		if raw, ok := result["meta"]; ok {
+			if err := json.Unmarshal(raw, &meta); err != nil {
+				return nil, fmt.Errorf("decode meta: %w", err)
+			}
 		}
 
-		allServers = append(allServers, result.Servers...)
-
 		// Check if there are more pages
-		if result.Meta.Pagination.NextPage == nil {
+		if meta.Pagination.NextPage == nil {
 			break
 		}
-		page = *result.Meta.Pagination.NextPage
+		page = *meta.Pagination.NextPage
 	}
 
-	return allServers, nil
+	return all, nil
 }
 
 // GetServerIP returns the primary IP address (IPv4 preferred)
@@ -202,9 +207,12 @@ func (s *Server) GetServerLocationDescription() string {
 
 // GetMonthlyPrice returns the monthly price for this server's location
 func (s *Server) GetMonthlyPrice() float64 {
-	location := s.GetServerLocation()
+	return monthlyPrice(s.ServerType.Prices, s.GetServerLocation())
+}
 
-	for _, price := range s.ServerType.Prices {
+// monthlyPrice returns gross monthly price for the location, falling back to the first available price
+func monthlyPrice(prices []ServerPrice, location string) float64 {
+	for _, price := range prices {
 		if price.Location == location {
 			if gross, err := strconv.ParseFloat(price.PriceMonthly.Gross, 64); err == nil {
 				return gross
@@ -213,8 +221,8 @@ func (s *Server) GetMonthlyPrice() float64 {
 	}
 
 	// Fallback: return first available price
-	if len(s.ServerType.Prices) > 0 {
-		if gross, err := strconv.ParseFloat(s.ServerType.Prices[0].PriceMonthly.Gross, 64); err == nil {
+	if len(prices) > 0 {
+		if gross, err := strconv.ParseFloat(prices[0].PriceMonthly.Gross, 64); err == nil {
 			return gross
 		}
 	}
@@ -227,6 +235,22 @@ func (s *Server) HasBackups() bool {
 	return s.BackupWindow != nil && *s.BackupWindow != ""
 }
 
+// GetOSFlavor returns OS flavor of the server image, e.g. "ubuntu", empty if unknown
+func (s *Server) GetOSFlavor() string {
+	if s.Image == nil {
+		return ""
+	}
+	return s.Image.OSFlavor
+}
+
+// GetOSVersion returns OS version of the server image, e.g. "20.04", empty if unknown
+func (s *Server) GetOSVersion() string {
+	if s.Image == nil || s.Image.OSVersion == "unknown" {
+		return ""
+	}
+	return s.Image.OSVersion
+}
+
 // GetDescription returns a description combining server type and OS info
 func (s *Server) GetDescription() string {
 	desc := s.ServerType.Description
@@ -0,0 +1,63 @@
+package hetzner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errors returned by Hetzner clients, APIError unwraps to one of them
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+	ErrNotFound     = errors.New("not found")
+)
+
+// APIError is an error response of Hetzner Cloud or Robot API
+type APIError struct {
+	StatusCode int
+	Code       string // error code from the response envelope, e.g. "unauthorized" or "RATE_LIMIT_EXCEEDED"
+	Message    string
+}
+
+// Error returns the error description
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error: status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ", " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap returns the error kind, nil for errors without specific kind
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.rateLimited():
+		return ErrRateLimited
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// rateLimited returns true for Cloud 429 responses and Robot 403 responses with rate limit code
+func (e *APIError) rateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests || strings.EqualFold(e.Code, "rate_limit_exceeded")
+}
+
+// errorEnvelope is the error response body shared by Cloud and Robot APIs
+type errorEnvelope struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
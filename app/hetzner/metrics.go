@@ -0,0 +1,115 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metric types accepted by the server metrics endpoint
+const (
+	MetricTypeCPU     = "cpu"
+	MetricTypeDisk    = "disk"
+	MetricTypeNetwork = "network"
+)
+
+// time series names reported by the server metrics endpoint
+const (
+	SeriesCPU             = "cpu"                     // CPU usage in percent, up to 100 per core
+	SeriesDiskReadBytes   = "disk.0.bandwidth.read"   // bytes/s
+	SeriesDiskWriteBytes  = "disk.0.bandwidth.write"  // bytes/s
+	SeriesNetworkInBytes  = "network.0.bandwidth.in"  // bytes/s
+	SeriesNetworkOutBytes = "network.0.bandwidth.out" // bytes/s
+)
+
+// Metrics contains time series of a server, values are averages over Step seconds
+type Metrics struct {
+	Start      time.Time               `json:"start"`
+	End        time.Time               `json:"end"`
+	Step       float64                 `json:"step"`
+	TimeSeries map[string]MetricSeries `json:"time_series"`
+}
+
+// MetricSeries contains values of a single time series as [unix timestamp, "value"] pairs
+type MetricSeries struct {
+	Values []MetricValue `json:"values"`
+}
+
+// MetricPoint is a single value of a time series
+type MetricPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// MetricValue is a [unix timestamp, "value"] pair as returned by the API
+type MetricValue MetricPoint
+
+// UnmarshalJSON decodes a [1435781470.622, "42"] pair
+func (v *MetricValue) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid metric value %s", data)
+	}
+
+	var ts float64
+	if err := json.Unmarshal(pair[0], &ts); err != nil {
+		return fmt.Errorf("invalid metric timestamp %s: %w", pair[0], err)
+	}
+	var raw string
+	if err := json.Unmarshal(pair[1], &raw); err != nil {
+		return fmt.Errorf("invalid metric value %s: %w", pair[1], err)
+	}
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+		val = 0
+	}
+
+	sec, frac := math.Modf(ts)
+	v.Time = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	v.Value = val
+	return nil
+}
+
+// Series returns points of the named time series, nil if not reported
+func (m *Metrics) Series(name string) []MetricPoint {
+	s, ok := m.TimeSeries[name]
+	if !ok {
+		return nil
+	}
+	res := make([]MetricPoint, len(s.Values))
+	for i, v := range s.Values {
+		res[i] = MetricPoint(v)
+	}
+	return res
+}
+
+// GetServerMetrics fetches metrics of the given types for the server between start and end,
+// averaged over step. The API limits the number of points per series, so step should be
+// chosen to keep (end - start) / step reasonably small.
+func (c *Client) GetServerMetrics(ctx context.Context, serverID int64, types []string, start, end time.Time,
+	step time.Duration) (*Metrics, error) {
+	q := url.Values{}
+	q.Set("type", strings.Join(types, ","))
+	q.Set("start", start.UTC().Format(time.RFC3339))
+	q.Set("end", end.UTC().Format(time.RFC3339))
+	if step > 0 {
+		q.Set("step", strconv.Itoa(int(step.Seconds())))
+	}
+
+	var wrapper struct {
+		Metrics Metrics `json:"metrics"`
+	}
+	path := "/servers/" + strconv.FormatInt(serverID, 10) + "/metrics?" + q.Encode()
+	if err := c.transport.get(ctx, path, &wrapper); err != nil {
+		return nil, fmt.Errorf("get metrics of server %d: %w", serverID, err)
+	}
+	return &wrapper.Metrics, nil
+}
@@ -0,0 +1,41 @@
+package hetzner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetServerMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/servers/42/metrics", r.URL.Path)
+		assert.Equal(t, "cpu,network", r.URL.Query().Get("type"))
+		assert.Equal(t, "2026-10-01T00:00:00Z", r.URL.Query().Get("start"))
+		assert.Equal(t, "2026-10-01T02:00:00Z", r.URL.Query().Get("end"))
+		assert.Equal(t, "3600", r.URL.Query().Get("step"))
+		_, _ = w.Write([]byte(`{"metrics":{"start":"2026-10-01T00:00:00Z","end":"2026-10-01T02:00:00Z","step":3600,
+			"time_series":{
+				"cpu":{"values":[[1790812800,"12.5"],[1790816400,"NaN"]]},
+				"network.0.bandwidth.in":{"values":[[1790812800,"2048"]]}}}}`))
+	}))
+	defer ts.Close()
+
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	metrics, err := NewClient("token", WithBaseURL(ts.URL)).GetServerMetrics(context.Background(), 42,
+		[]string{MetricTypeCPU, MetricTypeNetwork}, start, start.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+
+	cpu := metrics.Series(SeriesCPU)
+	require.Len(t, cpu, 2)
+	assert.Equal(t, start, cpu[0].Time)
+	assert.InDelta(t, 12.5, cpu[0].Value, 0.001)
+	assert.Zero(t, cpu[1].Value, "NaN is reported for periods without data")
+
+	assert.Len(t, metrics.Series(SeriesNetworkInBytes), 1)
+	assert.Nil(t, metrics.Series(SeriesDiskReadBytes))
+}
@@ -0,0 +1,188 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Volume represents a Hetzner Cloud block storage volume
+type Volume struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Size     int      `json:"size"` // GB
+	Status   string   `json:"status"`
+	Server   *int64   `json:"server"`
+	Location Location `json:"location"`
+}
+
+// FloatingIP represents a Hetzner Cloud floating IP
+type FloatingIP struct {
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	IP           string   `json:"ip"`   // address for ipv4, network in CIDR notation for ipv6
+	Type         string   `json:"type"` // ipv4 or ipv6
+	Server       *int64   `json:"server"`
+	HomeLocation Location `json:"home_location"`
+}
+
+// PrimaryIP represents a Hetzner Cloud primary IP
+type PrimaryIP struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	IP           string     `json:"ip"`
+	Type         string     `json:"type"` // ipv4 or ipv6
+	AssigneeID   *int64     `json:"assignee_id"`
+	AssigneeType string     `json:"assignee_type"` // server
+	Datacenter   Datacenter `json:"datacenter"`
+}
+
+// LoadBalancer represents a Hetzner Cloud load balancer
+type LoadBalancer struct {
+	ID               int64    `json:"id"`
+	Name             string   `json:"name"`
+	Location         Location `json:"location"`
+	LoadBalancerType struct {
+		Name   string        `json:"name"`
+		Prices []ServerPrice `json:"prices"`
+	} `json:"load_balancer_type"`
+	PublicNet struct {
+		IPv4 IPv4 `json:"ipv4"`
+	} `json:"public_net"`
+	Targets []struct {
+		Type   string `json:"type"` // server, label_selector or ip
+		Server *struct {
+			ID int64 `json:"id"`
+		} `json:"server"`
+	} `json:"targets"`
+}
+
+// MonthlyPrice returns the monthly price of the load balancer type in its location
+func (lb *LoadBalancer) MonthlyPrice() float64 {
+	return monthlyPrice(lb.LoadBalancerType.Prices, lb.Location.Name)
+}
+
+// Snapshot represents a Hetzner Cloud snapshot image
+type Snapshot struct {
+	ID          int64    `json:"id"`
+	Description string   `json:"description"`
+	ImageSize   *float64 `json:"image_size"` // GB, nil while the snapshot is being created
+	CreatedFrom *struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"created_from"`
+}
+
+// Size returns snapshot size in GB, zero if unknown
+func (s *Snapshot) Size() float64 {
+	if s.ImageSize == nil {
+		return 0
+	}
+	return *s.ImageSize
+}
+
+// Pricing contains Hetzner Cloud prices of resources which are not included in server type prices
+type Pricing struct {
+	FloatingIPs []ipPricing `json:"floating_ips"`
+	PrimaryIPs  []ipPricing `json:"primary_ips"`
+	Volume      struct {
+		PricePerGBMonth PriceAmount `json:"price_per_gb_month"`
+	} `json:"volume"`
+	Image struct {
+		PricePerGBMonth PriceAmount `json:"price_per_gb_month"`
+	} `json:"image"`
+}
+
+// ipPricing contains prices of an IP type per location
+type ipPricing struct {
+	Type   string        `json:"type"`
+	Prices []ServerPrice `json:"prices"`
+}
+
+// FloatingIPPrice returns the monthly price of a floating IP of the type in the location
+func (p *Pricing) FloatingIPPrice(ipType, location string) float64 {
+	return ipPrice(p.FloatingIPs, ipType, location)
+}
+
+// PrimaryIPPrice returns the monthly price of a primary IP of the type in the location
+func (p *Pricing) PrimaryIPPrice(ipType, location string) float64 {
+	return ipPrice(p.PrimaryIPs, ipType, location)
+}
+
+// VolumePrice returns the monthly price of a volume of the size in GB
+func (p *Pricing) VolumePrice(sizeGB int) float64 {
+	perGB, _ := strconv.ParseFloat(p.Volume.PricePerGBMonth.Gross, 64)
+	return perGB * float64(sizeGB)
+}
+
+// SnapshotPrice returns the monthly price of a snapshot of the size in GB
+func (p *Pricing) SnapshotPrice(sizeGB float64) float64 {
+	perGB, _ := strconv.ParseFloat(p.Image.PricePerGBMonth.Gross, 64)
+	return perGB * sizeGB
+}
+
+func ipPrice(pricing []ipPricing, ipType, location string) float64 {
+	for _, p := range pricing {
+		if p.Type == ipType {
+			return monthlyPrice(p.Prices, location)
+		}
+	}
+	return 0
+}
+
+// ListVolumes fetches all volumes of the project
+func (c *Client) ListVolumes(ctx context.Context) ([]Volume, error) {
+	volumes, err := listAll[Volume](ctx, c.transport, "/volumes", "volumes")
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	return volumes, nil
+}
+
+// ListFloatingIPs fetches all floating IPs of the project
+func (c *Client) ListFloatingIPs(ctx context.Context) ([]FloatingIP, error) {
+	ips, err := listAll[FloatingIP](ctx, c.transport, "/floating_ips", "floating_ips")
+	if err != nil {
+		return nil, fmt.Errorf("list floating ips: %w", err)
+	}
+	return ips, nil
+}
+
+// ListPrimaryIPs fetches all primary IPs of the project
+func (c *Client) ListPrimaryIPs(ctx context.Context) ([]PrimaryIP, error) {
+	ips, err := listAll[PrimaryIP](ctx, c.transport, "/primary_ips", "primary_ips")
+	if err != nil {
+		return nil, fmt.Errorf("list primary ips: %w", err)
+	}
+	return ips, nil
+}
+
+// ListLoadBalancers fetches all load balancers of the project
+func (c *Client) ListLoadBalancers(ctx context.Context) ([]LoadBalancer, error) {
+	lbs, err := listAll[LoadBalancer](ctx, c.transport, "/load_balancers", "load_balancers")
+	if err != nil {
+		return nil, fmt.Errorf("list load balancers: %w", err)
+	}
+	return lbs, nil
+}
+
+// ListSnapshots fetches all snapshot images of the project
+func (c *Client) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	snapshots, err := listAll[Snapshot](ctx, c.transport, "/images?type=snapshot", "images")
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetPricing fetches current prices of the project
+func (c *Client) GetPricing(ctx context.Context) (*Pricing, error) {
+	var result struct {
+		Pricing Pricing `json:"pricing"`
+	}
+	if err := c.transport.get(ctx, "/pricing", &result); err != nil {
+		return nil, fmt.Errorf("get pricing: %w", err)
+	}
+	return &result.Pricing, nil
+}
@@ -0,0 +1,57 @@
+package hetzner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListResources(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /volumes", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"volumes":[{"id":10,"name":"data","size":100,"server":1,"location":{"name":"fsn1"}}],
+			"meta":{"pagination":{"next_page":null}}}`))
+	})
+	mux.HandleFunc("GET /images", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snapshot", r.URL.Query().Get("type"))
+		assert.Equal(t, "1", r.URL.Query().Get("page"))
+		_, _ = w.Write([]byte(`{"images":[{"id":20,"description":"before upgrade","image_size":2.5,
+			"created_from":{"id":1,"name":"web-1"}}],"meta":{"pagination":{"next_page":null}}}`))
+	})
+	mux.HandleFunc("GET /pricing", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"pricing":{
+			"volume":{"price_per_gb_month":{"gross":"0.0440"}},
+			"image":{"price_per_gb_month":{"gross":"0.0119"}},
+			"floating_ips":[{"type":"ipv4","prices":[{"location":"fsn1","price_monthly":{"gross":"3.5700"}}]}],
+			"primary_ips":[{"type":"ipv4","prices":[{"location":"fsn1","price_monthly":{"gross":"0.6000"}}]},
+				{"type":"ipv6","prices":[{"location":"fsn1","price_monthly":{"gross":"0.0000"}}]}]}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL))
+
+	volumes, err := client.ListVolumes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	require.NotNil(t, volumes[0].Server)
+	assert.Equal(t, int64(1), *volumes[0].Server)
+
+	snapshots, err := client.ListSnapshots(context.Background())
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.InDelta(t, 2.5, snapshots[0].Size(), 0.001)
+	assert.Equal(t, "web-1", snapshots[0].CreatedFrom.Name)
+
+	pricing, err := client.GetPricing(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 4.4, pricing.VolumePrice(volumes[0].Size), 0.0001)
+	assert.InDelta(t, 0.02975, pricing.SnapshotPrice(snapshots[0].Size()), 0.0001)
+	assert.InDelta(t, 3.57, pricing.FloatingIPPrice("ipv4", "fsn1"), 0.0001)
+	assert.InDelta(t, 0.6, pricing.PrimaryIPPrice("ipv4", "fsn1"), 0.0001)
+	assert.Zero(t, pricing.PrimaryIPPrice("ipv6", "fsn1"))
+}
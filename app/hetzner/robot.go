@@ -2,37 +2,32 @@ package hetzner
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 )
 
 const robotBaseURL = "https://robot-ws.your-server.de"
 
 // RobotClient is a Hetzner Robot API client for dedicated servers
 type RobotClient struct {
-	httpClient *http.Client
-	username   string
-	password   string
+	transport *transport
 }
 
 // NewRobotClient creates a new Hetzner Robot API client
 // credentials should be in format "username:password"
-func NewRobotClient(credentials string) (*RobotClient, error) {
+func NewRobotClient(credentials string, opts ...Option) (*RobotClient, error) {
 	parts := strings.SplitN(credentials, ":", 2)
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid credentials format, expected 'username:password'")
 	}
 
-	return &RobotClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		username: parts[0],
-		password: parts[1],
-	}, nil
+	username, password := parts[0], parts[1]
+	auth := func(req *http.Request) { req.SetBasicAuth(username, password) }
+	return &RobotClient{transport: newTransport(robotBaseURL, auth, opts)}, nil
 }
 
 // RobotServer represents a Hetzner Robot dedicated server
@@ -57,6 +52,22 @@ type Subnet struct {
 	Mask string `json:"mask"`
 }
 
+// CIDR returns the subnet in CIDR notation. Robot reports the mask as prefix length for IPv6
+// and as dotted netmask for IPv4 subnets.
+func (s Subnet) CIDR() string {
+	if s.IP == "" {
+		return ""
+	}
+	if ones, err := strconv.Atoi(s.Mask); err == nil {
+		return s.IP + "/" + strconv.Itoa(ones)
+	}
+	if mask := net.ParseIP(s.Mask).To4(); mask != nil {
+		ones, _ := net.IPv4Mask(mask[0], mask[1], mask[2], mask[3]).Size()
+		return s.IP + "/" + strconv.Itoa(ones)
+	}
+	return s.IP
+}
+
 // robotServerWrapper wraps the server response
 type robotServerWrapper struct {
 	Server RobotServer `json:"server"`
@@ -64,37 +75,13 @@ type robotServerWrapper struct {
 
 // ListServers fetches all dedicated servers from Hetzner Robot
 func (c *RobotClient) ListServers(ctx context.Context) ([]RobotServer, error) {
-	url := robotBaseURL + "/server"
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.SetBasicAuth(c.username, c.password)
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication failed: invalid username or password")
-	}
-
-	if resp.StatusCode == http.StatusForbidden {
-		return nil, fmt.Errorf("access forbidden: rate limit exceeded or IP blocked")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
-	}
-
 	var wrappers []robotServerWrapper
-	if err := json.NewDecoder(resp.Body).Decode(&wrappers); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := c.transport.get(ctx, "/server", &wrappers); err != nil {
+		// Robot returns 404 when the account has no servers
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list servers: %w", err)
 	}
 
 	servers := make([]RobotServer, len(wrappers))
@@ -105,6 +92,14 @@ func (c *RobotClient) ListServers(ctx context.Context) ([]RobotServer, error) {
 	return servers, nil
 }
 
+// GetIPv6Net returns the main IPv6 network of the server in CIDR notation, empty if none
+func (s *RobotServer) GetIPv6Net() string {
+	if s.ServerIPv6Net == "" || strings.Contains(s.ServerIPv6Net, "/") {
+		return s.ServerIPv6Net
+	}
+	return s.ServerIPv6Net + "/64"
+}
+
 // GetServerIP returns the primary IP address
 func (s *RobotServer) GetServerIP() string {
 	return s.ServerIP
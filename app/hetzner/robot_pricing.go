@@ -0,0 +1,262 @@
+package hetzner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotDateLayout is the date format used by Robot API, e.g. "2024-05-31"
+const robotDateLayout = "2006-01-02"
+
+// RobotPrice is a monthly price of a Robot product in EUR, as decimal strings
+type RobotPrice struct {
+	Net   string `json:"net"`
+	Gross string `json:"gross"`
+}
+
+// GrossValue returns the gross price, 0 if not set
+func (p RobotPrice) GrossValue() float64 {
+	v, err := strconv.ParseFloat(p.Gross, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// RobotLocationPrice is a price of a Robot product in a location
+type RobotLocationPrice struct {
+	Location   string     `json:"location"`
+	Price      RobotPrice `json:"price"`
+	PriceSetup RobotPrice `json:"price_setup"`
+}
+
+// RobotProduct is a standard dedicated server product, e.g. "AX41-NVMe"
+type RobotProduct struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description []string             `json:"description"`
+	Traffic     string               `json:"traffic"`
+	Location    []string             `json:"location"`
+	Prices      []RobotLocationPrice `json:"prices"`
+}
+
+// MonthlyPrice returns gross monthly price in the location, e.g. "FSN1",
+// falling back to the first available price
+func (p *RobotProduct) MonthlyPrice(location string) float64 {
+	for _, price := range p.Prices {
+		if strings.EqualFold(price.Location, location) {
+			return price.Price.GrossValue()
+		}
+	}
+	if len(p.Prices) > 0 {
+		return p.Prices[0].Price.GrossValue()
+	}
+	return 0
+}
+
+// RobotMarketProduct is a server market (auction) offer
+type RobotMarketProduct struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Datacenter string `json:"datacenter"`
+	Price      string `json:"price"`     // monthly net price
+	PriceVAT   string `json:"price_vat"` // monthly gross price
+}
+
+// MonthlyPrice returns gross monthly price of the offer, 0 if not set
+func (p *RobotMarketProduct) MonthlyPrice() float64 {
+	v, err := strconv.ParseFloat(p.PriceVAT, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// RobotAddon is an add-on which can be ordered for a dedicated server, e.g. an additional IP or a subnet
+type RobotAddon struct {
+	ID    string             `json:"id"` // e.g. "primary_ipv4" or "subnet_ipv4_29"
+	Name  string             `json:"name"`
+	Type  string             `json:"type"` // e.g. "ip_ipv4" or "subnet_ipv4"
+	Price RobotLocationPrice `json:"price"`
+}
+
+// RobotCancellation is the cancellation state of a dedicated server
+type RobotCancellation struct {
+	ServerNumber             int64  `json:"server_number"`
+	Cancelled                bool   `json:"cancelled"`
+	CancellationDate         string `json:"cancellation_date"`          // set if cancelled
+	EarliestCancellationDate string `json:"earliest_cancellation_date"` // set if not cancelled
+}
+
+// GetCancellationDate returns the date the server is cancelled at, zero if not cancelled
+func (c *RobotCancellation) GetCancellationDate() time.Time {
+	return parseRobotDate(c.CancellationDate)
+}
+
+// ListProducts fetches standard dedicated server products with their prices
+func (c *RobotClient) ListProducts(ctx context.Context) ([]RobotProduct, error) {
+	var wrappers []struct {
+		Product RobotProduct `json:"product"`
+	}
+	if err := c.transport.get(ctx, "/order/server/product", &wrappers); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list products: %w", err)
+	}
+
+	products := make([]RobotProduct, len(wrappers))
+	for i, w := range wrappers {
+		products[i] = w.Product
+	}
+	return products, nil
+}
+
+// ListMarketProducts fetches current server market offers with their prices
+func (c *RobotClient) ListMarketProducts(ctx context.Context) ([]RobotMarketProduct, error) {
+	var wrappers []struct {
+		Product RobotMarketProduct `json:"product"`
+	}
+	if err := c.transport.get(ctx, "/order/server_market/product", &wrappers); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list market products: %w", err)
+	}
+
+	products := make([]RobotMarketProduct, len(wrappers))
+	for i, w := range wrappers {
+		products[i] = w.Product
+	}
+	return products, nil
+}
+
+// ListServerAddons fetches add-ons available for the server with their prices
+func (c *RobotClient) ListServerAddons(ctx context.Context, serverNumber int64) ([]RobotAddon, error) {
+	var wrappers []struct {
+		Product RobotAddon `json:"product"`
+	}
+	path := "/order/server_addon/" + strconv.FormatInt(serverNumber, 10) + "/product"
+	if err := c.transport.get(ctx, path, &wrappers); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list addons of server %d: %w", serverNumber, err)
+	}
+
+	addons := make([]RobotAddon, len(wrappers))
+	for i, w := range wrappers {
+		addons[i] = w.Product
+	}
+	return addons, nil
+}
+
+// GetCancellation fetches the cancellation state of the server
+func (c *RobotClient) GetCancellation(ctx context.Context, serverNumber int64) (*RobotCancellation, error) {
+	var wrapper struct {
+		Cancellation RobotCancellation `json:"cancellation"`
+	}
+	path := "/server/" + strconv.FormatInt(serverNumber, 10) + "/cancellation"
+	if err := c.transport.get(ctx, path, &wrapper); err != nil {
+		return nil, fmt.Errorf("get cancellation of server %d: %w", serverNumber, err)
+	}
+	return &wrapper.Cancellation, nil
+}
+
+// GetPaidUntil returns the date the server is paid until, zero if unknown
+func (s *RobotServer) GetPaidUntil() time.Time {
+	return parseRobotDate(s.PaidUntil)
+}
+
+// GetLocation returns the location of the server datacenter, e.g. "FSN1" for "FSN1-DC14"
+func (s *RobotServer) GetLocation() string {
+	location, _, _ := strings.Cut(s.DC, "-")
+	return location
+}
+
+// parseRobotDate parses a Robot API date, zero if empty or invalid
+func parseRobotDate(s string) time.Time {
+	t, err := time.Parse(robotDateLayout, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// RobotPriceList prices dedicated servers by their product, standard products first and
+// server market offers if the product is not a standard one
+type RobotPriceList struct {
+	Products []RobotProduct
+	Market   []RobotMarketProduct
+}
+
+// ServerPrice returns gross monthly price of the server product in the server location,
+// false if the product is not found
+func (l *RobotPriceList) ServerPrice(srv *RobotServer) (float64, bool) {
+	if srv.Product == "" {
+		return 0, false
+	}
+	for i := range l.Products {
+		p := &l.Products[i]
+		if strings.EqualFold(p.ID, srv.Product) || strings.HasSuffix(strings.ToLower(p.Name), " "+strings.ToLower(srv.Product)) {
+			return p.MonthlyPrice(srv.GetLocation()), true
+		}
+	}
+	for i := range l.Market {
+		if strings.EqualFold(l.Market[i].Name, srv.Product) {
+			return l.Market[i].MonthlyPrice(), true
+		}
+	}
+	return 0, false
+}
+
+// AddonsPrice returns gross monthly price of additional IPv4 addresses and IPv4 subnets of the server,
+// priced by add-ons available for it. IPv6 subnets are free.
+func (s *RobotServer) AddonsPrice(addons []RobotAddon) float64 {
+	var ipPrice float64
+	for _, a := range addons {
+		if a.Type == "ip_ipv4" && a.ID != "primary_ipv4" {
+			ipPrice = a.Price.Price.GrossValue()
+			break
+		}
+	}
+
+	var total float64
+	for _, ip := range s.IP {
+		if ip != s.ServerIP && !strings.Contains(ip, ":") {
+			total += ipPrice
+		}
+	}
+	for _, sn := range s.Subnet {
+		if strings.Contains(sn.IP, ":") {
+			continue
+		}
+		_, prefix, _ := strings.Cut(sn.CIDR(), "/")
+		for _, a := range addons {
+			if a.Type == "subnet_ipv4" && strings.HasSuffix(a.ID, "_"+prefix) {
+				total += a.Price.Price.GrossValue()
+				break
+			}
+		}
+	}
+	return total
+}
+
+// HasAddons returns true if the server has additional IPv4 addresses or IPv4 subnets
+func (s *RobotServer) HasAddons() bool {
+	for _, ip := range s.IP {
+		if ip != s.ServerIP && !strings.Contains(ip, ":") {
+			return true
+		}
+	}
+	for _, sn := range s.Subnet {
+		if !strings.Contains(sn.IP, ":") {
+			return true
+		}
+	}
+	return false
+}
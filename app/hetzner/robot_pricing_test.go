@@ -0,0 +1,84 @@
+package hetzner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobotClient_Pricing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/server/product", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"product":{"id":"AX41-NVMe","name":"Dedicated Root Server AX41-NVMe","prices":[
+			{"location":"FSN1","price":{"net":"39.0000","gross":"46.4100"}},
+			{"location":"HEL1","price":{"net":"37.0000","gross":"44.0300"}}]}}]`))
+	})
+	mux.HandleFunc("GET /order/server_market/product", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"product":{"id":1234,"name":"SB110","price":"50.0000","price_vat":"59.5000"}}]`))
+	})
+	mux.HandleFunc("GET /order/server_addon/321/product", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"product":{"id":"primary_ipv4","type":"ip_ipv4","price":{"price":{"gross":"2.0000"}}}},
+			{"product":{"id":"additional_ipv4","type":"ip_ipv4","price":{"price":{"gross":"1.5000"}}}},
+			{"product":{"id":"subnet_ipv4_29","type":"subnet_ipv4","price":{"price":{"gross":"10.0000"}}}}]`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	products, err := client.ListProducts(ctx)
+	require.NoError(t, err)
+	market, err := client.ListMarketProducts(ctx)
+	require.NoError(t, err)
+	prices := RobotPriceList{Products: products, Market: market}
+
+	srv := RobotServer{ServerNumber: 321, ServerIP: "1.2.3.4", Product: "AX41-NVMe", DC: "HEL1-DC2",
+		IP:     []string{"1.2.3.4", "1.2.3.5"},
+		Subnet: []Subnet{{IP: "2a01:4f8::", Mask: "64"}, {IP: "5.6.7.8", Mask: "255.255.255.248"}}}
+	price, ok := prices.ServerPrice(&srv)
+	require.True(t, ok)
+	assert.InDelta(t, 44.03, price, 0.001)
+
+	require.True(t, srv.HasAddons())
+	addons, err := client.ListServerAddons(ctx, srv.ServerNumber)
+	require.NoError(t, err)
+	assert.InDelta(t, 11.5, srv.AddonsPrice(addons), 0.001, "additional IPv4 and /29 subnet, IPv6 is free")
+
+	auction := RobotServer{Product: "SB110"}
+	price, ok = prices.ServerPrice(&auction)
+	require.True(t, ok)
+	assert.InDelta(t, 59.5, price, 0.001)
+
+	_, ok = prices.ServerPrice(&RobotServer{Product: "unknown"})
+	assert.False(t, ok)
+	assert.False(t, (&RobotServer{ServerIP: "1.2.3.4", IP: []string{"1.2.3.4"}}).HasAddons())
+}
+
+func TestRobotClient_GetCancellation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/server/321/cancellation", r.URL.Path)
+		_, _ = w.Write([]byte(`{"cancellation":{"server_number":321,"cancelled":true,
+			"cancellation_date":"2026-11-30","cancellation_reason":null}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	c, err := client.GetCancellation(context.Background(), 321)
+	require.NoError(t, err)
+	assert.True(t, c.Cancelled)
+	assert.Equal(t, time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC), c.GetCancellationDate())
+
+	srv := RobotServer{PaidUntil: "2026-10-31", DC: "FSN1-DC14"}
+	assert.Equal(t, time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC), srv.GetPaidUntil())
+	assert.Equal(t, "FSN1", srv.GetLocation())
+	assert.True(t, (&RobotServer{}).GetPaidUntil().IsZero())
+}
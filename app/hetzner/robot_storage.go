@@ -0,0 +1,109 @@
+package hetzner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// storageBoxPrices are gross monthly prices of storage box products in EUR. Robot API doesn't report
+// storage box prices, so current list prices are used, including 19% VAT like Robot order prices.
+var storageBoxPrices = map[string]float64{
+	"BX11": 4.53,
+	"BX21": 12.97,
+	"BX31": 24.75,
+	"BX41": 48.31,
+}
+
+// StorageBox represents a Hetzner Robot storage box. Disk quota and usage are reported
+// only by GetStorageBox, not in the list.
+type StorageBox struct {
+	ID           int64  `json:"id"`
+	Login        string `json:"login"`
+	Name         string `json:"name"`
+	Product      string `json:"product"` // e.g. "BX11"
+	Cancelled    bool   `json:"cancelled"`
+	Locked       bool   `json:"locked"`
+	Location     string `json:"location"` // e.g. "FSN1"
+	LinkedServer *int64 `json:"linked_server"`
+	PaidUntil    string `json:"paid_until"`
+	DiskQuota    int64  `json:"disk_quota"` // MB
+	DiskUsage    int64  `json:"disk_usage"` // MB
+}
+
+// GetName returns the storage box name, falling back to its login
+func (b *StorageBox) GetName() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.Login
+}
+
+// SizeGB returns disk quota of the storage box in GB
+func (b *StorageBox) SizeGB() float64 {
+	return float64(b.DiskQuota) / 1024
+}
+
+// UsedGB returns disk usage of the storage box in GB
+func (b *StorageBox) UsedGB() float64 {
+	return float64(b.DiskUsage) / 1024
+}
+
+// MonthlyPrice returns gross monthly price of the storage box product, false if the product is unknown
+func (b *StorageBox) MonthlyPrice() (float64, bool) {
+	price, ok := storageBoxPrices[strings.ToUpper(b.Product)]
+	return price, ok
+}
+
+// VSwitch represents a Hetzner Robot vSwitch connecting dedicated servers in a VLAN
+type VSwitch struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	VLAN      int    `json:"vlan"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// ListStorageBoxes fetches all storage boxes of the account, without disk quota and usage
+func (c *RobotClient) ListStorageBoxes(ctx context.Context) ([]StorageBox, error) {
+	var wrappers []struct {
+		StorageBox StorageBox `json:"storagebox"`
+	}
+	if err := c.transport.get(ctx, "/storagebox", &wrappers); err != nil {
+		// Robot returns 404 when the account has no storage boxes
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list storage boxes: %w", err)
+	}
+
+	boxes := make([]StorageBox, len(wrappers))
+	for i, w := range wrappers {
+		boxes[i] = w.StorageBox
+	}
+	return boxes, nil
+}
+
+// GetStorageBox fetches the storage box with its disk quota and usage
+func (c *RobotClient) GetStorageBox(ctx context.Context, id int64) (*StorageBox, error) {
+	var wrapper struct {
+		StorageBox StorageBox `json:"storagebox"`
+	}
+	if err := c.transport.get(ctx, "/storagebox/"+strconv.FormatInt(id, 10), &wrapper); err != nil {
+		return nil, fmt.Errorf("get storage box %d: %w", id, err)
+	}
+	return &wrapper.StorageBox, nil
+}
+
+// ListVSwitches fetches all vSwitches of the account
+func (c *RobotClient) ListVSwitches(ctx context.Context) ([]VSwitch, error) {
+	var switches []VSwitch
+	if err := c.transport.get(ctx, "/vswitch", &switches); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list vswitches: %w", err)
+	}
+	return switches, nil
+}
@@ -0,0 +1,66 @@
+package hetzner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobotClient_StorageBoxes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /storagebox", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"storagebox":{"id":123,"login":"u12345","name":"","product":"BX11",
+			"cancelled":false,"location":"FSN1","linked_server":321,"paid_until":"2025-03-31"}}]`))
+	})
+	mux.HandleFunc("GET /storagebox/123", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"storagebox":{"id":123,"login":"u12345","product":"BX11","location":"FSN1",
+			"linked_server":321,"disk_quota":1048576,"disk_usage":262144}}`))
+	})
+	mux.HandleFunc("GET /vswitch", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":4321,"name":"backend","vlan":4000,"cancelled":false}]`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	boxes, err := client.ListStorageBoxes(ctx)
+	require.NoError(t, err)
+	require.Len(t, boxes, 1)
+	assert.Equal(t, "u12345", boxes[0].GetName())
+	require.NotNil(t, boxes[0].LinkedServer)
+	assert.Equal(t, int64(321), *boxes[0].LinkedServer)
+
+	box, err := client.GetStorageBox(ctx, 123)
+	require.NoError(t, err)
+	assert.InDelta(t, 1024, box.SizeGB(), 0.001)
+	assert.InDelta(t, 256, box.UsedGB(), 0.001)
+	price, ok := box.MonthlyPrice()
+	require.True(t, ok)
+	assert.InDelta(t, 4.53, price, 0.001)
+
+	switches, err := client.ListVSwitches(ctx)
+	require.NoError(t, err)
+	require.Len(t, switches, 1)
+	assert.Equal(t, 4000, switches[0].VLAN)
+}
+
+func TestRobotClient_NoStorageBoxes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"status":404,"code":"STORAGEBOX_NOT_FOUND","message":"Storagebox not found"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	boxes, err := client.ListStorageBoxes(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, boxes)
+}
@@ -0,0 +1,240 @@
+package hetzner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// transport defaults
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	defaultMaxWait    = 30 * time.Second // longer rate limit waits fail with ErrRateLimited
+)
+
+// Option configures a Hetzner client
+type Option func(*transport)
+
+// WithBaseURL sets API base URL, used to point clients at a test server
+func WithBaseURL(baseURL string) Option {
+	return func(t *transport) { t.baseURL = baseURL }
+}
+
+// WithHTTPClient sets HTTP client used for requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *transport) { t.httpClient = client }
+}
+
+// WithRetries sets the number of retries of failed requests and the initial backoff, doubled on each retry
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(t *transport) {
+		t.maxRetries = maxRetries
+		t.backoff = backoff
+	}
+}
+
+// transport performs requests to Hetzner APIs. It retries network errors and 5xx responses of
+// idempotent requests with exponential backoff, waits for rate limit reset and parses error responses
+// into APIError.
+type transport struct {
+	httpClient *http.Client
+	baseURL    string
+	auth       func(*http.Request)
+	maxRetries int
+	backoff    time.Duration
+	maxWait    time.Duration
+
+	mu           sync.Mutex
+	blockedUntil time.Time // rate limit exhausted until this time
+}
+
+func newTransport(baseURL string, auth func(*http.Request), opts []Option) *transport {
+	t := &transport{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    baseURL,
+		auth:       auth,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		maxWait:    defaultMaxWait,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// get performs GET request to the API path and decodes JSON response into v
+func (t *transport) get(ctx context.Context, path string, v any) error {
+	return t.do(ctx, http.MethodGet, path, nil, "", v)
+}
+
+// postJSON performs POST request with JSON encoded body to the API path and decodes JSON response into v
+func (t *transport) postJSON(ctx context.Context, path string, body, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	return t.do(ctx, http.MethodPost, path, data, "application/json", v)
+}
+
+// postForm performs POST request with form encoded values to the API path and decodes JSON response into v
+func (t *transport) postForm(ctx context.Context, path string, values url.Values, v any) error {
+	return t.do(ctx, http.MethodPost, path, []byte(values.Encode()), "application/x-www-form-urlencoded", v)
+}
+
+// do performs request to the API path and decodes JSON response into v, if v is not nil.
+// Rate limited requests are retried for any method, as they were not processed by the API.
+func (t *transport) do(ctx context.Context, method, path string, body []byte, contentType string, v any) error {
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	backoff := t.backoff
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitRateLimit(ctx); err != nil {
+			return err
+		}
+
+		resp, err := t.send(ctx, method, path, body, contentType)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent || attempt >= t.maxRetries {
+				return err
+			}
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff *= 2
+			continue
+		}
+
+		t.trackRateLimit(resp.Header)
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if v == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			return nil
+		}
+
+		apiErr := readAPIError(resp)
+		retryable := apiErr.rateLimited() || (idempotent && resp.StatusCode >= 500)
+		if !retryable || attempt >= t.maxRetries {
+			return apiErr
+		}
+
+		wait := backoff
+		if apiErr.rateLimited() {
+			if reset := t.resetWait(resp.Header); reset > wait {
+				wait = reset
+			}
+			if wait > t.maxWait {
+				return apiErr
+			}
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// send sends a single request
+func (t *transport) send(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	t.auth(req)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	return resp, nil
+}
+
+// waitRateLimit waits until rate limit is reset if it was exhausted by previous requests
+func (t *transport) waitRateLimit(ctx context.Context) error {
+	t.mu.Lock()
+	wait := time.Until(t.blockedUntil)
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if wait > t.maxWait {
+		return &APIError{StatusCode: http.StatusTooManyRequests, Code: "rate_limit_exceeded",
+			Message: "rate limit exhausted until " + t.blockedUntil.Format(time.RFC3339)}
+	}
+	return sleep(ctx, wait)
+}
+
+// trackRateLimit remembers when the rate limit resets if no requests remain.
+// Hetzner Cloud reports RateLimit-Remaining and RateLimit-Reset (unix time) headers.
+func (t *transport) trackRateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	if reset := t.resetWait(h); reset > 0 {
+		t.mu.Lock()
+		t.blockedUntil = time.Now().Add(reset)
+		t.mu.Unlock()
+	}
+}
+
+// resetWait returns time until rate limit reset from RateLimit-Reset or Retry-After headers
+func (t *transport) resetWait(h http.Header) time.Duration {
+	if reset, err := strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64); err == nil {
+		return time.Until(time.Unix(reset, 0))
+	}
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// readAPIError reads error response into APIError and closes the body
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var env errorEnvelope
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err == nil && json.Unmarshal(data, &env) == nil {
+		apiErr.Code = env.Error.Code
+		apiErr.Message = env.Error.Message
+	}
+	return apiErr
+}
+
+// sleep waits for the duration or until context is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
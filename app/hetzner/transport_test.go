@@ -0,0 +1,194 @@
+package hetzner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListServersPaginated(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /servers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"servers":[{"id":1,"name":"web-1","labels":{"env":"prod"}}],"meta":{"pagination":{"page":1,"next_page":2}}}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"servers":[{"id":2,"name":"web-2"}],"meta":{"pagination":{"page":2,"next_page":null}}}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	servers, err := NewClient("token", WithBaseURL(ts.URL)).ListServers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, servers, 2)
+	assert.Equal(t, int64(1), servers[0].ID)
+	assert.Equal(t, map[string]string{"env": "prod"}, servers[0].Labels)
+	assert.Equal(t, "web-2", servers[1].Name)
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"servers":[{"id":1,"name":"web-1"}],"meta":{"pagination":{"next_page":null}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+	servers, err := client.ListServers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_GivesUpAfterRetries(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL), WithRetries(2, time.Millisecond))
+	_, err := client.ListServers(context.Background())
+	require.Error(t, err)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	tbl := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized,
+			`{"error":{"code":"unauthorized","message":"unable to authenticate"}}`, ErrUnauthorized},
+		{"forbidden", http.StatusForbidden,
+			`{"error":{"code":"forbidden","message":"insufficient permissions"}}`, ErrForbidden},
+		{"not found", http.StatusNotFound,
+			`{"error":{"code":"not_found","message":"server not found"}}`, ErrNotFound},
+		{"rate limited", http.StatusTooManyRequests,
+			`{"error":{"code":"rate_limit_exceeded","message":"limit reached"}}`, ErrRateLimited},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			client := NewClient("token", WithBaseURL(ts.URL), WithRetries(0, time.Millisecond))
+			_, err := client.ListServers(context.Background())
+			require.ErrorIs(t, err, tt.want)
+			assert.Contains(t, err.Error(), "status "+strconv.Itoa(tt.status))
+		})
+	}
+}
+
+func TestClient_RateLimitRetry(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"code":"rate_limit_exceeded","message":"limit reached"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"servers":[],"meta":{"pagination":{"next_page":null}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL), WithRetries(1, time.Millisecond))
+	_, err := client.ListServers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_RateLimitResetTooFar(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	client := NewClient("token", WithBaseURL(ts.URL), WithRetries(3, time.Millisecond))
+	start := time.Now()
+	_, err := client.ListServers(context.Background())
+	require.ErrorIs(t, err, ErrRateLimited)
+	assert.Less(t, time.Since(start), time.Second, "should not wait for a reset an hour away")
+
+	// subsequent requests fail fast until reset
+	_, err = client.ListServers(context.Background())
+	require.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestRobotClient_ListServers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		assert.Equal(t, "/server", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"server":{"server_number":321,"server_name":"db-1","server_ip":"1.2.3.4","dc":"FSN1-DC14"}}]`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	servers, err := client.ListServers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, int64(321), servers[0].ServerNumber)
+	assert.Equal(t, "1.2.3.4", servers[0].GetServerIP())
+}
+
+func TestRobotClient_RateLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"status":403,"code":"RATE_LIMIT_EXCEEDED","message":"Rate limit exceeded"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL), WithRetries(1, time.Millisecond))
+	require.NoError(t, err)
+	_, err = client.ListServers(context.Background())
+	require.ErrorIs(t, err, ErrRateLimited)
+	assert.NotErrorIs(t, err, ErrForbidden)
+}
+
+func TestRobotClient_NoServers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"status":404,"code":"SERVER_NOT_FOUND","message":"No server found"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewRobotClient("user:pass", WithBaseURL(ts.URL))
+	require.NoError(t, err)
+	servers, err := client.ListServers(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+}
@@ -0,0 +1,285 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvListSep separates values of list columns, e.g. server tags
+const csvListSep = ";"
+
+// column maps a CSV column to a field of the record
+type column[T any] struct {
+	name     string
+	required bool
+	get      func(*T) string
+	set      func(*T, string) error
+}
+
+var providerColumns = []column[Provider]{
+	{name: "ident", required: true, get: func(p *Provider) string { return p.Ident },
+		set: func(p *Provider, v string) error { p.Ident = v; return nil }},
+	{name: "name", get: func(p *Provider) string { return p.Name },
+		set: func(p *Provider, v string) error { p.Name = v; return nil }},
+	{name: "description", get: func(p *Provider) string { return p.Description },
+		set: func(p *Provider, v string) error { p.Description = v; return nil }},
+}
+
+var accountColumns = []column[Account]{
+	{name: "provider", required: true, get: func(a *Account) string { return a.Provider },
+		set: func(a *Account, v string) error { a.Provider = v; return nil }},
+	{name: "group", get: func(a *Account) string { return a.Group },
+		set: func(a *Account, v string) error { a.Group = v; return nil }},
+	{name: "name", required: true, get: func(a *Account) string { return a.Name },
+		set: func(a *Account, v string) error { a.Name = v; return nil }},
+	{name: "login", get: func(a *Account) string { return a.Login },
+		set: func(a *Account, v string) error { a.Login = v; return nil }},
+	{name: "api_key", get: func(a *Account) string { return a.APIKey },
+		set: func(a *Account, v string) error { a.APIKey = v; return nil }},
+	{name: "sync_interval", get: func(a *Account) string { return formatInt(a.SyncInterval) },
+		set: func(a *Account, v string) (err error) { a.SyncInterval, err = parseInt(v); return err }},
+}
+
+var serverColumns = []column[Server]{
+	{name: "provider", required: true, get: func(s *Server) string { return s.Provider },
+		set: func(s *Server, v string) error { s.Provider = v; return nil }},
+	{name: "account", required: true, get: func(s *Server) string { return s.Account },
+		set: func(s *Server, v string) error { s.Account = v; return nil }},
+	{name: "name", required: true, get: func(s *Server) string { return s.Name },
+		set: func(s *Server, v string) error { s.Name = v; return nil }},
+	{name: "ip", get: func(s *Server) string { return s.IP },
+		set: func(s *Server, v string) error { s.IP = v; return nil }},
+	{name: "status", get: func(s *Server) string { return s.Status },
+		set: func(s *Server, v string) error { s.Status = v; return nil }},
+	{name: "location", get: func(s *Server) string { return s.Location },
+		set: func(s *Server, v string) error { s.Location = v; return nil }},
+	{name: "description", get: func(s *Server) string { return s.Description },
+		set: func(s *Server, v string) error { s.Description = v; return nil }},
+	{name: "responsible", get: func(s *Server) string { return s.Responsible },
+		set: func(s *Server, v string) error { s.Responsible = v; return nil }},
+	{name: "cost", get: func(s *Server) string { return formatFloat(s.Cost) },
+		set: func(s *Server, v string) (err error) { s.Cost, err = parseFloat(v); return err }},
+	{name: "backups", get: func(s *Server) string { return formatBool(s.Backups) },
+		set: func(s *Server, v string) (err error) { s.Backups, err = parseBool(v); return err }},
+	{name: "external_id", get: func(s *Server) string { return s.ExternalID },
+		set: func(s *Server, v string) error { s.ExternalID = v; return nil }},
+	{name: "cpu_cores", get: func(s *Server) string { return formatInt(s.CPUCores) },
+		set: func(s *Server, v string) (err error) { s.CPUCores, err = parseInt(v); return err }},
+	{name: "memory_gb", get: func(s *Server) string { return formatFloat(s.MemoryGB) },
+		set: func(s *Server, v string) (err error) { s.MemoryGB, err = parseFloat(v); return err }},
+	{name: "disk_gb", get: func(s *Server) string { return formatInt(s.DiskGB) },
+		set: func(s *Server, v string) (err error) { s.DiskGB, err = parseInt(v); return err }},
+	{name: "server_type", get: func(s *Server) string { return s.ServerType },
+		set: func(s *Server, v string) error { s.ServerType = v; return nil }},
+	{name: "os_flavor", get: func(s *Server) string { return s.OSFlavor },
+		set: func(s *Server, v string) error { s.OSFlavor = v; return nil }},
+	{name: "os_version", get: func(s *Server) string { return s.OSVersion },
+		set: func(s *Server, v string) error { s.OSVersion = v; return nil }},
+	{name: "datacenter", get: func(s *Server) string { return s.Datacenter },
+		set: func(s *Server, v string) error { s.Datacenter = v; return nil }},
+	{name: "paid_until", get: func(s *Server) string { return s.PaidUntil },
+		set: func(s *Server, v string) error { s.PaidUntil = v; return nil }},
+	{name: "cancellation_date", get: func(s *Server) string { return s.CancellationDate },
+		set: func(s *Server, v string) error { s.CancellationDate = v; return nil }},
+	{name: "tags", get: func(s *Server) string { return strings.Join(s.Tags, csvListSep) },
+		set: func(s *Server, v string) error { s.Tags = splitList(v); return nil }},
+	{name: "addresses", get: func(s *Server) string { return strings.Join(s.Addresses, csvListSep) },
+		set: func(s *Server, v string) error { s.Addresses = splitList(v); return nil }},
+}
+
+var logColumns = []column[Log]{
+	{name: "provider", required: true, get: func(l *Log) string { return l.Provider },
+		set: func(l *Log, v string) error { l.Provider = v; return nil }},
+	{name: "account", required: true, get: func(l *Log) string { return l.Account },
+		set: func(l *Log, v string) error { l.Account = v; return nil }},
+	{name: "server", required: true, get: func(l *Log) string { return l.Server },
+		set: func(l *Log, v string) error { l.Server = v; return nil }},
+	{name: "action", required: true, get: func(l *Log) string { return l.Action },
+		set: func(l *Log, v string) error { l.Action = v; return nil }},
+	{name: "description", get: func(l *Log) string { return l.Description },
+		set: func(l *Log, v string) error { l.Description = v; return nil }},
+	{name: "time", required: true, get: func(l *Log) string { return l.Time.Format(time.RFC3339) },
+		set: func(l *Log, v string) (err error) { l.Time, err = time.Parse(time.RFC3339, v); return err }},
+}
+
+// writeCSV writes the section of the inventory as CSV with a header row
+func writeCSV(w io.Writer, inv *Inventory, section Section) error {
+	cw := csv.NewWriter(w)
+	var err error
+	switch section {
+	case SectionProviders:
+		err = writeRows(cw, providerColumns, inv.Providers)
+	case SectionAccounts:
+		err = writeRows(cw, accountColumns, inv.Accounts)
+	case SectionServers:
+		err = writeRows(cw, serverColumns, inv.Servers)
+	case SectionLogs:
+		err = writeRows(cw, logColumns, inv.Logs)
+	default:
+		return fmt.Errorf("unknown section %q", section)
+	}
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeRows[T any](cw *csv.Writer, cols []column[T], rows []T) error {
+	record := make([]string, len(cols))
+	for i, c := range cols {
+		record[i] = c.name
+	}
+	if err := cw.Write(record); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	for i := range rows {
+		for j, c := range cols {
+			record[j] = c.get(&rows[i])
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	return nil
+}
+
+// readCSV reads records of the section from CSV with a header row. Columns are matched by header
+// names in any order, missing optional columns are left empty.
+func readCSV(r io.Reader, section Section) (*Inventory, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	inv := &Inventory{Version: FormatVersion}
+	var err error
+	switch section {
+	case SectionProviders:
+		inv.Providers, err = readRows(cr, providerColumns)
+	case SectionAccounts:
+		inv.Accounts, err = readRows(cr, accountColumns)
+	case SectionServers:
+		inv.Servers, err = readRows(cr, serverColumns)
+	case SectionLogs:
+		inv.Logs, err = readRows(cr, logColumns)
+	default:
+		return nil, fmt.Errorf("unknown section %q", section)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func readRows[T any](cr *csv.Reader, cols []column[T]) ([]T, error) {
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	byName := make(map[string]column[T], len(cols))
+	for _, c := range cols {
+		byName[c.name] = c
+	}
+	mapped := make([]column[T], len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		// spreadsheets may start the file with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate csv column %q", name)
+		}
+		seen[name] = true
+		mapped[i] = c
+	}
+	for _, c := range cols {
+		if c.required && !seen[c.name] {
+			return nil, fmt.Errorf("missing csv column %q", c.name)
+		}
+	}
+
+	var rows []T
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		var row T
+		for i, v := range record {
+			if err := mapped[i].set(&row, strings.TrimSpace(v)); err != nil {
+				return nil, fmt.Errorf("line %d, column %q: %w", line, mapped[i].name, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, csvListSep) {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func formatInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func formatFloat(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func parseFloat(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func formatBool(v bool) string {
+	if v {
+		return "true"
+	}
+	return ""
+}
+
+// parseBool parses booleans as written by spreadsheets, empty is false
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y", "x":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
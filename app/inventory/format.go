@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Write encodes the inventory in the format. CSV holds a single section of the inventory,
+// JSON and YAML hold all of them and ignore the section.
+func Write(w io.Writer, inv *Inventory, format Format, section Section) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(inv); err != nil {
+			return fmt.Errorf("failed to write json: %w", err)
+		}
+		return nil
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(inv); err != nil {
+			return fmt.Errorf("failed to write yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("failed to write yaml: %w", err)
+		}
+		return nil
+	case FormatCSV:
+		return writeCSV(w, inv, section)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Read decodes an inventory in the format, CSV is read as records of the section
+func Read(r io.Reader, format Format, section Section) (*Inventory, error) {
+	var inv Inventory
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&inv); err != nil {
+			return nil, fmt.Errorf("failed to read json: %w", err)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&inv); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read yaml: %w", err)
+		}
+	case FormatCSV:
+		return readCSV(r, section)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if inv.Version > FormatVersion {
+		return nil, fmt.Errorf("inventory version %d is newer than supported %d", inv.Version, FormatVersion)
+	}
+	return &inv, nil
+}
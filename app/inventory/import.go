@@ -0,0 +1,603 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// Counts holds numbers of records of a section by import outcome
+type Counts struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   int // records with issues
+}
+
+// Issue describes a record skipped on import
+type Issue struct {
+	Section Section
+	Record  string // natural key of the record, e.g. "hetzner_cloud/main/web-1"
+	Message string
+}
+
+// String returns the issue as "section record: message"
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Section, i.Record, i.Message)
+}
+
+// Report is the outcome of an import, counts of a dry run are what the import would do
+type Report struct {
+	DryRun    bool
+	Providers Counts
+	Accounts  Counts
+	Servers   Counts
+	Logs      Counts
+	Issues    []Issue
+}
+
+// ImportOptions controls the import
+type ImportOptions struct {
+	DryRun bool // validate and count changes without writing them
+}
+
+// accountKey is the natural key of an account
+type accountKey struct {
+	provider string // provider ident
+	name     string
+}
+
+func (k accountKey) String() string {
+	return k.provider + "/" + k.name
+}
+
+// logKey identifies a log entry of a server, logs already present are not imported again
+type logKey struct {
+	action      string
+	description string
+	time        time.Time
+}
+
+// importer resolves natural keys of imported records against the store. Records created by a dry run
+// are tracked with zero IDs, so references to them resolve as they would on a real import.
+type importer struct {
+	st     store.Store
+	dryRun bool
+	report *Report
+
+	providers map[string]*store.Provider        // by ident
+	accounts  map[accountKey]*store.Account     // by provider ident and name
+	servers   map[accountKey][]*store.Server    // by account, loaded on first use
+	logs      map[*store.Server]map[logKey]bool // present logs by server, loaded on first use
+}
+
+// Import upserts the inventory records into the store, matching them by natural keys: providers by
+// ident, accounts by provider and name, servers within the account by external ID, name or IP.
+// Updated records take all fields from the inventory except empty account API keys, which keep the
+// stored ones as keys may be redacted on export. Records which can't be imported are skipped and
+// reported as issues. The import is not atomic, records before a store error stay imported.
+func Import(ctx context.Context, st store.Store, inv *Inventory, opts ImportOptions) (*Report, error) {
+	imp := &importer{
+		st:       st,
+		dryRun:   opts.DryRun,
+		report:   &Report{DryRun: opts.DryRun},
+		accounts: make(map[accountKey]*store.Account),
+		servers:  make(map[accountKey][]*store.Server),
+		logs:     make(map[*store.Server]map[logKey]bool),
+	}
+	if err := imp.load(ctx); err != nil {
+		return nil, err
+	}
+
+	seenProviders := make(map[string]bool)
+	for _, p := range inv.Providers {
+		if seenProviders[p.Ident] {
+			imp.skip(&imp.report.Providers, SectionProviders, p.Ident, "duplicate provider in the file")
+			continue
+		}
+		seenProviders[p.Ident] = true
+		if err := imp.importProvider(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	seenAccounts := make(map[accountKey]bool)
+	for _, a := range inv.Accounts {
+		key := accountKey{provider: a.Provider, name: a.Name}
+		if seenAccounts[key] {
+			imp.skip(&imp.report.Accounts, SectionAccounts, key.String(), "duplicate account in the file")
+			continue
+		}
+		seenAccounts[key] = true
+		if err := imp.importAccount(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	imported := make(map[*store.Server]bool)
+	for _, s := range inv.Servers {
+		if err := imp.importServer(ctx, s, imported); err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range inv.Logs {
+		if err := imp.importLog(ctx, l); err != nil {
+			return nil, err
+		}
+	}
+	return imp.report, nil
+}
+
+// load reads providers and accounts present in the store
+func (imp *importer) load(ctx context.Context) error {
+	providers, err := imp.st.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	imp.providers = make(map[string]*store.Provider, len(providers))
+	idents := make(map[int64]string, len(providers))
+	for i := range providers {
+		imp.providers[providers[i].Ident] = &providers[i]
+		idents[providers[i].ID] = providers[i].Ident
+	}
+
+	accounts, err := imp.st.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range accounts {
+		imp.accounts[accountKey{provider: idents[accounts[i].ProviderID], name: accounts[i].Name}] = &accounts[i]
+	}
+	return nil
+}
+
+func (imp *importer) skip(counts *Counts, section Section, record, msg string) {
+	counts.Skipped++
+	imp.report.Issues = append(imp.report.Issues, Issue{Section: section, Record: record, Message: msg})
+}
+
+func (imp *importer) importProvider(ctx context.Context, rec Provider) error {
+	counts := &imp.report.Providers
+	ident := strings.TrimSpace(rec.Ident)
+	if ident == "" {
+		imp.skip(counts, SectionProviders, rec.Name, "empty ident")
+		return nil
+	}
+	name := strings.TrimSpace(rec.Name)
+	if name == "" {
+		name = ident
+	}
+
+	p, ok := imp.providers[ident]
+	if !ok {
+		p = &store.Provider{Ident: ident, Name: name, Description: rec.Description}
+		if !imp.dryRun {
+			err := imp.st.CreateProvider(ctx, p)
+			if errors.Is(err, store.ErrInTrash) {
+				imp.skip(counts, SectionProviders, ident, "provider is in the trash, restore it first")
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import provider %s: %w", ident, err)
+			}
+		}
+		imp.providers[ident] = p
+		counts.Created++
+		return nil
+	}
+
+	if p.Name == name && p.Description == rec.Description {
+		counts.Unchanged++
+		return nil
+	}
+	p.Name, p.Description = name, rec.Description
+	if !imp.dryRun {
+		if err := imp.st.UpdateProvider(ctx, p); err != nil {
+			return fmt.Errorf("failed to import provider %s: %w", ident, err)
+		}
+	}
+	counts.Updated++
+	return nil
+}
+
+func (imp *importer) importAccount(ctx context.Context, rec Account) error {
+	counts := &imp.report.Accounts
+	key := accountKey{provider: rec.Provider, name: rec.Name}
+	p, ok := imp.providers[rec.Provider]
+	switch {
+	case strings.TrimSpace(rec.Name) == "":
+		imp.skip(counts, SectionAccounts, key.String(), "empty name")
+		return nil
+	case !ok:
+		imp.skip(counts, SectionAccounts, key.String(), fmt.Sprintf("unknown provider %q", rec.Provider))
+		return nil
+	case rec.SyncInterval < 0:
+		imp.skip(counts, SectionAccounts, key.String(), "negative sync interval")
+		return nil
+	}
+
+	a, ok := imp.accounts[key]
+	if !ok {
+		a = &store.Account{ProviderID: p.ID, GroupName: rec.Group, Name: rec.Name, Login: rec.Login,
+			ApiKey: rec.APIKey, SyncInterval: rec.SyncInterval}
+		if !imp.dryRun {
+			err := imp.st.CreateAccount(ctx, a)
+			if errors.Is(err, store.ErrInTrash) {
+				imp.skip(counts, SectionAccounts, key.String(), "account is in the trash, restore it first")
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import account %s: %w", key, err)
+			}
+		}
+		imp.accounts[key] = a
+		counts.Created++
+		return nil
+	}
+
+	apiKey := a.ApiKey
+	if rec.APIKey != "" {
+		apiKey = rec.APIKey
+	}
+	if a.GroupName == rec.Group && a.Login == rec.Login && a.ApiKey == apiKey && a.SyncInterval == rec.SyncInterval {
+		counts.Unchanged++
+		return nil
+	}
+	a.GroupName, a.Login, a.ApiKey, a.SyncInterval = rec.Group, rec.Login, apiKey, rec.SyncInterval
+	if !imp.dryRun {
+		if err := imp.st.UpdateAccount(ctx, a); err != nil {
+			return fmt.Errorf("failed to import account %s: %w", key, err)
+		}
+	}
+	counts.Updated++
+	return nil
+}
+
+// accountServers returns servers of the account, loading them from the store on first use
+func (imp *importer) accountServers(ctx context.Context, key accountKey) ([]*store.Server, error) {
+	if servers, ok := imp.servers[key]; ok {
+		return servers, nil
+	}
+	var servers []*store.Server
+	if a := imp.accounts[key]; a != nil && a.ID != 0 {
+		list, err := imp.st.ListServersByAccount(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			servers = append(servers, &list[i])
+		}
+	}
+	imp.servers[key] = servers
+	return servers, nil
+}
+
+// matchServer finds the server of the account the record refers to, by external ID, name or IP.
+// Returns an error message if the record matches different servers.
+func matchServer(servers []*store.Server, rec Server) (*store.Server, string) {
+	var byExternal, byName, byIP *store.Server
+	for _, s := range servers {
+		if rec.ExternalID != "" && s.ExternalID == rec.ExternalID && byExternal == nil {
+			byExternal = s
+		}
+		if s.Name == rec.Name && byName == nil {
+			byName = s
+		}
+		if rec.IP != "" && s.IP == rec.IP && byIP == nil {
+			byIP = s
+		}
+	}
+
+	match, matchedBy := byExternal, "external ID"
+	for _, m := range []struct {
+		srv *store.Server
+		by  string
+	}{{byName, "name"}, {byIP, "IP"}} {
+		switch {
+		case m.srv == nil:
+		case match == nil:
+			match, matchedBy = m.srv, m.by
+		case match != m.srv:
+			return nil, fmt.Sprintf("%s matches server %q, but %s matches server %q", matchedBy, match.Name, m.by, m.srv.Name)
+		}
+	}
+	if match != nil && rec.ExternalID != "" && match.ExternalID != "" && match.ExternalID != rec.ExternalID {
+		return nil, fmt.Sprintf("matches server %q with external ID %s", match.Name, match.ExternalID)
+	}
+	return match, ""
+}
+
+func (imp *importer) importServer(ctx context.Context, rec Server, imported map[*store.Server]bool) error {
+	counts := &imp.report.Servers
+	key := accountKey{provider: rec.Provider, name: rec.Account}
+	record := key.String() + "/" + rec.Name
+	a, ok := imp.accounts[key]
+	switch {
+	case strings.TrimSpace(rec.Name) == "":
+		imp.skip(counts, SectionServers, record, "empty name")
+		return nil
+	case !ok:
+		imp.skip(counts, SectionServers, record, fmt.Sprintf("unknown account %s", key))
+		return nil
+	}
+
+	srv, tags, addrs, err := parseServer(rec)
+	if err != nil {
+		imp.skip(counts, SectionServers, record, err.Error())
+		return nil
+	}
+
+	servers, err := imp.accountServers(ctx, key)
+	if err != nil {
+		return err
+	}
+	existing, conflict := matchServer(servers, rec)
+	switch {
+	case conflict != "":
+		imp.skip(counts, SectionServers, record, conflict)
+		return nil
+	case existing != nil && imported[existing]:
+		imp.skip(counts, SectionServers, record, "duplicate server in the file")
+		return nil
+	}
+
+	srv.AccountID = a.ID
+	if srv.ExternalID != "" {
+		srv.ProviderIdent = rec.Provider
+	}
+
+	if existing == nil {
+		if !imp.dryRun {
+			if err := imp.st.CreateServer(ctx, srv); err != nil {
+				return fmt.Errorf("failed to import server %s: %w", record, err)
+			}
+			if err := imp.setServerDetails(ctx, srv.ID, tags, addrs); err != nil {
+				return fmt.Errorf("failed to import server %s: %w", record, err)
+			}
+		}
+		imp.servers[key] = append(imp.servers[key], srv)
+		imported[srv] = true
+		counts.Created++
+		return nil
+	}
+	imported[existing] = true
+
+	changed, err := imp.serverChanged(ctx, existing, srv, tags, addrs)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		counts.Unchanged++
+		return nil
+	}
+
+	// keep what the inventory doesn't carry
+	srv.ID, srv.LockedFields, srv.CreatedAt = existing.ID, existing.LockedFields, existing.CreatedAt
+	if srv.ExternalID == "" {
+		srv.ExternalID, srv.ProviderIdent = existing.ExternalID, existing.ProviderIdent
+	}
+	if !imp.dryRun {
+		if err := imp.st.UpdateServer(ctx, srv); err != nil {
+			return fmt.Errorf("failed to import server %s: %w", record, err)
+		}
+		if err := imp.setServerDetails(ctx, srv.ID, tags, addrs); err != nil {
+			return fmt.Errorf("failed to import server %s: %w", record, err)
+		}
+	}
+	*existing = *srv
+	counts.Updated++
+	return nil
+}
+
+// parseServer converts the record to a server with its user tags and addresses
+func parseServer(rec Server) (*store.Server, []store.ServerTag, []store.ServerAddress, error) {
+	srv := &store.Server{
+		Name:            rec.Name,
+		IP:              rec.IP,
+		Status:          enum.ServerStatusActive,
+		Location:        rec.Location,
+		Description:     rec.Description,
+		Responsible:     rec.Responsible,
+		ApproximateCost: rec.Cost,
+		Backups:         rec.Backups,
+		ExternalID:      rec.ExternalID,
+		CPUCores:        rec.CPUCores,
+		MemoryGB:        rec.MemoryGB,
+		DiskGB:          rec.DiskGB,
+		ServerType:      rec.ServerType,
+		OSFlavor:        rec.OSFlavor,
+		OSVersion:       rec.OSVersion,
+		Datacenter:      rec.Datacenter,
+	}
+	if rec.Status != "" {
+		status, err := enum.ParseServerStatus(rec.Status)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid status %q", rec.Status)
+		}
+		srv.Status = status
+	}
+	if rec.Cost < 0 {
+		return nil, nil, nil, fmt.Errorf("negative cost")
+	}
+
+	var err error
+	if srv.PaidUntil, err = parseDate(rec.PaidUntil); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid paid until date %q", rec.PaidUntil)
+	}
+	if srv.CancellationDate, err = parseDate(rec.CancellationDate); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid cancellation date %q", rec.CancellationDate)
+	}
+
+	tags := make([]store.ServerTag, 0, len(rec.Tags))
+	for _, s := range rec.Tags {
+		tag, err := store.ParseServerTag(s, enum.TagSourceUser)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	addrs := make([]store.ServerAddress, 0, len(rec.Addresses))
+	for _, s := range rec.Addresses {
+		addr, err := parseAddress(s)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return srv, tags, addrs, nil
+}
+
+// parseAddress parses "kind:address", e.g. "private:10.0.0.2", an address without kind is public
+func parseAddress(s string) (store.ServerAddress, error) {
+	if kindName, addr, ok := strings.Cut(s, ":"); ok {
+		if kind, err := enum.ParseAddressKind(kindName); err == nil {
+			return store.ParseServerAddress(addr, kind)
+		}
+	}
+	return store.ParseServerAddress(s, enum.AddressKindPublic)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, s)
+}
+
+// serverChanged returns true if the imported server differs from the stored one
+func (imp *importer) serverChanged(ctx context.Context, old, srv *store.Server, tags []store.ServerTag,
+	addrs []store.ServerAddress) (bool, error) {
+	if old.Name != srv.Name || old.IP != srv.IP || old.Status != srv.Status || old.Location != srv.Location ||
+		old.Description != srv.Description || old.Responsible != srv.Responsible ||
+		old.ApproximateCost != srv.ApproximateCost || old.Backups != srv.Backups ||
+		(srv.ExternalID != "" && old.ExternalID != srv.ExternalID) || old.CPUCores != srv.CPUCores ||
+		old.MemoryGB != srv.MemoryGB || old.DiskGB != srv.DiskGB || old.ServerType != srv.ServerType ||
+		old.OSFlavor != srv.OSFlavor || old.OSVersion != srv.OSVersion || old.Datacenter != srv.Datacenter ||
+		!old.PaidUntil.Equal(srv.PaidUntil) || !old.CancellationDate.Equal(srv.CancellationDate) {
+		return true, nil
+	}
+	if old.ID == 0 {
+		return false, nil // created by this dry run
+	}
+
+	oldTags, err := imp.st.ListServerTags(ctx, old.ID)
+	if err != nil {
+		return false, err
+	}
+	var userTags []string
+	for _, t := range oldTags {
+		if t.Source == enum.TagSourceUser {
+			userTags = append(userTags, t.String())
+		}
+	}
+	newTags := make([]string, 0, len(tags))
+	for _, t := range tags {
+		newTags = append(newTags, t.String())
+	}
+
+	oldAddrs, err := imp.st.ListServerAddresses(ctx, old.ID)
+	if err != nil {
+		return false, err
+	}
+	return !sameStrings(userTags, newTags) || !sameStrings(addressStrings(oldAddrs), addressStrings(addrs)), nil
+}
+
+func (imp *importer) setServerDetails(ctx context.Context, id int64, tags []store.ServerTag, addrs []store.ServerAddress) error {
+	if err := imp.st.SetServerTags(ctx, id, enum.TagSourceUser, tags); err != nil {
+		return err
+	}
+	return imp.st.SetServerAddresses(ctx, id, addrs)
+}
+
+func addressStrings(addrs []store.ServerAddress) []string {
+	res := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		res = append(res, a.Kind.String()+":"+a.String())
+	}
+	return res
+}
+
+// sameStrings returns true if both slices hold the same values in any order
+func sameStrings(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func (imp *importer) importLog(ctx context.Context, rec Log) error {
+	counts := &imp.report.Logs
+	key := accountKey{provider: rec.Provider, name: rec.Account}
+	record := key.String() + "/" + rec.Server
+
+	action, err := enum.ParseLogAction(rec.Action)
+	switch {
+	case err != nil:
+		imp.skip(counts, SectionLogs, record, fmt.Sprintf("invalid action %q", rec.Action))
+		return nil
+	case rec.Time.IsZero():
+		imp.skip(counts, SectionLogs, record, "empty time")
+		return nil
+	}
+
+	servers, err := imp.accountServers(ctx, key)
+	if err != nil {
+		return err
+	}
+	var srv *store.Server
+	for _, s := range servers {
+		if s.Name == rec.Server {
+			srv = s
+			break
+		}
+	}
+	if srv == nil {
+		imp.skip(counts, SectionLogs, record, "unknown server")
+		return nil
+	}
+
+	present, err := imp.serverLogs(ctx, srv)
+	if err != nil {
+		return err
+	}
+	lk := logKey{action: action.String(), description: rec.Description, time: rec.Time.UTC().Truncate(time.Microsecond)}
+	if present[lk] {
+		counts.Unchanged++
+		return nil
+	}
+	if !imp.dryRun {
+		l := &store.ServerLog{ServerID: srv.ID, Action: action, Description: rec.Description, CreatedAt: rec.Time}
+		if err := imp.st.CreateLog(ctx, l); err != nil {
+			return fmt.Errorf("failed to import log of %s: %w", record, err)
+		}
+	}
+	present[lk] = true
+	counts.Created++
+	return nil
+}
+
+// serverLogs returns logs of the server present in the store, loading them on first use
+func (imp *importer) serverLogs(ctx context.Context, srv *store.Server) (map[logKey]bool, error) {
+	if present, ok := imp.logs[srv]; ok {
+		return present, nil
+	}
+	present := make(map[logKey]bool)
+	if srv.ID != 0 {
+		logs, err := imp.st.ListLogsByServer(ctx, srv.ID, math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			present[logKey{action: l.Action.String(), description: l.Description,
+				time: l.CreatedAt.UTC().Truncate(time.Microsecond)}] = true
+		}
+	}
+	imp.logs[srv] = present
+	return present, nil
+}
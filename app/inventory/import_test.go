@@ -0,0 +1,213 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+var inventoryTime = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func TestImport_IntoEmptyStore(t *testing.T) {
+	ctx := context.Background()
+	inv, err := Export(ctx, newTestStore(t), ExportOptions{})
+	require.NoError(t, err)
+
+	dst := store.NewMemory()
+	report, err := Import(ctx, dst, inv, ImportOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, Counts{Created: 1, Unchanged: 5}, report.Providers, "default providers present")
+	assert.Equal(t, Counts{Created: 2}, report.Accounts)
+	assert.Equal(t, Counts{Created: 1}, report.Servers)
+	assert.Equal(t, Counts{Created: 2}, report.Logs)
+
+	got, err := Export(ctx, dst, ExportOptions{})
+	require.NoError(t, err)
+	got.ExportedAt = inv.ExportedAt
+	assert.Equal(t, inv, got, "imported store exports the same inventory")
+
+	// importing again changes nothing
+	report, err = Import(ctx, dst, inv, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Unchanged: 6}, report.Providers)
+	assert.Equal(t, Counts{Unchanged: 2}, report.Accounts)
+	assert.Equal(t, Counts{Unchanged: 1}, report.Servers)
+	assert.Equal(t, Counts{Unchanged: 2}, report.Logs)
+}
+
+func TestImport_Upsert(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+
+	inv := &Inventory{
+		Providers: []Provider{{Ident: "ovh", Name: "OVHcloud"}},
+		Accounts: []Account{
+			{Provider: "ovh", Group: "prod", Name: "main", Login: "admin"}, // redacted key
+			{Provider: "ovh", Name: "staging", APIKey: "new"},
+		},
+		Servers: []Server{
+			{Provider: "ovh", Account: "main", Name: "web-renamed", IP: "1.2.3.4", Cost: 50}, // matched by IP
+			{Provider: "ovh", Account: "staging", Name: "stage-1", Tags: []string{"env=stage"}},
+		},
+	}
+	report, err := Import(ctx, st, inv, ImportOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, Counts{Updated: 1}, report.Providers)
+	assert.Equal(t, Counts{Created: 1, Updated: 1}, report.Accounts)
+	assert.Equal(t, Counts{Created: 1, Updated: 1}, report.Servers)
+
+	accounts, err := st.ListAccountsWithProviders(ctx)
+	require.NoError(t, err)
+	var main *store.AccountWithProvider
+	for i := range accounts {
+		if accounts[i].Name == "main" {
+			main = &accounts[i]
+		}
+	}
+	require.NotNil(t, main)
+	assert.Equal(t, "OVHcloud", main.ProviderName)
+	assert.Equal(t, "admin", main.Login)
+	assert.Equal(t, "secret", main.ApiKey, "redacted key keeps the stored one")
+
+	servers, err := st.ListServersByAccount(ctx, main.ID)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "web-renamed", servers[0].Name)
+	assert.InDelta(t, 50, servers[0].ApproximateCost, 0.001)
+	assert.Equal(t, enum.ServerStatusActive, servers[0].Status)
+	tags, err := st.ListServerTags(ctx, servers[0].ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1, "user tags replaced, provider tags kept")
+	assert.Equal(t, enum.TagSourceProvider, tags[0].Source)
+}
+
+func TestImport_Issues(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	main := func(name, ip string) Server { return Server{Provider: "ovh", Account: "main", Name: name, IP: ip} }
+
+	inv := &Inventory{
+		Providers: []Provider{{Name: "no ident"}},
+		Accounts: []Account{
+			{Provider: "linode", Name: "main"},
+			{Provider: "ovh", Name: "extra"},
+			{Provider: "ovh", Name: "extra"},
+		},
+		Servers: []Server{
+			{Provider: "ovh", Account: "unknown", Name: "web-1"},
+			{Provider: "ovh", Account: "main", Name: "db", Status: "running"},
+			{Provider: "ovh", Account: "main", Name: "db", PaidUntil: "31.12.2026"},
+			{Provider: "ovh", Account: "main", Name: "db", Tags: []string{"bad key"}},
+			{Provider: "ovh", Account: "main", Name: "db", Addresses: []string{"public:not-an-ip"}},
+			main("new", "5.6.7.8"),
+			main("new", ""),
+			main("web-1", "5.6.7.8"), // IP of the new server
+		},
+		Logs: []Log{
+			{Provider: "ovh", Account: "main", Server: "gone", Action: "added", Time: inventoryTime},
+			{Provider: "ovh", Account: "main", Server: "web-1", Action: "rebooted", Time: inventoryTime},
+		},
+	}
+	report, err := Import(ctx, st, inv, ImportOptions{})
+	require.NoError(t, err)
+
+	var issues []string
+	for _, i := range report.Issues {
+		issues = append(issues, i.String())
+	}
+	assert.Equal(t, []string{
+		"providers no ident: empty ident",
+		`accounts linode/main: unknown provider "linode"`,
+		"accounts ovh/extra: duplicate account in the file",
+		"servers ovh/unknown/web-1: unknown account ovh/unknown",
+		`servers ovh/main/db: invalid status "running"`,
+		`servers ovh/main/db: invalid paid until date "31.12.2026"`,
+		`servers ovh/main/db: invalid tag "bad key": key can't contain spaces or commas`,
+		`servers ovh/main/db: invalid address "not-an-ip": ParseAddr("not-an-ip"): unable to parse IP`,
+		"servers ovh/main/new: duplicate server in the file",
+		`servers ovh/main/web-1: name matches server "web-1", but IP matches server "new"`,
+		"logs ovh/main/gone: unknown server",
+		`logs ovh/main/web-1: invalid action "rebooted"`,
+	}, issues)
+	assert.Equal(t, Counts{Created: 1, Skipped: 2}, report.Accounts)
+	assert.Equal(t, Counts{Created: 1, Skipped: 7}, report.Servers)
+}
+
+func TestImport_DryRun(t *testing.T) {
+	ctx := context.Background()
+	inv, err := Export(ctx, newTestStore(t), ExportOptions{})
+	require.NoError(t, err)
+
+	dst := store.NewMemory()
+	report, err := Import(ctx, dst, inv, ImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Empty(t, report.Issues, "references to records created by the dry run resolve")
+	assert.Equal(t, Counts{Created: 1, Unchanged: 5}, report.Providers)
+	assert.Equal(t, Counts{Created: 2}, report.Accounts)
+	assert.Equal(t, Counts{Created: 1}, report.Servers)
+	assert.Equal(t, Counts{Created: 2}, report.Logs)
+
+	accounts, err := dst.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, accounts, "dry run writes nothing")
+}
+
+func TestImport_SpreadsheetCSV(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+
+	// columns in any order, a subset of them, booleans as written by spreadsheets
+	data := "\ufeffName,Provider,Account,IP,Cost,Backups,Tags\n" +
+		"db-1,ovh,main,5.6.7.8,19.99,yes,env=prod;role=db\n" +
+		"db-2,ovh,main,,9.5,,\n"
+	inv, err := Read(strings.NewReader(data), FormatCSV, SectionServers)
+	require.NoError(t, err)
+	report, err := Import(ctx, st, inv, ImportOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, Counts{Created: 2}, report.Servers)
+
+	servers, err := st.ListServersFiltered(ctx, store.ServerFilter{Search: "5.6.7.8"})
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	srv := servers[0]
+	assert.Equal(t, "db-1", srv.Name)
+	assert.Equal(t, "main", srv.AccountName)
+	assert.True(t, srv.Backups)
+	tags, err := st.ListServerTags(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Len(t, tags, 2)
+}
+
+func TestImport_AccountInTrash(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	accounts, err := st.ListAccounts(ctx)
+	require.NoError(t, err)
+	for _, a := range accounts {
+		if a.Name == "main" {
+			require.NoError(t, st.DeleteAccount(ctx, a.ID))
+		}
+	}
+
+	inv := &Inventory{
+		Accounts: []Account{{Provider: "ovh", Name: "main"}},
+		Servers:  []Server{{Provider: "ovh", Account: "main", Name: "web-1"}},
+	}
+	report, err := Import(ctx, st, inv, ImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 2)
+	assert.Equal(t, "accounts ovh/main: account is in the trash, restore it first", report.Issues[0].String())
+	assert.Equal(t, Counts{Skipped: 1}, report.Accounts)
+	assert.Equal(t, Counts{Skipped: 1}, report.Servers)
+}
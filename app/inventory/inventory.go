@@ -0,0 +1,247 @@
+// Package inventory exports providers, accounts, servers and logs to JSON, YAML and CSV files
+// and imports them back, matching records by their natural keys
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// FormatVersion is the version of the inventory file layout
+const FormatVersion = 1
+
+// dateLayout is the layout of server contract dates
+const dateLayout = "2006-01-02"
+
+// Inventory is the exported content of servers-manager. Records reference each other by natural
+// keys instead of database IDs: providers by ident, accounts by provider ident and name,
+// servers by account and name.
+type Inventory struct {
+	Version    int        `json:"version" yaml:"version"`
+	ExportedAt time.Time  `json:"exported_at" yaml:"exported_at"`
+	Providers  []Provider `json:"providers,omitempty" yaml:"providers,omitempty"`
+	Accounts   []Account  `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+	Servers    []Server   `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Logs       []Log      `json:"logs,omitempty" yaml:"logs,omitempty"`
+}
+
+// Provider is an exported provider
+type Provider struct {
+	Ident       string `json:"ident" yaml:"ident"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Account is an exported account, APIKey is empty if keys are redacted
+type Account struct {
+	Provider     string `json:"provider" yaml:"provider"` // provider ident
+	Group        string `json:"group,omitempty" yaml:"group,omitempty"`
+	Name         string `json:"name" yaml:"name"`
+	Login        string `json:"login,omitempty" yaml:"login,omitempty"`
+	APIKey       string `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	SyncInterval int    `json:"sync_interval,omitempty" yaml:"sync_interval,omitempty"` // minutes, 0 uses default
+}
+
+// Server is an exported server with its user tags and addresses
+type Server struct {
+	Provider         string   `json:"provider" yaml:"provider"` // provider ident of the account
+	Account          string   `json:"account" yaml:"account"`   // account name
+	Name             string   `json:"name" yaml:"name"`
+	IP               string   `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Status           string   `json:"status,omitempty" yaml:"status,omitempty"` // active if empty
+	Location         string   `json:"location,omitempty" yaml:"location,omitempty"`
+	Description      string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Responsible      string   `json:"responsible,omitempty" yaml:"responsible,omitempty"`
+	Cost             float64  `json:"cost,omitempty" yaml:"cost,omitempty"` // approximate monthly cost
+	Backups          bool     `json:"backups,omitempty" yaml:"backups,omitempty"`
+	ExternalID       string   `json:"external_id,omitempty" yaml:"external_id,omitempty"`
+	CPUCores         int      `json:"cpu_cores,omitempty" yaml:"cpu_cores,omitempty"`
+	MemoryGB         float64  `json:"memory_gb,omitempty" yaml:"memory_gb,omitempty"`
+	DiskGB           int      `json:"disk_gb,omitempty" yaml:"disk_gb,omitempty"`
+	ServerType       string   `json:"server_type,omitempty" yaml:"server_type,omitempty"`
+	OSFlavor         string   `json:"os_flavor,omitempty" yaml:"os_flavor,omitempty"`
+	OSVersion        string   `json:"os_version,omitempty" yaml:"os_version,omitempty"`
+	Datacenter       string   `json:"datacenter,omitempty" yaml:"datacenter,omitempty"`
+	PaidUntil        string   `json:"paid_until,omitempty" yaml:"paid_until,omitempty"`               // YYYY-MM-DD
+	CancellationDate string   `json:"cancellation_date,omitempty" yaml:"cancellation_date,omitempty"` // YYYY-MM-DD
+	Tags             []string `json:"tags,omitempty" yaml:"tags,omitempty"`                           // user tags as key=value
+	Addresses        []string `json:"addresses,omitempty" yaml:"addresses,omitempty"`                 // kind:address, e.g. public:1.2.3.4
+}
+
+// Log is an exported server log entry
+type Log struct {
+	Provider    string    `json:"provider" yaml:"provider"` // provider ident of the server account
+	Account     string    `json:"account" yaml:"account"`   // server account name
+	Server      string    `json:"server" yaml:"server"`     // server name
+	Action      string    `json:"action" yaml:"action"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+	Time        time.Time `json:"time" yaml:"time"`
+}
+
+// Format is the encoding of an inventory file
+type Format string
+
+// supported formats
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatCSV  Format = "csv"
+)
+
+// ParseFormat parses a format name, "yml" is accepted for YAML
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected json, yaml or csv", s)
+}
+
+// FormatOf returns the format of the file by its extension
+func FormatOf(path string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// Section is a kind of records, CSV files hold records of a single section
+type Section string
+
+// inventory sections
+const (
+	SectionProviders Section = "providers"
+	SectionAccounts  Section = "accounts"
+	SectionServers   Section = "servers"
+	SectionLogs      Section = "logs"
+)
+
+// AllSections returns all inventory sections in import order
+func AllSections() []Section {
+	return []Section{SectionProviders, SectionAccounts, SectionServers, SectionLogs}
+}
+
+// ParseSection parses a section name
+func ParseSection(s string) (Section, error) {
+	for _, sec := range AllSections() {
+		if strings.EqualFold(strings.TrimSpace(s), string(sec)) {
+			return sec, nil
+		}
+	}
+	return "", fmt.Errorf("unknown section %q, expected providers, accounts, servers or logs", s)
+}
+
+// ExportOptions controls what is exported
+type ExportOptions struct {
+	RedactKeys bool // leave account API keys out
+}
+
+// Export reads all providers, accounts, servers and logs from the store
+func Export(ctx context.Context, st store.Store, opts ExportOptions) (*Inventory, error) {
+	inv := &Inventory{Version: FormatVersion, ExportedAt: time.Now().UTC()}
+
+	providers, err := st.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idents := make(map[int64]string, len(providers))
+	for _, p := range providers {
+		idents[p.ID] = p.Ident
+		inv.Providers = append(inv.Providers, Provider{Ident: p.Ident, Name: p.Name, Description: p.Description})
+	}
+
+	accounts, err := st.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		acc := Account{Provider: idents[a.ProviderID], Group: a.GroupName, Name: a.Name, Login: a.Login,
+			SyncInterval: a.SyncInterval}
+		if !opts.RedactKeys {
+			acc.APIKey = a.ApiKey
+		}
+		inv.Accounts = append(inv.Accounts, acc)
+	}
+
+	servers, err := st.ListServersWithAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := st.ListAllServerTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type serverKey struct{ provider, account, name string }
+	keys := make(map[int64]serverKey, len(servers))
+	for _, s := range servers {
+		srv := exportServer(s, idents[s.ProviderID], tags[s.ID])
+		addrs, err := st.ListServerAddresses(ctx, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			srv.Addresses = append(srv.Addresses, a.Kind.String()+":"+a.String())
+		}
+		keys[s.ID] = serverKey{provider: srv.Provider, account: srv.Account, name: srv.Name}
+		inv.Servers = append(inv.Servers, srv)
+	}
+
+	logs, err := st.ListLogs(ctx, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	// oldest first, so an import recreates them in the original order
+	for i := len(logs) - 1; i >= 0; i-- {
+		l := logs[i]
+		key := keys[l.ServerID]
+		inv.Logs = append(inv.Logs, Log{Provider: key.provider, Account: key.account, Server: key.name,
+			Action: l.Action.String(), Description: l.Description, Time: l.CreatedAt.UTC()})
+	}
+
+	return inv, nil
+}
+
+// exportServer converts the server with its tags, only user tags are exported as provider
+// tags are restored by sync
+func exportServer(s store.ServerWithAccount, providerIdent string, tags []store.ServerTag) Server {
+	srv := Server{
+		Provider:    providerIdent,
+		Account:     s.AccountName,
+		Name:        s.Name,
+		IP:          s.IP,
+		Status:      s.Status.String(),
+		Location:    s.Location,
+		Description: s.Description,
+		Responsible: s.Responsible,
+		Cost:        s.ApproximateCost,
+		Backups:     s.Backups,
+		ExternalID:  s.ExternalID,
+		CPUCores:    s.CPUCores,
+		MemoryGB:    s.MemoryGB,
+		DiskGB:      s.DiskGB,
+		ServerType:  s.ServerType,
+		OSFlavor:    s.OSFlavor,
+		OSVersion:   s.OSVersion,
+		Datacenter:  s.Datacenter,
+	}
+	if !s.PaidUntil.IsZero() {
+		srv.PaidUntil = s.PaidUntil.Format(dateLayout)
+	}
+	if !s.CancellationDate.IsZero() {
+		srv.CancellationDate = s.CancellationDate.Format(dateLayout)
+	}
+	for _, t := range tags {
+		if t.Source == enum.TagSourceUser {
+			srv.Tags = append(srv.Tags, t.String())
+		}
+	}
+	return srv
+}
@@ -0,0 +1,145 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// newTestStore returns a store with an account of a custom provider, a server with tags,
+// addresses and logs, and a Hetzner Cloud account without servers
+func newTestStore(t *testing.T) *store.Memory {
+	ctx := context.Background()
+	st := store.NewMemory()
+
+	p := &store.Provider{Ident: "ovh", Name: "OVH", Description: "dedicated"}
+	require.NoError(t, st.CreateProvider(ctx, p))
+	acc := &store.Account{ProviderID: p.ID, GroupName: "prod", Name: "main", Login: "ops", ApiKey: "secret"}
+	require.NoError(t, st.CreateAccount(ctx, acc))
+
+	srv := &store.Server{AccountID: acc.ID, Name: "web-1", IP: "1.2.3.4", Status: enum.ServerStatusPaused,
+		Location: "gra", ApproximateCost: 42.5, Backups: true, CPUCores: 8, MemoryGB: 32, DiskGB: 480,
+		PaidUntil: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, st.CreateServer(ctx, srv))
+	require.NoError(t, st.SetServerTags(ctx, srv.ID, enum.TagSourceUser, []store.ServerTag{{Key: "env", Value: "prod"}}))
+	require.NoError(t, st.SetServerTags(ctx, srv.ID, enum.TagSourceProvider, []store.ServerTag{{Key: "synced"}}))
+	require.NoError(t, st.SetServerAddresses(ctx, srv.ID, []store.ServerAddress{
+		{Address: "10.0.0.2", Family: 4, Kind: enum.AddressKindPrivate},
+		{Address: "2a01:4f8::", Family: 6, Kind: enum.AddressKindSubnet, Prefix: 64},
+	}))
+	for i, action := range []enum.LogAction{enum.LogActionAdded, enum.LogActionPaused} {
+		require.NoError(t, st.CreateLog(ctx, &store.ServerLog{ServerID: srv.ID, Action: action,
+			Description: action.String(), CreatedAt: time.Date(2025, 1, 1+i, 10, 0, 0, 0, time.UTC)}))
+	}
+
+	providers, err := st.ListProviders(ctx)
+	require.NoError(t, err)
+	for _, pr := range providers {
+		if pr.Ident == "hetzner_cloud" {
+			require.NoError(t, st.CreateAccount(ctx, &store.Account{ProviderID: pr.ID, Name: "cloud", ApiKey: "token"}))
+		}
+	}
+	return st
+}
+
+func TestExport(t *testing.T) {
+	inv, err := Export(context.Background(), newTestStore(t), ExportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatVersion, inv.Version)
+	assert.Len(t, inv.Providers, 6, "default providers and the custom one")
+	require.Len(t, inv.Accounts, 2)
+	assert.Contains(t, inv.Accounts, Account{Provider: "ovh", Group: "prod", Name: "main", Login: "ops", APIKey: "secret"})
+
+	require.Len(t, inv.Servers, 1)
+	assert.Equal(t, Server{Provider: "ovh", Account: "main", Name: "web-1", IP: "1.2.3.4", Status: "paused",
+		Location: "gra", Cost: 42.5, Backups: true, CPUCores: 8, MemoryGB: 32, DiskGB: 480, PaidUntil: "2026-12-31",
+		Tags: []string{"env=prod"}, Addresses: []string{"subnet:2a01:4f8::/64", "private:10.0.0.2"}}, inv.Servers[0])
+
+	require.Len(t, inv.Logs, 2)
+	assert.Equal(t, Log{Provider: "ovh", Account: "main", Server: "web-1", Action: "added", Description: "added",
+		Time: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)}, inv.Logs[0], "oldest first")
+
+	inv, err = Export(context.Background(), newTestStore(t), ExportOptions{RedactKeys: true})
+	require.NoError(t, err)
+	for _, a := range inv.Accounts {
+		assert.Empty(t, a.APIKey)
+	}
+}
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	inv, err := Export(context.Background(), newTestStore(t), ExportOptions{})
+	require.NoError(t, err)
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, inv, format, ""))
+			got, err := Read(&buf, format, "")
+			require.NoError(t, err)
+			assert.True(t, inv.ExportedAt.Equal(got.ExportedAt))
+			got.ExportedAt = inv.ExportedAt
+			assert.Equal(t, inv, got)
+		})
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		for _, section := range AllSections() {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, inv, FormatCSV, section))
+			got, err := Read(&buf, FormatCSV, section)
+			require.NoError(t, err, section)
+			switch section {
+			case SectionProviders:
+				assert.Equal(t, inv.Providers, got.Providers)
+			case SectionAccounts:
+				assert.Equal(t, inv.Accounts, got.Accounts)
+			case SectionServers:
+				assert.Equal(t, inv.Servers, got.Servers)
+			case SectionLogs:
+				assert.Equal(t, inv.Logs, got.Logs)
+			}
+		}
+	})
+}
+
+func TestRead_Errors(t *testing.T) {
+	tbl := []struct {
+		name    string
+		format  Format
+		section Section
+		data    string
+		err     string
+	}{
+		{"unknown json field", FormatJSON, "", `{"version":1,"hosts":[]}`, "unknown field"},
+		{"newer version", FormatYAML, "", "version: 2\n", "newer than supported"},
+		{"unknown csv column", FormatCSV, SectionServers, "provider,account,name,colour\n", `unknown csv column "colour"`},
+		{"missing csv column", FormatCSV, SectionServers, "provider,name\n", `missing csv column "account"`},
+		{"invalid csv value", FormatCSV, SectionServers, "provider,account,name,cost\novh,main,web,cheap\n",
+			`line 2, column "cost"`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.data), tt.format, tt.section)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{"a.json": FormatJSON, "b.YAML": FormatYAML, "c.yml": FormatYAML, "d.csv": FormatCSV} {
+		got, err := FormatOf(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, path)
+	}
+	_, err := FormatOf("servers.xlsx")
+	require.Error(t, err)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,15 +12,43 @@ import (
 	log "github.com/go-pkgz/lgr"
 	"github.com/jessevdk/go-flags"
 
+	"github.com/nilBora/servers-manager/app/inventory"
 	"github.com/nilBora/servers-manager/app/server"
 	"github.com/nilBora/servers-manager/app/store"
 )
 
 // opts holds command line options
 var opts struct {
-	DB      string `long:"db" env:"DB" default:"servers.db" description:"database file path"`
+	DB      string `long:"db" env:"DB" default:"servers.db" description:"SQLite database file path or postgres:// URL"`
 	Address string `long:"address" env:"ADDRESS" default:":8080" description:"server address"`
 	Debug   bool   `long:"debug" env:"DEBUG" description:"enable debug mode"`
+
+	PendingMigrations bool   `long:"pending-migrations" description:"print pending database migrations and exit"`
+	CopyTo            string `long:"copy-to" description:"copy the database to an empty one at the postgres:// URL or SQLite path and exit"`
+	Restore           string `long:"restore" description:"replace the SQLite database with the backup file and exit, the server must be stopped"`
+
+	EncryptionKey     string `long:"encryption-key" env:"ENCRYPTION_KEY" description:"base64 or hex encoded 32 bytes key account credentials are encrypted with"`
+	EncryptionKeyFile string `long:"encryption-key-file" env:"ENCRYPTION_KEY_FILE" description:"file with the key account credentials are encrypted with"`
+	RotateKeyFile     string `long:"rotate-encryption-key" description:"re-encrypt account credentials with the key from the file and exit"`
+
+	AWSRegions   []string      `long:"aws-region" env:"AWS_REGIONS" env-delim:"," description:"AWS region to sync EC2 instances from, all common regions if not set"`
+	SyncInterval time.Duration `long:"sync-interval" env:"SYNC_INTERVAL" default:"1h" description:"default background sync interval, 0 to disable"`
+	SyncWorkers  int           `long:"sync-workers" env:"SYNC_WORKERS" default:"4" description:"max number of accounts synced concurrently"`
+
+	MetricsInterval time.Duration `long:"metrics-interval" env:"METRICS_INTERVAL" default:"1h" description:"server metrics collection interval, 0 to disable"`
+	NoScheduler     bool          `long:"no-scheduler" env:"NO_SCHEDULER" description:"don't run background sync and metrics collection, set on all replicas sharing a postgres database but one"`
+
+	Export           string `long:"export" description:"export providers, accounts, servers and logs to the .json, .yaml or .csv file and exit"`
+	ExportRedactKeys bool   `long:"export-redact-keys" description:"leave account API keys out of the export"`
+	Import           string `long:"import" description:"import providers, accounts, servers and logs from the .json, .yaml or .csv file and exit"`
+	ImportDryRun     bool   `long:"import-dry-run" description:"validate the import and report changes without writing them"`
+	CSVSection       string `long:"csv-section" default:"servers" choice:"providers" choice:"accounts" choice:"servers" choice:"logs" description:"section of the inventory in CSV files"`
+
+	BackupDir      string        `long:"backup-dir" env:"BACKUP_DIR" description:"directory database snapshots are written to, snapshots are disabled if not set"`
+	BackupInterval time.Duration `long:"backup-interval" env:"BACKUP_INTERVAL" default:"24h" description:"database snapshot interval"`
+	BackupKeep     int           `long:"backup-keep" env:"BACKUP_KEEP" default:"7" description:"number of most recent snapshots kept, 0 to keep all"`
+
+	TrashRetention time.Duration `long:"trash-retention" env:"TRASH_RETENTION" default:"720h" description:"how long deleted providers, accounts and servers are kept in the trash, 0 to keep them forever"`
 }
 
 func main() {
@@ -27,9 +57,61 @@ func main() {
 	}
 
 	setupLog(opts.Debug)
+
+	if opts.PendingMigrations {
+		if err := printPendingMigrations(opts.DB); err != nil {
+			log.Fatalf("[ERROR] failed to check migrations: %v", err)
+		}
+		return
+	}
+
+	if opts.Restore != "" {
+		if err := restoreDatabase(opts.Restore, opts.DB); err != nil {
+			log.Fatalf("[ERROR] failed to restore database: %v", err)
+		}
+		return
+	}
+
+	key, err := loadEncryptionKey(opts.EncryptionKey, opts.EncryptionKeyFile)
+	if err != nil {
+		log.Fatalf("[ERROR] invalid encryption key: %v", err)
+	}
+	var storeOpts []store.Option
+	if key != nil {
+		storeOpts = append(storeOpts, store.WithEncryptionKey(key))
+	}
+
+	if opts.RotateKeyFile != "" {
+		if err := rotateEncryptionKey(opts.DB, opts.RotateKeyFile, storeOpts); err != nil {
+			log.Fatalf("[ERROR] failed to rotate encryption key: %v", err)
+		}
+		return
+	}
+
+	if opts.CopyTo != "" {
+		if err := copyDatabase(opts.DB, opts.CopyTo, storeOpts); err != nil {
+			log.Fatalf("[ERROR] failed to copy database: %v", err)
+		}
+		return
+	}
+
+	if opts.Export != "" {
+		if err := exportInventory(opts.DB, opts.Export, storeOpts); err != nil {
+			log.Fatalf("[ERROR] failed to export: %v", err)
+		}
+		return
+	}
+
+	if opts.Import != "" {
+		if err := importInventory(opts.DB, opts.Import, storeOpts); err != nil {
+			log.Fatalf("[ERROR] failed to import: %v", err)
+		}
+		return
+	}
+
 	log.Printf("[INFO] servers-manager starting")
 	// initialize store
-	st, err := store.New(opts.DB)
+	st, err := store.New(opts.DB, storeOpts...)
 	if err != nil {
 		log.Fatalf("[ERROR] failed to initialize store: %v", err)
 	}
@@ -42,6 +124,15 @@ func main() {
 		WriteTimeout:    30 * time.Second,
 		IdleTimeout:     60 * time.Second,
 		ShutdownTimeout: 10 * time.Second,
+		AWSRegions:      opts.AWSRegions,
+		SyncInterval:    opts.SyncInterval,
+		SyncWorkers:     opts.SyncWorkers,
+		MetricsInterval: opts.MetricsInterval,
+		BackupDir:       opts.BackupDir,
+		BackupInterval:  opts.BackupInterval,
+		BackupKeep:      opts.BackupKeep,
+		TrashRetention:  opts.TrashRetention,
+		NoScheduler:     opts.NoScheduler,
 	})
 	if err != nil {
 		log.Fatalf("[ERROR] failed to create server: %v", err)
@@ -65,6 +156,172 @@ func main() {
 	log.Printf("[INFO] servers-manager stopped")
 }
 
+// printPendingMigrations prints migrations which will be applied to the database on start
+func printPendingMigrations(dbPath string) error {
+	pending, err := store.PendingMigrations(dbPath)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Printf("no pending migrations for %s\n", dbPath)
+		return nil
+	}
+	fmt.Printf("%d pending migrations for %s:\n", len(pending), dbPath)
+	for _, m := range pending {
+		fmt.Printf("  %s\n", m)
+	}
+	return nil
+}
+
+// loadEncryptionKey returns the key set directly or in the file, nil if neither is set
+func loadEncryptionKey(value, file string) ([]byte, error) {
+	switch {
+	case value != "" && file != "":
+		return nil, errors.New("both key and key file are set")
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		return store.ParseEncryptionKey(string(data))
+	case value != "":
+		return store.ParseEncryptionKey(value)
+	}
+	return nil, nil
+}
+
+// rotateEncryptionKey re-encrypts account credentials with the key from the file. The server
+// must be stopped and started with the new key afterwards.
+func rotateEncryptionKey(dbPath, keyFile string, storeOpts []store.Option) error {
+	newKey, err := loadEncryptionKey("", keyFile)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.New(dbPath, storeOpts...)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	n, err := st.RotateEncryptionKey(context.Background(), newKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("re-encrypted credentials of %d accounts, start the server with the new key\n", n)
+	return nil
+}
+
+// copyDatabase copies all data of the source database into the empty target one, both are migrated
+// to the latest schema first. Credentials are copied encrypted, the target is used with the same key.
+func copyDatabase(srcDSN, dstDSN string, storeOpts []store.Option) error {
+	src, err := store.New(srcDSN, storeOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := store.New(dstDSN, storeOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to open target: %w", err)
+	}
+	defer dst.Close()
+
+	n, err := store.CopyDatabase(context.Background(), src, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("copied %d rows, start the server with --db pointing to the target\n", n)
+	return nil
+}
+
+// restoreDatabase replaces the database with the backup after checking its integrity and schema version
+func restoreDatabase(backupPath, dbPath string) error {
+	version, err := store.RestoreBackup(backupPath, dbPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %s from %s, schema version %d\n", dbPath, backupPath, version)
+	return nil
+}
+
+// exportInventory writes providers, accounts, servers and logs to the file in the format of its extension
+func exportInventory(dsn, path string, storeOpts []store.Option) error {
+	format, err := inventory.FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.New(dsn, storeOpts...)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	inv, err := inventory.Export(context.Background(), st, inventory.ExportOptions{RedactKeys: opts.ExportRedactKeys})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path) //nolint:gosec // path is set by the operator
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := inventory.Write(f, inv, format, inventory.Section(opts.CSVSection)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("exported %d providers, %d accounts, %d servers and %d logs to %s\n",
+		len(inv.Providers), len(inv.Accounts), len(inv.Servers), len(inv.Logs), path)
+	return nil
+}
+
+// importInventory upserts providers, accounts, servers and logs from the file and prints the report
+func importInventory(dsn, path string, storeOpts []store.Option) error {
+	format, err := inventory.FormatOf(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path) //nolint:gosec // path is set by the operator
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	inv, err := inventory.Read(f, format, inventory.Section(opts.CSVSection))
+	if err != nil {
+		return err
+	}
+
+	st, err := store.New(dsn, storeOpts...)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	report, err := inventory.Import(context.Background(), st, inv, inventory.ImportOptions{DryRun: opts.ImportDryRun})
+	if err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		fmt.Printf("dry run of %s, nothing written\n", path)
+	}
+	for _, c := range []struct {
+		name   string
+		counts inventory.Counts
+	}{{"providers", report.Providers}, {"accounts", report.Accounts}, {"servers", report.Servers}, {"logs", report.Logs}} {
+		fmt.Printf("%-10s %d created, %d updated, %d unchanged, %d skipped\n", c.name,
+			c.counts.Created, c.counts.Updated, c.counts.Unchanged, c.counts.Skipped)
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	return nil
+}
+
 func setupLog(debug bool) {
 	if debug {
 		log.Setup(log.Debug, log.CallerFile, log.CallerFunc, log.Msec, log.LevelBraces)
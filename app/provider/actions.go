@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// actionTimeout bounds a single server action, including waiting for its completion
+const actionTimeout = 15 * time.Minute
+
+// ErrActionUnsupported is returned when the provider can't run the action on the server
+var ErrActionUnsupported = errors.New("action not supported")
+
+// ErrActionInProgress is returned when another action is already running on the server
+var ErrActionInProgress = errors.New("action already in progress")
+
+// ErrEngineClosed is returned when starting an action after the engine is closed
+var ErrEngineClosed = errors.New("server is shutting down")
+
+// actionNames are display names of server actions, used in server logs
+var actionNames = map[enum.ServerAction]string{
+	enum.ServerActionPowerOn:        "Power on",
+	enum.ServerActionShutdown:       "Shutdown",
+	enum.ServerActionReboot:         "Reboot",
+	enum.ServerActionReset:          "Reset",
+	enum.ServerActionEnableBackups:  "Enable backups",
+	enum.ServerActionDisableBackups: "Disable backups",
+	enum.ServerActionCreateSnapshot: "Create snapshot",
+}
+
+// ActionName returns the display name of the server action
+func ActionName(action enum.ServerAction) string {
+	if name, ok := actionNames[action]; ok {
+		return name
+	}
+	return action.String()
+}
+
+// ServerActions returns actions which can be run on the server at the provider of the account,
+// nil if the server is not synced from a provider supporting actions
+func (e *Engine) ServerActions(acc *store.AccountWithProvider, srv *store.Server) []enum.ServerAction {
+	actioner, err := e.actioner(acc, srv)
+	if err != nil {
+		return nil
+	}
+	return actioner.Actions()
+}
+
+// RunningAction returns the action currently running on the server, false if none
+func (e *Engine) RunningAction(serverID int64) (enum.ServerAction, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	action, ok := e.actions[serverID]
+	return action, ok
+}
+
+// StartAction starts the action on the server at its provider in background and returns immediately.
+// The outcome is recorded in the server log. Returns ErrActionUnsupported if the provider can't run
+// the action, ErrActionInProgress if another action is running on the server and ErrEngineClosed
+// after Close. Running actions are canceled by Close.
+func (e *Engine) StartAction(acc *store.AccountWithProvider, srv *store.Server, action enum.ServerAction) error {
+	actioner, err := e.actioner(acc, srv)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(actioner.Actions(), action) {
+		return fmt.Errorf("%w: %s", ErrActionUnsupported, ActionName(action))
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrEngineClosed
+	}
+	if running, ok := e.actions[srv.ID]; ok {
+		e.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrActionInProgress, ActionName(running))
+	}
+	e.actions[srv.ID] = action
+	e.actionsWg.Add(1)
+	e.mu.Unlock()
+
+	go func() {
+		defer e.actionsWg.Done()
+		defer func() {
+			e.mu.Lock()
+			delete(e.actions, srv.ID)
+			e.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(e.ctx, actionTimeout)
+		defer cancel()
+		_ = e.runAction(ctx, actioner, srv, action)
+	}()
+	return nil
+}
+
+// runAction runs the action and waits for its completion, records the outcome in the server log
+// and applies the expected state change to the stored server
+func (e *Engine) runAction(ctx context.Context, actioner Actioner, srv *store.Server, action enum.ServerAction) error {
+	log.Printf("[INFO] running %s on server %s", action, srv.Name)
+	err := actioner.RunAction(ctx, srv.ExternalID, action)
+
+	// the action context may be expired, the outcome is recorded anyway
+	storeCtx := context.WithoutCancel(ctx)
+	entry := &store.ServerLog{ServerID: srv.ID, Action: enum.LogActionExecuted}
+	if err != nil {
+		log.Printf("[WARN] %s on server %s failed: %v", action, srv.Name, err)
+		entry.Description = fmt.Sprintf("%s failed: %v", ActionName(action), err)
+	} else {
+		log.Printf("[INFO] %s on server %s completed", action, srv.Name)
+		entry.Description = ActionName(action) + " completed"
+	}
+	if logErr := e.store.CreateLog(storeCtx, entry); logErr != nil {
+		log.Printf("[WARN] failed to log %s on server %s: %v", action, srv.Name, logErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if updErr := e.applyActionState(storeCtx, srv.ID, action); updErr != nil {
+		log.Printf("[WARN] failed to update server %s after %s: %v", srv.Name, action, updErr)
+	}
+	return nil
+}
+
+// applyActionState updates the stored server to the state the completed action results in,
+// so it is shown before the next sync
+func (e *Engine) applyActionState(ctx context.Context, serverID int64, action enum.ServerAction) error {
+	switch action {
+	case enum.ServerActionPowerOn:
+		return e.store.UpdateServerStatus(ctx, serverID, enum.ServerStatusActive)
+	case enum.ServerActionShutdown:
+		return e.store.UpdateServerStatus(ctx, serverID, enum.ServerStatusPaused)
+	case enum.ServerActionEnableBackups, enum.ServerActionDisableBackups:
+		srv, err := e.store.GetServer(ctx, serverID)
+		if err != nil {
+			return err
+		}
+		srv.Backups = action == enum.ServerActionEnableBackups
+		return e.store.UpdateServer(ctx, srv)
+	}
+	return nil
+}
+
+// actioner returns the actioner for servers of the account, ErrActionUnsupported if the server
+// is not synced from the account provider or the provider doesn't support actions
+func (e *Engine) actioner(acc *store.AccountWithProvider, srv *store.Server) (Actioner, error) {
+	if srv.ExternalID == "" || srv.ProviderIdent != acc.ProviderIdent {
+		return nil, fmt.Errorf("%w: server %s is not synced from %s", ErrActionUnsupported, srv.Name, acc.ProviderName)
+	}
+	syncer, err := e.registry.Syncer(acc.ProviderIdent, acc.Account)
+	if err != nil {
+		return nil, err
+	}
+	actioner, ok := syncer.(Actioner)
+	if !ok {
+		return nil, fmt.Errorf("%w by %s", ErrActionUnsupported, acc.ProviderName)
+	}
+	return actioner, nil
+}
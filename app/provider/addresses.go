@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// addressList collects server addresses reported by a provider
+type addressList []store.ServerAddress
+
+// add parses and appends the address or network of the kind. Empty, invalid and already added
+// values are skipped, so the first reported kind wins.
+func (l *addressList) add(addr string, kind enum.AddressKind) {
+	if addr == "" {
+		return
+	}
+	a, err := store.ParseServerAddress(addr, kind)
+	if err != nil {
+		return
+	}
+	for _, existing := range *l {
+		if existing.String() == a.String() {
+			return
+		}
+	}
+	*l = append(*l, a)
+}
+
+// addressesString returns a stable, comparable representation of addresses, e.g. "10.0.0.2 (private), 1.2.3.4"
+func addressesString(addrs []store.ServerAddress) string {
+	res := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		s := a.String()
+		if a.Kind != enum.AddressKindPublic {
+			s += " (" + a.Kind.String() + ")"
+		}
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
+
+// diffAddresses compares stored addresses with the remote ones, returns nil if the provider doesn't report
+// addresses or they are equal. Addresses follow the IP field lock, the change is marked skipped if it is locked.
+func diffAddresses(stored []store.ServerAddress, srv Server, locked bool) *FieldChange {
+	if srv.Addresses == nil {
+		return nil
+	}
+	oldVal, newVal := addressesString(stored), addressesString(srv.Addresses)
+	if oldVal == newVal {
+		return nil
+	}
+	return &FieldChange{Field: "Addresses", Old: oldVal, New: newVal, Skipped: locked}
+}
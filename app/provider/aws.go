@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nilBora/servers-manager/app/aws"
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// awsSyncer lists EC2 instances from AWS API
+type awsSyncer struct {
+	client *aws.Client
+}
+
+// NewAWSFactory returns a factory creating EC2 syncers scanning the given regions.
+// Account login is used as access key and API key as secret key.
+func NewAWSFactory(regions []string) Factory {
+	return func(acc store.Account) (Syncer, error) {
+		if acc.Login == "" {
+			return nil, errors.New("access key is not set, expected in account login")
+		}
+		return &awsSyncer{client: aws.NewClient(acc.Login, acc.ApiKey, regions)}, nil
+	}
+}
+
+// ListServers returns EC2 instances as normalized records
+func (s *awsSyncer) ListServers(ctx context.Context) ([]Server, error) {
+	instances, err := s.client.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Server, 0, len(instances))
+	for _, inst := range instances {
+		addrs := addressList{}
+		addrs.add(inst.IPAddress, enum.AddressKindPublic)
+		addrs.add(inst.IPv6Address, enum.AddressKindPublic)
+		addrs.add(inst.PrivateIPAddress, enum.AddressKindPrivate)
+		price, hasPrice := inst.GetMonthlyPrice()
+
+		res = append(res, Server{
+			ExternalID:  inst.InstanceID,
+			Name:        inst.GetName(),
+			IP:          inst.GetServerIP(),
+			Location:    inst.Placement.AvailabilityZone,
+			Description: inst.GetDescription(),
+			Cost:        price,
+			Status:      mapAWSStatus(inst.State.Name),
+			ServerType:  inst.InstanceType,
+			Datacenter:  inst.Placement.AvailabilityZone,
+			Addresses:   addrs,
+			Tags:        reportedTags(inst.GetLabels()),
+			HasCost:     hasPrice, // unknown types keep the stored cost
+		})
+	}
+	return res, nil
+}
+
+// mapAWSStatus maps EC2 instance state to our status
+func mapAWSStatus(state string) enum.ServerStatus {
+	switch state {
+	case "pending", "running":
+		return enum.ServerStatusActive
+	case "stopping", "stopped":
+		return enum.ServerStatusPaused
+	case "shutting-down", "terminated":
+		return enum.ServerStatusDeleted
+	default:
+		return enum.ServerStatusActive
+	}
+}
@@ -0,0 +1,605 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// planTTL is how long a previewed plan can be applied
+const planTTL = time.Hour
+
+// ErrSyncInProgress is returned when a sync of the same account is already running
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+// ErrPlanNotFound is returned when applying a plan which doesn't exist or has expired
+var ErrPlanNotFound = errors.New("sync plan not found or expired")
+
+// Engine reconciles remote provider inventory with servers stored locally. Sync locks are shared with
+// other replicas if the store implements store.SyncLockStore; plans and running actions are kept in memory.
+type Engine struct {
+	store    store.Store
+	registry *Registry
+
+	// ctx is the parent of background server actions, canceled by Close
+	ctx       context.Context
+	cancel    context.CancelFunc
+	actionsWg sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	running map[int64]bool              // account IDs with sync in progress
+	plans   map[string]*Plan            // previewed plans waiting to be applied
+	actions map[int64]enum.ServerAction // server IDs with provider action in progress
+}
+
+// Result holds counts of a single account sync
+type Result struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Deleted   int
+}
+
+// Synced returns the number of servers found in the provider API
+func (r Result) Synced() int {
+	return r.Added + r.Updated + r.Unchanged
+}
+
+// NewEngine creates a sync engine using syncers from the registry
+func NewEngine(st store.Store, registry *Registry) *Engine {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Engine{
+		store:    st,
+		registry: registry,
+		ctx:      ctx,
+		cancel:   cancel,
+		running:  make(map[int64]bool),
+		plans:    make(map[string]*Plan),
+		actions:  make(map[int64]enum.ServerAction),
+	}
+}
+
+// Close cancels running server actions and waits for their outcome to be recorded.
+// Actions can't be started after Close.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	e.cancel()
+	e.actionsWg.Wait()
+}
+
+// Supports returns true if the engine can sync accounts of the provider ident
+func (e *Engine) Supports(ident string) bool {
+	return e.registry.Supports(ident)
+}
+
+// SyncAccount lists servers of the account from its provider and reconciles them with the store.
+// The run and its outcome are recorded in sync history.
+// Returns ErrSyncInProgress if the account is already being synced.
+func (e *Engine) SyncAccount(ctx context.Context, acc *store.AccountWithProvider, trigger enum.SyncTrigger) (Result, error) {
+	unlock, err := e.lockAccount(ctx, acc)
+	if err != nil {
+		return Result{}, err
+	}
+	defer unlock()
+
+	log.Printf("[INFO] syncing %s account: %s", acc.ProviderName, acc.Name)
+
+	return e.recordRun(ctx, acc, trigger, func() (Result, error) {
+		plan, err := e.plan(ctx, acc)
+		if err != nil {
+			return Result{}, err
+		}
+		return e.apply(ctx, plan, nil), nil
+	})
+}
+
+// PlanAccount lists servers of the account from its provider and computes the changes a sync would make,
+// without modifying the store. The plan is kept for an hour and can be applied with ApplyPlan.
+func (e *Engine) PlanAccount(ctx context.Context, acc *store.AccountWithProvider) (*Plan, error) {
+	log.Printf("[INFO] planning sync of %s account: %s", acc.ProviderName, acc.Name)
+
+	plan, err := e.plan(ctx, acc)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	plan.ID = id
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for planID, p := range e.plans {
+		if time.Since(p.CreatedAt) > planTTL {
+			delete(e.plans, planID)
+		}
+	}
+	e.plans[plan.ID] = plan
+
+	return plan, nil
+}
+
+// ApplyPlan applies the selected changes of a previously computed plan, all changes if changeIDs is nil.
+// The plan is discarded afterwards. The run and its outcome are recorded in sync history.
+// If the account is being synced, returns ErrSyncInProgress and keeps the plan to be applied later.
+func (e *Engine) ApplyPlan(ctx context.Context, planID string, changeIDs []string) (Result, error) {
+	plan, ok := e.getPlan(planID)
+	if !ok {
+		return Result{}, ErrPlanNotFound
+	}
+
+	acc := &plan.Account
+	unlock, err := e.lockAccount(ctx, acc)
+	if err != nil {
+		return Result{}, err
+	}
+	defer unlock()
+
+	// the plan is taken only under the account lock, so it is applied once even if posted twice
+	if !e.takePlan(planID) {
+		return Result{}, ErrPlanNotFound
+	}
+
+	log.Printf("[INFO] applying sync plan of %s account: %s", acc.ProviderName, acc.Name)
+
+	var selected map[string]bool
+	if changeIDs != nil {
+		selected = make(map[string]bool, len(changeIDs))
+		for _, id := range changeIDs {
+			selected[id] = true
+		}
+	}
+
+	return e.recordRun(ctx, acc, enum.SyncTriggerManual, func() (Result, error) {
+		return e.apply(ctx, plan, selected), nil
+	})
+}
+
+// getPlan returns a previewed plan by ID, expired plans are not returned
+func (e *Engine) getPlan(planID string) (*Plan, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	plan, ok := e.plans[planID]
+	if !ok || time.Since(plan.CreatedAt) > planTTL {
+		return nil, false
+	}
+	return plan, true
+}
+
+// takePlan removes a plan, returns false if it was already removed
+func (e *Engine) takePlan(planID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.plans[planID]; !ok {
+		return false
+	}
+	delete(e.plans, planID)
+	return true
+}
+
+// recordRun records a sync run of the account in sync history around fn
+func (e *Engine) recordRun(ctx context.Context, acc *store.AccountWithProvider, trigger enum.SyncTrigger,
+	fn func() (Result, error)) (Result, error) {
+	run := &store.SyncRun{AccountID: acc.ID, Trigger: trigger}
+	if err := e.store.CreateSyncRun(ctx, run); err != nil {
+		log.Printf("[WARN] failed to record sync run of account %s: %v", acc.Name, err)
+	}
+
+	res, err := fn()
+
+	// record outcome even if sync was interrupted by shutdown
+	if run.ID != 0 {
+		run.Added, run.Updated, run.Deleted, run.Unchanged = res.Added, res.Updated, res.Deleted, res.Unchanged
+		if err != nil {
+			run.Error = err.Error()
+		}
+		if ferr := e.store.FinishSyncRun(context.WithoutCancel(ctx), run); ferr != nil {
+			log.Printf("[WARN] failed to record sync run outcome of account %s: %v", acc.Name, ferr)
+		}
+	}
+
+	return res, err
+}
+
+// plan lists remote servers with the syncer registered for account provider and computes changes
+func (e *Engine) plan(ctx context.Context, acc *store.AccountWithProvider) (*Plan, error) {
+	syncer, err := e.registry.Syncer(acc.ProviderIdent, acc.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := syncer.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Account: *acc, CreatedAt: time.Now()}
+	if lister, ok := syncer.(ResourceLister); ok {
+		if plan.Resources, err = lister.ListResources(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list resources: %w", err)
+		}
+		plan.ResourceKinds = lister.ResourceKinds()
+	}
+
+	seenIDs := make(map[int64]bool)
+
+	for _, srv := range servers {
+		existing := e.findExistingServer(ctx, srv, acc.ID, seenIDs)
+
+		if existing == nil {
+			plan.Changes = append(plan.Changes, Change{ID: plan.nextChangeID(), Kind: ChangeCreate,
+				Name: srv.Name, Remote: srv})
+			continue
+		}
+
+		seenIDs[existing.ID] = true
+
+		updated := *existing
+		skipped := applyRemote(&updated, srv, acc.ProviderIdent)
+		fields := fieldChanges(existing, &updated)
+
+		addrChange, err := e.addressChange(ctx, existing, srv)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case addrChange != nil && addrChange.Skipped:
+			skipped = append(skipped, *addrChange)
+		case addrChange != nil:
+			fields = append(fields, *addrChange)
+		}
+
+		tagChange, err := e.tagChange(ctx, existing, srv)
+		if err != nil {
+			return nil, err
+		}
+		if tagChange != nil {
+			fields = append(fields, *tagChange)
+		}
+
+		// differences in locked fields alone don't make an update
+		if len(fields) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{ID: plan.nextChangeID(), Kind: ChangeUpdate,
+			ServerID: existing.ID, Name: existing.Name, Remote: srv, Fields: append(fields, skipped...)})
+	}
+
+	// servers not found in API are planned to be marked as deleted
+	dbServers, err := e.store.ListServersByAccount(ctx, acc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers for account %d: %w", acc.ID, err)
+	}
+	for _, srv := range dbServers {
+		if seenIDs[srv.ID] || srv.Status == enum.ServerStatusDeleted {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{ID: plan.nextChangeID(), Kind: ChangeDelete,
+			ServerID: srv.ID, Name: srv.Name,
+			Fields: []FieldChange{{Field: "Status", Old: srv.Status.String(), New: enum.ServerStatusDeleted.String()}}})
+	}
+
+	return plan, nil
+}
+
+// apply writes the selected changes of the plan to the store, all changes if selected is nil.
+// Changes are re-applied on top of the current server state, so edits made after planning are preserved.
+func (e *Engine) apply(ctx context.Context, plan *Plan, selected map[string]bool) Result {
+	res := Result{Unchanged: plan.Unchanged}
+	acc := &plan.Account
+
+	for _, c := range plan.Changes {
+		if selected != nil && !selected[c.ID] {
+			continue
+		}
+
+		switch c.Kind {
+		case ChangeCreate:
+			if e.createServer(ctx, acc, c.Remote) {
+				res.Added++
+			}
+		case ChangeUpdate:
+			if e.updateServer(ctx, acc, c.ServerID, c.Remote) {
+				res.Updated++
+			}
+		case ChangeDelete:
+			if e.markDeleted(ctx, c.ServerID) {
+				res.Deleted++
+			}
+		}
+	}
+
+	// resources are stored after servers, so they can be attached to servers created above
+	if len(plan.ResourceKinds) > 0 && (selected == nil || selected[ResourcesChangeID]) {
+		e.storeResources(ctx, acc, plan.ResourceKinds, plan.Resources)
+	}
+
+	return res
+}
+
+// storeResources replaces stored resources of the account, linking them to local servers by external ID
+func (e *Engine) storeResources(ctx context.Context, acc *store.AccountWithProvider, kinds []enum.ResourceKind,
+	resources []Resource) {
+	serverIDs := make(map[string]int64)
+	servers, err := e.store.ListServersByAccount(ctx, acc.ID)
+	if err != nil {
+		log.Printf("[ERROR] failed to list servers of account %s: %v", acc.Name, err)
+		return
+	}
+	for _, srv := range servers {
+		if srv.ExternalID != "" {
+			serverIDs[srv.ExternalID] = srv.ID
+		}
+	}
+
+	stored := make([]store.Resource, 0, len(resources))
+	for _, r := range resources {
+		stored = append(stored, store.Resource{
+			ServerID:    serverIDs[r.ServerExternalID],
+			Kind:        r.Kind,
+			ExternalID:  r.ExternalID,
+			Name:        r.Name,
+			Location:    r.Location,
+			Description: r.Description,
+			SizeGB:      r.SizeGB,
+			UsedGB:      r.UsedGB,
+			Cost:        r.Cost,
+		})
+	}
+
+	if err := e.store.ReplaceAccountResources(ctx, acc.ID, kinds, stored); err != nil {
+		log.Printf("[ERROR] failed to store resources of account %s: %v", acc.Name, err)
+		return
+	}
+	log.Printf("[DEBUG] stored %d resources of %s account %s", len(stored), acc.ProviderName, acc.Name)
+}
+
+// createServer creates a local server from the remote record, returns true on success
+func (e *Engine) createServer(ctx context.Context, acc *store.AccountWithProvider, srv Server) bool {
+	newServer := &store.Server{AccountID: acc.ID}
+	applyRemote(newServer, srv, acc.ProviderIdent)
+
+	if err := e.store.CreateServer(ctx, newServer); err != nil {
+		log.Printf("[ERROR] failed to create server %s: %v", srv.Name, err)
+		return false
+	}
+
+	// keep at least the primary IP if the provider doesn't report addresses
+	addrs := addressList(srv.Addresses)
+	if srv.Addresses == nil {
+		addrs.add(srv.IP, enum.AddressKindPublic)
+	}
+	if err := e.store.SetServerAddresses(ctx, newServer.ID, addrs); err != nil {
+		log.Printf("[WARN] failed to set addresses of server %s: %v", srv.Name, err)
+	}
+	if len(srv.Tags) > 0 {
+		if err := e.store.SetServerTags(ctx, newServer.ID, enum.TagSourceProvider, tagList(srv.Tags)); err != nil {
+			log.Printf("[WARN] failed to set tags of server %s: %v", srv.Name, err)
+		}
+	}
+
+	logEntry := &store.ServerLog{
+		ServerID:    newServer.ID,
+		Action:      enum.LogActionAdded,
+		Description: fmt.Sprintf("Added from %s sync", acc.ProviderName),
+	}
+	_ = e.store.CreateLog(ctx, logEntry)
+	return true
+}
+
+// updateServer applies the remote record to the current local server, returns true if it was changed
+func (e *Engine) updateServer(ctx context.Context, acc *store.AccountWithProvider, serverID int64, srv Server) bool {
+	existing, err := e.store.GetServer(ctx, serverID)
+	if err != nil {
+		log.Printf("[ERROR] failed to load server %d: %v", serverID, err)
+		return false
+	}
+
+	updated := *existing
+	skipped := applyRemote(&updated, srv, acc.ProviderIdent)
+
+	// detect changes before overwriting, locked fields are reported along with them
+	changes := fieldChanges(existing, &updated)
+	addrChange, err := e.addressChange(ctx, existing, srv)
+	if err != nil {
+		log.Printf("[ERROR] failed to load addresses of server %s: %v", srv.Name, err)
+		return false
+	}
+	updateAddrs := addrChange != nil && !addrChange.Skipped
+	switch {
+	case updateAddrs:
+		changes = append(changes, *addrChange)
+	case addrChange != nil:
+		skipped = append(skipped, *addrChange)
+	}
+	tagChange, err := e.tagChange(ctx, existing, srv)
+	if err != nil {
+		log.Printf("[ERROR] failed to load tags of server %s: %v", srv.Name, err)
+		return false
+	}
+	if tagChange != nil {
+		changes = append(changes, *tagChange)
+	}
+	if len(changes) == 0 {
+		return false
+	}
+	diff := joinFieldChanges(append(changes, skipped...))
+
+	if err := e.store.UpdateServer(ctx, &updated); err != nil {
+		log.Printf("[ERROR] failed to update server %s: %v", srv.Name, err)
+		return false
+	}
+	if updateAddrs {
+		if err := e.store.SetServerAddresses(ctx, existing.ID, srv.Addresses); err != nil {
+			log.Printf("[ERROR] failed to update addresses of server %s: %v", srv.Name, err)
+		}
+	}
+	if tagChange != nil {
+		if err := e.store.SetServerTags(ctx, existing.ID, enum.TagSourceProvider, tagList(srv.Tags)); err != nil {
+			log.Printf("[ERROR] failed to update tags of server %s: %v", srv.Name, err)
+		}
+	}
+
+	logEntry := &store.ServerLog{
+		ServerID:    existing.ID,
+		Action:      enum.LogActionSynced,
+		Description: diff,
+	}
+	_ = e.store.CreateLog(ctx, logEntry)
+	return true
+}
+
+// markDeleted marks a server no longer found in the provider API as deleted, returns true on success
+func (e *Engine) markDeleted(ctx context.Context, serverID int64) bool {
+	srv, err := e.store.GetServer(ctx, serverID)
+	if err != nil {
+		log.Printf("[ERROR] failed to load server %d: %v", serverID, err)
+		return false
+	}
+	if srv.Status == enum.ServerStatusDeleted {
+		return false // already deleted, skip
+	}
+
+	srv.Status = enum.ServerStatusDeleted
+	if err := e.store.UpdateServer(ctx, srv); err != nil {
+		log.Printf("[ERROR] failed to mark server %s as deleted: %v", srv.Name, err)
+		return false
+	}
+
+	logEntry := &store.ServerLog{
+		ServerID:    srv.ID,
+		Action:      enum.LogActionDeleted,
+		Description: "Server no longer found in API, marked as deleted",
+	}
+	_ = e.store.CreateLog(ctx, logEntry)
+	log.Printf("[INFO] marked server %s (IP: %s) as deleted — not found in API", srv.Name, srv.IP)
+	return true
+}
+
+// addressChange compares stored addresses of the server with the remote ones, see diffAddresses
+func (e *Engine) addressChange(ctx context.Context, existing *store.Server, srv Server) (*FieldChange, error) {
+	if srv.Addresses == nil {
+		return nil, nil
+	}
+	stored, err := e.store.ListServerAddresses(ctx, existing.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses of server %d: %w", existing.ID, err)
+	}
+	return diffAddresses(stored, srv, existing.IsLocked(enum.ServerFieldIP)), nil
+}
+
+// tagChange compares stored provider tags of the server with the remote ones, see diffTags
+func (e *Engine) tagChange(ctx context.Context, existing *store.Server, srv Server) (*FieldChange, error) {
+	if srv.Tags == nil {
+		return nil, nil
+	}
+	stored, err := e.store.ListServerTags(ctx, existing.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of server %d: %w", existing.ID, err)
+	}
+	return diffTags(stored, srv), nil
+}
+
+// findExistingServer looks up an existing server by external ID first, then by IP and by name
+// within the same account. IP and name matches are rejected if the server is already matched
+// in this sync or carries a different external ID. Returns nil if not found.
+func (e *Engine) findExistingServer(ctx context.Context, srv Server, accountID int64, matched map[int64]bool) *store.Server {
+	// match by external ID first, it survives renames and IP changes
+	if srv.ExternalID != "" {
+		existing, err := e.store.FindServerByExternalID(ctx, srv.ExternalID, accountID)
+		if err == nil {
+			return existing
+		}
+	}
+
+	// fallback to heuristics for servers without external ID yet
+	usable := func(existing *store.Server) bool {
+		if matched[existing.ID] {
+			return false
+		}
+		return existing.ExternalID == "" || existing.ExternalID == srv.ExternalID
+	}
+
+	if srv.IP != "" {
+		existing, err := e.store.FindServerByIPAndAccount(ctx, srv.IP, accountID)
+		if err == nil && usable(existing) {
+			return existing
+		}
+	}
+
+	existing, err := e.store.FindServerByNameAndAccount(ctx, srv.Name, accountID)
+	if err == nil && usable(existing) {
+		return existing
+	}
+
+	return nil
+}
+
+// lockAccount marks account sync as running in this process and, if the store supports it, for replicas
+// sharing the database. Returns ErrSyncInProgress if the account is already being synced.
+func (e *Engine) lockAccount(ctx context.Context, acc *store.AccountWithProvider) (unlock func(), err error) {
+	if !e.lock(acc.ID) {
+		return nil, fmt.Errorf("%w for account %s", ErrSyncInProgress, acc.Name)
+	}
+
+	ls, ok := e.store.(store.SyncLockStore)
+	if !ok {
+		return func() { e.unlock(acc.ID) }, nil
+	}
+
+	release, locked, err := ls.TryLockAccountSync(ctx, acc.ID)
+	if err != nil {
+		e.unlock(acc.ID)
+		return nil, fmt.Errorf("failed to lock sync of account %s: %w", acc.Name, err)
+	}
+	if !locked {
+		e.unlock(acc.ID)
+		return nil, fmt.Errorf("%w for account %s on another replica", ErrSyncInProgress, acc.Name)
+	}
+	return func() {
+		release()
+		e.unlock(acc.ID)
+	}, nil
+}
+
+// lock marks account sync as running, returns false if it is already running
+func (e *Engine) lock(accountID int64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running[accountID] {
+		return false
+	}
+	e.running[accountID] = true
+	return true
+}
+
+// unlock marks account sync as finished
+func (e *Engine) unlock(accountID int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.running, accountID)
+}
+
+// newID generates a random ID for plans and jobs
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
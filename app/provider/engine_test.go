@@ -0,0 +1,350 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// fakeSyncer reports servers and resources set by the test
+type fakeSyncer struct {
+	servers   []Server
+	resources []Resource
+	kinds     []enum.ResourceKind
+	err       error
+	listing   chan struct{} // if set, ListServers is blocked until canceled, the channel is closed on call
+}
+
+func (f *fakeSyncer) ListServers(ctx context.Context) ([]Server, error) {
+	if f.listing != nil {
+		close(f.listing)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.servers, f.err
+}
+
+func (f *fakeSyncer) ResourceKinds() []enum.ResourceKind { return f.kinds }
+
+func (f *fakeSyncer) ListResources(context.Context) ([]Resource, error) { return f.resources, nil }
+
+// newTestEngine creates an engine over a memory store with a Hetzner Cloud account synced by the syncer
+func newTestEngine(t *testing.T, syncer *fakeSyncer) (*Engine, store.Store, *store.AccountWithProvider) {
+	t.Helper()
+	ctx := context.Background()
+	st := store.NewMemory()
+
+	providers, err := st.ListProviders(ctx)
+	require.NoError(t, err)
+	acc := &store.Account{Name: "main", ApiKey: "token"}
+	for _, p := range providers {
+		if p.Ident == IdentHetznerCloud {
+			acc.ProviderID = p.ID
+		}
+	}
+	require.NoError(t, st.CreateAccount(ctx, acc))
+	accWithProvider, err := st.GetAccountWithProvider(ctx, acc.ID)
+	require.NoError(t, err)
+
+	registry := NewRegistry()
+	registry.Register(IdentHetznerCloud, func(store.Account) (Syncer, error) { return syncer, nil })
+	engine := NewEngine(st, registry)
+	t.Cleanup(engine.Close)
+	return engine, st, accWithProvider
+}
+
+func TestEngine_SyncAccount(t *testing.T) {
+	active := enum.ServerStatusActive
+
+	tests := []struct {
+		name     string
+		existing []store.Server
+		remote   []Server
+		want     Result
+		check    func(t *testing.T, servers map[string]store.Server) // by external ID
+	}{
+		{
+			name:   "new server created",
+			remote: []Server{{ExternalID: "1", Name: "web", IP: "1.1.1.1", Status: active, Cost: 5, HasCost: true}},
+			want:   Result{Added: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				require.Len(t, servers, 1)
+				assert.Equal(t, IdentHetznerCloud, servers["1"].ProviderIdent)
+				assert.InDelta(t, 5, servers["1"].ApproximateCost, 0.001)
+			},
+		},
+		{
+			name:     "matched by external ID across rename and IP change",
+			existing: []store.Server{{ExternalID: "1", Name: "old", IP: "1.1.1.1", Status: active}},
+			remote:   []Server{{ExternalID: "1", Name: "web", IP: "2.2.2.2", Status: active}},
+			want:     Result{Updated: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				require.Len(t, servers, 1)
+				assert.Equal(t, "web", servers["1"].Name)
+				assert.Equal(t, "2.2.2.2", servers["1"].IP)
+			},
+		},
+		{
+			name:     "external ID backfilled on IP match",
+			existing: []store.Server{{Name: "manual", IP: "1.1.1.1", Status: active}},
+			remote:   []Server{{ExternalID: "1", Name: "web", IP: "1.1.1.1", Status: active}},
+			want:     Result{Updated: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				require.Len(t, servers, 1)
+				assert.Equal(t, "web", servers["1"].Name, "matched server got the external ID")
+			},
+		},
+		{
+			name:     "server with another external ID not matched by IP",
+			existing: []store.Server{{ExternalID: "2", Name: "db", IP: "1.1.1.1", Status: active}},
+			remote:   []Server{{ExternalID: "1", Name: "web", IP: "1.1.1.1", Status: active}},
+			want:     Result{Added: 1, Deleted: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				require.Len(t, servers, 2)
+				assert.Equal(t, enum.ServerStatusDeleted, servers["2"].Status, "db no longer reported")
+				assert.Equal(t, "web", servers["1"].Name)
+			},
+		},
+		{
+			name: "stored cost and specs kept if not reported",
+			existing: []store.Server{{ExternalID: "1", Name: "web", Status: active, ApproximateCost: 10,
+				CPUCores: 4, MemoryGB: 8}},
+			remote: []Server{{ExternalID: "1", Name: "web", Status: active}},
+			want:   Result{Unchanged: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				assert.InDelta(t, 10, servers["1"].ApproximateCost, 0.001)
+				assert.Equal(t, 4, servers["1"].CPUCores)
+			},
+		},
+		{
+			name: "reported cost and specs applied",
+			existing: []store.Server{{ExternalID: "1", Name: "web", Status: active, ApproximateCost: 10,
+				CPUCores: 4, MemoryGB: 8}},
+			remote: []Server{{ExternalID: "1", Name: "web", Status: active, Cost: 5, HasCost: true,
+				CPUCores: 2, MemoryGB: 4, HasSpecs: true}},
+			want: Result{Updated: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				assert.InDelta(t, 5, servers["1"].ApproximateCost, 0.001)
+				assert.Equal(t, 2, servers["1"].CPUCores)
+			},
+		},
+		{
+			name: "locked fields not overwritten",
+			existing: []store.Server{{ExternalID: "1", Name: "my-web", Status: active, ApproximateCost: 10,
+				LockedFields: []enum.ServerField{enum.ServerFieldName, enum.ServerFieldCost}}},
+			remote: []Server{{ExternalID: "1", Name: "web", Status: active, Cost: 5, HasCost: true}},
+			want:   Result{Unchanged: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				assert.Equal(t, "my-web", servers["1"].Name)
+				assert.InDelta(t, 10, servers["1"].ApproximateCost, 0.001)
+			},
+		},
+		{
+			name: "locked and unlocked fields changed together",
+			existing: []store.Server{{ExternalID: "1", Name: "my-web", IP: "1.1.1.1", Status: active,
+				LockedFields: []enum.ServerField{enum.ServerFieldName}}},
+			remote: []Server{{ExternalID: "1", Name: "web", IP: "2.2.2.2", Status: active}},
+			want:   Result{Updated: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				assert.Equal(t, "my-web", servers["1"].Name)
+				assert.Equal(t, "2.2.2.2", servers["1"].IP)
+			},
+		},
+		{
+			name: "servers missing from the provider marked deleted once",
+			existing: []store.Server{{ExternalID: "1", Name: "web", Status: active},
+				{ExternalID: "2", Name: "gone", Status: active},
+				{ExternalID: "3", Name: "deleted", Status: enum.ServerStatusDeleted}},
+			remote: []Server{{ExternalID: "1", Name: "web", Status: active}},
+			want:   Result{Unchanged: 1, Deleted: 1},
+			check: func(t *testing.T, servers map[string]store.Server) {
+				require.Len(t, servers, 3, "deleted servers are kept")
+				assert.Equal(t, enum.ServerStatusActive, servers["1"].Status)
+				assert.Equal(t, enum.ServerStatusDeleted, servers["2"].Status)
+				assert.Equal(t, enum.ServerStatusDeleted, servers["3"].Status)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			engine, st, acc := newTestEngine(t, &fakeSyncer{servers: tt.remote})
+			for _, srv := range tt.existing {
+				srv.AccountID = acc.ID
+				require.NoError(t, st.CreateServer(ctx, &srv))
+			}
+
+			res, err := engine.SyncAccount(ctx, acc, enum.SyncTriggerManual)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+
+			servers, err := st.ListServersByAccount(ctx, acc.ID)
+			require.NoError(t, err)
+			byExternalID := make(map[string]store.Server, len(servers))
+			for _, srv := range servers {
+				byExternalID[srv.ExternalID] = srv
+			}
+			tt.check(t, byExternalID)
+
+			runs, err := st.ListSyncRunsByAccount(ctx, acc.ID, 10)
+			require.NoError(t, err)
+			require.Len(t, runs, 1, "run recorded")
+			assert.Equal(t, tt.want.Added, runs[0].Added)
+			assert.Equal(t, tt.want.Deleted, runs[0].Deleted)
+		})
+	}
+}
+
+func TestEngine_SyncAccountFailure(t *testing.T) {
+	ctx := context.Background()
+	engine, st, acc := newTestEngine(t, &fakeSyncer{err: errors.New("unauthorized")})
+	require.NoError(t, st.CreateServer(ctx, &store.Server{AccountID: acc.ID, Name: "web", ExternalID: "1",
+		Status: enum.ServerStatusActive}))
+
+	_, err := engine.SyncAccount(ctx, acc, enum.SyncTriggerManual)
+	require.EqualError(t, err, "unauthorized")
+
+	servers, err := st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enum.ServerStatusActive, servers[0].Status, "servers not deleted on failed listing")
+	runs, err := st.ListSyncRunsByAccount(ctx, acc.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "unauthorized", runs[0].Error)
+}
+
+func TestEngine_SyncAccountInProgress(t *testing.T) {
+	ctx := context.Background()
+	engine, _, acc := newTestEngine(t, &fakeSyncer{})
+
+	require.True(t, engine.lock(acc.ID))
+	_, err := engine.SyncAccount(ctx, acc, enum.SyncTriggerManual)
+	require.ErrorIs(t, err, ErrSyncInProgress)
+
+	engine.unlock(acc.ID)
+	_, err = engine.SyncAccount(ctx, acc, enum.SyncTriggerManual)
+	require.NoError(t, err)
+	assert.True(t, engine.lock(acc.ID), "unlocked after sync")
+}
+
+func TestEngine_ApplyPlan(t *testing.T) {
+	active := enum.ServerStatusActive
+
+	tests := []struct {
+		name      string
+		selected  []ChangeKind // nil applies all changes
+		resources bool         // select resources refresh
+		want      Result
+		names     []string // names of not deleted servers after apply
+		resCount  int
+	}{
+		{name: "all changes", want: Result{Added: 1, Updated: 1, Deleted: 1},
+			names: []string{"new", "web"}, resCount: 1},
+		{name: "nothing selected", selected: []ChangeKind{},
+			names: []string{"gone", "old"}},
+		{name: "only create", selected: []ChangeKind{ChangeCreate}, want: Result{Added: 1},
+			names: []string{"gone", "new", "old"}},
+		{name: "update and delete", selected: []ChangeKind{ChangeUpdate, ChangeDelete},
+			want: Result{Updated: 1, Deleted: 1}, names: []string{"web"}},
+		{name: "only resources", selected: []ChangeKind{}, resources: true,
+			names: []string{"gone", "old"}, resCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			syncer := &fakeSyncer{
+				servers: []Server{{ExternalID: "1", Name: "web", Status: active},
+					{ExternalID: "3", Name: "new", Status: active}},
+				resources: []Resource{{Kind: enum.ResourceKindVolume, ExternalID: "v1", Name: "data", Cost: 2}},
+				kinds:     []enum.ResourceKind{enum.ResourceKindVolume},
+			}
+			engine, st, acc := newTestEngine(t, syncer)
+			require.NoError(t, st.CreateServer(ctx, &store.Server{AccountID: acc.ID, Name: "old", ExternalID: "1",
+				Status: active}))
+			require.NoError(t, st.CreateServer(ctx, &store.Server{AccountID: acc.ID, Name: "gone", ExternalID: "2",
+				Status: active}))
+
+			plan, err := engine.PlanAccount(ctx, acc)
+			require.NoError(t, err)
+			require.Len(t, plan.Changes, 3)
+			assert.Equal(t, Result{Added: 1, Updated: 1, Deleted: 1}, plan.Counts())
+			servers, err := st.ListServersByAccount(ctx, acc.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"gone", "old"}, activeNames(servers), "plan doesn't change the store")
+
+			var changeIDs []string
+			if tt.selected != nil {
+				changeIDs = []string{}
+				for _, c := range plan.Changes {
+					for _, kind := range tt.selected {
+						if c.Kind == kind {
+							changeIDs = append(changeIDs, c.ID)
+						}
+					}
+				}
+				if tt.resources {
+					changeIDs = append(changeIDs, ResourcesChangeID)
+				}
+			}
+
+			res, err := engine.ApplyPlan(ctx, plan.ID, changeIDs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+
+			servers, err = st.ListServersByAccount(ctx, acc.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.names, activeNames(servers))
+			resources, err := st.ListResources(ctx)
+			require.NoError(t, err)
+			assert.Len(t, resources, tt.resCount)
+
+			_, err = engine.ApplyPlan(ctx, plan.ID, nil)
+			require.ErrorIs(t, err, ErrPlanNotFound, "plan discarded after apply")
+		})
+	}
+}
+
+func TestEngine_ApplyPlanInProgress(t *testing.T) {
+	ctx := context.Background()
+	engine, st, acc := newTestEngine(t, &fakeSyncer{servers: []Server{{ExternalID: "1", Name: "web",
+		Status: enum.ServerStatusActive}}})
+
+	plan, err := engine.PlanAccount(ctx, acc)
+	require.NoError(t, err)
+
+	require.True(t, engine.lock(acc.ID))
+	_, err = engine.ApplyPlan(ctx, plan.ID, nil)
+	require.ErrorIs(t, err, ErrSyncInProgress)
+	engine.unlock(acc.ID)
+
+	res, err := engine.ApplyPlan(ctx, plan.ID, nil)
+	require.NoError(t, err, "plan kept while the account was locked")
+	assert.Equal(t, Result{Added: 1}, res)
+	servers, err := st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Len(t, servers, 1)
+
+	_, err = engine.ApplyPlan(ctx, "unknown", nil)
+	require.ErrorIs(t, err, ErrPlanNotFound)
+}
+
+// activeNames returns sorted names of servers not marked deleted
+func activeNames(servers []store.Server) []string {
+	var names []string
+	for _, srv := range servers {
+		if srv.Status != enum.ServerStatusDeleted {
+			names = append(names, srv.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,503 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/hetzner"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// hetznerCloudSyncer lists servers from Hetzner Cloud API. A syncer is created for every sync,
+// floating IPs listed for servers are reused for resources of the same sync.
+type hetznerCloudSyncer struct {
+	client *hetzner.Client
+
+	floatingIPs       []hetzner.FloatingIP
+	floatingIPsListed bool
+}
+
+// NewHetznerCloudFactory returns a factory creating Hetzner Cloud syncers. Options are passed to the API client,
+// e.g. to point it at a test server.
+func NewHetznerCloudFactory(opts ...hetzner.Option) Factory {
+	return func(acc store.Account) (Syncer, error) {
+		return &hetznerCloudSyncer{client: hetzner.NewClient(acc.ApiKey, opts...)}, nil
+	}
+}
+
+// ListServers returns Hetzner Cloud servers as normalized records
+func (s *hetznerCloudSyncer) ListServers(ctx context.Context) ([]Server, error) {
+	servers, err := s.client.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	floatingIPs, err := s.listFloatingIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	floatingByServer := make(map[int64][]string)
+	for _, fip := range floatingIPs {
+		if fip.Server != nil {
+			floatingByServer[*fip.Server] = append(floatingByServer[*fip.Server], fip.IP)
+		}
+	}
+
+	res := make([]Server, 0, len(servers))
+	for _, srv := range servers {
+		addrs := addressList{}
+		addrs.add(srv.PublicNet.IPv4.IP, enum.AddressKindPublic)
+		addrs.add(srv.PublicNet.IPv6.IP, enum.AddressKindPublic)
+		for _, ip := range floatingByServer[srv.ID] {
+			addrs.add(ip, enum.AddressKindFloating)
+		}
+		for _, pn := range srv.PrivateNet {
+			addrs.add(pn.IP, enum.AddressKindPrivate)
+			for _, alias := range pn.AliasIPs {
+				addrs.add(alias, enum.AddressKindPrivate)
+			}
+		}
+
+		cost := srv.GetMonthlyPrice()
+		backups := srv.HasBackups()
+		if backups {
+			cost *= 1.2 // Hetzner charges +20% for backups
+		}
+		res = append(res, Server{
+			ExternalID:  strconv.FormatInt(srv.ID, 10),
+			Name:        srv.Name,
+			IP:          srv.GetServerIP(),
+			Location:    srv.GetServerLocationDescription(),
+			Description: srv.GetDescription(),
+			Cost:        cost,
+			Backups:     backups,
+			Status:      mapHetznerCloudStatus(srv.Status),
+			ServerType:  srv.ServerType.Name,
+			Datacenter:  srv.Datacenter.Name,
+			CPUCores:    srv.ServerType.Cores,
+			MemoryGB:    srv.ServerType.Memory,
+			DiskGB:      srv.ServerType.Disk,
+			OSFlavor:    srv.GetOSFlavor(),
+			OSVersion:   srv.GetOSVersion(),
+			Addresses:   addrs,
+			Tags:        reportedTags(srv.Labels),
+			HasCost:     true,
+			HasBackups:  true,
+			HasSpecs:    true,
+		})
+	}
+	return res, nil
+}
+
+// ResourceKinds returns kinds of billable resources listed from Hetzner Cloud
+func (s *hetznerCloudSyncer) ResourceKinds() []enum.ResourceKind {
+	return []enum.ResourceKind{enum.ResourceKindVolume, enum.ResourceKindFloatingIP, enum.ResourceKindPrimaryIP,
+		enum.ResourceKindLoadBalancer, enum.ResourceKindSnapshot}
+}
+
+// ListResources returns volumes, floating IPs, primary IPs, load balancers and snapshots of the project
+// with their monthly prices
+func (s *hetznerCloudSyncer) ListResources(ctx context.Context) ([]Resource, error) {
+	pricing, err := s.client.GetPricing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []Resource
+
+	volumes, err := s.client.ListVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		res = append(res, Resource{Kind: enum.ResourceKindVolume, ExternalID: strconv.FormatInt(v.ID, 10),
+			Name: v.Name, Location: v.Location.Name, SizeGB: float64(v.Size), Cost: pricing.VolumePrice(v.Size),
+			ServerExternalID: externalID(v.Server)})
+	}
+
+	floatingIPs, err := s.listFloatingIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range floatingIPs {
+		res = append(res, Resource{Kind: enum.ResourceKindFloatingIP, ExternalID: strconv.FormatInt(ip.ID, 10),
+			Name: ip.Name, Location: ip.HomeLocation.Name, Description: ip.IP,
+			Cost: pricing.FloatingIPPrice(ip.Type, ip.HomeLocation.Name), ServerExternalID: externalID(ip.Server)})
+	}
+
+	primaryIPs, err := s.client.ListPrimaryIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range primaryIPs {
+		var server *int64
+		if ip.AssigneeType == "server" {
+			server = ip.AssigneeID
+		}
+		location := ip.Datacenter.Location.Name
+		res = append(res, Resource{Kind: enum.ResourceKindPrimaryIP, ExternalID: strconv.FormatInt(ip.ID, 10),
+			Name: ip.Name, Location: location, Description: ip.IP,
+			Cost: pricing.PrimaryIPPrice(ip.Type, location), ServerExternalID: externalID(server)})
+	}
+
+	lbs, err := s.client.ListLoadBalancers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range lbs {
+		res = append(res, Resource{Kind: enum.ResourceKindLoadBalancer, ExternalID: strconv.FormatInt(lb.ID, 10),
+			Name: lb.Name, Location: lb.Location.Name, Description: lb.LoadBalancerType.Name,
+			Cost: lb.MonthlyPrice()})
+	}
+
+	snapshots, err := s.client.ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snapshots {
+		r := Resource{Kind: enum.ResourceKindSnapshot, ExternalID: strconv.FormatInt(snap.ID, 10),
+			Name: snap.Description, SizeGB: snap.Size(), Cost: pricing.SnapshotPrice(snap.Size())}
+		if snap.CreatedFrom != nil {
+			r.ServerExternalID = strconv.FormatInt(snap.CreatedFrom.ID, 10)
+			r.Description = "from " + snap.CreatedFrom.Name
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// listFloatingIPs lists floating IPs of the project once per syncer
+func (s *hetznerCloudSyncer) listFloatingIPs(ctx context.Context) ([]hetzner.FloatingIP, error) {
+	if s.floatingIPsListed {
+		return s.floatingIPs, nil
+	}
+	ips, err := s.client.ListFloatingIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.floatingIPs, s.floatingIPsListed = ips, true
+	return ips, nil
+}
+
+// actionPollInterval is how often running Hetzner Cloud actions are polled for completion
+const actionPollInterval = 2 * time.Second
+
+// cloudActionCommands maps server actions to Hetzner Cloud action commands, snapshots are created separately
+var cloudActionCommands = map[enum.ServerAction]string{
+	enum.ServerActionPowerOn:        hetzner.ActionPowerOn,
+	enum.ServerActionShutdown:       hetzner.ActionShutdown,
+	enum.ServerActionReboot:         hetzner.ActionReboot,
+	enum.ServerActionReset:          hetzner.ActionReset,
+	enum.ServerActionEnableBackups:  hetzner.ActionEnableBackup,
+	enum.ServerActionDisableBackups: hetzner.ActionDisableBackup,
+}
+
+// Actions returns actions supported for Hetzner Cloud servers
+func (s *hetznerCloudSyncer) Actions() []enum.ServerAction {
+	return enum.AllServerActions()
+}
+
+// RunAction starts the action on the server and polls it until completion
+func (s *hetznerCloudSyncer) RunAction(ctx context.Context, externalID string, action enum.ServerAction) error {
+	id, err := strconv.ParseInt(externalID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid server ID %q", externalID)
+	}
+
+	var started *hetzner.Action
+	if action == enum.ServerActionCreateSnapshot {
+		description := "servers-manager snapshot " + time.Now().UTC().Format("2006-01-02 15:04")
+		started, err = s.client.CreateSnapshot(ctx, id, description)
+	} else {
+		cmd, ok := cloudActionCommands[action]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrActionUnsupported, action)
+		}
+		started, err = s.client.ServerAction(ctx, id, cmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.WaitAction(ctx, started, actionPollInterval)
+	return err
+}
+
+// ServerMetrics returns CPU, disk and network samples of the server, points of all series
+// are merged by their time
+func (s *hetznerCloudSyncer) ServerMetrics(ctx context.Context, externalID string, start, end time.Time,
+	step time.Duration) ([]MetricSample, error) {
+	id, err := strconv.ParseInt(externalID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server ID %q", externalID)
+	}
+
+	types := []string{hetzner.MetricTypeCPU, hetzner.MetricTypeDisk, hetzner.MetricTypeNetwork}
+	metrics, err := s.client.GetServerMetrics(ctx, id, types, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []MetricSample
+	byTime := make(map[time.Time]int)
+	merge := func(series string, set func(*MetricSample, float64)) {
+		for _, p := range metrics.Series(series) {
+			t := p.Time.Truncate(step)
+			i, ok := byTime[t]
+			if !ok {
+				i = len(res)
+				byTime[t] = i
+				res = append(res, MetricSample{Time: t})
+			}
+			set(&res[i], p.Value)
+		}
+	}
+	merge(hetzner.SeriesCPU, func(m *MetricSample, v float64) { m.CPU = v })
+	merge(hetzner.SeriesDiskReadBytes, func(m *MetricSample, v float64) { m.DiskRead = v })
+	merge(hetzner.SeriesDiskWriteBytes, func(m *MetricSample, v float64) { m.DiskWrite = v })
+	merge(hetzner.SeriesNetworkInBytes, func(m *MetricSample, v float64) { m.NetIn = v })
+	merge(hetzner.SeriesNetworkOutBytes, func(m *MetricSample, v float64) { m.NetOut = v })
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	return res, nil
+}
+
+// externalID formats optional numeric Hetzner ID, empty if not set
+func externalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+// hetznerRobotSyncer lists dedicated servers from Hetzner Robot API
+type hetznerRobotSyncer struct {
+	client *hetzner.RobotClient
+}
+
+func newHetznerRobotSyncer(acc store.Account) (Syncer, error) {
+	client, err := hetzner.NewRobotClient(acc.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &hetznerRobotSyncer{client: client}, nil
+}
+
+// ListServers returns Hetzner Robot servers as normalized records with their prices and contract dates.
+// Robot API doesn't provide backups or hardware specs, so locally stored values are kept. Servers whose
+// product price can't be determined keep their stored cost.
+func (s *hetznerRobotSyncer) ListServers(ctx context.Context) ([]Server, error) {
+	servers, err := s.client.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices := s.priceList(ctx)
+
+	res := make([]Server, 0, len(servers))
+	for _, srv := range servers {
+		addrs := addressList{}
+		addrs.add(srv.ServerIP, enum.AddressKindPublic)
+		for _, ip := range srv.IP {
+			addrs.add(ip, enum.AddressKindPublic)
+		}
+		addrs.add(srv.GetIPv6Net(), enum.AddressKindPublic)
+		for _, sn := range srv.Subnet {
+			addrs.add(sn.CIDR(), enum.AddressKindSubnet)
+		}
+
+		rec := Server{
+			ExternalID:  strconv.FormatInt(srv.ServerNumber, 10),
+			Name:        srv.GetServerName(),
+			IP:          srv.GetServerIP(),
+			Location:    srv.GetServerLocation(),
+			Description: srv.GetDescription(),
+			ServerType:  srv.Product,
+			Datacenter:  srv.DC,
+			Addresses:   addrs,
+			PaidUntil:   srv.GetPaidUntil(),
+			HasContract: true,
+		}
+
+		cancelled := srv.Cancelled
+		if srv.Cancelled {
+			c, err := s.client.GetCancellation(ctx, srv.ServerNumber)
+			switch {
+			case ctx.Err() != nil:
+				return nil, ctx.Err()
+			case err != nil:
+				log.Printf("[WARN] failed to get cancellation of robot server %d: %v", srv.ServerNumber, err)
+				rec.HasContract = false
+			default:
+				rec.CancellationDate = c.GetCancellationDate()
+				cancelled = contractEnded(rec.CancellationDate)
+			}
+		}
+		rec.Status = mapHetznerRobotStatus(srv.Status, cancelled)
+
+		if prices != nil {
+			if rec.Cost, rec.HasCost, err = s.serverCost(ctx, prices, &srv); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// priceList fetches prices of standard and server market products, nil if they are not available,
+// e.g. if the webservice user has no access to ordering
+func (s *hetznerRobotSyncer) priceList(ctx context.Context) *hetzner.RobotPriceList {
+	products, err := s.client.ListProducts(ctx)
+	if err != nil {
+		log.Printf("[WARN] robot product prices are not available: %v", err)
+		return nil
+	}
+	market, err := s.client.ListMarketProducts(ctx)
+	if err != nil {
+		log.Printf("[WARN] robot server market prices are not available: %v", err)
+		return nil
+	}
+	return &hetzner.RobotPriceList{Products: products, Market: market}
+}
+
+// serverCost returns monthly cost of the server product and its additional IPs and subnets.
+// Returns false if the product is unknown or add-on prices can't be fetched, error only if ctx is done.
+func (s *hetznerRobotSyncer) serverCost(ctx context.Context, prices *hetzner.RobotPriceList,
+	srv *hetzner.RobotServer) (float64, bool, error) {
+	cost, ok := prices.ServerPrice(srv)
+	if !ok || !srv.HasAddons() {
+		return cost, ok, nil
+	}
+
+	addons, err := s.client.ListServerAddons(ctx, srv.ServerNumber)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, false, ctx.Err()
+		}
+		log.Printf("[WARN] failed to get addon prices of robot server %d: %v", srv.ServerNumber, err)
+		return 0, false, nil
+	}
+	return cost + srv.AddonsPrice(addons), true, nil
+}
+
+// contractEnded returns true if the cancellation date has passed, cancelled servers keep running
+// until the end of the cancellation day. Unknown date is treated as ended.
+func contractEnded(cancellationDate time.Time) bool {
+	return cancellationDate.IsZero() || time.Now().After(cancellationDate.AddDate(0, 0, 1))
+}
+
+// ResourceKinds returns kinds of billable resources listed from Hetzner Robot. Additional IPs and subnets
+// are priced with their servers.
+func (s *hetznerRobotSyncer) ResourceKinds() []enum.ResourceKind {
+	return []enum.ResourceKind{enum.ResourceKindStorageBox, enum.ResourceKindVSwitch}
+}
+
+// ListResources returns storage boxes with their usage and monthly prices, and vSwitches of the account.
+// vSwitches are free, they are listed to show which VLANs the account has.
+func (s *hetznerRobotSyncer) ListResources(ctx context.Context) ([]Resource, error) {
+	boxes, err := s.client.ListStorageBoxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []Resource
+	for _, b := range boxes {
+		// quota and usage are reported only in details, the box is listed without them if they fail
+		if details, err := s.client.GetStorageBox(ctx, b.ID); err == nil {
+			b.DiskQuota, b.DiskUsage = details.DiskQuota, details.DiskUsage
+		} else {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("[WARN] failed to get details of robot storage box %d: %v", b.ID, err)
+		}
+
+		desc := b.Product
+		if b.Cancelled {
+			desc += ", cancelled"
+		}
+		price, _ := b.MonthlyPrice()
+		res = append(res, Resource{Kind: enum.ResourceKindStorageBox, ExternalID: strconv.FormatInt(b.ID, 10),
+			Name: b.GetName(), Location: b.Location, Description: desc, SizeGB: b.SizeGB(), UsedGB: b.UsedGB(),
+			Cost: price, ServerExternalID: externalID(b.LinkedServer)})
+	}
+
+	switches, err := s.client.ListVSwitches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, vs := range switches {
+		desc := fmt.Sprintf("VLAN %d", vs.VLAN)
+		if vs.Cancelled {
+			desc += ", cancelled"
+		}
+		res = append(res, Resource{Kind: enum.ResourceKindVSwitch, ExternalID: strconv.FormatInt(vs.ID, 10),
+			Name: vs.Name, Description: desc})
+	}
+
+	return res, nil
+}
+
+// robotResetTypes maps server actions to Robot reset types, power on is done with Wake-on-LAN
+var robotResetTypes = map[enum.ServerAction]string{
+	enum.ServerActionShutdown: hetzner.RobotResetPower,
+	enum.ServerActionReboot:   hetzner.RobotResetSoftware,
+	enum.ServerActionReset:    hetzner.RobotResetHardware,
+}
+
+// Actions returns actions supported for Hetzner Robot servers. Robot has no backups or snapshots.
+func (s *hetznerRobotSyncer) Actions() []enum.ServerAction {
+	return []enum.ServerAction{enum.ServerActionPowerOn, enum.ServerActionShutdown,
+		enum.ServerActionReboot, enum.ServerActionReset}
+}
+
+// RunAction runs the action on the dedicated server, Robot completes it before responding
+func (s *hetznerRobotSyncer) RunAction(ctx context.Context, externalID string, action enum.ServerAction) error {
+	number, err := strconv.ParseInt(externalID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid server number %q", externalID)
+	}
+
+	if action == enum.ServerActionPowerOn {
+		return s.client.WakeOnLAN(ctx, number)
+	}
+	resetType, ok := robotResetTypes[action]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrActionUnsupported, action)
+	}
+	return s.client.Reset(ctx, number, resetType)
+}
+
+// mapHetznerCloudStatus maps Hetzner Cloud server status to our status
+func mapHetznerCloudStatus(hetznerStatus string) enum.ServerStatus {
+	switch hetznerStatus {
+	case "running":
+		return enum.ServerStatusActive
+	case "off", "stopped":
+		return enum.ServerStatusPaused
+	case "deleting":
+		return enum.ServerStatusDeleted
+	default:
+		return enum.ServerStatusActive
+	}
+}
+
+// mapHetznerRobotStatus maps Hetzner Robot server status to our status
+func mapHetznerRobotStatus(status string, cancelled bool) enum.ServerStatus {
+	if cancelled {
+		return enum.ServerStatusDeleted
+	}
+	switch status {
+	case "ready":
+		return enum.ServerStatusActive
+	case "in process":
+		return enum.ServerStatusPaused
+	default:
+		return enum.ServerStatusActive
+	}
+}
@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// jobTTL is how long a finished job and its events are kept for late subscribers
+const jobTTL = time.Hour
+
+// JobEventKind is the kind of a sync job progress event
+type JobEventKind string
+
+// Job event kinds
+const (
+	JobEventStarted  JobEventKind = "started"  // account sync started
+	JobEventDone     JobEventKind = "done"     // account sync completed
+	JobEventFailed   JobEventKind = "failed"   // account sync failed
+	JobEventSkipped  JobEventKind = "skipped"  // account is already being synced
+	JobEventFinished JobEventKind = "finished" // all accounts processed, last event of a job
+)
+
+// String returns the event kind as string
+func (k JobEventKind) String() string {
+	return string(k)
+}
+
+// JobEvent is a progress event of a sync job
+type JobEvent struct {
+	Seq          int // position in job events, starting from 1
+	Kind         JobEventKind
+	Time         time.Time
+	AccountID    int64
+	AccountName  string
+	ProviderName string
+	Result       Result // account result for done, totals for finished
+	Error        string
+	Failed       int // number of failed accounts, for finished
+}
+
+// Job is a sync of multiple accounts running in background
+type Job struct {
+	ID        string
+	StartedAt time.Time
+	Accounts  int
+
+	mu       sync.Mutex
+	events   []JobEvent
+	finished bool
+	notify   chan struct{} // closed and replaced on every new event
+}
+
+// Events returns events after the given sequence number, whether the job is finished,
+// and a channel closed when the next event is added
+func (j *Job) Events(after int) (events []JobEvent, finished bool, next <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if after < len(j.events) {
+		events = append(events, j.events[after:]...)
+	}
+	return events, j.finished, j.notify
+}
+
+// Finished returns true if all accounts of the job were processed
+func (j *Job) Finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished
+}
+
+// add appends an event and wakes up subscribers
+func (j *Job) add(ev JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ev.Seq = len(j.events) + 1
+	ev.Time = time.Now()
+	j.events = append(j.events, ev)
+	if ev.Kind == JobEventFinished {
+		j.finished = true
+	}
+	close(j.notify)
+	j.notify = make(chan struct{})
+}
+
+// JobRunner runs multi-account syncs in background with a bounded number of concurrent account syncs
+type JobRunner struct {
+	engine  *Engine
+	workers int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // running jobs
+
+	mu     sync.Mutex
+	closed bool
+	jobs   map[string]*Job
+}
+
+// NewJobRunner creates a job runner syncing up to workers accounts at once
+func NewJobRunner(engine *Engine, workers int) *JobRunner {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobRunner{
+		engine:  engine,
+		workers: workers,
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*Job),
+	}
+}
+
+// Start starts a background sync of the accounts and returns immediately.
+// Progress is reported as job events. Returns ErrEngineClosed after the runner is closed.
+func (r *JobRunner) Start(accounts []store.AccountWithProvider, trigger enum.SyncTrigger) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{ID: id, StartedAt: time.Now(), Accounts: len(accounts), notify: make(chan struct{})}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrEngineClosed
+	}
+	for jobID, j := range r.jobs {
+		if j.Finished() && time.Since(j.StartedAt) > jobTTL {
+			delete(r.jobs, jobID)
+		}
+	}
+	r.jobs[job.ID] = job
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	go func() {
+		defer r.wg.Done()
+		r.run(job, accounts, trigger)
+	}()
+	return job, nil
+}
+
+// Job returns a job by ID
+func (r *JobRunner) Job(id string) (*Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+// Close cancels running jobs and waits for them to record their runs and finish
+func (r *JobRunner) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.cancel()
+	r.wg.Wait()
+}
+
+// run syncs accounts of the job in a pool of workers
+func (r *JobRunner) run(job *Job, accounts []store.AccountWithProvider, trigger enum.SyncTrigger) {
+	log.Printf("[INFO] sync job %s started for %d accounts", job.ID, len(accounts))
+
+	queue := make(chan store.AccountWithProvider)
+	var mu sync.Mutex
+	var total Result
+	var failed int
+
+	var wg sync.WaitGroup
+	for range min(r.workers, max(len(accounts), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for acc := range queue {
+				res, err := r.syncAccount(job, &acc, trigger)
+				mu.Lock()
+				if err != nil {
+					failed++
+				}
+				total.Added += res.Added
+				total.Updated += res.Updated
+				total.Unchanged += res.Unchanged
+				total.Deleted += res.Deleted
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, acc := range accounts {
+		if r.ctx.Err() != nil {
+			break
+		}
+		queue <- acc
+	}
+	close(queue)
+	wg.Wait()
+
+	job.add(JobEvent{Kind: JobEventFinished, Result: total, Failed: failed})
+	log.Printf("[INFO] sync job %s finished: %d servers synced, %d accounts failed", job.ID, total.Synced(), failed)
+}
+
+// syncAccount syncs a single account of the job and reports its progress
+func (r *JobRunner) syncAccount(job *Job, acc *store.AccountWithProvider, trigger enum.SyncTrigger) (Result, error) {
+	ev := JobEvent{AccountID: acc.ID, AccountName: acc.Name, ProviderName: acc.ProviderName}
+
+	started := ev
+	started.Kind = JobEventStarted
+	job.add(started)
+
+	res, err := r.engine.SyncAccount(r.ctx, acc, trigger)
+	switch {
+	case errors.Is(err, ErrSyncInProgress):
+		ev.Kind = JobEventSkipped
+		ev.Error = err.Error()
+		job.add(ev)
+		return res, nil
+	case err != nil:
+		log.Printf("[ERROR] failed to sync %s account %s: %v", acc.ProviderName, acc.Name, err)
+		ev.Kind = JobEventFailed
+		ev.Error = err.Error()
+		job.add(ev)
+		return res, err
+	}
+
+	ev.Kind = JobEventDone
+	ev.Result = res
+	job.add(ev)
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+func TestJobRunner_Start(t *testing.T) {
+	engine, _, acc := newTestEngine(t, &fakeSyncer{servers: []Server{{ExternalID: "1", Name: "web",
+		Status: enum.ServerStatusActive}}})
+	runner := NewJobRunner(engine, 2)
+	defer runner.Close()
+
+	job, err := runner.Start([]store.AccountWithProvider{*acc}, enum.SyncTriggerManual)
+	require.NoError(t, err)
+	got, ok := runner.Job(job.ID)
+	require.True(t, ok)
+	assert.Same(t, job, got)
+
+	for {
+		events, finished, next := job.Events(0)
+		if !finished {
+			<-next
+			continue
+		}
+		kinds := make([]JobEventKind, 0, len(events))
+		for _, ev := range events {
+			kinds = append(kinds, ev.Kind)
+		}
+		assert.Equal(t, []JobEventKind{JobEventStarted, JobEventDone, JobEventFinished}, kinds)
+		assert.Equal(t, 1, events[2].Result.Added)
+		break
+	}
+}
+
+func TestJobRunner_Close(t *testing.T) {
+	ctx := context.Background()
+	syncer := &fakeSyncer{listing: make(chan struct{})}
+	engine, st, acc := newTestEngine(t, syncer)
+	runner := NewJobRunner(engine, 1)
+
+	job, err := runner.Start([]store.AccountWithProvider{*acc}, enum.SyncTriggerManual)
+	require.NoError(t, err)
+	<-syncer.listing
+
+	// close cancels the running sync and waits for its run to be recorded
+	runner.Close()
+	assert.True(t, job.Finished())
+	runs, err := st.ListSyncRunsByAccount(ctx, acc.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.False(t, runs[0].FinishedAt.IsZero(), "run outcome recorded before close returns")
+	assert.Contains(t, runs[0].Error, "context canceled")
+
+	_, err = runner.Start([]store.AccountWithProvider{*acc}, enum.SyncTriggerManual)
+	require.ErrorIs(t, err, ErrEngineClosed)
+}
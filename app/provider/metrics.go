@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// metrics collection and retention periods
+const (
+	metricsBackfill        = 24 * time.Hour      // how far back metrics of a server are fetched the first time
+	metricsHourlyRetention = 7 * 24 * time.Hour  // hourly samples older than this are rolled up into daily ones
+	metricsRetention       = 90 * 24 * time.Hour // samples older than this are removed
+)
+
+// ErrMetricsUnsupported is returned when the provider doesn't report server metrics
+var ErrMetricsUnsupported = errors.New("metrics not supported")
+
+// CollectMetrics fetches hourly utilization samples of active servers of the account, synced from its
+// provider, since their last stored sample up to the last full hour. Returns the number of stored samples.
+func (e *Engine) CollectMetrics(ctx context.Context, acc *store.AccountWithProvider, now time.Time) (int, error) {
+	syncer, err := e.registry.Syncer(acc.ProviderIdent, acc.Account)
+	if err != nil {
+		return 0, err
+	}
+	source, ok := syncer.(MetricsSource)
+	if !ok {
+		return 0, fmt.Errorf("%w by %s", ErrMetricsUnsupported, acc.ProviderName)
+	}
+
+	servers, err := e.store.ListServersByAccount(ctx, acc.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	step := time.Duration(store.MetricStepHour) * time.Second
+	end := now.UTC().Truncate(step)
+	var stored int
+	for _, srv := range servers {
+		if srv.ExternalID == "" || srv.ProviderIdent != acc.ProviderIdent || srv.Status != enum.ServerStatusActive {
+			continue
+		}
+
+		start, err := e.store.GetLastServerMetricTime(ctx, srv.ID)
+		if err != nil {
+			return stored, err
+		}
+		if start.Before(end.Add(-metricsBackfill)) {
+			start = end.Add(-metricsBackfill)
+		}
+		if !start.Before(end) {
+			continue
+		}
+
+		samples, err := source.ServerMetrics(ctx, srv.ExternalID, start, end, step)
+		if err != nil {
+			return stored, fmt.Errorf("failed to get metrics of server %s: %w", srv.Name, err)
+		}
+
+		metrics := make([]store.ServerMetric, 0, len(samples))
+		for _, m := range samples {
+			if m.Time.Before(start) || !m.Time.Before(end) {
+				continue // partial samples at the edges of the period
+			}
+			metrics = append(metrics, store.ServerMetric{ServerID: srv.ID, Time: m.Time, Step: store.MetricStepHour,
+				CPU: cpuPercent(m.CPU, srv.CPUCores), DiskRead: m.DiskRead, DiskWrite: m.DiskWrite,
+				NetIn: m.NetIn, NetOut: m.NetOut})
+		}
+		if err := e.store.AddServerMetrics(ctx, metrics); err != nil {
+			return stored, err
+		}
+		stored += len(metrics)
+	}
+	return stored, nil
+}
+
+// SupportsMetrics returns true if the provider of the account reports server metrics
+func (e *Engine) SupportsMetrics(acc *store.AccountWithProvider) bool {
+	syncer, err := e.registry.Syncer(acc.ProviderIdent, acc.Account)
+	if err != nil {
+		return false
+	}
+	_, ok := syncer.(MetricsSource)
+	return ok
+}
+
+// cpuPercent normalizes CPU usage reported up to 100 per core to percent of all cores
+func cpuPercent(cpu float64, cores int) float64 {
+	if cores > 1 {
+		cpu /= float64(cores)
+	}
+	return math.Min(math.Max(cpu, 0), 100)
+}
+
+// MetricsCollector periodically collects utilization metrics of servers of all accounts
+// whose provider reports them, and downsamples stored samples as they age
+type MetricsCollector struct {
+	store    store.Store
+	engine   *Engine
+	interval time.Duration
+}
+
+// NewMetricsCollector creates a collector running every interval
+func NewMetricsCollector(st store.Store, engine *Engine, interval time.Duration) *MetricsCollector {
+	return &MetricsCollector{store: st, engine: engine, interval: interval}
+}
+
+// Run collects metrics every interval until context is canceled, zero interval disables collection
+func (c *MetricsCollector) Run(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+	log.Printf("[INFO] metrics collector started, interval %v", c.interval)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.collect(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			log.Printf("[INFO] metrics collector stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect collects metrics of all supported accounts and downsamples old samples
+func (c *MetricsCollector) collect(ctx context.Context, now time.Time) {
+	accounts, err := c.store.ListAccountsWithProviders(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[ERROR] metrics collector failed to list accounts: %v", err)
+		}
+		return
+	}
+
+	for _, acc := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if acc.ApiKey == "" || !c.engine.SupportsMetrics(&acc) {
+			continue
+		}
+		n, err := c.engine.CollectMetrics(ctx, &acc, now)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[WARN] metrics collection of %s account %s failed: %v", acc.ProviderName, acc.Name, err)
+			}
+			continue
+		}
+		log.Printf("[DEBUG] collected %d metric samples of %s account %s", n, acc.ProviderName, acc.Name)
+	}
+
+	if err := c.store.DownsampleServerMetrics(ctx, now.Add(-metricsHourlyRetention), now.Add(-metricsRetention)); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[ERROR] failed to downsample metrics: %v", err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// ChangeKind is the kind of a planned change
+type ChangeKind string
+
+// Change kinds
+const (
+	ChangeCreate ChangeKind = "create"
+	ChangeUpdate ChangeKind = "update"
+	ChangeDelete ChangeKind = "delete"
+)
+
+// String returns the change kind as string
+func (k ChangeKind) String() string {
+	return string(k)
+}
+
+// ResourcesChangeID selects replacing stored billable resources of the account when applying a plan
+// with ResourceKinds, in addition to the IDs of selected server changes
+const ResourcesChangeID = "resources"
+
+// Plan is the set of changes a sync of an account would make, computed without touching the store
+type Plan struct {
+	ID        string
+	Account   store.AccountWithProvider
+	CreatedAt time.Time
+	Changes   []Change
+	Unchanged int
+
+	// billable resources reported by the provider, replaced on apply if ResourceKinds is not empty
+	// and ResourcesChangeID is selected
+	Resources     []Resource
+	ResourceKinds []enum.ResourceKind
+}
+
+// ResourceCost returns monthly cost of the planned resources
+func (p *Plan) ResourceCost() float64 {
+	var total float64
+	for _, r := range p.Resources {
+		total += r.Cost
+	}
+	return total
+}
+
+// Change is a single planned modification of a local server
+type Change struct {
+	ID       string // unique within the plan
+	Kind     ChangeKind
+	ServerID int64  // existing server for update and delete, 0 for create
+	Name     string // server name for display
+	Remote   Server // remote record for create and update
+	Fields   []FieldChange
+}
+
+// FieldChange is a change of a single server field
+type FieldChange struct {
+	Field   string
+	Old     string
+	New     string
+	Skipped bool // field is locked by user, remote value is not applied
+}
+
+// String returns a human-readable description of the field change
+func (f FieldChange) String() string {
+	switch {
+	case f.Skipped:
+		return f.Field + ": skipped due to lock"
+	case f.Field == "Description":
+		return "Description updated"
+	case f.Field == "External ID" && f.Old == "":
+		return "External ID set to " + f.New
+	}
+	return fmt.Sprintf("%s: %s → %s", f.Field, f.Old, f.New)
+}
+
+// Counts returns the result the plan would have if applied in full
+func (p *Plan) Counts() Result {
+	res := Result{Unchanged: p.Unchanged}
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case ChangeCreate:
+			res.Added++
+		case ChangeUpdate:
+			res.Updated++
+		case ChangeDelete:
+			res.Deleted++
+		}
+	}
+	return res
+}
+
+// nextChangeID returns ID for the next change appended to the plan
+func (p *Plan) nextChangeID() string {
+	return strconv.Itoa(len(p.Changes) + 1)
+}
+
+// syncedField describes a server field written by sync
+type syncedField struct {
+	name     string
+	lock     enum.ServerField // lock guarding the field, meaningful only if lockable
+	lockable bool
+	value    func(*store.Server) string   // formatted value, used for comparison and display
+	copy     func(dst, src *store.Server) // copies the field from src to dst
+}
+
+// syncedFields lists fields written by sync in the order they are reported in diffs
+var syncedFields = []syncedField{
+	{name: "Name", lock: enum.ServerFieldName, lockable: true,
+		value: func(s *store.Server) string { return s.Name },
+		copy:  func(dst, src *store.Server) { dst.Name = src.Name }},
+	{name: "IP", lock: enum.ServerFieldIP, lockable: true,
+		value: func(s *store.Server) string { return s.IP },
+		copy:  func(dst, src *store.Server) { dst.IP = src.IP }},
+	{name: "Location", lock: enum.ServerFieldLocation, lockable: true,
+		value: func(s *store.Server) string { return s.Location },
+		copy:  func(dst, src *store.Server) { dst.Location = src.Location }},
+	{name: "Status",
+		value: func(s *store.Server) string { return s.Status.String() },
+		copy:  func(dst, src *store.Server) { dst.Status = src.Status }},
+	{name: "Cost", lock: enum.ServerFieldCost, lockable: true,
+		value: func(s *store.Server) string { return fmt.Sprintf("$%.2f", s.ApproximateCost) },
+		copy:  func(dst, src *store.Server) { dst.ApproximateCost = src.ApproximateCost }},
+	{name: "Backups", lock: enum.ServerFieldBackups, lockable: true,
+		value: func(s *store.Server) string { return strconv.FormatBool(s.Backups) },
+		copy:  func(dst, src *store.Server) { dst.Backups = src.Backups }},
+	{name: "Server type",
+		value: func(s *store.Server) string { return s.ServerType },
+		copy:  func(dst, src *store.Server) { dst.ServerType = src.ServerType }},
+	{name: "Datacenter",
+		value: func(s *store.Server) string { return s.Datacenter },
+		copy:  func(dst, src *store.Server) { dst.Datacenter = src.Datacenter }},
+	{name: "CPU cores",
+		value: func(s *store.Server) string { return strconv.Itoa(s.CPUCores) },
+		copy:  func(dst, src *store.Server) { dst.CPUCores = src.CPUCores }},
+	{name: "Memory",
+		value: func(s *store.Server) string { return strconv.FormatFloat(s.MemoryGB, 'f', -1, 64) + " GB" },
+		copy:  func(dst, src *store.Server) { dst.MemoryGB = src.MemoryGB }},
+	{name: "Disk",
+		value: func(s *store.Server) string { return strconv.Itoa(s.DiskGB) + " GB" },
+		copy:  func(dst, src *store.Server) { dst.DiskGB = src.DiskGB }},
+	{name: "OS",
+		value: func(s *store.Server) string { return s.OS() },
+		copy: func(dst, src *store.Server) {
+			dst.OSFlavor = src.OSFlavor
+			dst.OSVersion = src.OSVersion
+		}},
+	{name: "Paid until",
+		value: func(s *store.Server) string { return formatDate(s.PaidUntil) },
+		copy:  func(dst, src *store.Server) { dst.PaidUntil = src.PaidUntil }},
+	{name: "Cancellation date",
+		value: func(s *store.Server) string { return formatDate(s.CancellationDate) },
+		copy:  func(dst, src *store.Server) { dst.CancellationDate = src.CancellationDate }},
+	{name: "Description", lock: enum.ServerFieldDescription, lockable: true,
+		value: func(s *store.Server) string { return s.Description },
+		copy:  func(dst, src *store.Server) { dst.Description = src.Description }},
+	{name: "External ID",
+		value: func(s *store.Server) string { return s.ExternalID },
+		copy: func(dst, src *store.Server) {
+			dst.ExternalID = src.ExternalID
+			dst.ProviderIdent = src.ProviderIdent
+		}},
+}
+
+// applyRemote copies provider-managed fields of the remote record onto the local server,
+// except fields locked by user. Returns locked fields whose remote value differs from the local one.
+// The external ID is only assigned when the provider reports one, so it is backfilled on servers
+// matched by IP or name and never cleared.
+func applyRemote(dst *store.Server, srv Server, providerIdent string) (skipped []FieldChange) {
+	remote := *dst
+	remote.Name = srv.Name
+	remote.IP = srv.IP
+	remote.Location = srv.Location
+	remote.Description = srv.Description
+	remote.Status = srv.Status
+	remote.ServerType = srv.ServerType
+	remote.Datacenter = srv.Datacenter
+	if srv.HasSpecs {
+		remote.CPUCores = srv.CPUCores
+		remote.MemoryGB = srv.MemoryGB
+		remote.DiskGB = srv.DiskGB
+		remote.OSFlavor = srv.OSFlavor
+		remote.OSVersion = srv.OSVersion
+	}
+	if srv.HasCost {
+		remote.ApproximateCost = srv.Cost
+	}
+	if srv.HasContract {
+		remote.PaidUntil = srv.PaidUntil
+		remote.CancellationDate = srv.CancellationDate
+	}
+	if srv.HasBackups {
+		remote.Backups = srv.Backups
+	}
+	if srv.ExternalID != "" {
+		remote.ExternalID = srv.ExternalID
+		remote.ProviderIdent = providerIdent
+	}
+
+	for _, f := range syncedFields {
+		if f.lockable && dst.IsLocked(f.lock) {
+			if f.value(dst) != f.value(&remote) {
+				skipped = append(skipped, FieldChange{Field: f.name, Old: f.value(dst), New: f.value(&remote), Skipped: true})
+			}
+			continue
+		}
+		f.copy(dst, &remote)
+	}
+	return skipped
+}
+
+// fieldChanges compares existing server with updated values and returns changed fields
+func fieldChanges(existing, updated *store.Server) []FieldChange {
+	var changes []FieldChange
+	for _, f := range syncedFields {
+		if old, upd := f.value(existing), f.value(updated); old != upd {
+			changes = append(changes, FieldChange{Field: f.name, Old: old, New: upd})
+		}
+	}
+	return changes
+}
+
+// formatDate formats a contract date for diffs, empty if not set
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// joinFieldChanges formats field changes as a comma-separated list
+func joinFieldChanges(changes []FieldChange) string {
+	res := make([]string, 0, len(changes))
+	for _, c := range changes {
+		res = append(res, c.String())
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,179 @@
+// Package provider defines the interface for listing server inventory from
+// infrastructure providers and the engine reconciling it with the store
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// Provider idents, matching store.Provider.Ident
+const (
+	IdentHetznerCloud = "hetzner_cloud"
+	IdentHetznerRobot = "hetzner_robot"
+	IdentScaleway     = "scaleway"
+	IdentAWS          = "aws"
+)
+
+// ErrUnsupported is returned when no syncer is registered for a provider ident
+var ErrUnsupported = errors.New("unsupported provider")
+
+// Server is a normalized server record as reported by a provider
+type Server struct {
+	ExternalID  string // stable provider-side identifier, survives renames and IP changes
+	Name        string
+	IP          string
+	Location    string
+	Description string
+	Cost        float64
+	Backups     bool
+	Status      enum.ServerStatus
+	ServerType  string // provider plan or product
+	Datacenter  string
+
+	// Addresses are all IP addresses and subnets of the server, including IP.
+	// Nil if the provider doesn't report them, stored addresses are kept then.
+	Addresses []store.ServerAddress
+
+	// Tags are provider labels of the server. Nil if the provider doesn't report them,
+	// stored provider tags are kept then.
+	Tags map[string]string
+
+	// hardware and OS specs, applied only if HasSpecs is set
+	CPUCores  int
+	MemoryGB  float64
+	DiskGB    int
+	OSFlavor  string
+	OSVersion string
+
+	// contract dates of dedicated servers, applied only if HasContract is set
+	PaidUntil        time.Time
+	CancellationDate time.Time // zero if not cancelled
+
+	// HasCost, HasBackups, HasSpecs and HasContract report whether the provider knows cost, backup state,
+	// specs and contract dates, if not the values already stored locally are kept
+	HasCost     bool
+	HasBackups  bool
+	HasSpecs    bool
+	HasContract bool
+}
+
+// Resource is a normalized billable resource other than a server as reported by a provider
+type Resource struct {
+	Kind             enum.ResourceKind
+	ExternalID       string
+	Name             string
+	Location         string
+	Description      string
+	SizeGB           float64
+	UsedGB           float64 // used space of storage, 0 if not reported
+	Cost             float64 // monthly
+	ServerExternalID string  // external ID of the attached server, empty if not attached
+}
+
+// Syncer lists the remote server inventory of a single account
+type Syncer interface {
+	ListServers(ctx context.Context) ([]Server, error)
+}
+
+// ResourceLister is implemented by syncers which also list billable resources besides servers
+type ResourceLister interface {
+	// ResourceKinds returns kinds of resources managed by the lister,
+	// stored resources of these kinds not reported by ListResources are removed
+	ResourceKinds() []enum.ResourceKind
+	ListResources(ctx context.Context) ([]Resource, error)
+}
+
+// Actioner is implemented by syncers which can run power and lifecycle actions on servers
+type Actioner interface {
+	// Actions returns actions supported for servers of the account
+	Actions() []enum.ServerAction
+	// RunAction runs the action on the server with the external ID and waits until it is completed
+	RunAction(ctx context.Context, externalID string, action enum.ServerAction) error
+}
+
+// MetricSample is a utilization sample of a server averaged over the period starting at Time
+type MetricSample struct {
+	Time      time.Time
+	CPU       float64 // percent, up to 100 per CPU core
+	DiskRead  float64 // bytes/s
+	DiskWrite float64 // bytes/s
+	NetIn     float64 // bytes/s
+	NetOut    float64 // bytes/s
+}
+
+// MetricsSource is implemented by syncers which report utilization metrics of servers
+type MetricsSource interface {
+	// ServerMetrics returns samples of the server with the external ID between start and end,
+	// each averaged over step, oldest first
+	ServerMetrics(ctx context.Context, externalID string, start, end time.Time, step time.Duration) ([]MetricSample, error)
+}
+
+// Factory creates a Syncer for the given account credentials
+type Factory func(acc store.Account) (Syncer, error)
+
+// Registry maps provider idents to syncer factories
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// DefaultRegistry creates a registry with all built-in syncers registered
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(IdentHetznerCloud, NewHetznerCloudFactory())
+	r.Register(IdentHetznerRobot, newHetznerRobotSyncer)
+	r.Register(IdentScaleway, newScalewaySyncer)
+	r.Register(IdentAWS, NewAWSFactory(nil))
+	return r
+}
+
+// Register adds a factory for the given provider ident, replacing any existing one
+func (r *Registry) Register(ident string, f Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[ident] = f
+}
+
+// Supports returns true if a syncer is registered for the provider ident
+func (r *Registry) Supports(ident string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.factories[ident]
+	return ok
+}
+
+// Idents returns sorted idents of all registered providers
+func (r *Registry) Idents() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]string, 0, len(r.factories))
+	for ident := range r.factories {
+		res = append(res, ident)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Syncer creates a syncer for the account using the factory registered for ident
+func (r *Registry) Syncer(ident string, acc store.Account) (Syncer, error) {
+	r.mu.RLock()
+	f, ok := r.factories[ident]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, ident)
+	}
+	return f(acc)
+}
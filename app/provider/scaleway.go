@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/scaleway"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// scalewaySyncer lists Instances and Elastic Metal servers from Scaleway API
+type scalewaySyncer struct {
+	client *scaleway.Client
+}
+
+func newScalewaySyncer(acc store.Account) (Syncer, error) {
+	return &scalewaySyncer{client: scaleway.NewClient(acc.ApiKey)}, nil
+}
+
+// ListServers returns Scaleway servers as normalized records
+func (s *scalewaySyncer) ListServers(ctx context.Context) ([]Server, error) {
+	servers, err := s.client.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Server, 0, len(servers))
+	for _, srv := range servers {
+		desc := srv.CommercialType
+		status := mapScalewayInstanceStatus(srv.State)
+		if srv.Baremetal {
+			desc = "Elastic Metal " + srv.CommercialType
+			status = mapScalewayBaremetalStatus(srv.State)
+		}
+		addrs := addressList{}
+		addrs.add(srv.PublicIP, enum.AddressKindPublic)
+		for _, ip := range srv.PublicIPs {
+			addrs.add(ip, enum.AddressKindPublic)
+		}
+		addrs.add(srv.PrivateIP, enum.AddressKindPrivate)
+
+		res = append(res, Server{
+			ExternalID:  srv.ID,
+			Name:        srv.Name,
+			IP:          srv.PublicIP,
+			Location:    srv.Zone,
+			Description: desc,
+			Cost:        srv.MonthlyPrice(),
+			Status:      status,
+			ServerType:  srv.CommercialType,
+			Datacenter:  srv.Zone,
+			Addresses:   addrs,
+			HasCost:     srv.HasPrice,
+		})
+	}
+	return res, nil
+}
+
+// mapScalewayInstanceStatus maps Scaleway Instance state to our status
+func mapScalewayInstanceStatus(state string) enum.ServerStatus {
+	switch state {
+	case "running", "starting":
+		return enum.ServerStatusActive
+	case "stopped", "stopped in place", "stopping", "locked":
+		return enum.ServerStatusPaused
+	default:
+		return enum.ServerStatusActive
+	}
+}
+
+// mapScalewayBaremetalStatus maps Scaleway Elastic Metal status to our status
+func mapScalewayBaremetalStatus(status string) enum.ServerStatus {
+	switch status {
+	case "ready", "starting", "delivering", "resetting", "migrating":
+		return enum.ServerStatusActive
+	case "stopped", "stopping", "locked", "out_of_stock", "ordered", "error":
+		return enum.ServerStatusPaused
+	case "deleting":
+		return enum.ServerStatusDeleted
+	default:
+		return enum.ServerStatusActive
+	}
+}
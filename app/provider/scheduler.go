@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// schedulerTick is how often the scheduler checks for accounts due for sync
+const schedulerTick = time.Minute
+
+// Scheduler periodically syncs all accounts with an API key
+type Scheduler struct {
+	store           store.Store
+	engine          *Engine
+	defaultInterval time.Duration
+	lastRun         map[int64]time.Time // account ID -> last scheduled sync start
+}
+
+// NewScheduler creates a scheduler syncing accounts every defaultInterval,
+// unless account has its own interval set. Zero defaultInterval syncs only accounts with own interval.
+func NewScheduler(st store.Store, engine *Engine, defaultInterval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:           st,
+		engine:          engine,
+		defaultInterval: defaultInterval,
+		lastRun:         make(map[int64]time.Time),
+	}
+}
+
+// Run checks for due accounts every minute and syncs them. Blocks until context is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("[INFO] sync scheduler started, default interval %v", s.defaultInterval)
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		s.syncDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			log.Printf("[INFO] sync scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncDue syncs all accounts whose interval has elapsed since their last scheduled sync
+func (s *Scheduler) syncDue(ctx context.Context, now time.Time) {
+	accounts, err := s.store.ListAccountsWithProviders(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[ERROR] scheduler failed to list accounts: %v", err)
+		}
+		return
+	}
+
+	for _, acc := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if acc.ApiKey == "" || !s.engine.Supports(acc.ProviderIdent) {
+			continue
+		}
+
+		interval := s.interval(acc.Account)
+		if interval <= 0 {
+			continue
+		}
+		if last, ok := s.lastRun[acc.ID]; ok && now.Sub(last) < interval {
+			continue
+		}
+		s.lastRun[acc.ID] = now
+
+		res, err := s.engine.SyncAccount(ctx, &acc, enum.SyncTriggerScheduled)
+		if err != nil {
+			if errors.Is(err, ErrSyncInProgress) || ctx.Err() != nil {
+				continue
+			}
+			log.Printf("[ERROR] scheduled sync of %s account %s failed: %v", acc.ProviderName, acc.Name, err)
+			continue
+		}
+		log.Printf("[INFO] scheduled sync of %s account %s completed: %d servers synced",
+			acc.ProviderName, acc.Name, res.Synced())
+	}
+}
+
+// interval returns sync interval for the account, own interval takes precedence over default
+func (s *Scheduler) interval(acc store.Account) time.Duration {
+	if acc.SyncInterval > 0 {
+		return time.Duration(acc.SyncInterval) * time.Minute
+	}
+	return s.defaultInterval
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+func TestScheduler_SyncDue(t *testing.T) {
+	ctx := context.Background()
+	engine, st, acc := newTestEngine(t, &fakeSyncer{})
+
+	// own interval of the second account takes precedence, the account without API key is not synced
+	fast := &store.Account{ProviderID: acc.ProviderID, Name: "fast", ApiKey: "token", SyncInterval: 10}
+	require.NoError(t, st.CreateAccount(ctx, fast))
+	noKey := &store.Account{ProviderID: acc.ProviderID, Name: "no-key"}
+	require.NoError(t, st.CreateAccount(ctx, noKey))
+
+	runs := func(accountID int64) int {
+		t.Helper()
+		list, err := st.ListSyncRunsByAccount(ctx, accountID, 100)
+		require.NoError(t, err)
+		for _, r := range list {
+			assert.Equal(t, enum.SyncTriggerScheduled, r.Trigger)
+		}
+		return len(list)
+	}
+
+	s := NewScheduler(st, engine, time.Hour)
+	now := time.Now()
+	tests := []struct {
+		name          string
+		at            time.Duration // since the first check
+		main, fastAcc int           // runs of the accounts after the check
+	}{
+		{name: "all due on start", at: 0, main: 1, fastAcc: 1},
+		{name: "within intervals", at: 5 * time.Minute, main: 1, fastAcc: 1},
+		{name: "own interval elapsed", at: 10 * time.Minute, main: 1, fastAcc: 2},
+		{name: "default interval elapsed", at: time.Hour, main: 2, fastAcc: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.syncDue(ctx, now.Add(tt.at))
+			assert.Equal(t, tt.main, runs(acc.ID))
+			assert.Equal(t, tt.fastAcc, runs(fast.ID))
+			assert.Zero(t, runs(noKey.ID))
+		})
+	}
+}
+
+func TestScheduler_SyncDueSkipsRunning(t *testing.T) {
+	ctx := context.Background()
+	engine, st, acc := newTestEngine(t, &fakeSyncer{})
+
+	require.True(t, engine.lock(acc.ID))
+	NewScheduler(st, engine, time.Hour).syncDue(ctx, time.Now())
+	engine.unlock(acc.ID)
+
+	list, err := st.ListSyncRunsByAccount(ctx, acc.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, list, "account synced elsewhere is skipped")
+}
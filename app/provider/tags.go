@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// reportedTags returns provider labels as server tags. The result is never nil, so labels
+// removed at the provider are removed locally as well.
+func reportedTags(labels map[string]string) map[string]string {
+	res := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k = strings.TrimSpace(k); k != "" {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// tagList converts tags of the provider to store tags ordered by key
+func tagList(tags map[string]string) []store.ServerTag {
+	res := make([]store.ServerTag, 0, len(tags))
+	for k, v := range tags {
+		res = append(res, store.ServerTag{Key: k, Value: v, Source: enum.TagSourceProvider})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// tagsString returns a stable, comparable representation of tags, e.g. "env=prod, team=billing"
+func tagsString(tags []store.ServerTag) string {
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, t.String())
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
+
+// diffTags compares stored provider tags with the remote ones, returns nil if the provider doesn't report
+// tags or they are equal. Remote tags with a key set by user are ignored, as user tags take precedence.
+func diffTags(stored []store.ServerTag, srv Server) *FieldChange {
+	if srv.Tags == nil {
+		return nil
+	}
+
+	var provided []store.ServerTag
+	userKeys := make(map[string]bool)
+	for _, t := range stored {
+		if t.Source == enum.TagSourceUser {
+			userKeys[t.Key] = true
+			continue
+		}
+		provided = append(provided, t)
+	}
+
+	var remote []store.ServerTag
+	for _, t := range tagList(srv.Tags) {
+		if !userKeys[t.Key] {
+			remote = append(remote, t)
+		}
+	}
+
+	oldVal, newVal := tagsString(provided), tagsString(remote)
+	if oldVal == newVal {
+		return nil
+	}
+	return &FieldChange{Field: "Tags", Old: oldVal, New: newVal}
+}
@@ -0,0 +1,409 @@
+// Package scaleway provides a client for Scaleway Instances and Elastic Metal APIs
+package scaleway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	baseURL       = "https://api.scaleway.com"
+	perPage       = 50
+	hoursPerMonth = 730 // average hours in a month, used to estimate monthly price from hourly
+)
+
+// InstanceZones lists zones where Scaleway Instances are available
+var InstanceZones = []string{
+	"fr-par-1", "fr-par-2", "fr-par-3",
+	"nl-ams-1", "nl-ams-2", "nl-ams-3",
+	"pl-waw-1", "pl-waw-2", "pl-waw-3",
+}
+
+// BaremetalZones lists zones where Scaleway Elastic Metal is available
+var BaremetalZones = []string{
+	"fr-par-1", "fr-par-2",
+	"nl-ams-1", "nl-ams-2",
+	"pl-waw-2", "pl-waw-3",
+}
+
+// Client is a Scaleway API client
+type Client struct {
+	httpClient *http.Client
+	secretKey  string
+	baseURL    string
+}
+
+// NewClient creates a new Scaleway API client authenticated with the given secret key
+func NewClient(secretKey string) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		secretKey: secretKey,
+		baseURL:   baseURL,
+	}
+}
+
+// Server represents a Scaleway server, either an Instance or an Elastic Metal server
+type Server struct {
+	ID             string
+	Name           string
+	State          string
+	Zone           string
+	CommercialType string
+	PublicIP       string
+	PublicIPs      []string // all public addresses, including PublicIP
+	PrivateIP      string
+	HourlyPrice    float64
+	HasPrice       bool // price of the commercial type is found in the zone catalogue
+	Baremetal      bool
+}
+
+// MonthlyPrice returns the estimated monthly price based on hourly price
+func (s *Server) MonthlyPrice() float64 {
+	return s.HourlyPrice * hoursPerMonth
+}
+
+// instance is the Instances API server representation
+type instance struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	Zone           string `json:"zone"`
+	CommercialType string `json:"commercial_type"`
+	PublicIP       *struct {
+		Address string `json:"address"`
+	} `json:"public_ip"`
+	PublicIPs []struct {
+		Address string `json:"address"`
+		Family  string `json:"family"`
+	} `json:"public_ips"`
+	PrivateIP *string `json:"private_ip"`
+	IPv6      *struct {
+		Address string `json:"address"`
+	} `json:"ipv6"`
+}
+
+// publicIPs returns all public addresses of the instance
+func (i *instance) publicIPs() []string {
+	var res []string
+	if i.PublicIP != nil && i.PublicIP.Address != "" {
+		res = append(res, i.PublicIP.Address)
+	}
+	for _, ip := range i.PublicIPs {
+		res = append(res, ip.Address)
+	}
+	if i.IPv6 != nil && i.IPv6.Address != "" {
+		res = append(res, i.IPv6.Address)
+	}
+	return res
+}
+
+// privateIP returns the private address of the instance, empty if none
+func (i *instance) privateIP() string {
+	if i.PrivateIP == nil {
+		return ""
+	}
+	return *i.PrivateIP
+}
+
+// publicIP returns the primary public address, IPv4 preferred
+func (i *instance) publicIP() string {
+	if i.PublicIP != nil && i.PublicIP.Address != "" {
+		return i.PublicIP.Address
+	}
+	for _, ip := range i.PublicIPs {
+		if ip.Family == "inet" {
+			return ip.Address
+		}
+	}
+	if len(i.PublicIPs) > 0 {
+		return i.PublicIPs[0].Address
+	}
+	return ""
+}
+
+type listInstancesResponse struct {
+	Servers []instance `json:"servers"`
+}
+
+type instanceProductsResponse struct {
+	Servers map[string]struct {
+		HourlyPrice float64 `json:"hourly_price"`
+	} `json:"servers"`
+}
+
+// baremetalServer is the Elastic Metal API server representation
+type baremetalServer struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Zone      string `json:"zone"`
+	OfferID   string `json:"offer_id"`
+	OfferName string `json:"offer_name"`
+	IPs       []struct {
+		Address string `json:"address"`
+		Version string `json:"version"`
+	} `json:"ips"`
+}
+
+// publicIPs returns all addresses of the server, Elastic Metal servers have public addresses only
+func (b *baremetalServer) publicIPs() []string {
+	res := make([]string, 0, len(b.IPs))
+	for _, ip := range b.IPs {
+		res = append(res, ip.Address)
+	}
+	return res
+}
+
+// publicIP returns the primary address, IPv4 preferred
+func (b *baremetalServer) publicIP() string {
+	for _, ip := range b.IPs {
+		if ip.Version == "IPv4" {
+			return ip.Address
+		}
+	}
+	if len(b.IPs) > 0 {
+		return b.IPs[0].Address
+	}
+	return ""
+}
+
+type listBaremetalResponse struct {
+	TotalCount int               `json:"total_count"`
+	Servers    []baremetalServer `json:"servers"`
+}
+
+// money is the Scaleway representation of a monetary amount
+type money struct {
+	CurrencyCode string `json:"currency_code"`
+	Units        int64  `json:"units"`
+	Nanos        int64  `json:"nanos"`
+}
+
+// Float returns the amount as a float
+func (m money) Float() float64 {
+	return float64(m.Units) + float64(m.Nanos)/1e9
+}
+
+type baremetalOffersResponse struct {
+	TotalCount int `json:"total_count"`
+	Offers     []struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		PricePerHour *money `json:"price_per_hour"`
+	} `json:"offers"`
+}
+
+// ListServers fetches all Instances and Elastic Metal servers across all zones
+func (c *Client) ListServers(ctx context.Context) ([]Server, error) {
+	instances, err := c.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baremetal, err := c.ListBaremetalServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(instances, baremetal...), nil
+}
+
+// ListInstances fetches Instances from all zones with their hourly prices
+func (c *Client) ListInstances(ctx context.Context) ([]Server, error) {
+	var allServers []Server
+
+	for _, zone := range InstanceZones {
+		var zoneServers []instance
+		for page := 1; ; page++ {
+			var result listInstancesResponse
+			path := fmt.Sprintf("/instance/v1/zones/%s/servers?page=%d&per_page=%d", zone, page, perPage)
+			total, err := c.get(ctx, path, &result)
+			if err != nil {
+				return nil, fmt.Errorf("list instances in %s: %w", zone, err)
+			}
+
+			zoneServers = append(zoneServers, result.Servers...)
+			if len(result.Servers) < perPage || (total > 0 && len(zoneServers) >= total) {
+				break
+			}
+		}
+
+		if len(zoneServers) == 0 {
+			continue
+		}
+
+		prices, err := c.instancePrices(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, srv := range zoneServers {
+			srvZone := srv.Zone
+			if srvZone == "" {
+				srvZone = zone
+			}
+			price, hasPrice := prices[srv.CommercialType]
+			allServers = append(allServers, Server{
+				ID:             srv.ID,
+				Name:           srv.Name,
+				State:          srv.State,
+				Zone:           srvZone,
+				CommercialType: srv.CommercialType,
+				PublicIP:       srv.publicIP(),
+				PublicIPs:      srv.publicIPs(),
+				PrivateIP:      srv.privateIP(),
+				HourlyPrice:    price,
+				HasPrice:       hasPrice,
+			})
+		}
+	}
+
+	return allServers, nil
+}
+
+// ListBaremetalServers fetches Elastic Metal servers from all zones with their hourly prices
+func (c *Client) ListBaremetalServers(ctx context.Context) ([]Server, error) {
+	var allServers []Server
+
+	for _, zone := range BaremetalZones {
+		var zoneServers []baremetalServer
+		for page := 1; ; page++ {
+			var result listBaremetalResponse
+			path := fmt.Sprintf("/baremetal/v1/zones/%s/servers?page=%d&page_size=%d", zone, page, perPage)
+			if _, err := c.get(ctx, path, &result); err != nil {
+				return nil, fmt.Errorf("list elastic metal servers in %s: %w", zone, err)
+			}
+
+			zoneServers = append(zoneServers, result.Servers...)
+			if len(result.Servers) < perPage || len(zoneServers) >= result.TotalCount {
+				break
+			}
+		}
+
+		if len(zoneServers) == 0 {
+			continue
+		}
+
+		prices, err := c.baremetalPrices(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, srv := range zoneServers {
+			srvZone := srv.Zone
+			if srvZone == "" {
+				srvZone = zone
+			}
+			offer, hasOffer := prices[srv.OfferID]
+			commercialType := srv.OfferName
+			if commercialType == "" {
+				commercialType = offer.name
+			}
+			allServers = append(allServers, Server{
+				ID:             srv.ID,
+				Name:           srv.Name,
+				State:          srv.Status,
+				Zone:           srvZone,
+				CommercialType: commercialType,
+				PublicIP:       srv.publicIP(),
+				PublicIPs:      srv.publicIPs(),
+				HourlyPrice:    offer.hourly,
+				HasPrice:       hasOffer && offer.priced,
+				Baremetal:      true,
+			})
+		}
+	}
+
+	return allServers, nil
+}
+
+// instancePrices returns hourly prices of Instance commercial types in the zone following pagination
+func (c *Client) instancePrices(ctx context.Context, zone string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for page := 1; ; page++ {
+		var result instanceProductsResponse
+		path := fmt.Sprintf("/instance/v1/zones/%s/products/servers?page=%d&per_page=%d", zone, page, perPage)
+		total, err := c.get(ctx, path, &result)
+		if err != nil {
+			return nil, fmt.Errorf("list instance products in %s: %w", zone, err)
+		}
+
+		for commercialType, p := range result.Servers {
+			prices[commercialType] = p.HourlyPrice
+		}
+		if len(result.Servers) < perPage || (total > 0 && len(prices) >= total) {
+			break
+		}
+	}
+	return prices, nil
+}
+
+type baremetalOffer struct {
+	name   string
+	hourly float64
+	priced bool // offer has a price per hour
+}
+
+// baremetalPrices returns Elastic Metal offers in the zone keyed by offer ID
+func (c *Client) baremetalPrices(ctx context.Context, zone string) (map[string]baremetalOffer, error) {
+	offers := make(map[string]baremetalOffer)
+	for page := 1; ; page++ {
+		var result baremetalOffersResponse
+		path := fmt.Sprintf("/baremetal/v1/zones/%s/offers?page=%d&page_size=%d", zone, page, perPage)
+		if _, err := c.get(ctx, path, &result); err != nil {
+			return nil, fmt.Errorf("list elastic metal offers in %s: %w", zone, err)
+		}
+
+		for _, o := range result.Offers {
+			offer := baremetalOffer{name: o.Name}
+			if o.PricePerHour != nil {
+				offer.hourly = o.PricePerHour.Float()
+				offer.priced = true
+			}
+			offers[o.ID] = offer
+		}
+		if len(result.Offers) < perPage || len(offers) >= result.TotalCount {
+			break
+		}
+	}
+	return offers, nil
+}
+
+// get performs an authenticated GET request and decodes JSON response into dst.
+// Returns the value of X-Total-Count header, or 0 if not present.
+func (c *Client) get(ctx context.Context, path string, dst any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, http.NoBody)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("X-Auth-Token", c.secretKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return 0, fmt.Errorf("authentication failed: invalid secret key")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("API error: status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+
+	total, _ := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	return total, nil
+}
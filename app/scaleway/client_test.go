@@ -0,0 +1,122 @@
+package scaleway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListServers(t *testing.T) {
+	var instancePages []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/instance/v1/zones/{zone}/servers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Auth-Token"))
+		if r.PathValue("zone") != "fr-par-1" {
+			writeJSON(t, w, map[string]any{"servers": []any{}})
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		instancePages = append(instancePages, page)
+		w.Header().Set("X-Total-Count", strconv.Itoa(perPage+1))
+		servers := make([]map[string]any, 0, perPage)
+		switch page {
+		case 1:
+			for i := 0; i < perPage; i++ {
+				servers = append(servers, map[string]any{
+					"id": fmt.Sprintf("id-%d", i), "name": fmt.Sprintf("web-%d", i), "state": "running",
+					"zone": "fr-par-1", "commercial_type": "DEV1-S", "public_ip": map[string]any{"address": "51.15.0.1"},
+				})
+			}
+		case 2:
+			servers = append(servers, map[string]any{
+				"id": "id-last", "name": "db", "state": "stopped", "zone": "fr-par-1", "commercial_type": "GP1-XS",
+				"public_ips": []any{map[string]any{"address": "2001:bc8::1", "family": "inet6"},
+					map[string]any{"address": "51.15.0.2", "family": "inet"}},
+			})
+		}
+		writeJSON(t, w, map[string]any{"servers": servers})
+	})
+	var productPages []int
+	mux.HandleFunc("/instance/v1/zones/{zone}/products/servers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fr-par-1", r.PathValue("zone"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		productPages = append(productPages, page)
+		w.Header().Set("X-Total-Count", strconv.Itoa(perPage+1))
+		products := map[string]any{}
+		switch page {
+		case 1:
+			products["DEV1-S"] = map[string]any{"hourly_price": 0.01}
+			for i := 1; i < perPage; i++ {
+				products[fmt.Sprintf("TYPE-%d", i)] = map[string]any{"hourly_price": 1}
+			}
+		case 2:
+			products["GP1-XS"] = map[string]any{"hourly_price": 0.1}
+		}
+		writeJSON(t, w, map[string]any{"servers": products})
+	})
+	mux.HandleFunc("/baremetal/v1/zones/{zone}/servers", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("zone") != "nl-ams-1" {
+			writeJSON(t, w, map[string]any{"total_count": 0, "servers": []any{}})
+			return
+		}
+		writeJSON(t, w, map[string]any{"total_count": 1, "servers": []any{map[string]any{
+			"id": "bm-1", "name": "metal", "status": "ready", "zone": "nl-ams-1", "offer_id": "offer-1",
+			"ips": []any{map[string]any{"address": "2001:bc8::2", "version": "IPv6"},
+				map[string]any{"address": "62.210.0.1", "version": "IPv4"}},
+		}}})
+	})
+	mux.HandleFunc("/baremetal/v1/zones/{zone}/offers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "nl-ams-1", r.PathValue("zone"))
+		writeJSON(t, w, map[string]any{"total_count": 1, "offers": []any{map[string]any{
+			"id": "offer-1", "name": "EM-A210R-HDD",
+			"price_per_hour": map[string]any{"currency_code": "EUR", "units": 0, "nanos": 200000000},
+		}}})
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewClient("secret")
+	client.baseURL = ts.URL
+
+	servers, err := client.ListServers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, servers, perPage+2)
+	assert.Equal(t, []int{1, 2}, instancePages)
+	assert.Equal(t, []int{1, 2}, productPages, "price catalogue paginated")
+
+	assert.Equal(t, Server{ID: "id-0", Name: "web-0", State: "running", Zone: "fr-par-1", CommercialType: "DEV1-S",
+		PublicIP: "51.15.0.1", PublicIPs: []string{"51.15.0.1"}, HourlyPrice: 0.01, HasPrice: true}, servers[0])
+	assert.Equal(t, Server{ID: "id-last", Name: "db", State: "stopped", Zone: "fr-par-1", CommercialType: "GP1-XS",
+		PublicIP: "51.15.0.2", PublicIPs: []string{"2001:bc8::1", "51.15.0.2"}, HourlyPrice: 0.1, HasPrice: true}, servers[perPage])
+	assert.Equal(t, Server{ID: "bm-1", Name: "metal", State: "ready", Zone: "nl-ams-1", CommercialType: "EM-A210R-HDD",
+		PublicIP: "62.210.0.1", PublicIPs: []string{"2001:bc8::2", "62.210.0.1"}, HourlyPrice: 0.2, HasPrice: true, Baremetal: true},
+		servers[perPage+1])
+	assert.InDelta(t, 146.0, servers[perPage+1].MonthlyPrice(), 0.001)
+}
+
+func TestClient_ListServersUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := NewClient("bad")
+	client.baseURL = ts.URL
+
+	_, err := client.ListServers(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authentication failed")
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
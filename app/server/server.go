@@ -1,4 +1,10 @@
-// Package server provides HTTP server for the servers manager web UI
+// Package server provides HTTP server for the servers manager web UI.
+//
+// Several replicas can share a Postgres database behind a load balancer with two constraints. Previewed
+// sync plans, sync jobs and running server actions are kept in memory of the replica serving the request,
+// so the load balancer must route requests of a session to the same replica (sticky sessions).
+// Background sync and metrics collection must run on a single replica, the others start with NoScheduler.
+// Migrations on start, trash purge and syncs of the same account are coordinated between replicas by the store.
 package server
 
 import (
@@ -7,12 +13,14 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	log "github.com/go-pkgz/lgr"
 
+	"github.com/nilBora/servers-manager/app/provider"
 	"github.com/nilBora/servers-manager/app/server/web"
 	"github.com/nilBora/servers-manager/app/store"
 )
@@ -22,6 +30,12 @@ type Server struct {
 	Config
 	store      store.Store
 	webHandler *web.Handler
+	engine     *provider.Engine
+	scheduler  *provider.Scheduler
+	metrics    *provider.MetricsCollector
+	backups    *store.BackupScheduler
+	trash      *store.TrashPurger
+	jobs       *provider.JobRunner
 	staticFS   fs.FS
 }
 
@@ -33,6 +47,15 @@ type Config struct {
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
 	Version         string
+	AWSRegions      []string      // regions scanned for EC2 instances, aws.DefaultRegions if empty
+	SyncInterval    time.Duration // default background sync interval, 0 disables it for accounts without own interval
+	SyncWorkers     int           // max number of accounts synced concurrently by manual sync
+	MetricsInterval time.Duration // how often server metrics are collected, 0 disables collection
+	BackupDir       string        // directory database snapshots are written to, empty disables snapshots
+	BackupInterval  time.Duration // how often database snapshots are written
+	BackupKeep      int           // number of most recent snapshots kept, 0 keeps all
+	TrashRetention  time.Duration // how long deleted records are kept in the trash, 0 keeps them forever
+	NoScheduler     bool          // don't run background sync and metrics collection, another replica runs them
 }
 
 // New creates a new Server instance
@@ -42,21 +65,62 @@ func New(st store.Store, cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to load static files: %w", err)
 	}
 
-	webHandler, err := web.New(st)
+	registry := provider.DefaultRegistry()
+	if len(cfg.AWSRegions) > 0 {
+		registry.Register(provider.IdentAWS, provider.NewAWSFactory(cfg.AWSRegions))
+	}
+	engine := provider.NewEngine(st, registry)
+	jobs := provider.NewJobRunner(engine, cfg.SyncWorkers)
+
+	webHandler, err := web.New(st, engine, jobs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create web handler: %w", err)
 	}
 
+	var backups *store.BackupScheduler
+	if bs, ok := st.(store.BackupStore); ok && cfg.BackupDir != "" {
+		backups = store.NewBackupScheduler(bs, cfg.BackupDir, cfg.BackupInterval, cfg.BackupKeep)
+	}
+
 	return &Server{
 		Config:     cfg,
 		store:      st,
 		webHandler: webHandler,
+		engine:     engine,
+		scheduler:  provider.NewScheduler(st, engine, cfg.SyncInterval),
+		metrics:    provider.NewMetricsCollector(st, engine, cfg.MetricsInterval),
+		backups:    backups,
+		trash:      store.NewTrashPurger(st, cfg.TrashRetention),
+		jobs:       jobs,
 		staticFS:   staticContent,
 	}, nil
 }
 
-// Run starts the HTTP server and blocks until context is canceled
+// Run starts the HTTP server, background sync scheduler and metrics collector unless NoScheduler is set,
+// backup scheduler and trash purger, blocks until context is canceled
 func (s *Server) Run(ctx context.Context) error {
+	// background loops stop on ctx cancellation, Run waits for them before returning,
+	// so none of them uses the store after it is closed
+	var wg sync.WaitGroup
+	background := func(run func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(ctx)
+		}()
+	}
+
+	if s.NoScheduler {
+		log.Printf("[INFO] background sync and metrics collection are disabled")
+	} else {
+		background(s.scheduler.Run)
+		background(s.metrics.Run)
+	}
+	background(s.trash.Run)
+	if s.backups != nil {
+		background(s.backups.Run)
+	}
+
 	httpServer := &http.Server{
 		Addr:              s.Address,
 		Handler:           s.routes(),
@@ -66,9 +130,13 @@ func (s *Server) Run(ctx context.Context) error {
 	}
 
 	// graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-ctx.Done()
 		log.Printf("[INFO] shutting down server")
+		s.jobs.Close()
+		s.engine.Close()
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
 		defer cancel()
@@ -81,6 +149,10 @@ func (s *Server) Run(ctx context.Context) error {
 	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("server error: %w", err)
 	}
+
+	// listener is closed at the start of shutdown, wait for the rest of it
+	<-shutdownDone
+	wg.Wait()
 	return nil
 }
 
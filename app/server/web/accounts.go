@@ -16,8 +16,15 @@ func (h *Handler) handleAccountTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastSyncRuns, err := h.store.GetLastSyncRuns(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load sync history")
+		return
+	}
+
 	data := templateData{
-		Accounts: accounts,
+		Accounts:     accounts,
+		LastSyncRuns: lastSyncRuns,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "account-table", data); err != nil {
@@ -97,12 +104,25 @@ func (h *Handler) handleAccountCreate(w http.ResponseWriter, r *http.Request) {
 		ApiKey:     r.FormValue("api_key"),
 	}
 
+	if v := r.FormValue("sync_interval"); v != "" {
+		interval, err := strconv.Atoi(v)
+		if err != nil || interval < 0 {
+			h.renderError(w, http.StatusBadRequest, "Invalid sync interval")
+			return
+		}
+		account.SyncInterval = interval
+	}
+
 	if account.Name == "" {
 		h.renderError(w, http.StatusBadRequest, "Name is required")
 		return
 	}
 
 	if err := h.store.CreateAccount(r.Context(), account); err != nil {
+		if errors.Is(err, store.ErrInTrash) {
+			h.renderError(w, http.StatusConflict, "Account with this name is in the trash, restore it first")
+			return
+		}
 		if errors.Is(err, store.ErrConflict) {
 			h.renderError(w, http.StatusConflict, "Account with this name already exists for this provider")
 			return
@@ -143,6 +163,15 @@ func (h *Handler) handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
 		ApiKey:     r.FormValue("api_key"),
 	}
 
+	if v := r.FormValue("sync_interval"); v != "" {
+		interval, err := strconv.Atoi(v)
+		if err != nil || interval < 0 {
+			h.renderError(w, http.StatusBadRequest, "Invalid sync interval")
+			return
+		}
+		account.SyncInterval = interval
+	}
+
 	if account.Name == "" {
 		h.renderError(w, http.StatusBadRequest, "Name is required")
 		return
@@ -153,6 +182,10 @@ func (h *Handler) handleAccountUpdate(w http.ResponseWriter, r *http.Request) {
 			h.renderError(w, http.StatusNotFound, "Account not found")
 			return
 		}
+		if errors.Is(err, store.ErrInTrash) {
+			h.renderError(w, http.StatusConflict, "Account with this name is in the trash, restore it first")
+			return
+		}
 		if errors.Is(err, store.ErrConflict) {
 			h.renderError(w, http.StatusConflict, "Account with this name already exists for this provider")
 			return
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// handleBackupDownload streams a consistent backup of the database taken while the server is running
+func (h *Handler) handleBackupDownload(w http.ResponseWriter, r *http.Request) {
+	bs, ok := h.store.(store.BackupStore)
+	if !ok {
+		h.renderError(w, http.StatusNotImplemented, "Backups are not supported by the store")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "servers-backup-")
+	if err != nil {
+		log.Printf("[ERROR] failed to create backup directory: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "servers.db")
+	if err := bs.Backup(r.Context(), path); err != nil {
+		if errors.Is(err, store.ErrBackupUnsupported) {
+			h.renderError(w, http.StatusNotImplemented, "Backups are supported for SQLite databases only")
+			return
+		}
+		log.Printf("[ERROR] failed to backup database: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+
+	f, err := os.Open(path) //nolint:gosec // path is created above
+	if err != nil {
+		log.Printf("[ERROR] failed to open backup: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("servers-%s.db", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, time.Now(), f)
+}
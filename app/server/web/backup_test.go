@@ -0,0 +1,49 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/provider"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+func TestHandler_BackupDownload(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "servers.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = st.Close() })
+	s := newTestServerWithStore(t, st, provider.NewRegistry())
+
+	assert.Equal(t, "/login", s.redirect(http.MethodGet, "/web/backup", nil), "backup requires login")
+
+	s.login()
+	p := &store.Provider{Ident: "custom", Name: "Custom"}
+	require.NoError(t, st.CreateProvider(context.Background(), p))
+
+	resp, body := s.request(http.MethodGet, "/web/backup", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Regexp(t, `^attachment; filename="servers-\d{8}-\d{6}\.db"$`, resp.Header.Get("Content-Disposition"))
+
+	// the downloaded backup is a complete database
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	restored, err := store.New(path)
+	require.NoError(t, err)
+	defer restored.Close()
+	got, err := restored.GetProvider(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Custom", got.Name)
+}
+
+func TestHandler_BackupUnsupported(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	code, _ := s.do(http.MethodGet, "/web/backup", nil)
+	assert.Equal(t, http.StatusNotImplemented, code, "in-memory store has no backups")
+}
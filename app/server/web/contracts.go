@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// contractDayOptions are the periods the contracts page can look ahead, in days
+var contractDayOptions = []int{7, 14, 30, 60, 90}
+
+// handleContractTable renders the table of servers whose contract ends soon
+func (h *Handler) handleContractTable(w http.ResponseWriter, r *http.Request) {
+	days := parseContractDays(r)
+	servers, err := h.listContracts(r, days)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load contracts")
+		return
+	}
+
+	data := templateData{
+		Servers:      servers,
+		ContractDays: days,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "contract-table", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// listContracts returns servers whose contract ends or has to be renewed within days from today
+func (h *Handler) listContracts(r *http.Request, days int) ([]store.ServerWithAccount, error) {
+	return h.store.ListServersWithContractEnding(r.Context(), time.Now().UTC().AddDate(0, 0, days))
+}
+
+// parseContractDays parses the look-ahead period from the days query parameter,
+// falling back to store.ContractWarningDays for missing or invalid values
+func parseContractDays(r *http.Request) int {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days <= 0 || days > 366 {
+		return store.ContractWarningDays
+	}
+	return days
+}
+
+// daysUntil returns the number of whole days from today to the date, negative for past dates
+func daysUntil(t time.Time) int {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Sub(today).Hours() / 24)
+}
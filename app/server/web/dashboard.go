@@ -24,17 +24,27 @@ func (h *Handler) handleDashboardContent(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	providerGroups, err := h.store.GetServersGroupedHierarchically(r.Context(), status)
+	tagFilter := store.ParseTagFilter(r.URL.Query().Get("tag"))
+	providerGroups, err := h.store.GetServersGroupedHierarchically(r.Context(), status, tagFilter)
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load servers")
 		return
 	}
 
+	tags, err := h.loadServerTags(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
 	data := templateData{
 		Stats:          stats,
 		ProviderGroups: providerGroups,
 		Statuses:       enum.AllServerStatuses(),
 		StatusFilter:   statusFilter,
+		ServerTags:     tags.tags,
+		TagOptions:     tags.options,
+		TagFilter:      tagFilter.String(),
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "dashboard-accounts", data); err != nil {
@@ -81,20 +91,12 @@ func (h *Handler) handleThemeToggle(w http.ResponseWriter, r *http.Request) {
 // handleLogTable renders the log table partial
 func (h *Handler) handleLogTable(w http.ResponseWriter, r *http.Request) {
 	actionFilter := r.URL.Query().Get("action")
-
-	var logs []store.ServerLogWithServer
-	var err error
-
-	if actionFilter != "" {
-		action, parseErr := enum.ParseLogAction(actionFilter)
-		if parseErr == nil {
-			logs, err = h.store.ListLogsByAction(r.Context(), action, 100)
-		}
-	}
-	if logs == nil {
-		logs, err = h.store.ListLogs(r.Context(), 100)
+	filter := store.LogFilter{Tag: store.ParseTagFilter(r.URL.Query().Get("tag"))}
+	if action, err := enum.ParseLogAction(actionFilter); err == nil {
+		filter.Action = &action
 	}
 
+	logs, err := h.store.ListLogsFiltered(r.Context(), filter, 100)
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load logs")
 		return
@@ -104,6 +106,7 @@ func (h *Handler) handleLogTable(w http.ResponseWriter, r *http.Request) {
 		Logs:         logs,
 		Actions:      enum.AllLogActions(),
 		ActionFilter: actionFilter,
+		TagFilter:    filter.Tag.String(),
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "server-logs", data); err != nil {
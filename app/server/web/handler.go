@@ -14,6 +14,8 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/inventory"
+	"github.com/nilBora/servers-manager/app/provider"
 	"github.com/nilBora/servers-manager/app/store"
 )
 
@@ -34,20 +36,24 @@ func StaticFS() (fs.FS, error) {
 
 // Handler handles web UI requests
 type Handler struct {
-	store store.Store
-	tmpl  *template.Template
+	store  store.Store
+	engine *provider.Engine
+	jobs   *provider.JobRunner
+	tmpl   *template.Template
 }
 
 // New creates a new web handler
-func New(st store.Store) (*Handler, error) {
+func New(st store.Store, engine *provider.Engine, jobs *provider.JobRunner) (*Handler, error) {
 	tmpl, err := parseTemplates()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
 	return &Handler{
-		store: st,
-		tmpl:  tmpl,
+		store:  st,
+		engine: engine,
+		jobs:   jobs,
+		tmpl:   tmpl,
 	}, nil
 }
 
@@ -70,6 +76,11 @@ func (h *Handler) Register(r chi.Router) {
 		r.Get("/accounts", h.handleAccounts)
 		r.Get("/servers", h.handleServers)
 		r.Get("/logs", h.handleLogs)
+		r.Get("/sync-runs", h.handleSyncRuns)
+		r.Get("/contracts", h.handleContracts)
+		r.Get("/utilization", h.handleUtilization)
+		r.Get("/data", h.handleData)
+		r.Get("/trash", h.handleTrash)
 
 		// provider CRUD
 		r.Get("/web/providers", h.handleProviderTable)
@@ -95,13 +106,25 @@ func (h *Handler) Register(r chi.Router) {
 		r.Post("/web/servers", h.handleServerCreate)
 		r.Put("/web/servers/{id}", h.handleServerUpdate)
 		r.Put("/web/servers/{id}/status", h.handleServerStatusUpdate)
+		r.Post("/web/servers/{id}/actions/{action}", h.handleServerAction)
 		r.Delete("/web/servers/{id}", h.handleServerDelete)
 
 		// logs
 		r.Get("/web/logs", h.handleLogTable)
 
 		// sync
-		r.Post("/web/sync/hetzner", h.handleHetznerSync)
+		r.Post("/web/sync", h.handleSync)
+		r.Get("/web/sync/jobs/{id}/events", h.handleSyncJobEvents)
+		r.Post("/web/sync/preview", h.handleSyncPreview)
+		r.Post("/web/sync/preview/accounts/{id}", h.handleSyncPreviewAccount)
+		r.Post("/web/sync/apply", h.handleSyncApply)
+		r.Get("/web/sync-runs", h.handleSyncRunTable)
+
+		// contracts
+		r.Get("/web/contracts", h.handleContractTable)
+
+		// utilization
+		r.Get("/web/utilization", h.handleUtilizationTable)
 
 		// dashboard
 		r.Get("/web/dashboard", h.handleDashboardContent)
@@ -109,9 +132,31 @@ func (h *Handler) Register(r chi.Router) {
 
 		// settings
 		r.Post("/web/theme", h.handleThemeToggle)
+
+		// backup
+		r.Get("/web/backup", h.handleBackupDownload)
+
+		// import and export
+		r.Get("/web/export", h.handleExport)
+		r.Post("/web/import", h.handleImport)
+
+		// trash
+		r.Get("/web/trash", h.handleTrashTable)
+		r.Post("/web/trash/{kind}/{id}/restore", h.handleTrashRestore)
 	})
 }
 
+// resourceKindNames are display names of resource kinds
+var resourceKindNames = map[enum.ResourceKind]string{
+	enum.ResourceKindVolume:       "Volume",
+	enum.ResourceKindFloatingIP:   "Floating IP",
+	enum.ResourceKindPrimaryIP:    "Primary IP",
+	enum.ResourceKindLoadBalancer: "Load Balancer",
+	enum.ResourceKindSnapshot:     "Snapshot",
+	enum.ResourceKindStorageBox:   "Storage Box",
+	enum.ResourceKindVSwitch:      "vSwitch",
+}
+
 // templateFuncs returns custom template functions
 func templateFuncs() template.FuncMap {
 	return template.FuncMap{
@@ -150,6 +195,30 @@ func templateFuncs() template.FuncMap {
 			}
 			return ""
 		},
+		"fieldLock": func(srv *store.ServerWithAccount, field string) fieldLock {
+			f, err := enum.ParseServerField(field)
+			return fieldLock{Field: field, Locked: err == nil && srv.IsLocked(f), Synced: srv.ExternalID != ""}
+		},
+		"sortOptions":           func() []sortOption { return serverSortOptions },
+		"daysUntil":             daysUntil,
+		"contractDayOptions":    func() []int { return contractDayOptions },
+		"actionName":            provider.ActionName,
+		"resourcesChangeID":     func() string { return provider.ResourcesChangeID },
+		"sparkline":             sparkline,
+		"formatRate":            formatRate,
+		"formatPercent":         func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+		"utilizationDayOptions": func() []int { return utilizationDayOptions },
+		"utilizationCPUOptions": func() []int { return utilizationCPUOptions },
+		"destructiveAction": func(action enum.ServerAction) bool {
+			switch action {
+			case enum.ServerActionShutdown, enum.ServerActionReset, enum.ServerActionDisableBackups:
+				return true
+			}
+			return false
+		},
+		"resourceKindName": func(kind enum.ResourceKind) string {
+			return resourceKindNames[kind]
+		},
 		"actionClass": func(action enum.LogAction) string {
 			switch action {
 			case enum.LogActionAdded:
@@ -162,6 +231,8 @@ func templateFuncs() template.FuncMap {
 				return "action-updated"
 			case enum.LogActionSynced:
 				return "action-synced"
+			case enum.LogActionExecuted:
+				return "action-executed"
 			}
 			return ""
 		},
@@ -181,7 +252,15 @@ func parseTemplates() (*template.Template, error) {
 		"server-table",
 		"server-form",
 		"server-card",
+		"field-lock",
 		"server-logs",
+		"sync-run-table",
+		"sync-preview",
+		"sync-progress",
+		"contract-table",
+		"utilization-table",
+		"import-report",
+		"trash-table",
 		"dashboard-stats",
 		"dashboard-accounts",
 		"status-badge",
@@ -206,6 +285,11 @@ func parseTemplates() (*template.Template, error) {
 		"accounts.html",
 		"servers.html",
 		"logs.html",
+		"sync-runs.html",
+		"contracts.html",
+		"utilization.html",
+		"data.html",
+		"trash.html",
 		"login.html",
 		"setup.html",
 	}
@@ -224,6 +308,13 @@ func parseTemplates() (*template.Template, error) {
 	return tmpl, nil
 }
 
+// fieldLock holds lock state of a server form field
+type fieldLock struct {
+	Field  string
+	Locked bool
+	Synced bool // server is managed by provider sync
+}
+
 // templateData holds common data passed to templates
 type templateData struct {
 	Theme        enum.Theme
@@ -242,18 +333,48 @@ type templateData struct {
 	Provider  *store.Provider
 
 	// accounts data
-	Accounts []store.AccountWithProvider
-	Account  *store.AccountWithProvider
+	Accounts     []store.AccountWithProvider
+	Account      *store.AccountWithProvider
+	LastSyncRuns map[int64]store.SyncRun
 
 	// servers data
-	Servers  []store.ServerWithAccount
-	Server   *store.ServerWithAccount
-	Statuses []enum.ServerStatus
+	Servers    []store.ServerWithAccount
+	Server     *store.ServerWithAccount
+	Statuses   []enum.ServerStatus
+	SyncErrors []string
+	Filter     store.ServerFilter
+	Specs      *store.ServerSpecOptions
+	Addresses  []store.ServerAddress
+	Tags       []store.ServerTag           // user tags of the edited server
+	ServerTags map[int64][]store.ServerTag // tags of listed servers by server ID
+	TagOptions []store.TagFilter
+	TagFilter  string // tag filter of dashboard and logs
+
+	// sync preview and progress data
+	SyncPreviews []syncPreview
+	SyncJob      *provider.Job
+
+	// sync history data
+	SyncRuns      []store.SyncRunWithAccount
+	AccountFilter string
+
+	// contracts data
+	ContractDays int
+
+	// utilization data
+	Utilization *utilizationReport
 
 	// logs data
 	Logs         []store.ServerLogWithServer
 	Actions      []enum.LogAction
 	ActionFilter string
+
+	// import and export data
+	Sections     []inventory.Section
+	ImportReport *inventory.Report
+
+	// trash data
+	Trash *store.Trash
 }
 
 // getTheme returns the current theme from cookie
@@ -0,0 +1,300 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/provider"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// testServer is the web UI served on a test store
+type testServer struct {
+	t      *testing.T
+	st     store.Store
+	url    string
+	client *http.Client
+}
+
+// newTestServer starts the web UI on an in-memory store, registry provides syncers of the sync engine
+func newTestServer(t *testing.T, registry *provider.Registry) *testServer {
+	return newTestServerWithStore(t, store.NewMemory(), registry)
+}
+
+// newTestServerWithStore starts the web UI on the store
+func newTestServerWithStore(t *testing.T, st store.Store, registry *provider.Registry) *testServer {
+	engine := provider.NewEngine(st, registry)
+	t.Cleanup(engine.Close)
+	jobs := provider.NewJobRunner(engine, 2)
+	t.Cleanup(jobs.Close)
+
+	h, err := New(st, engine, jobs)
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	h.Register(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar, Timeout: 10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	return &testServer{t: t, st: st, url: ts.URL, client: client}
+}
+
+// login creates a user with a session and sets the session cookie of the client
+func (s *testServer) login() {
+	ctx := context.Background()
+	user := &store.User{Username: "admin", PasswordHash: "not used"}
+	require.NoError(s.t, s.st.CreateUser(ctx, user))
+	sess := &store.Session{ID: "test-session", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(s.t, s.st.CreateSession(ctx, sess))
+
+	u, err := url.Parse(s.url)
+	require.NoError(s.t, err)
+	s.client.Jar.SetCookies(u, []*http.Cookie{{Name: sessionCookieName, Value: sess.ID, Path: "/"}})
+}
+
+// do sends the request with form values in the body and returns response status and body
+func (s *testServer) do(method, path string, form url.Values) (int, string) {
+	resp, body := s.request(method, path, form)
+	return resp.StatusCode, body
+}
+
+// redirect sends the request and returns the location it redirects to
+func (s *testServer) redirect(method, path string, form url.Values) string {
+	resp, _ := s.request(method, path, form)
+	require.Equal(s.t, http.StatusSeeOther, resp.StatusCode, "%s %s redirects", method, path)
+	return resp.Header.Get("Location")
+}
+
+// request sends the request with form values in the body, redirects are not followed
+func (s *testServer) request(method, path string, form url.Values) (*http.Response, string) {
+	req, err := http.NewRequest(method, s.url+path, strings.NewReader(form.Encode()))
+	require.NoError(s.t, err)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := s.client.Do(req)
+	require.NoError(s.t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(s.t, err)
+	return resp, string(body)
+}
+
+func TestHandler_SetupAndLogin(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+
+	assert.Equal(t, "/login", s.redirect(http.MethodGet, "/", nil), "pages require login")
+	assert.Equal(t, "/setup", s.redirect(http.MethodGet, "/login", nil), "setup first without users")
+
+	code, body := s.do(http.MethodPost, "/setup", url.Values{"username": {"admin"}, "password": {"secret123"},
+		"confirm_password": {"other"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "Passwords do not match")
+
+	code, _ = s.do(http.MethodPost, "/setup", url.Values{"username": {"admin"}, "password": {"secret123"},
+		"confirm_password": {"secret123"}})
+	require.Equal(t, http.StatusSeeOther, code)
+	count, err := s.st.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "/login", s.redirect(http.MethodGet, "/setup", nil), "setup done only once")
+
+	code, body = s.do(http.MethodPost, "/login", url.Values{"username": {"admin"}, "password": {"wrong"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "Invalid username or password")
+
+	code, _ = s.do(http.MethodPost, "/login", url.Values{"username": {"admin"}, "password": {"secret123"}})
+	require.Equal(t, http.StatusSeeOther, code)
+	code, body = s.do(http.MethodGet, "/", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "<html")
+
+	assert.Equal(t, "/login", s.redirect(http.MethodGet, "/logout", nil))
+	assert.Equal(t, "/login", s.redirect(http.MethodGet, "/servers", nil), "session removed on logout")
+}
+
+func TestHandler_Pages(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+
+	ctx := context.Background()
+	acc := &store.Account{ProviderID: 1, Name: "main"}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+	srv := &store.Server{AccountID: acc.ID, Name: "web-1", IP: "10.0.0.1", Status: enum.ServerStatusActive,
+		PaidUntil: time.Now().AddDate(0, 0, 3)}
+	require.NoError(t, s.st.CreateServer(ctx, srv))
+
+	for _, path := range []string{"/", "/providers", "/accounts", "/servers", "/logs", "/sync-runs", "/contracts",
+		"/utilization", "/web/dashboard", "/web/dashboard/stats", "/web/servers?search=web&sort=-cost",
+		"/web/servers/" + strconv.FormatInt(srv.ID, 10) + "/view", "/web/servers/new", "/web/accounts/new",
+		"/web/providers/new", "/web/logs", "/web/sync-runs", "/web/contracts", "/web/utilization", "/trash", "/web/trash"} {
+		t.Run(path, func(t *testing.T) {
+			code, body := s.do(http.MethodGet, path, nil)
+			assert.Equal(t, http.StatusOK, code)
+			assert.NotEmpty(t, body)
+		})
+	}
+
+	_, body := s.do(http.MethodGet, "/web/contracts", nil)
+	assert.Contains(t, body, "web-1", "contract ending soon listed")
+}
+
+func TestHandler_ProviderCRUD(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+
+	code, body := s.do(http.MethodPost, "/web/providers", url.Values{"ident": {"ovh"}, "name": {"OVH"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "OVH")
+
+	code, body = s.do(http.MethodPost, "/web/providers", url.Values{"ident": {"ovh"}, "name": {"OVH again"}})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, "already exists")
+
+	code, _ = s.do(http.MethodPost, "/web/providers", url.Values{"ident": {"x"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	p, err := s.st.GetProviderByName(context.Background(), "OVH")
+	require.NoError(t, err)
+	path := "/web/providers/" + strconv.FormatInt(p.ID, 10)
+
+	code, body = s.do(http.MethodPut, path, url.Values{"ident": {"ovh"}, "name": {"OVHcloud"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "OVHcloud")
+
+	code, _ = s.do(http.MethodGet, path+"/edit", nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = s.do(http.MethodDelete, path, nil)
+	require.Equal(t, http.StatusOK, code)
+	_, err = s.st.GetProvider(context.Background(), p.ID)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	code, _ = s.do(http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandler_AccountCRUD(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	ctx := context.Background()
+
+	form := url.Values{"provider_id": {"1"}, "name": {"main"}, "group_name": {"prod"}, "api_key": {"token"},
+		"sync_interval": {"30"}}
+	code, body := s.do(http.MethodPost, "/web/accounts", form)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "main")
+
+	code, _ = s.do(http.MethodPost, "/web/accounts", form)
+	assert.Equal(t, http.StatusConflict, code)
+	form.Set("sync_interval", "-1")
+	code, _ = s.do(http.MethodPost, "/web/accounts", form)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	accounts, err := s.st.ListAccounts(ctx)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	acc := accounts[0]
+	assert.Equal(t, "token", acc.ApiKey)
+	assert.Equal(t, 30, acc.SyncInterval)
+	path := "/web/accounts/" + strconv.FormatInt(acc.ID, 10)
+
+	code, _ = s.do(http.MethodPut, path, url.Values{"provider_id": {"1"}, "name": {"renamed"}, "api_key": {"token"}})
+	require.Equal(t, http.StatusOK, code)
+	got, err := s.st.GetAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", got.Name)
+
+	code, _ = s.do(http.MethodGet, path+"/edit", nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = s.do(http.MethodDelete, path, nil)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = s.do(http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandler_ServerCRUD(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	ctx := context.Background()
+
+	acc := &store.Account{ProviderID: 1, Name: "main"}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+	accountID := strconv.FormatInt(acc.ID, 10)
+
+	code, _ := s.do(http.MethodPost, "/web/servers", url.Values{"account_id": {accountID}})
+	assert.Equal(t, http.StatusBadRequest, code, "name is required")
+
+	code, body := s.do(http.MethodPost, "/web/servers", url.Values{"account_id": {accountID}, "name": {"web-1"},
+		"ip": {"10.0.0.1"}, "approximate_cost": {"12.5"}, "backups": {"on"}, "cpu_cores": {"4"},
+		"addresses": {"10.0.0.2\n192.168.0.0/24"}, "tags": {"env=prod"}, "paid_until": {"2030-01-01"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "web-1")
+
+	servers, err := s.st.ListServers(ctx)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	srv := servers[0]
+	assert.Equal(t, enum.ServerStatusActive, srv.Status)
+	assert.InDelta(t, 12.5, srv.ApproximateCost, 0.001)
+	assert.True(t, srv.Backups)
+	assert.Equal(t, 4, srv.CPUCores)
+	assert.Equal(t, 2030, srv.PaidUntil.Year())
+	addrs, err := s.st.ListServerAddresses(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, addrs)
+	tags, err := s.st.ListServerTags(ctx, srv.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "prod", tags[0].Value)
+	path := "/web/servers/" + strconv.FormatInt(srv.ID, 10)
+
+	code, _ = s.do(http.MethodPut, path, url.Values{"account_id": {accountID}, "name": {"web-1-renamed"},
+		"status": {"active"}})
+	require.Equal(t, http.StatusOK, code)
+	got, err := s.st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1-renamed", got.Name)
+
+	code, _ = s.do(http.MethodPut, path+"/status", url.Values{"status": {"paused"}})
+	require.Equal(t, http.StatusOK, code)
+	got, err = s.st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enum.ServerStatusPaused, got.Status)
+
+	code, _ = s.do(http.MethodPut, path+"/status", url.Values{"status": {"unknown"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	logs, err := s.st.ListLogsByServer(ctx, srv.ID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	assert.Equal(t, enum.LogActionPaused, logs[0].Action)
+
+	code, _ = s.do(http.MethodGet, path+"/edit", nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = s.do(http.MethodDelete, path, nil)
+	require.Equal(t, http.StatusOK, code)
+	_, err = s.st.GetServer(ctx, srv.ID)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	code, _ = s.do(http.MethodDelete, "/web/servers/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
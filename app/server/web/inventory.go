@@ -0,0 +1,100 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	"github.com/nilBora/servers-manager/app/inventory"
+)
+
+// maxImportSize limits the size of uploaded inventory files
+const maxImportSize = 32 << 20
+
+// handleExport downloads providers, accounts, servers and logs in the requested format,
+// CSV holds the single requested section
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	format, err := inventory.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid format")
+		return
+	}
+	section := inventory.SectionServers
+	if format == inventory.FormatCSV {
+		if section, err = inventory.ParseSection(r.URL.Query().Get("section")); err != nil {
+			h.renderError(w, http.StatusBadRequest, "Invalid section")
+			return
+		}
+	}
+
+	inv, err := inventory.Export(r.Context(), h.store, inventory.ExportOptions{RedactKeys: r.URL.Query().Get("redact") == "on"})
+	if err != nil {
+		log.Printf("[ERROR] failed to export inventory: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to export")
+		return
+	}
+
+	name := "servers-manager-" + time.Now().UTC().Format("20060102-150405")
+	if format == inventory.FormatCSV {
+		name += "-" + string(section)
+	}
+	contentTypes := map[inventory.Format]string{inventory.FormatJSON: "application/json",
+		inventory.FormatYAML: "application/yaml", inventory.FormatCSV: "text/csv"}
+	w.Header().Set("Content-Type", contentTypes[format]+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+string(format)))
+	if err := inventory.Write(w, inv, format, section); err != nil {
+		log.Printf("[WARN] failed to write export: %v", err)
+	}
+}
+
+// handleImport imports the uploaded inventory file and renders the report,
+// a dry run only validates the file and counts the changes
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.renderError(w, http.StatusRequestEntityTooLarge, "File is too large")
+			return
+		}
+		h.renderError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	format, err := inventory.FormatOf(header.Filename)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Unsupported file type, expected .json, .yaml or .csv")
+		return
+	}
+	var section inventory.Section
+	if format == inventory.FormatCSV {
+		if section, err = inventory.ParseSection(r.FormValue("section")); err != nil {
+			h.renderError(w, http.StatusBadRequest, "Invalid section")
+			return
+		}
+	}
+
+	inv, err := inventory.Read(file, format, section)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, html.EscapeString(err.Error()))
+		return
+	}
+
+	report, err := inventory.Import(r.Context(), h.store, inv, inventory.ImportOptions{DryRun: r.FormValue("dry_run") == "on"})
+	if err != nil {
+		log.Printf("[ERROR] failed to import inventory: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to import, records before the failure are imported")
+		return
+	}
+	log.Printf("[INFO] imported %s, dry run %v, %d issues", header.Filename, report.DryRun, len(report.Issues))
+
+	if err := h.tmpl.ExecuteTemplate(w, "import-report", templateData{ImportReport: report}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
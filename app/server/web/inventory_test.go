@@ -0,0 +1,111 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/inventory"
+	"github.com/nilBora/servers-manager/app/provider"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// upload posts the file as multipart form with the other form values
+func (s *testServer) upload(path, filename, content string, values map[string]string) (int, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	require.NoError(s.t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(s.t, err)
+	for k, v := range values {
+		require.NoError(s.t, mw.WriteField(k, v))
+	}
+	require.NoError(s.t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, s.url+path, &buf)
+	require.NoError(s.t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := s.client.Do(req)
+	require.NoError(s.t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(s.t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestHandler_Export(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	ctx := context.Background()
+	acc := &store.Account{ProviderID: 1, Name: "main", ApiKey: "secret"}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+	require.NoError(t, s.st.CreateServer(ctx, &store.Server{AccountID: acc.ID, Name: "web-1", IP: "1.2.3.4"}))
+
+	code, body := s.do(http.MethodGet, "/data", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "Import / Export")
+
+	resp, body := s.request(http.MethodGet, "/web/export?format=json&redact=on", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Regexp(t, `^attachment; filename="servers-manager-\d{8}-\d{6}\.json"$`, resp.Header.Get("Content-Disposition"))
+	var inv inventory.Inventory
+	require.NoError(t, json.Unmarshal([]byte(body), &inv))
+	require.Len(t, inv.Accounts, 1)
+	assert.Empty(t, inv.Accounts[0].APIKey, "keys redacted")
+	require.Len(t, inv.Servers, 1)
+	assert.Equal(t, "web-1", inv.Servers[0].Name)
+
+	resp, body = s.request(http.MethodGet, "/web/export?format=csv&section=servers", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "-servers.csv")
+	assert.Contains(t, body, "main,web-1,1.2.3.4")
+
+	code, _ = s.do(http.MethodGet, "/web/export?format=xml", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = s.do(http.MethodGet, "/web/export?format=csv&section=users", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestHandler_Import(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	ctx := context.Background()
+	acc := &store.Account{ProviderID: 1, Name: "main"}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+
+	p, err := s.st.GetProvider(ctx, 1)
+	require.NoError(t, err)
+	data := "provider,account,name,ip\n" +
+		p.Ident + ",main,web-1,1.2.3.4\n" +
+		p.Ident + ",other,web-2,\n"
+
+	code, body := s.upload("/web/import", "servers.csv", data, map[string]string{"section": "servers", "dry_run": "on"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "Dry run")
+	assert.Contains(t, body, "unknown account "+p.Ident+"/other")
+	servers, err := s.st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Empty(t, servers, "dry run writes nothing")
+
+	code, body = s.upload("/web/import", "servers.csv", data, map[string]string{"section": "servers"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "Imported")
+	servers, err = s.st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "1.2.3.4", servers[0].IP)
+
+	code, _ = s.upload("/web/import", "servers.xlsx", data, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, body = s.upload("/web/import", "servers.csv", "provider,<b>\n", map[string]string{"section": "servers"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "&lt;b&gt;", "file content escaped in errors")
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/inventory"
+	"github.com/nilBora/servers-manager/app/store"
 )
 
 // handleDashboard renders the dashboard page
@@ -14,18 +16,26 @@ func (h *Handler) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	providerGroups, err := h.store.GetServersGroupedHierarchically(r.Context(), nil)
+	providerGroups, err := h.store.GetServersGroupedHierarchically(r.Context(), nil, store.TagFilter{})
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load servers")
 		return
 	}
 
+	tags, err := h.loadServerTags(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
 	data := templateData{
 		Theme:          h.getTheme(r),
 		ActivePage:     "dashboard",
 		Stats:          stats,
 		ProviderGroups: providerGroups,
 		Statuses:       enum.AllServerStatuses(),
+		ServerTags:     tags.tags,
+		TagOptions:     tags.options,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "dashboard.html", data); err != nil {
@@ -66,11 +76,18 @@ func (h *Handler) handleAccounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastSyncRuns, err := h.store.GetLastSyncRuns(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load sync history")
+		return
+	}
+
 	data := templateData{
-		Theme:      h.getTheme(r),
-		ActivePage: "accounts",
-		Accounts:   accounts,
-		Providers:  providers,
+		Theme:        h.getTheme(r),
+		ActivePage:   "accounts",
+		Accounts:     accounts,
+		Providers:    providers,
+		LastSyncRuns: lastSyncRuns,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "accounts.html", data); err != nil {
@@ -80,24 +97,41 @@ func (h *Handler) handleAccounts(w http.ResponseWriter, r *http.Request) {
 
 // handleServers renders the servers page
 func (h *Handler) handleServers(w http.ResponseWriter, r *http.Request) {
-	servers, err := h.store.ListServersWithAccounts(r.Context())
+	filter := parseServerFilter(r)
+	servers, err := h.store.ListServersFiltered(r.Context(), filter)
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load servers")
 		return
 	}
 
+	specs, err := h.store.GetServerSpecOptions(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load server specs")
+		return
+	}
+
 	accounts, err := h.store.ListAccountsWithProviders(r.Context())
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load accounts")
 		return
 	}
 
+	tags, err := h.loadServerTags(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
 	data := templateData{
 		Theme:      h.getTheme(r),
 		ActivePage: "servers",
 		Servers:    servers,
 		Accounts:   accounts,
 		Statuses:   enum.AllServerStatuses(),
+		Filter:     filter,
+		Specs:      specs,
+		ServerTags: tags.tags,
+		TagOptions: tags.options,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "servers.html", data); err != nil {
@@ -113,14 +147,122 @@ func (h *Handler) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tagOptions, err := h.store.ListTagOptions(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
 	data := templateData{
 		Theme:      h.getTheme(r),
 		ActivePage: "logs",
 		Logs:       logs,
 		Actions:    enum.AllLogActions(),
+		TagOptions: tagOptions,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "logs.html", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// handleSyncRuns renders the sync history page
+func (h *Handler) handleSyncRuns(w http.ResponseWriter, r *http.Request) {
+	accountFilter := r.URL.Query().Get("account_id")
+
+	runs, err := h.listSyncRuns(r, accountFilter)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load sync history")
+		return
+	}
+
+	accounts, err := h.store.ListAccountsWithProviders(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load accounts")
+		return
+	}
+
+	data := templateData{
+		Theme:         h.getTheme(r),
+		ActivePage:    "sync-runs",
+		SyncRuns:      runs,
+		Accounts:      accounts,
+		AccountFilter: accountFilter,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "sync-runs.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleContracts renders the page of servers whose contract ends soon
+func (h *Handler) handleContracts(w http.ResponseWriter, r *http.Request) {
+	days := parseContractDays(r)
+	servers, err := h.listContracts(r, days)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load contracts")
+		return
+	}
+
+	data := templateData{
+		Theme:        h.getTheme(r),
+		ActivePage:   "contracts",
+		Servers:      servers,
+		ContractDays: days,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "contracts.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleUtilization renders the page of underutilized servers
+func (h *Handler) handleUtilization(w http.ResponseWriter, r *http.Request) {
+	report, err := h.underutilizedServers(r)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load utilization")
+		return
+	}
+
+	data := templateData{
+		Theme:       h.getTheme(r),
+		ActivePage:  "utilization",
+		Utilization: report,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "utilization.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleData renders the import and export page
+func (h *Handler) handleData(w http.ResponseWriter, r *http.Request) {
+	data := templateData{
+		Theme:      h.getTheme(r),
+		ActivePage: "data",
+		Sections:   inventory.AllSections(),
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "data.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleTrash renders the page of deleted providers, accounts and servers
+func (h *Handler) handleTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.store.ListTrash(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load trash")
+		return
+	}
+
+	data := templateData{
+		Theme:      h.getTheme(r),
+		ActivePage: "trash",
+		Trash:      trash,
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "trash.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
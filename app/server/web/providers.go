@@ -84,6 +84,10 @@ func (h *Handler) handleProviderCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.CreateProvider(r.Context(), provider); err != nil {
+		if errors.Is(err, store.ErrInTrash) {
+			h.renderError(w, http.StatusConflict, "Provider with this ident is in the trash, restore it first")
+			return
+		}
 		if errors.Is(err, store.ErrConflict) {
 			h.renderError(w, http.StatusConflict, "Provider with this ident already exists")
 			return
@@ -131,6 +135,10 @@ func (h *Handler) handleProviderUpdate(w http.ResponseWriter, r *http.Request) {
 			h.renderError(w, http.StatusNotFound, "Provider not found")
 			return
 		}
+		if errors.Is(err, store.ErrInTrash) {
+			h.renderError(w, http.StatusConflict, "Provider with this ident is in the trash, restore it first")
+			return
+		}
 		if errors.Is(err, store.ErrConflict) {
 			h.renderError(w, http.StatusConflict, "Provider with this ident already exists")
 			return
@@ -2,24 +2,54 @@ package web
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/provider"
 	"github.com/nilBora/servers-manager/app/store"
 )
 
-// handleServerTable renders the server table partial
+// handleServerTable renders the server table partial, filtered and sorted by query parameters
 func (h *Handler) handleServerTable(w http.ResponseWriter, r *http.Request) {
-	servers, err := h.store.ListServersWithAccounts(r.Context())
+	h.renderServerTable(w, r, nil)
+}
+
+// renderServerTable renders the server table partial with the filter bar, filtered and sorted
+// by the request parameters, and sync errors shown above it
+func (h *Handler) renderServerTable(w http.ResponseWriter, r *http.Request, syncErrors []string) {
+	filter := parseServerFilter(r)
+	servers, err := h.store.ListServersFiltered(r.Context(), filter)
 	if err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to load servers")
 		return
 	}
 
+	specs, err := h.store.GetServerSpecOptions(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load server specs")
+		return
+	}
+
+	tags, err := h.loadServerTags(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
 	data := templateData{
-		Servers:  servers,
-		Statuses: enum.AllServerStatuses(),
+		Servers:    servers,
+		Statuses:   enum.AllServerStatuses(),
+		Filter:     filter,
+		Specs:      specs,
+		ServerTags: tags.tags,
+		TagOptions: tags.options,
+		SyncErrors: syncErrors,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "server-table", data); err != nil {
@@ -69,10 +99,31 @@ func (h *Handler) handleServerEditForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	addrs, err := h.store.ListServerAddresses(r.Context(), id)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load addresses")
+		return
+	}
+
+	tags, err := h.store.ListServerTags(r.Context(), id)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
+	var userTags []store.ServerTag
+	for _, t := range tags {
+		if t.Source == enum.TagSourceUser {
+			userTags = append(userTags, t)
+		}
+	}
+
 	data := templateData{
-		Server:   server,
-		Accounts: accounts,
-		Statuses: enum.AllServerStatuses(),
+		Server:    server,
+		Accounts:  accounts,
+		Statuses:  enum.AllServerStatuses(),
+		Addresses: addrs,
+		Tags:      userTags,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "server-form", data); err != nil {
@@ -87,7 +138,49 @@ func (h *Handler) handleServerView(w http.ResponseWriter, r *http.Request) {
 		h.renderError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	h.renderServerCard(w, r, id, "")
+}
+
+// handleServerAction starts a power or lifecycle action on the server at its provider
+// and renders the server card showing the action progress
+func (h *Handler) handleServerAction(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	action, err := enum.ParseServerAction(chi.URLParam(r, "action"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Unknown action")
+		return
+	}
+
+	server, err := h.store.GetServer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			h.renderError(w, http.StatusNotFound, "Server not found")
+			return
+		}
+		h.renderError(w, http.StatusInternalServerError, "Failed to load server")
+		return
+	}
+
+	acc, err := h.store.GetAccountWithProvider(r.Context(), server.AccountID)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load account")
+		return
+	}
+
+	var actionErr string
+	if err := h.engine.StartAction(acc, server, action); err != nil {
+		actionErr = err.Error()
+	}
+	h.renderServerCard(w, r, id, actionErr)
+}
+
+// renderServerCard renders the server view modal with an optional error of the last action request
+func (h *Handler) renderServerCard(w http.ResponseWriter, r *http.Request, id int64, actionErr string) {
 	server, err := h.store.GetServerWithAccount(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -104,12 +197,61 @@ func (h *Handler) handleServerView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	addrs, err := h.store.ListServerAddresses(r.Context(), id)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load addresses")
+		return
+	}
+
+	tags, err := h.store.ListServerTags(r.Context(), id)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load tags")
+		return
+	}
+
+	resources, err := h.store.ListResourcesByServer(r.Context(), id)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load resources")
+		return
+	}
+
+	acc, err := h.store.GetAccountWithProvider(r.Context(), server.AccountID)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load account")
+		return
+	}
+
+	metrics, err := h.store.ListServerMetrics(r.Context(), id, time.Now().UTC().AddDate(0, 0, -7))
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load metrics")
+		return
+	}
+
+	var running string
+	if action, ok := h.engine.RunningAction(id); ok {
+		running = provider.ActionName(action)
+	}
+
 	data := struct {
-		Server *store.ServerWithAccount
-		Logs   []store.ServerLog
+		Server        *store.ServerWithAccount
+		Addresses     []store.ServerAddress
+		Tags          []store.ServerTag
+		Resources     []store.Resource
+		Logs          []store.ServerLog
+		Utilization   *utilizationView // last 7 days, nil if no metrics are collected
+		Actions       []enum.ServerAction
+		RunningAction string // name of the action in progress, empty if none
+		ActionError   string
 	}{
-		Server: server,
-		Logs:   logs,
+		Server:        server,
+		Addresses:     addrs,
+		Tags:          tags,
+		Resources:     resources,
+		Logs:          logs,
+		Utilization:   newUtilizationView(metrics),
+		Actions:       h.engine.ServerActions(acc, &server.Server),
+		RunningAction: running,
+		ActionError:   actionErr,
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "server-card", data); err != nil {
@@ -148,17 +290,41 @@ func (h *Handler) handleServerCreate(w http.ResponseWriter, r *http.Request) {
 		Backups:         r.FormValue("backups") == "on",
 		Status:          status,
 	}
+	setServerSpecs(server, r)
+	setServerContract(server, r)
 
 	if server.Name == "" {
 		h.renderError(w, http.StatusBadRequest, "Name is required")
 		return
 	}
 
+	addrs, err := parseAddresses(r.FormValue("addresses"), server.IP)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid addresses: "+err.Error())
+		return
+	}
+
+	tags, err := parseTags(r.FormValue("tags"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid tags: "+err.Error())
+		return
+	}
+
 	if err := h.store.CreateServer(r.Context(), server); err != nil {
 		h.renderError(w, http.StatusInternalServerError, "Failed to create server")
 		return
 	}
 
+	if err := h.store.SetServerAddresses(r.Context(), server.ID, addrs); err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to save addresses")
+		return
+	}
+
+	if err := h.store.SetServerTags(r.Context(), server.ID, enum.TagSourceUser, tags); err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to save tags")
+		return
+	}
+
 	// create log entry
 	logEntry := &store.ServerLog{
 		ServerID:    server.ID,
@@ -198,17 +364,44 @@ func (h *Handler) handleServerUpdate(w http.ResponseWriter, r *http.Request) {
 
 	cost, _ := strconv.ParseFloat(r.FormValue("approximate_cost"), 64)
 
-	server := &store.Server{
-		ID:              id,
-		AccountID:       accountID,
-		Name:            r.FormValue("name"),
-		IP:              r.FormValue("ip"),
-		Location:        r.FormValue("location"),
-		Description:     r.FormValue("description"),
-		Responsible:     r.FormValue("responsible"),
-		ApproximateCost: cost,
-		Backups:         r.FormValue("backups") == "on",
-		Status:          status,
+	// load existing server to keep fields not present in the form, e.g. external ID set by sync
+	server, err := h.store.GetServer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			h.renderError(w, http.StatusNotFound, "Server not found")
+			return
+		}
+		h.renderError(w, http.StatusInternalServerError, "Failed to load server")
+		return
+	}
+
+	// external ID is only meaningful within the account it was synced from
+	if server.AccountID != accountID {
+		server.ExternalID = ""
+		server.ProviderIdent = ""
+	}
+
+	server.AccountID = accountID
+	server.Name = r.FormValue("name")
+	server.IP = r.FormValue("ip")
+	server.Location = r.FormValue("location")
+	server.Description = r.FormValue("description")
+	server.Responsible = r.FormValue("responsible")
+	server.ApproximateCost = cost
+	server.Backups = r.FormValue("backups") == "on"
+	server.Status = status
+	setServerSpecs(server, r)
+	setServerContract(server, r)
+
+	// fields locked against sync
+	server.LockedFields = nil
+	for _, v := range r.Form["locked"] {
+		field, err := enum.ParseServerField(v)
+		if err != nil {
+			h.renderError(w, http.StatusBadRequest, "Invalid locked field")
+			return
+		}
+		server.LockedFields = append(server.LockedFields, field)
 	}
 
 	if server.Name == "" {
@@ -216,6 +409,18 @@ func (h *Handler) handleServerUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	addrs, err := parseAddresses(r.FormValue("addresses"), server.IP)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid addresses: "+err.Error())
+		return
+	}
+
+	tags, err := parseTags(r.FormValue("tags"))
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid tags: "+err.Error())
+		return
+	}
+
 	if err := h.store.UpdateServer(r.Context(), server); err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			h.renderError(w, http.StatusNotFound, "Server not found")
@@ -225,6 +430,16 @@ func (h *Handler) handleServerUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.store.SetServerAddresses(r.Context(), id, addrs); err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to save addresses")
+		return
+	}
+
+	if err := h.store.SetServerTags(r.Context(), id, enum.TagSourceUser, tags); err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to save tags")
+		return
+	}
+
 	// create log entry
 	logEntry := &store.ServerLog{
 		ServerID:    id,
@@ -306,3 +521,96 @@ func (h *Handler) handleServerDelete(w http.ResponseWriter, r *http.Request) {
 	// return updated table
 	h.handleServerTable(w, r)
 }
+
+// sortOption is a server table sort order offered in the filter bar
+type sortOption struct {
+	Value string
+	Label string
+}
+
+// serverSortOptions lists sort orders supported by store.ListServersFiltered
+var serverSortOptions = []sortOption{
+	{"", "Default order"},
+	{"name", "Name"},
+	{"-cpu_cores", "Most cores"},
+	{"cpu_cores", "Fewest cores"},
+	{"-memory", "Most memory"},
+	{"-disk", "Largest disk"},
+	{"-cost", "Highest cost"},
+	{"cost", "Lowest cost"},
+	{"server_type", "Server type"},
+	{"os", "OS"},
+	{"datacenter", "Datacenter"},
+}
+
+// setServerSpecs sets hardware and OS specs of the server from form values, invalid numbers are treated as unknown
+func setServerSpecs(server *store.Server, r *http.Request) {
+	server.CPUCores, _ = strconv.Atoi(r.FormValue("cpu_cores"))
+	server.MemoryGB, _ = strconv.ParseFloat(r.FormValue("memory_gb"), 64)
+	server.DiskGB, _ = strconv.Atoi(r.FormValue("disk_gb"))
+	server.ServerType = strings.TrimSpace(r.FormValue("server_type"))
+	server.OSFlavor = strings.TrimSpace(r.FormValue("os_flavor"))
+	server.OSVersion = strings.TrimSpace(r.FormValue("os_version"))
+	server.Datacenter = strings.TrimSpace(r.FormValue("datacenter"))
+}
+
+// setServerContract sets contract dates of the server from form values, empty or invalid dates are treated as unknown
+func setServerContract(server *store.Server, r *http.Request) {
+	server.PaidUntil, _ = time.Parse("2006-01-02", r.FormValue("paid_until"))
+	server.CancellationDate, _ = time.Parse("2006-01-02", r.FormValue("cancellation_date"))
+}
+
+// parseServerFilter parses server table filter and sort order from query parameters,
+// or from the posted form of requests re-rendering the table
+func parseServerFilter(r *http.Request) store.ServerFilter {
+	_ = r.ParseForm() // malformed parameters are ignored, the same as for URL.Query
+	q := r.Form
+	cores, _ := strconv.Atoi(q.Get("cpu_cores"))
+	return store.ServerFilter{
+		Search:     strings.TrimSpace(q.Get("q")),
+		CPUCores:   cores,
+		OSFlavor:   q.Get("os_flavor"),
+		OSVersion:  q.Get("os_version"),
+		ServerType: q.Get("server_type"),
+		Datacenter: q.Get("datacenter"),
+		Tag:        store.ParseTagFilter(q.Get("tag")),
+		Sort:       q.Get("sort"),
+	}
+}
+
+// parseAddresses parses server addresses from the form, one per line as "address[/prefix] [kind]",
+// kind is public if omitted. The primary IP is added as public address if it is not listed.
+func parseAddresses(text, primaryIP string) ([]store.ServerAddress, error) {
+	var addrs []store.ServerAddress
+	hasPrimary := false
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		kind := enum.AddressKindPublic
+		if len(fields) > 1 {
+			k, err := enum.ParseAddressKind(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("unknown kind %q of %s, expected public, private, floating or subnet", fields[1], fields[0])
+			}
+			kind = k
+		}
+
+		addr, err := store.ParseServerAddress(fields[0], kind)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or network", fields[0])
+		}
+		if addr.Address == primaryIP && addr.Prefix == 0 {
+			hasPrimary = true
+		}
+		addrs = append(addrs, addr)
+	}
+
+	// primary IP field is free text, it's added only if it is a valid address
+	if addr, err := store.ParseServerAddress(primaryIP, enum.AddressKindPublic); err == nil && !hasPrimary {
+		addrs = append([]store.ServerAddress{addr}, addrs...)
+	}
+	return addrs, nil
+}
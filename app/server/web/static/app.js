@@ -114,23 +114,39 @@ function updateApiKeyHint(select) {
 
     const robotHint = document.getElementById('api-key-hint-robot');
     const cloudHint = document.getElementById('api-key-hint-cloud');
+    const scalewayHint = document.getElementById('api-key-hint-scaleway');
+    const awsHint = document.getElementById('api-key-hint-aws');
+    const awsLoginHint = document.getElementById('login-hint-aws');
 
-    if (!robotHint || !cloudHint) return;
+    if (!robotHint || !cloudHint || !scalewayHint || !awsHint || !awsLoginHint) return;
 
     // Hide all hints first
     robotHint.style.display = 'none';
     cloudHint.style.display = 'none';
+    scalewayHint.style.display = 'none';
+    awsHint.style.display = 'none';
+    awsLoginHint.style.display = 'none';
 
     // Show relevant hint
     if (ident === 'hetzner_robot') {
         robotHint.style.display = 'block';
     } else if (ident === 'hetzner_cloud') {
         cloudHint.style.display = 'block';
+    } else if (ident === 'scaleway') {
+        scalewayHint.style.display = 'block';
+    } else if (ident === 'aws') {
+        awsHint.style.display = 'block';
+        awsLoginHint.style.display = 'block';
     }
 }
 
 // Initialize hints on modal load and dashboard sorting on content updates
 document.body.addEventListener('htmx:afterSwap', function(event) {
+    const syncProgress = event.detail.target.querySelector('[data-sync-events]');
+    if (syncProgress) {
+        watchSyncProgress(syncProgress);
+    }
+
     const providerSelect = document.getElementById('provider_id');
     if (providerSelect) {
         updateApiKeyHint(providerSelect);
@@ -258,3 +274,29 @@ function applySavedOrder(container) {
         console.warn('Failed to apply saved dashboard order:', e);
     }
 }
+
+// Sync progress: stream job events and refresh servers table when the job is finished
+function watchSyncProgress(panel) {
+    const list = panel.querySelector('.sync-progress-list');
+    const status = panel.querySelector('.sync-progress-status');
+    const source = new EventSource(panel.dataset.syncEvents);
+
+    source.addEventListener('progress', function(event) {
+        const tmp = document.createElement('ul');
+        tmp.innerHTML = event.data;
+        const item = tmp.firstElementChild;
+        // replace "syncing" line of the account with its outcome
+        const existing = list.querySelector('[data-account-id="' + item.dataset.accountId + '"]');
+        if (existing) {
+            existing.replaceWith(item);
+        } else {
+            list.appendChild(item);
+        }
+    });
+
+    source.addEventListener('finished', function(event) {
+        source.close();
+        status.innerHTML = event.data;
+        htmx.ajax('GET', '/web/servers', {target: '#servers-table', swap: 'innerHTML'});
+    });
+}
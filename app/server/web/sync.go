@@ -1,355 +1,279 @@
 package web
 
 import (
-	"context"
+	"bytes"
+	"errors"
 	"fmt"
-	"math"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	log "github.com/go-pkgz/lgr"
 
 	"github.com/nilBora/servers-manager/app/enum"
-	"github.com/nilBora/servers-manager/app/hetzner"
+	"github.com/nilBora/servers-manager/app/provider"
 	"github.com/nilBora/servers-manager/app/store"
 )
 
-// Provider idents for sync
 const (
-	ProviderIdentHetznerCloud = "hetzner_cloud"
-	ProviderIdentHetznerRobot = "hetzner_robot"
+	syncHistoryLimit = 100              // number of sync runs shown in sync history
+	sseMaxDuration   = 25 * time.Second // max duration of a single event stream, below server write timeout
+	sseRetry         = time.Second      // browser reconnect delay after the stream is closed
 )
 
-// handleHetznerSync syncs servers from all Hetzner accounts (both Cloud and Robot)
-func (h *Handler) handleHetznerSync(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	// Get all accounts with provider info
-	accounts, err := h.store.ListAccountsWithProviders(ctx)
+// handleSync starts a background sync of all accounts of providers supported by the sync engine
+// and renders a progress panel streaming the job events
+func (h *Handler) handleSync(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.syncableAccounts(r)
 	if err != nil {
 		log.Printf("[ERROR] failed to list accounts: %v", err)
 		h.renderError(w, http.StatusInternalServerError, "Failed to load accounts")
 		return
 	}
 
-	var synced int
-
-	for _, acc := range accounts {
-		// Skip accounts without API keys
-		if acc.ApiKey == "" {
-			continue
-		}
-
-		switch acc.ProviderIdent {
-		case ProviderIdentHetznerCloud:
-			count, err := h.syncHetznerCloud(ctx, &acc)
-			if err != nil {
-				log.Printf("[ERROR] failed to sync Hetzner Cloud account %s: %v", acc.Name, err)
-				continue
-			}
-			synced += count
-
-		case ProviderIdentHetznerRobot:
-			count, err := h.syncHetznerRobot(ctx, &acc)
-			if err != nil {
-				log.Printf("[ERROR] failed to sync Hetzner Robot account %s: %v", acc.Name, err)
-				continue
-			}
-			synced += count
-		}
+	job, err := h.jobs.Start(accounts, enum.SyncTriggerManual)
+	if err != nil {
+		log.Printf("[ERROR] failed to start sync job: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to start sync")
+		return
 	}
 
-	log.Printf("[INFO] Hetzner sync completed: %d servers synced", synced)
-
-	// Return updated server table
-	h.handleServerTable(w, r)
+	if err := h.tmpl.ExecuteTemplate(w, "sync-progress", templateData{SyncJob: job}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-// findExistingServer looks up an existing server by IP first, then by name within the same account.
-// Returns nil if not found.
-func (h *Handler) findExistingServer(ctx context.Context, ip, name string, accountID int64) *store.Server {
-	// match by IP first (most reliable identifier)
-	if ip != "" {
-		existing, err := h.store.FindServerByIPAndAccount(ctx, ip, accountID)
-		if err == nil {
-			return existing
-		}
+// handleSyncJobEvents streams progress events of a sync job as Server-Sent Events.
+// The stream is closed before server write timeout; the browser reconnects with Last-Event-ID
+// and continues from the next event.
+func (h *Handler) handleSyncJobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.Job(chi.URLParam(r, "id"))
+	if !ok {
+		http.Error(w, "Sync job not found", http.StatusNotFound)
+		return
 	}
 
-	// fallback to name
-	existing, err := h.store.FindServerByNameAndAccount(ctx, name, accountID)
-	if err == nil {
-		return existing
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
 	}
 
-	return nil
-}
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
 
-// markDeletedServers marks servers that are in DB but not in API response as deleted.
-// seenIDs contains the IDs of servers that were found in the API.
-func (h *Handler) markDeletedServers(ctx context.Context, accountID int64, seenIDs map[int64]bool) {
-	dbServers, err := h.store.ListServersByAccount(ctx, accountID)
-	if err != nil {
-		log.Printf("[ERROR] failed to list servers for account %d: %v", accountID, err)
-		return
-	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	_, _ = fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	flusher.Flush()
 
-	for _, srv := range dbServers {
-		if seenIDs[srv.ID] {
-			continue // server exists in API, skip
-		}
-		if srv.Status == enum.ServerStatusDeleted {
-			continue // already deleted, skip
+	deadline := time.NewTimer(sseMaxDuration)
+	defer deadline.Stop()
+
+	for {
+		events, finished, next := job.Events(after)
+		for _, ev := range events {
+			if err := h.writeSyncJobEvent(w, ev); err != nil {
+				log.Printf("[WARN] failed to write sync job event: %v", err)
+				return
+			}
+			after = ev.Seq
 		}
+		flusher.Flush()
 
-		srv.Status = enum.ServerStatusDeleted
-		if err := h.store.UpdateServer(ctx, &srv); err != nil {
-			log.Printf("[ERROR] failed to mark server %s as deleted: %v", srv.Name, err)
-			continue
+		if finished {
+			return
 		}
 
-		logEntry := &store.ServerLog{
-			ServerID:    srv.ID,
-			Action:      enum.LogActionDeleted,
-			Description: "Server no longer found in API, marked as deleted",
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-next:
 		}
-		_ = h.store.CreateLog(ctx, logEntry)
-		log.Printf("[INFO] marked server %s (IP: %s) as deleted — not found in API", srv.Name, srv.IP)
 	}
 }
 
-// serverChanges compares existing server with new values and returns a human-readable diff.
-// Returns empty string if nothing changed.
-func serverChanges(existing *store.Server, name, ip, location, desc string, cost float64, backups bool, status enum.ServerStatus) string {
-	var changes []string
-
-	if existing.Name != name {
-		changes = append(changes, fmt.Sprintf("Name: %s → %s", existing.Name, name))
-	}
-	if existing.IP != ip {
-		changes = append(changes, fmt.Sprintf("IP: %s → %s", existing.IP, ip))
-	}
-	if existing.Location != location {
-		changes = append(changes, fmt.Sprintf("Location: %s → %s", existing.Location, location))
+// writeSyncJobEvent writes a single job event rendered as HTML fragment in SSE format
+func (h *Handler) writeSyncJobEvent(w io.Writer, ev provider.JobEvent) error {
+	var buf bytes.Buffer
+	if err := h.tmpl.ExecuteTemplate(&buf, "sync-job-event", ev); err != nil {
+		return err
 	}
-	if existing.Status != status {
-		changes = append(changes, fmt.Sprintf("Status: %s → %s", existing.Status.String(), status.String()))
-	}
-	if math.Abs(existing.ApproximateCost-cost) > 0.01 {
-		changes = append(changes, fmt.Sprintf("Cost: $%.2f → $%.2f", existing.ApproximateCost, cost))
+
+	name := "progress"
+	if ev.Kind == provider.JobEventFinished {
+		name = "finished"
 	}
-	if existing.Backups != backups {
-		changes = append(changes, fmt.Sprintf("Backups: %v → %v", existing.Backups, backups))
+
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\n", ev.Seq, name); err != nil {
+		return err
 	}
-	if existing.Description != desc {
-		changes = append(changes, "Description updated")
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if _, err := fmt.Fprintf(w, "data: %s\n", line); err != nil {
+			return err
+		}
 	}
-
-	return strings.Join(changes, ", ")
+	_, err := io.WriteString(w, "\n")
+	return err
 }
 
-// syncHetznerCloud syncs servers from Hetzner Cloud API
-func (h *Handler) syncHetznerCloud(ctx context.Context, acc *store.AccountWithProvider) (int, error) {
-	log.Printf("[INFO] syncing Hetzner Cloud account: %s", acc.Name)
-
-	client := hetzner.NewClient(acc.ApiKey)
-	servers, err := client.ListServers(ctx)
+// syncableAccounts returns accounts with an API key of providers supported by the sync engine
+func (h *Handler) syncableAccounts(r *http.Request) ([]store.AccountWithProvider, error) {
+	accounts, err := h.store.ListAccountsWithProviders(r.Context())
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	var synced int
-	seenIDs := make(map[int64]bool)
-
-	for _, srv := range servers {
-		status := mapHetznerCloudStatus(srv.Status)
-		ip := srv.GetServerIP()
-		location := srv.GetServerLocationDescription()
-		desc := srv.GetDescription()
-		cost := srv.GetMonthlyPrice()
-		backups := srv.HasBackups()
-		if backups {
-			cost *= 1.2 // Hetzner charges +20% for backups
+	res := make([]store.AccountWithProvider, 0, len(accounts))
+	for _, acc := range accounts {
+		if acc.ApiKey == "" || !h.engine.Supports(acc.ProviderIdent) {
+			continue
 		}
+		res = append(res, acc)
+	}
+	return res, nil
+}
 
-		existing := h.findExistingServer(ctx, ip, srv.Name, acc.ID)
-
-		if existing != nil {
-			seenIDs[existing.ID] = true
-
-			// detect changes before overwriting
-			diff := serverChanges(existing, srv.Name, ip, location, desc, cost, backups, status)
-
-			existing.Name = srv.Name
-			existing.IP = ip
-			existing.Location = location
-			existing.Description = desc
-			existing.Status = status
-			existing.ApproximateCost = cost
-			existing.Backups = backups
-
-			if err := h.store.UpdateServer(ctx, existing); err != nil {
-				log.Printf("[ERROR] failed to update server %s: %v", srv.Name, err)
-				continue
-			}
-
-			// only log if something actually changed
-			if diff != "" {
-				logEntry := &store.ServerLog{
-					ServerID:    existing.ID,
-					Action:      enum.LogActionSynced,
-					Description: diff,
-				}
-				_ = h.store.CreateLog(ctx, logEntry)
-			}
-		} else {
-			newServer := &store.Server{
-				AccountID:       acc.ID,
-				Name:            srv.Name,
-				IP:              ip,
-				Location:        location,
-				Description:     desc,
-				Responsible:     "",
-				ApproximateCost: cost,
-				Backups:         backups,
-				Status:          status,
-			}
-
-			if err := h.store.CreateServer(ctx, newServer); err != nil {
-				log.Printf("[ERROR] failed to create server %s: %v", srv.Name, err)
-				continue
-			}
-
-			seenIDs[newServer.ID] = true
+// syncPreview holds the sync plan of a single account, or the error of computing it.
+// Plan and Error are empty while the plan is being computed.
+type syncPreview struct {
+	Account store.AccountWithProvider
+	Plan    *provider.Plan
+	Error   string
+}
 
-			logEntry := &store.ServerLog{
-				ServerID:    newServer.ID,
-				Action:      enum.LogActionAdded,
-				Description: "Added from Hetzner Cloud sync",
-			}
-			_ = h.store.CreateLog(ctx, logEntry)
-		}
+// handleSyncPreview renders the sync preview of all supported accounts. Plans are computed
+// by a separate request per account, so slow provider APIs don't hit the request timeout.
+func (h *Handler) handleSyncPreview(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.syncableAccounts(r)
+	if err != nil {
+		log.Printf("[ERROR] failed to list accounts: %v", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to load accounts")
+		return
+	}
 
-		synced++
+	previews := make([]syncPreview, 0, len(accounts))
+	for _, acc := range accounts {
+		previews = append(previews, syncPreview{Account: acc})
 	}
 
-	// Mark servers not found in API as deleted
-	h.markDeletedServers(ctx, acc.ID, seenIDs)
+	data := templateData{SyncPreviews: previews}
 
-	return synced, nil
+	if err := h.tmpl.ExecuteTemplate(w, "sync-preview", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-// syncHetznerRobot syncs servers from Hetzner Robot API (dedicated servers)
-func (h *Handler) syncHetznerRobot(ctx context.Context, acc *store.AccountWithProvider) (int, error) {
-	log.Printf("[INFO] syncing Hetzner Robot account: %s", acc.Name)
-
-	client, err := hetzner.NewRobotClient(acc.ApiKey)
+// handleSyncPreviewAccount computes the sync plan of a single account without applying it
+// and renders its part of the sync preview
+func (h *Handler) handleSyncPreviewAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
 	if err != nil {
-		return 0, err
+		h.renderError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	servers, err := client.ListServers(ctx)
+	acc, err := h.store.GetAccountWithProvider(r.Context(), id)
 	if err != nil {
-		return 0, err
+		if errors.Is(err, store.ErrNotFound) {
+			h.renderError(w, http.StatusNotFound, "Account not found")
+			return
+		}
+		h.renderError(w, http.StatusInternalServerError, "Failed to load account")
+		return
+	}
+	if acc.ApiKey == "" || !h.engine.Supports(acc.ProviderIdent) {
+		h.renderError(w, http.StatusBadRequest, "Account can't be synced")
+		return
 	}
 
-	var synced int
-	seenIDs := make(map[int64]bool)
-
-	for _, srv := range servers {
-		status := mapHetznerRobotStatus(srv.Status, srv.Cancelled)
-		ip := srv.GetServerIP()
-		location := srv.GetServerLocation()
-		desc := srv.GetDescription()
-		name := srv.GetServerName()
+	preview := syncPreview{Account: *acc}
+	plan, err := h.engine.PlanAccount(r.Context(), acc)
+	if err != nil {
+		log.Printf("[ERROR] failed to plan sync of %s account %s: %v", acc.ProviderName, acc.Name, err)
+		preview.Error = err.Error()
+	}
+	preview.Plan = plan
 
-		existing := h.findExistingServer(ctx, ip, name, acc.ID)
+	if err := h.tmpl.ExecuteTemplate(w, "sync-preview-account", preview); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
 
-		if existing != nil {
-			seenIDs[existing.ID] = true
+// handleSyncApply applies selected changes of previewed sync plans.
+// Each selected change is posted as "planID:changeID".
+func (h *Handler) handleSyncApply(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-			diff := serverChanges(existing, name, ip, location, desc, existing.ApproximateCost, existing.Backups, status)
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
 
-			existing.Name = name
-			existing.IP = ip
-			existing.Location = location
-			existing.Description = desc
-			existing.Status = status
+	// group selected changes by plan, keeping plans with nothing selected so they are discarded too
+	selected := make(map[string][]string)
+	for _, planID := range r.Form["plan"] {
+		selected[planID] = []string{}
+	}
+	for _, v := range r.Form["change"] {
+		planID, changeID, ok := strings.Cut(v, ":")
+		if !ok {
+			continue
+		}
+		selected[planID] = append(selected[planID], changeID)
+	}
 
-			if err := h.store.UpdateServer(ctx, existing); err != nil {
-				log.Printf("[ERROR] failed to update server %s: %v", name, err)
-				continue
-			}
+	var applied int
+	var syncErrors []string
 
-			if diff != "" {
-				logEntry := &store.ServerLog{
-					ServerID:    existing.ID,
-					Action:      enum.LogActionSynced,
-					Description: diff,
-				}
-				_ = h.store.CreateLog(ctx, logEntry)
-			}
-		} else {
-			newServer := &store.Server{
-				AccountID:       acc.ID,
-				Name:            name,
-				IP:              ip,
-				Location:        location,
-				Description:     desc,
-				Responsible:     "",
-				ApproximateCost: 0, // Robot API doesn't provide pricing
-				Status:          status,
-			}
+	for planID, changeIDs := range selected {
+		res, err := h.engine.ApplyPlan(ctx, planID, changeIDs)
+		if err != nil {
+			log.Printf("[ERROR] failed to apply sync plan %s: %v", planID, err)
+			syncErrors = append(syncErrors, fmt.Sprintf("previewed changes: %v", err))
+			continue
+		}
+		applied += res.Added + res.Updated + res.Deleted
+	}
 
-			if err := h.store.CreateServer(ctx, newServer); err != nil {
-				log.Printf("[ERROR] failed to create server %s: %v", name, err)
-				continue
-			}
+	log.Printf("[INFO] sync plan applied: %d changes", applied)
 
-			seenIDs[newServer.ID] = true
+	// the preview form posts the filter of the servers page along with selected changes
+	h.renderServerTable(w, r, syncErrors)
+}
 
-			logEntry := &store.ServerLog{
-				ServerID:    newServer.ID,
-				Action:      enum.LogActionAdded,
-				Description: "Added from Hetzner Robot sync",
-			}
-			_ = h.store.CreateLog(ctx, logEntry)
-		}
+// handleSyncRunTable renders the sync history table partial, optionally filtered by account
+func (h *Handler) handleSyncRunTable(w http.ResponseWriter, r *http.Request) {
+	accountFilter := r.URL.Query().Get("account_id")
 
-		synced++
+	runs, err := h.listSyncRuns(r, accountFilter)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load sync history")
+		return
 	}
 
-	// Mark servers not found in API as deleted
-	h.markDeletedServers(ctx, acc.ID, seenIDs)
-
-	return synced, nil
-}
+	data := templateData{
+		SyncRuns:      runs,
+		AccountFilter: accountFilter,
+	}
 
-// mapHetznerCloudStatus maps Hetzner Cloud server status to our status
-func mapHetznerCloudStatus(hetznerStatus string) enum.ServerStatus {
-	switch hetznerStatus {
-	case "running":
-		return enum.ServerStatusActive
-	case "off", "stopped":
-		return enum.ServerStatusPaused
-	case "deleting":
-		return enum.ServerStatusDeleted
-	default:
-		return enum.ServerStatusActive
+	if err := h.tmpl.ExecuteTemplate(w, "sync-run-table", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-// mapHetznerRobotStatus maps Hetzner Robot server status to our status
-func mapHetznerRobotStatus(status string, cancelled bool) enum.ServerStatus {
-	if cancelled {
-		return enum.ServerStatusDeleted
-	}
-	switch status {
-	case "ready":
-		return enum.ServerStatusActive
-	case "in process":
-		return enum.ServerStatusPaused
-	default:
-		return enum.ServerStatusActive
+// listSyncRuns returns recent sync runs, of a single account if accountFilter is a valid ID
+func (h *Handler) listSyncRuns(r *http.Request, accountFilter string) ([]store.SyncRunWithAccount, error) {
+	if accountFilter != "" {
+		if accountID, err := strconv.ParseInt(accountFilter, 10, 64); err == nil {
+			return h.store.ListSyncRunsByAccount(r.Context(), accountID, syncHistoryLimit)
+		}
 	}
+	return h.store.ListSyncRuns(r.Context(), syncHistoryLimit)
 }
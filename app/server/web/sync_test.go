@@ -0,0 +1,234 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/hetzner"
+	"github.com/nilBora/servers-manager/app/provider"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// hetznerStandIn serves Hetzner Cloud API endpoints used by sync, servers are set by the test
+type hetznerStandIn struct {
+	mu      sync.Mutex
+	servers string // JSON array of servers
+	status  int    // response status of the servers endpoint, 200 if not set
+
+	floatingIPCalls int // number of floating IP list requests
+}
+
+func (h *hetznerStandIn) set(servers string, status int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.servers, h.status = servers, status
+}
+
+// newHetznerStandIn starts a Hetzner Cloud API stand-in and returns a registry with a syncer pointed at it
+func newHetznerStandIn(t *testing.T) (*hetznerStandIn, *provider.Registry) {
+	h := &hetznerStandIn{servers: "[]"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /servers", func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" || h.status == http.StatusUnauthorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":"unauthorized","message":"unable to authenticate"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"servers":` + h.servers + `,"meta":{"pagination":{"next_page":null}}}`))
+	})
+	mux.HandleFunc("GET /pricing", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"pricing":{"volume":{"price_per_gb_month":{"gross":"0.0440"}}}}`))
+	})
+	mux.HandleFunc("GET /volumes", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"volumes":[{"id":10,"name":"data","size":100,"server":101,"location":{"name":"fsn1"}}]}`))
+	})
+	mux.HandleFunc("GET /floating_ips", func(w http.ResponseWriter, _ *http.Request) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.floatingIPCalls++
+		_, _ = w.Write([]byte(`{}`))
+	})
+	for _, path := range []string{"/primary_ips", "/load_balancers", "/images"} {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(`{}`)) })
+	}
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	registry := provider.NewRegistry()
+	registry.Register(provider.IdentHetznerCloud, provider.NewHetznerCloudFactory(hetzner.WithBaseURL(ts.URL),
+		hetzner.WithRetries(0, 0)))
+	return h, registry
+}
+
+const testHetznerServer = `{"id":101,"name":"web-1","status":"running",
+	"public_net":{"ipv4":{"ip":"1.2.3.4"},"ipv6":{"ip":"2a01:4f8::/64"}},
+	"server_type":{"name":"cx22","cores":2,"memory":4,"disk":40,
+		"prices":[{"location":"fsn1","price_monthly":{"gross":"4.5000"}}]},
+	"datacenter":{"name":"fsn1-dc14","location":{"name":"fsn1"}},
+	"image":{"os_flavor":"ubuntu","os_version":"24.04"},"labels":{"env":"prod"}}`
+
+// newSyncTestServer starts the web UI with a Hetzner Cloud account synced from the stand-in
+func newSyncTestServer(t *testing.T) (*testServer, *hetznerStandIn, *store.Account) {
+	h, registry := newHetznerStandIn(t)
+	s := newTestServer(t, registry)
+	s.login()
+
+	ctx := context.Background()
+	providers, err := s.st.ListProviders(ctx)
+	require.NoError(t, err)
+	acc := &store.Account{Name: "cloud", ApiKey: "token"}
+	for _, p := range providers {
+		if p.Ident == provider.IdentHetznerCloud {
+			acc.ProviderID = p.ID
+		}
+	}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+	return s, h, acc
+}
+
+func TestHandler_Sync(t *testing.T) {
+	s, h, acc := newSyncTestServer(t)
+	h.set(`[`+testHetznerServer+`]`, http.StatusOK)
+
+	code, body := s.do(http.MethodPost, "/web/sync", nil)
+	require.Equal(t, http.StatusOK, code)
+	jobID := regexp.MustCompile(`/web/sync/jobs/([0-9a-f]+)/events`).FindStringSubmatch(body)
+	require.Len(t, jobID, 2, "progress panel links job events")
+
+	// the stream is closed when the job finishes
+	code, body = s.do(http.MethodGet, "/web/sync/jobs/"+jobID[1]+"/events", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "event: finished")
+	assert.Contains(t, body, "1 added")
+
+	ctx := context.Background()
+	srv, err := s.st.FindServerByExternalID(ctx, "101", acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1", srv.Name)
+	assert.Equal(t, "1.2.3.4", srv.IP)
+	assert.Equal(t, enum.ServerStatusActive, srv.Status)
+	assert.InDelta(t, 4.5, srv.ApproximateCost, 0.001)
+	assert.Equal(t, 2, srv.CPUCores)
+	assert.Equal(t, "ubuntu", srv.OSFlavor)
+
+	tags, err := s.st.ListServerTags(ctx, srv.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, enum.TagSourceProvider, tags[0].Source)
+	resources, err := s.st.ListResourcesByServer(ctx, srv.ID)
+	require.NoError(t, err)
+	require.Len(t, resources, 1, "volume attached to the synced server")
+	assert.InDelta(t, 4.4, resources[0].Cost, 0.001)
+	h.mu.Lock()
+	assert.Equal(t, 1, h.floatingIPCalls, "floating IPs listed once for servers and resources")
+	h.mu.Unlock()
+
+	runs, err := s.st.ListSyncRunsByAccount(ctx, acc.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Added)
+	assert.Empty(t, runs[0].Error)
+
+	code, _ = s.do(http.MethodGet, "/web/sync/jobs/unknown/events", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandler_SyncFailure(t *testing.T) {
+	s, h, acc := newSyncTestServer(t)
+	h.set("[]", http.StatusUnauthorized)
+
+	_, body := s.do(http.MethodPost, "/web/sync", nil)
+	jobID := regexp.MustCompile(`/web/sync/jobs/([0-9a-f]+)/events`).FindStringSubmatch(body)
+	require.Len(t, jobID, 2)
+	_, body = s.do(http.MethodGet, "/web/sync/jobs/"+jobID[1]+"/events", nil)
+	assert.Contains(t, body, "1 account failed")
+
+	runs, err := s.st.ListSyncRunsByAccount(context.Background(), acc.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.NotEmpty(t, runs[0].Error, "failure recorded in sync history")
+
+	code, body := s.do(http.MethodGet, "/web/sync-runs", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "cloud")
+}
+
+func TestHandler_SyncPreviewAndApply(t *testing.T) {
+	s, h, acc := newSyncTestServer(t)
+	ctx := context.Background()
+
+	// a manually added server missing from the provider is marked deleted
+	stale := &store.Server{AccountID: acc.ID, Name: "old", ExternalID: "99", Status: enum.ServerStatusActive}
+	require.NoError(t, s.st.CreateServer(ctx, stale))
+	h.set(`[`+testHetznerServer+`]`, http.StatusOK)
+
+	// preview lists accounts, the plan of each is loaded by a separate request
+	accountID := strconv.FormatInt(acc.ID, 10)
+	code, body := s.do(http.MethodPost, "/web/sync/preview", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `hx-post="/web/sync/preview/accounts/`+accountID+`"`)
+	assert.NotContains(t, body, "web-1", "plan is not computed by the preview request")
+
+	code, body = s.do(http.MethodPost, "/web/sync/preview/accounts/"+accountID, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "web-1")
+	assert.Contains(t, body, "old")
+	planID := regexp.MustCompile(`name="plan" value="([^"]+)"`).FindStringSubmatch(body)
+	require.Len(t, planID, 2)
+	changes := regexp.MustCompile(`name="change" value="([^"]+)"`).FindAllStringSubmatch(body, -1)
+	require.Len(t, changes, 3)
+	assert.Equal(t, planID[1]+":"+provider.ResourcesChangeID, changes[0][1], "resource refresh is selectable")
+
+	servers, err := s.st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Len(t, servers, 1, "preview doesn't change the store")
+
+	// apply only the first server change, creating the new server
+	code, body = s.do(http.MethodPost, "/web/sync/apply", url.Values{"plan": {planID[1]}, "change": {changes[1][1]},
+		"q": {"web"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `id="server-filter"`, "filter bar kept after apply")
+	assert.Contains(t, body, `name="q" value="web"`, "posted filter kept")
+	assert.Contains(t, body, "web-1")
+	assert.NotContains(t, body, `name-cell">old`, "servers not matching the filter hidden")
+
+	servers, err = s.st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	require.Len(t, servers, 2)
+	got, err := s.st.GetServer(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enum.ServerStatusActive, got.Status, "unselected change not applied")
+	resources, err := s.st.ListResources(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resources, "resources not refreshed unless selected")
+
+	// plan is discarded after apply
+	code, body = s.do(http.MethodPost, "/web/sync/apply", url.Values{"plan": {planID[1]}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "not found or expired")
+}
+
+func TestHandler_SyncPreviewAccountErrors(t *testing.T) {
+	s, h, acc := newSyncTestServer(t)
+	h.set("[]", http.StatusUnauthorized)
+
+	code, body := s.do(http.MethodPost, "/web/sync/preview/accounts/"+strconv.FormatInt(acc.ID, 10), nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `class="error"`, "failed plan shown in the account section")
+	assert.NotContains(t, body, `name="plan"`)
+
+	code, _ = s.do(http.MethodPost, "/web/sync/preview/accounts/999", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
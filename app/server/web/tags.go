@@ -0,0 +1,50 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// serverTags holds tag filter options and tags of all servers, shown in server lists
+type serverTags struct {
+	options []store.TagFilter
+	tags    map[int64][]store.ServerTag
+}
+
+// loadServerTags loads tag filter options and tags of all servers
+func (h *Handler) loadServerTags(ctx context.Context) (serverTags, error) {
+	options, err := h.store.ListTagOptions(ctx)
+	if err != nil {
+		return serverTags{}, err
+	}
+	tags, err := h.store.ListAllServerTags(ctx)
+	if err != nil {
+		return serverTags{}, err
+	}
+	return serverTags{options: options, tags: tags}, nil
+}
+
+// parseTags parses user tags from the form, separated by commas or new lines as "key=value" or "key"
+func parseTags(text string) ([]store.ServerTag, error) {
+	var tags []store.ServerTag
+	seen := make(map[string]bool)
+	for _, s := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		tag, err := store.ParseServerTag(s, enum.TagSourceUser)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag.Key] {
+			return nil, fmt.Errorf("duplicate tag %q", tag.Key)
+		}
+		seen[tag.Key] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"errors"
+	"html"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// handleTrashTable renders the tables of deleted providers, accounts and servers
+func (h *Handler) handleTrashTable(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.store.ListTrash(r.Context())
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load trash")
+		return
+	}
+
+	data := templateData{Trash: trash}
+
+	if err := h.tmpl.ExecuteTemplate(w, "trash-table", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleTrashRestore restores a provider, account or server from the trash.
+// Providers and accounts are restored with the records deleted along with them.
+func (h *Handler) handleTrashRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var restore func(*http.Request, int64) error
+	switch chi.URLParam(r, "kind") {
+	case "providers":
+		restore = func(r *http.Request, id int64) error { return h.store.RestoreProvider(r.Context(), id) }
+	case "accounts":
+		restore = func(r *http.Request, id int64) error { return h.store.RestoreAccount(r.Context(), id) }
+	case "servers":
+		restore = func(r *http.Request, id int64) error { return h.store.RestoreServer(r.Context(), id) }
+	default:
+		h.renderError(w, http.StatusNotFound, "Unknown record type")
+		return
+	}
+
+	if err := restore(r, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			h.renderError(w, http.StatusNotFound, "Record is not in the trash")
+		case errors.Is(err, store.ErrParentDeleted), errors.Is(err, store.ErrConflict):
+			h.renderError(w, http.StatusConflict, html.EscapeString(err.Error()))
+		default:
+			h.renderError(w, http.StatusInternalServerError, "Failed to restore")
+		}
+		return
+	}
+
+	// return updated tables
+	h.handleTrashTable(w, r)
+}
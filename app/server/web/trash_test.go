@@ -0,0 +1,59 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+	"github.com/nilBora/servers-manager/app/provider"
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+func TestHandler_TrashRestore(t *testing.T) {
+	s := newTestServer(t, provider.NewRegistry())
+	s.login()
+	ctx := context.Background()
+
+	acc := &store.Account{ProviderID: 1, Name: "main"}
+	require.NoError(t, s.st.CreateAccount(ctx, acc))
+	srv := &store.Server{AccountID: acc.ID, Name: "web-1", Status: enum.ServerStatusActive}
+	require.NoError(t, s.st.CreateServer(ctx, srv))
+	accountID, serverID := strconv.FormatInt(acc.ID, 10), strconv.FormatInt(srv.ID, 10)
+
+	_, body := s.do(http.MethodGet, "/web/trash", nil)
+	assert.Contains(t, body, "Trash is empty")
+
+	code, _ := s.do(http.MethodDelete, "/web/accounts/"+accountID, nil)
+	require.Equal(t, http.StatusOK, code)
+	_, body = s.do(http.MethodGet, "/trash", nil)
+	assert.Contains(t, body, "main")
+	assert.Contains(t, body, "web-1", "servers of the account moved to the trash")
+
+	code, body = s.do(http.MethodPost, "/web/accounts", url.Values{"provider_id": {"1"}, "name": {"main"},
+		"api_key": {"token"}})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, "in the trash, restore it first")
+
+	code, body = s.do(http.MethodPost, "/web/trash/servers/"+serverID+"/restore", nil)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, "restore it first")
+
+	code, _ = s.do(http.MethodPost, "/web/trash/hosts/"+serverID+"/restore", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = s.do(http.MethodPost, "/web/trash/accounts/"+accountID+"/restore", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "Trash is empty")
+	got, err := s.st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1", got.Name)
+
+	code, _ = s.do(http.MethodPost, "/web/trash/accounts/"+accountID+"/restore", nil)
+	assert.Equal(t, http.StatusNotFound, code, "account is not in the trash")
+}
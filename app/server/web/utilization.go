@@ -0,0 +1,172 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/store"
+)
+
+// utilization report options: periods in days and average CPU thresholds in percent
+var (
+	utilizationDayOptions = []int{7, 30, 90}
+	utilizationCPUOptions = []int{5, 10, 20, 50}
+)
+
+// utilization report defaults
+const (
+	defaultUtilizationDays = 7
+	defaultUtilizationCPU  = 10
+)
+
+// sparkline dimensions in pixels
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// utilizationView holds utilization series of a server shown on its card
+type utilizationView struct {
+	CPU     []float64 // percent
+	Net     []float64 // inbound and outbound, bytes/s
+	Disk    []float64 // read and write, bytes/s
+	AvgCPU  float64
+	MaxCPU  float64
+	AvgNet  float64
+	AvgDisk float64
+}
+
+// newUtilizationView builds utilization series from samples, nil if there are none
+func newUtilizationView(metrics []store.ServerMetric) *utilizationView {
+	if len(metrics) == 0 {
+		return nil
+	}
+	v := &utilizationView{}
+	for _, m := range metrics {
+		v.CPU = append(v.CPU, m.CPU)
+		v.Net = append(v.Net, m.NetIn+m.NetOut)
+		v.Disk = append(v.Disk, m.DiskRead+m.DiskWrite)
+		v.AvgCPU += m.CPU
+		v.AvgNet += m.NetIn + m.NetOut
+		v.AvgDisk += m.DiskRead + m.DiskWrite
+		v.MaxCPU = max(v.MaxCPU, m.CPU)
+	}
+	n := float64(len(metrics))
+	v.AvgCPU /= n
+	v.AvgNet /= n
+	v.AvgDisk /= n
+	return v
+}
+
+// utilizationReport holds the underutilized servers report
+type utilizationReport struct {
+	Servers   []store.ServerUtilization
+	Days      int
+	MaxCPU    int     // only servers with average CPU usage below this percent are listed
+	TotalCost float64 // monthly cost of listed servers
+}
+
+// handleUtilizationTable renders the table of underutilized servers
+func (h *Handler) handleUtilizationTable(w http.ResponseWriter, r *http.Request) {
+	report, err := h.underutilizedServers(r)
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "Failed to load utilization")
+		return
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "utilization-table", templateData{Utilization: report}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// underutilizedServers returns active servers whose average CPU usage over the requested period
+// is below the requested threshold, ranked by cost per percent of CPU used
+func (h *Handler) underutilizedServers(r *http.Request) (*utilizationReport, error) {
+	report := &utilizationReport{
+		Days:   parseOption(r.URL.Query().Get("days"), utilizationDayOptions, defaultUtilizationDays),
+		MaxCPU: parseOption(r.URL.Query().Get("cpu"), utilizationCPUOptions, defaultUtilizationCPU),
+	}
+
+	servers, err := h.store.ListServerUtilization(r.Context(), time.Now().UTC().AddDate(0, 0, -report.Days))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, srv := range servers {
+		if srv.AvgCPU >= float64(report.MaxCPU) {
+			continue
+		}
+		report.Servers = append(report.Servers, srv)
+		report.TotalCost += srv.ApproximateCost
+	}
+	sort.SliceStable(report.Servers, func(i, j int) bool {
+		return report.Servers[i].CostPerCPU() > report.Servers[j].CostPerCPU()
+	})
+	return report, nil
+}
+
+// parseOption parses an integer query value, falling back to def if it is not one of the options
+func parseOption(s string, options []int, def int) int {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	for _, o := range options {
+		if o == v {
+			return v
+		}
+	}
+	return def
+}
+
+// sparkline renders values as an inline SVG polyline scaled to fit the sparkline box.
+// Values are scaled to maxValue if it is positive, e.g. 100 for percents, to the largest value otherwise.
+func sparkline(values []float64, maxValue float64) template.HTML {
+	if len(values) == 0 {
+		return ""
+	}
+	top := maxValue
+	if top <= 0 {
+		for _, v := range values {
+			top = max(top, v)
+		}
+	}
+	if top <= 0 {
+		top = 1
+	}
+
+	dx := float64(sparklineWidth)
+	if len(values) > 1 {
+		dx = float64(sparklineWidth) / float64(len(values)-1)
+	}
+	points := make([]string, len(values))
+	for i, v := range values {
+		y := float64(sparklineHeight) - min(v/top, 1)*float64(sparklineHeight-2) - 1
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*dx, y)
+	}
+
+	// values are formatted numbers only, safe to render unescaped
+	return template.HTML(fmt.Sprintf(
+		`<svg class="sparkline" width="%d" height="%d" viewBox="0 0 %d %d" preserveAspectRatio="none">`+
+			`<polyline fill="none" stroke="currentColor" stroke-width="1.5" points="%s"/></svg>`,
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight, strings.Join(points, " ")))
+}
+
+// formatRate formats bytes per second with a binary unit, e.g. "1.5 MiB/s"
+func formatRate(bps float64) string {
+	units := []string{"B/s", "KiB/s", "MiB/s", "GiB/s"}
+	i := 0
+	for bps >= 1024 && i < len(units)-1 {
+		bps /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bps, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bps, units[i])
+}
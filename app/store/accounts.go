@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	log "github.com/go-pkgz/lgr"
 )
 
 // CreateAccount creates a new account
@@ -13,26 +15,28 @@ func (s *DB) CreateAccount(ctx context.Context, a *Account) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	apiKey, err := s.encryptSecret(a.ApiKey)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now().UTC()
 	a.CreatedAt = now
 	a.UpdatedAt = now
 
-	query := `INSERT INTO accounts (provider_id, group_name, name, login, api_key, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO accounts (provider_id, group_name, name, login, api_key, sync_interval,
+		created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query, a.ProviderID, a.GroupName, a.Name, a.Login, a.ApiKey,
-		a.CreatedAt, a.UpdatedAt)
+	id, err := s.db.insertContext(ctx, query, a.ProviderID, a.GroupName, a.Name, a.Login, apiKey,
+		a.SyncInterval, a.CreatedAt, a.UpdatedAt)
 	if err != nil {
 		if isUniqueViolation(err) {
-			return fmt.Errorf("%w: account with name %q already exists for this provider", ErrConflict, a.Name)
+			return s.accountConflict(ctx, a.ProviderID, a.Name)
 		}
 		return fmt.Errorf("failed to create account: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
 	a.ID = id
 
 	return nil
@@ -44,8 +48,8 @@ func (s *DB) GetAccount(ctx context.Context, id int64) (*Account, error) {
 	defer s.mu.RUnlock()
 
 	var a Account
-	query := `SELECT id, provider_id, group_name, name, login, api_key, created_at, updated_at
-		FROM accounts WHERE id = ?`
+	query := `SELECT id, provider_id, group_name, name, login, api_key, sync_interval, created_at, updated_at
+		FROM accounts WHERE id = ? AND deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &a, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -53,6 +57,11 @@ func (s *DB) GetAccount(ctx context.Context, id int64) (*Account, error) {
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 
+	apiKey, err := s.decryptSecret(a.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	a.ApiKey = apiKey
 	return &a, nil
 }
 
@@ -62,13 +71,13 @@ func (s *DB) GetAccountWithProvider(ctx context.Context, id int64) (*AccountWith
 	defer s.mu.RUnlock()
 
 	var a AccountWithProvider
-	query := `SELECT a.id, a.provider_id, a.group_name, a.name, a.login, a.api_key,
+	query := `SELECT a.id, a.provider_id, a.group_name, a.name, a.login, a.api_key, a.sync_interval,
 		a.created_at, a.updated_at,
 		p.ident as provider_ident, p.name as provider_name,
-		(SELECT COUNT(*) FROM servers WHERE account_id = a.id) as server_count
+		(SELECT COUNT(*) FROM servers WHERE account_id = a.id AND deleted_at IS NULL) as server_count
 		FROM accounts a
 		JOIN providers p ON a.provider_id = p.id
-		WHERE a.id = ?`
+		WHERE a.id = ? AND a.deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &a, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -76,6 +85,11 @@ func (s *DB) GetAccountWithProvider(ctx context.Context, id int64) (*AccountWith
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 
+	apiKey, err := s.decryptSecret(a.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	a.ApiKey = apiKey
 	return &a, nil
 }
 
@@ -85,12 +99,19 @@ func (s *DB) ListAccounts(ctx context.Context) ([]Account, error) {
 	defer s.mu.RUnlock()
 
 	var accounts []Account
-	query := `SELECT id, provider_id, group_name, name, login, api_key, created_at, updated_at
-		FROM accounts ORDER BY group_name, name`
+	query := `SELECT id, provider_id, group_name, name, login, api_key, sync_interval, created_at, updated_at
+		FROM accounts WHERE deleted_at IS NULL ORDER BY group_name, name`
 	if err := s.db.SelectContext(ctx, &accounts, query); err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
 
+	for i := range accounts {
+		apiKey, err := s.decryptSecret(accounts[i].ApiKey)
+		if err != nil {
+			return nil, err
+		}
+		accounts[i].ApiKey = apiKey
+	}
 	return accounts, nil
 }
 
@@ -100,17 +121,25 @@ func (s *DB) ListAccountsWithProviders(ctx context.Context) ([]AccountWithProvid
 	defer s.mu.RUnlock()
 
 	var accounts []AccountWithProvider
-	query := `SELECT a.id, a.provider_id, a.group_name, a.name, a.login, a.api_key,
+	query := `SELECT a.id, a.provider_id, a.group_name, a.name, a.login, a.api_key, a.sync_interval,
 		a.created_at, a.updated_at,
 		p.ident as provider_ident, p.name as provider_name,
-		(SELECT COUNT(*) FROM servers WHERE account_id = a.id) as server_count
+		(SELECT COUNT(*) FROM servers WHERE account_id = a.id AND deleted_at IS NULL) as server_count
 		FROM accounts a
 		JOIN providers p ON a.provider_id = p.id
+		WHERE a.deleted_at IS NULL
 		ORDER BY p.name, a.group_name, a.name`
 	if err := s.db.SelectContext(ctx, &accounts, query); err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
 
+	for i := range accounts {
+		apiKey, err := s.decryptSecret(accounts[i].ApiKey)
+		if err != nil {
+			return nil, err
+		}
+		accounts[i].ApiKey = apiKey
+	}
 	return accounts, nil
 }
 
@@ -120,12 +149,19 @@ func (s *DB) ListAccountsByProvider(ctx context.Context, providerID int64) ([]Ac
 	defer s.mu.RUnlock()
 
 	var accounts []Account
-	query := `SELECT id, provider_id, group_name, name, login, api_key, created_at, updated_at
-		FROM accounts WHERE provider_id = ? ORDER BY group_name, name`
+	query := `SELECT id, provider_id, group_name, name, login, api_key, sync_interval, created_at, updated_at
+		FROM accounts WHERE provider_id = ? AND deleted_at IS NULL ORDER BY group_name, name`
 	if err := s.db.SelectContext(ctx, &accounts, query, providerID); err != nil {
 		return nil, fmt.Errorf("failed to list accounts: %w", err)
 	}
 
+	for i := range accounts {
+		apiKey, err := s.decryptSecret(accounts[i].ApiKey)
+		if err != nil {
+			return nil, err
+		}
+		accounts[i].ApiKey = apiKey
+	}
 	return accounts, nil
 }
 
@@ -134,15 +170,20 @@ func (s *DB) UpdateAccount(ctx context.Context, a *Account) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	apiKey, err := s.encryptSecret(a.ApiKey)
+	if err != nil {
+		return err
+	}
+
 	a.UpdatedAt = time.Now().UTC()
 
 	query := `UPDATE accounts SET provider_id = ?, group_name = ?, name = ?, login = ?, api_key = ?,
-		updated_at = ? WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, a.ProviderID, a.GroupName, a.Name, a.Login, a.ApiKey,
-		a.UpdatedAt, a.ID)
+		sync_interval = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, a.ProviderID, a.GroupName, a.Name, a.Login, apiKey,
+		a.SyncInterval, a.UpdatedAt, a.ID)
 	if err != nil {
 		if isUniqueViolation(err) {
-			return fmt.Errorf("%w: account with name %q already exists for this provider", ErrConflict, a.Name)
+			return s.accountConflict(ctx, a.ProviderID, a.Name)
 		}
 		return fmt.Errorf("failed to update account: %w", err)
 	}
@@ -158,13 +199,21 @@ func (s *DB) UpdateAccount(ctx context.Context, a *Account) error {
 	return nil
 }
 
-// DeleteAccount deletes an account by ID
+// DeleteAccount moves an account to the trash along with its servers, restoring the account restores them.
+// Resources and sync runs of the account are kept until it is purged.
 func (s *DB) DeleteAccount(ctx context.Context, id int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	query := `DELETE FROM accounts WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	query := `UPDATE accounts SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, now, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete account: %w", err)
 	}
@@ -177,5 +226,126 @@ func (s *DB) DeleteAccount(ctx context.Context, id int64) error {
 		return ErrNotFound
 	}
 
+	query = `UPDATE servers SET deleted_at = ? WHERE account_id = ? AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, now, id); err != nil {
+		return fmt.Errorf("failed to delete servers of account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+	return nil
+}
+
+// RotateEncryptionKey re-encrypts credentials of all accounts with the new key, which is used from then on.
+// Plaintext credentials are encrypted as well. Returns the number of re-encrypted accounts.
+func (s *DB) RotateEncryptionKey(ctx context.Context, newKey []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := newSecretCipher(newKey)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.reencryptAccountSecrets(ctx, secret)
+	if err != nil {
+		return 0, err
+	}
+	s.secret = secret
+	return n, nil
+}
+
+// encryptAccountSecrets checks stored credentials can be decrypted with the configured key and encrypts
+// plaintext ones, if the key is set. Called on start, before the store is used.
+func (s *DB) encryptAccountSecrets() error {
+	var keys []string
+	if err := s.db.Select(&keys, `SELECT COALESCE(api_key, '') FROM accounts WHERE api_key != ''`); err != nil {
+		return fmt.Errorf("failed to list account credentials: %w", err)
+	}
+
+	plaintext := 0
+	for _, k := range keys {
+		if !isEncrypted(k) {
+			plaintext++
+			continue
+		}
+		if _, err := s.secret.decrypt(k); err != nil {
+			return err
+		}
+	}
+	if plaintext == 0 {
+		return nil
+	}
+	if s.secret == nil {
+		log.Printf("[WARN] credentials of %d accounts are stored in plaintext, set encryption key to encrypt them", plaintext)
+		return nil
+	}
+
+	n, err := s.reencryptAccountSecrets(context.Background(), s.secret)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] encrypted credentials of %d accounts", n)
 	return nil
 }
+
+// reencryptAccountSecrets decrypts credentials of all accounts with the current key and encrypts them
+// with the given one in a single transaction, caller holds the lock
+func (s *DB) reencryptAccountSecrets(ctx context.Context, secret *secretCipher) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rows []struct {
+		ID     int64  `db:"id"`
+		ApiKey string `db:"api_key"`
+	}
+	if err := tx.SelectContext(ctx, &rows, `SELECT id, api_key FROM accounts WHERE api_key != ''`); err != nil {
+		return 0, fmt.Errorf("failed to list account credentials: %w", err)
+	}
+
+	for _, r := range rows {
+		plain, err := s.secret.decrypt(r.ApiKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt credentials of account %d: %w", r.ID, err)
+		}
+		encrypted, err := secret.encrypt(plain)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET api_key = ? WHERE id = ?`, encrypted, r.ID); err != nil {
+			return 0, fmt.Errorf("failed to update credentials of account %d: %w", r.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit credentials: %w", err)
+	}
+	return len(rows), nil
+}
+
+// encryptSecret encrypts the value with the configured key, kept as is if no key is set
+func (s *DB) encryptSecret(value string) (string, error) {
+	if s.secret == nil {
+		return value, nil
+	}
+	return s.secret.encrypt(value)
+}
+
+// decryptSecret decrypts the value encrypted with the configured key, plaintext values are returned as is
+func (s *DB) decryptSecret(value string) (string, error) {
+	return s.secret.decrypt(value)
+}
+
+// accountConflict returns ErrConflict for the account name taken by another account of the provider,
+// along with ErrInTrash if that account is in the trash
+func (s *DB) accountConflict(ctx context.Context, providerID int64, name string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM accounts WHERE provider_id = ? AND name = ? AND deleted_at IS NOT NULL`
+	if err := s.db.GetContext(ctx, &count, query, providerID, name); err == nil && count > 0 {
+		return fmt.Errorf("%w: account %q %w", ErrConflict, name, ErrInTrash)
+	}
+	return fmt.Errorf("%w: account with name %q already exists for this provider", ErrConflict, name)
+}
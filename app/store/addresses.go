@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+// ListServerAddresses lists addresses of a server, public first
+func (s *DB) ListServerAddresses(ctx context.Context, serverID int64) ([]ServerAddress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []serverAddressRow
+	query := `SELECT id, server_id, address, family, kind, prefix, created_at FROM server_addresses
+		WHERE server_id = ?
+		ORDER BY CASE kind WHEN 'public' THEN 0 WHEN 'floating' THEN 1 WHEN 'subnet' THEN 2 ELSE 3 END, family, id`
+	if err := s.db.SelectContext(ctx, &rows, query, serverID); err != nil {
+		return nil, fmt.Errorf("failed to list server addresses: %w", err)
+	}
+
+	addrs := make([]ServerAddress, 0, len(rows))
+	for _, r := range rows {
+		a, err := r.toServerAddress()
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, *a)
+	}
+
+	return addrs, nil
+}
+
+// SetServerAddresses replaces all addresses of a server. Duplicates of the same address and prefix are skipped.
+func (s *DB) SetServerAddresses(ctx context.Context, serverID int64, addrs []ServerAddress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM server_addresses WHERE server_id = ?`, serverID); err != nil {
+		return fmt.Errorf("failed to delete server addresses: %w", err)
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		if seen[a.String()] {
+			continue
+		}
+		seen[a.String()] = true
+
+		query := `INSERT INTO server_addresses (server_id, address, family, kind, prefix, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, serverID, a.Address, a.Family, a.Kind.String(), a.Prefix, now); err != nil {
+			return fmt.Errorf("failed to add server address %s: %w", a, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit server addresses: %w", err)
+	}
+	return nil
+}
+
+type serverAddressRow struct {
+	ID        int64     `db:"id"`
+	ServerID  int64     `db:"server_id"`
+	Address   string    `db:"address"`
+	Family    int       `db:"family"`
+	Kind      string    `db:"kind"`
+	Prefix    int       `db:"prefix"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *serverAddressRow) toServerAddress() (*ServerAddress, error) {
+	kind, err := enum.ParseAddressKind(r.Kind)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerAddress{
+		ID:        r.ID,
+		ServerID:  r.ServerID,
+		Address:   r.Address,
+		Family:    r.Family,
+		Kind:      kind,
+		Prefix:    r.Prefix,
+		CreatedAt: r.CreatedAt,
+	}, nil
+}
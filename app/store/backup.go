@@ -0,0 +1,260 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrBackupUnsupported is returned when backing up a database other than SQLite
+var ErrBackupUnsupported = errors.New("backups are supported for SQLite databases only")
+
+// snapshot file names are snapshotPrefix, UTC time in snapshotTimeFormat and snapshotExt
+const (
+	snapshotPrefix     = "servers-"
+	snapshotTimeFormat = "20060102-150405"
+	snapshotExt        = ".db"
+)
+
+// Backup writes a consistent copy of the database to the file at path with VACUUM INTO, while the
+// database stays online. The file must not exist. Returns ErrBackupUnsupported for Postgres databases.
+func (s *DB) Backup(ctx context.Context, path string) error {
+	if s.db.dialect != dialectSQLite {
+		return ErrBackupUnsupported
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to backup database: %w", err)
+	}
+	return nil
+}
+
+// Snapshot writes a backup of the store into the directory named by the current time, and removes
+// the oldest snapshots so only keep of them are left, all are kept if keep is 0. Returns the snapshot path.
+func Snapshot(ctx context.Context, b BackupStore, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := snapshotPrefix + time.Now().UTC().Format(snapshotTimeFormat) + snapshotExt
+	path := filepath.Join(dir, name)
+
+	// written under a temporary name, so a failed backup is never taken for a snapshot
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	_ = os.Remove(tmp)
+	if err := b.Backup(ctx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if keep > 0 {
+		if err := removeOldSnapshots(dir, keep); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// removeOldSnapshots removes snapshots in the directory except the keep most recent ones,
+// files not named as snapshots are not touched
+func removeOldSnapshots(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotExt)
+		if _, err := time.Parse(snapshotTimeFormat, ts); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, name)
+	}
+	if len(snapshots) <= keep {
+		return nil
+	}
+
+	// names sort by time, oldest first
+	sort.Strings(snapshots)
+	for _, name := range snapshots[:len(snapshots)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to remove old snapshot: %w", err)
+		}
+		log.Printf("[DEBUG] removed old snapshot %s", name)
+	}
+	return nil
+}
+
+// RestoreBackup replaces the SQLite database at dbPath with the backup file. The backup is checked for
+// integrity and its schema version has to be known to this version of the application, older schemas
+// are migrated on the next start. The replaced database is kept next to it with a ".before-restore"
+// suffix and the restore time. The server must be stopped while restoring.
+// Returns the schema version of the restored database.
+func RestoreBackup(backupPath, dbPath string) (int, error) {
+	if dialectOf(dbPath) != dialectSQLite {
+		return 0, ErrBackupUnsupported
+	}
+
+	// the backup is validated as a copy next to the database, so the swap is a rename
+	tmp := dbPath + ".restore"
+	if err := copyFile(backupPath, tmp); err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	version, err := validateBackup(tmp)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		prev := dbPath + ".before-restore-" + time.Now().UTC().Format(snapshotTimeFormat)
+		// write-ahead log files belong to the replaced database
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Rename(dbPath+suffix, prev+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return 0, fmt.Errorf("failed to keep current database: %w", err)
+			}
+		}
+		log.Printf("[INFO] current database kept as %s", prev)
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return 0, fmt.Errorf("failed to replace database: %w", err)
+	}
+	return version, nil
+}
+
+// validateBackup checks the SQLite file is an intact database of this application with a known
+// schema version, and returns the version
+func validateBackup(path string) (int, error) {
+	conn, err := sqlx.Connect("sqlite", path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup: %w", err)
+	}
+	db := &sqlDB{DB: conn, dialect: dialectSQLite}
+	defer db.Close()
+
+	var integrity string
+	if err := db.Get(&integrity, `PRAGMA integrity_check`); err != nil {
+		return 0, fmt.Errorf("failed to check backup integrity: %w", err)
+	}
+	if integrity != "ok" {
+		return 0, fmt.Errorf("backup is corrupted: %s", integrity)
+	}
+
+	tracked, err := db.tableExists("schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	if !tracked {
+		return 0, errors.New("backup has no schema version, not a servers-manager database")
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := loadMigrations(migrationsFS, dialectSQLite)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := pendingMigrations(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		version = max(version, v)
+	}
+	if version == 0 {
+		return 0, errors.New("backup has no applied migrations")
+	}
+	return version, nil
+}
+
+// copyFile copies the file at src to dst, replacing dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec // path is set by the operator
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // path is set by the operator
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	return nil
+}
+
+// BackupScheduler periodically writes snapshots of the database into a directory, keeping a limited
+// number of the most recent ones
+type BackupScheduler struct {
+	store    BackupStore
+	dir      string
+	interval time.Duration
+	keep     int
+}
+
+// NewBackupScheduler creates a scheduler writing a snapshot every interval into dir and keeping
+// the keep most recent ones, all if keep is 0
+func NewBackupScheduler(st BackupStore, dir string, interval time.Duration, keep int) *BackupScheduler {
+	return &BackupScheduler{store: st, dir: dir, interval: interval, keep: keep}
+}
+
+// Run writes snapshots every interval until context is canceled, the first one after the first interval.
+// Zero interval or empty directory disables snapshots.
+func (b *BackupScheduler) Run(ctx context.Context) {
+	if b.interval <= 0 || b.dir == "" {
+		return
+	}
+	log.Printf("[INFO] backup scheduler started, interval %v, keeping %d snapshots in %s", b.interval, b.keep, b.dir)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[INFO] backup scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+
+		path, err := Snapshot(ctx, b.store, b.dir, b.keep)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[ERROR] failed to write database snapshot: %v", err)
+			}
+			continue
+		}
+		log.Printf("[INFO] database snapshot written to %s", path)
+	}
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_BackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "servers.db")
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	ctx := context.Background()
+	p := &Provider{Ident: "custom", Name: "Custom"}
+	require.NoError(t, db.CreateProvider(ctx, p))
+
+	backupPath := filepath.Join(dir, "backup.db")
+	require.NoError(t, db.Backup(ctx, backupPath))
+
+	// changes after the backup are lost on restore
+	require.NoError(t, db.DeleteProvider(ctx, p.ID))
+	require.NoError(t, db.Close())
+
+	version, err := RestoreBackup(backupPath, dbPath)
+	require.NoError(t, err)
+	migrations, err := loadMigrations(migrationsFS, dialectSQLite)
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, version)
+
+	prev, err := filepath.Glob(dbPath + ".before-restore-*")
+	require.NoError(t, err)
+	assert.NotEmpty(t, prev, "replaced database is kept")
+
+	db, err = New(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	got, err := db.GetProvider(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Custom", got.Name)
+}
+
+func TestRestoreBackup_Rejects(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "servers.db")
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	t.Run("newer schema", func(t *testing.T) {
+		backupPath := filepath.Join(dir, "future.db")
+		future, err := New(backupPath)
+		require.NoError(t, err)
+		_, err = future.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)`)
+		require.NoError(t, err)
+		require.NoError(t, future.Close())
+
+		_, err = RestoreBackup(backupPath, dbPath)
+		require.ErrorIs(t, err, ErrSchemaTooNew)
+	})
+
+	t.Run("not a database", func(t *testing.T) {
+		backupPath := filepath.Join(dir, "garbage.db")
+		require.NoError(t, os.WriteFile(backupPath, []byte("not a database at all, just some text"), 0o600))
+		_, err := RestoreBackup(backupPath, dbPath)
+		require.Error(t, err)
+	})
+
+	t.Run("foreign database", func(t *testing.T) {
+		backupPath := filepath.Join(dir, "foreign.db")
+		conn, err := connectSQLite(backupPath)
+		require.NoError(t, err)
+		_, err = conn.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY)`)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		_, err = RestoreBackup(backupPath, dbPath)
+		require.ErrorContains(t, err, "not a servers-manager database")
+	})
+
+	// the current database is untouched by rejected restores
+	prev, err := filepath.Glob(dbPath + ".before-restore-*")
+	require.NoError(t, err)
+	assert.Empty(t, prev)
+	_, err = os.Stat(dbPath + ".restore")
+	assert.True(t, os.IsNotExist(err), "temporary copy removed")
+}
+
+func TestSnapshot_Rotation(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "servers.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	require.NoError(t, os.MkdirAll(dir, 0o750))
+	// older snapshots and an unrelated file
+	for _, name := range []string{"servers-20240101-000000.db", "servers-20240102-000000.db", "servers-20240103-000000.db", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	path, err := Snapshot(context.Background(), db, dir, 2)
+	require.NoError(t, err)
+	assert.Regexp(t, `servers-\d{8}-\d{6}\.db$`, path)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"notes.txt", "servers-20240103-000000.db", filepath.Base(path)}, names)
+
+	_, err = validateBackup(path)
+	require.NoError(t, err, "snapshot is a valid backup")
+}
+
+func TestBackupScheduler_Run(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "servers.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewBackupScheduler(db, dir, 10*time.Millisecond, 1).Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		snapshots, err := filepath.Glob(filepath.Join(dir, "servers-*.db"))
+		return err == nil && len(snapshots) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// ErrTargetNotEmpty is returned when copying into a database which already has data
+var ErrTargetNotEmpty = errors.New("target database is not empty")
+
+// copyTables are tables copied between databases, parents before children. serial tables have
+// an id sequence on Postgres which is moved past the copied ids.
+var copyTables = []struct {
+	name   string
+	serial bool
+}{
+	{"providers", true},
+	{"accounts", true},
+	{"servers", true},
+	{"server_logs", true},
+	{"sync_runs", true},
+	{"server_addresses", true},
+	{"server_tags", true},
+	{"resources", true},
+	{"server_metrics", false},
+	{"users", true},
+	{"sessions", false},
+}
+
+// CopyDatabase copies all data of src into dst keeping ids, e.g. to move a SQLite database to Postgres.
+// Both stores are migrated to the same schema when opened. dst must not have accounts or users yet,
+// its default providers are replaced by the providers of src. Account credentials are copied as is,
+// so dst has to be opened with the same encryption key as src. Returns number of copied rows.
+func CopyDatabase(ctx context.Context, src, dst *DB) (int, error) {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	for _, table := range []string{"accounts", "users"} {
+		var count int
+		if err := dst.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM `+table); err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		if count > 0 {
+			return 0, fmt.Errorf("%w: it has %d %s", ErrTargetNotEmpty, count, table)
+		}
+	}
+
+	tx, err := dst.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// default providers seeded on open
+	if _, err := tx.ExecContext(ctx, `DELETE FROM providers`); err != nil {
+		return 0, fmt.Errorf("failed to clear providers: %w", err)
+	}
+
+	total := 0
+	for _, t := range copyTables {
+		n, err := copyTable(ctx, src.db, tx, t.name)
+		if err != nil {
+			return 0, err
+		}
+		if t.serial && dst.db.dialect == dialectPostgres {
+			query := `SELECT setval(pg_get_serial_sequence('` + t.name + `', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM ` + t.name
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return 0, fmt.Errorf("failed to reset %s id sequence: %w", t.name, err)
+			}
+		}
+		log.Printf("[INFO] copied %d rows of %s", n, t.name)
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit copied data: %w", err)
+	}
+	return total, nil
+}
+
+// copyTable inserts all rows of the table from src within the transaction of the target database
+func copyTable(ctx context.Context, src *sqlDB, tx *sqlTx, table string) (int, error) {
+	// column types of the target decide how values are converted, SQLite keeps booleans as integers
+	boolColumns, err := booleanColumns(ctx, tx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := src.QueryxContext(ctx, `SELECT * FROM `+table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s columns: %w", table, err)
+	}
+	query := `INSERT INTO ` + table + ` (` + strings.Join(columns, ", ") + `) VALUES (` +
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + `)`
+
+	n := 0
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		for i, c := range columns {
+			if v, ok := values[i].(int64); ok && boolColumns[c] {
+				values[i] = v != 0
+			}
+		}
+		if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+			return 0, fmt.Errorf("failed to copy %s row: %w", table, err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	return n, nil
+}
+
+// booleanColumns returns names of boolean columns of the table
+func booleanColumns(ctx context.Context, tx *sqlTx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryxContext(ctx, `SELECT * FROM `+table+` WHERE 1 = 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s column types: %w", table, err)
+	}
+	res := make(map[string]bool)
+	for _, t := range types {
+		if name := strings.ToUpper(t.DatabaseTypeName()); name == "BOOL" || name == "BOOLEAN" {
+			res[t.Name()] = true
+		}
+	}
+	return res, nil
+}
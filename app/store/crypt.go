@@ -0,0 +1,105 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks encrypted values as "enc:v1:<key id>:<base64 of nonce and ciphertext>"
+const encryptedPrefix = "enc:v1:"
+
+// encryption key errors
+var (
+	ErrEncryptionKeyRequired = errors.New("account credentials are encrypted, encryption key is required")
+	ErrEncryptionKeyMismatch = errors.New("account credentials are encrypted with a different key")
+)
+
+// ParseEncryptionKey parses a 32 bytes AES-256 key encoded as base64 or hex,
+// e.g. generated with "openssl rand -base64 32"
+func ParseEncryptionKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("encryption key must be 32 bytes encoded as base64 or hex, e.g. generated with \"openssl rand -base64 32\"")
+}
+
+// secretCipher encrypts secrets with AES-256-GCM. Encrypted values carry the key ID,
+// so values encrypted with another key are detected instead of failing authentication.
+type secretCipher struct {
+	keyID string
+	aead  cipher.AEAD
+}
+
+// newSecretCipher creates a cipher with the 32 bytes key
+func newSecretCipher(key []byte) (*secretCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid encryption key length %d, expected 32 bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	sum := sha256.Sum256(key)
+	return &secretCipher{keyID: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// encrypt encrypts the value with a random nonce, empty value is kept empty
+func (c *secretCipher) encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + c.keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts the encrypted value, plaintext values are returned as is
+func (c *secretCipher) decrypt(value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	keyID, data, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	if c == nil {
+		return "", ErrEncryptionKeyRequired
+	}
+	if keyID != c.keyID {
+		return "", fmt.Errorf("%w: encrypted with key %s, configured key is %s", ErrEncryptionKeyMismatch, keyID, c.keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// isEncrypted returns true if the value was encrypted by secretCipher
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
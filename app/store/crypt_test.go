@@ -0,0 +1,108 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	parsed, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	parsed, err = ParseEncryptionKey(hex.EncodeToString(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseEncryptionKey("too-short")
+	require.Error(t, err)
+}
+
+func TestSecretCipher(t *testing.T) {
+	c, err := newSecretCipher(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	encrypted, err := c.encrypt("user:password")
+	require.NoError(t, err)
+	assert.True(t, isEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "password")
+
+	plain, err := c.decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "user:password", plain)
+
+	empty, err := c.encrypt("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	plain, err = c.decrypt("plaintext-token")
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext-token", plain)
+
+	other, err := newSecretCipher(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	_, err = other.decrypt(encrypted)
+	require.ErrorIs(t, err, ErrEncryptionKeyMismatch)
+
+	var none *secretCipher
+	_, err = none.decrypt(encrypted)
+	require.ErrorIs(t, err, ErrEncryptionKeyRequired)
+}
+
+func TestDB_EncryptsAccountCredentials(t *testing.T) {
+	ctx := context.Background()
+	dbPath := t.TempDir() + "/test.db"
+	key1, key2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+
+	// account stored in plaintext before encryption was enabled
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	acc := &Account{ProviderID: 1, Name: "main", ApiKey: "token"}
+	require.NoError(t, db.CreateAccount(ctx, acc))
+	require.NoError(t, db.Close())
+
+	db, err = New(dbPath, WithEncryptionKey(key1))
+	require.NoError(t, err)
+	var stored string
+	require.NoError(t, db.db.Get(&stored, `SELECT api_key FROM accounts WHERE id = ?`, acc.ID))
+	assert.True(t, isEncrypted(stored), "existing credentials encrypted on start")
+
+	got, err := db.GetAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "token", got.ApiKey)
+
+	got.ApiKey = "new-token"
+	require.NoError(t, db.UpdateAccount(ctx, got))
+	accounts, err := db.ListAccountsWithProviders(ctx)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "new-token", accounts[0].ApiKey)
+
+	n, err := db.RotateEncryptionKey(ctx, key2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	got, err = db.GetAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", got.ApiKey)
+	require.NoError(t, db.Close())
+
+	_, err = New(dbPath)
+	require.ErrorIs(t, err, ErrEncryptionKeyRequired)
+	_, err = New(dbPath, WithEncryptionKey(key1))
+	require.ErrorIs(t, err, ErrEncryptionKeyMismatch)
+
+	db, err = New(dbPath, WithEncryptionKey(key2))
+	require.NoError(t, err)
+	defer db.Close()
+	got, err = db.GetAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", got.ApiKey)
+}
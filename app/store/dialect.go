@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // postgres driver
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite" // sqlite driver
+)
+
+// dialect is the SQL flavour of the database the store is connected to
+type dialect string
+
+const (
+	dialectSQLite   dialect = "sqlite"
+	dialectPostgres dialect = "postgres"
+)
+
+// dialectOf returns the dialect of the database the DSN points to, postgres:// and postgresql:// URLs
+// are Postgres, anything else is a SQLite database file path
+func dialectOf(dsn string) dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return dialectPostgres
+	}
+	return dialectSQLite
+}
+
+// redactDSN returns the DSN with the password hidden, for logging
+func redactDSN(dsn string) string {
+	if dialectOf(dsn) != dialectPostgres {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres database"
+	}
+	return u.Redacted()
+}
+
+// sqlDB wraps sqlx.DB rebinding "?" placeholders of queries to the placeholders of the dialect,
+// so queries are written once for all supported databases
+type sqlDB struct {
+	*sqlx.DB
+	dialect dialect
+}
+
+// connect establishes connection to the database the DSN points to, see dialectOf
+func connect(dsn string) (*sqlDB, error) {
+	if dialectOf(dsn) == dialectPostgres {
+		return connectPostgres(dsn)
+	}
+	return connectSQLite(dsn)
+}
+
+// connectSQLite establishes SQLite connection with pragmas
+func connectSQLite(dbPath string) (*sqlDB, error) {
+	db, err := sqlx.Connect("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sqlite: %w", err)
+	}
+
+	// set pragmas for performance and reliability
+	pragmas := []string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA busy_timeout=5000",
+		"PRAGMA synchronous=NORMAL",
+		"PRAGMA cache_size=1000",
+		"PRAGMA foreign_keys=ON",
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to set pragma %q: %w", pragma, err)
+		}
+	}
+
+	// limit connections for SQLite (single writer)
+	db.SetMaxOpenConns(1)
+
+	return &sqlDB{DB: db, dialect: dialectSQLite}, nil
+}
+
+// connectPostgres establishes Postgres connection
+func connectPostgres(dsn string) (*sqlDB, error) {
+	db, err := sqlx.Connect("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	return &sqlDB{DB: db, dialect: dialectPostgres}, nil
+}
+
+// Exec executes the query with placeholders rebound to the dialect
+func (d *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(d.Rebind(query), args...)
+}
+
+// ExecContext executes the query with placeholders rebound to the dialect
+func (d *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, d.Rebind(query), args...)
+}
+
+// Get scans a single row of the query with placeholders rebound to the dialect
+func (d *sqlDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return d.DB.Get(dest, d.Rebind(query), args...)
+}
+
+// GetContext scans a single row of the query with placeholders rebound to the dialect
+func (d *sqlDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.DB.GetContext(ctx, dest, d.Rebind(query), args...)
+}
+
+// Select scans all rows of the query with placeholders rebound to the dialect
+func (d *sqlDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return d.DB.Select(dest, d.Rebind(query), args...)
+}
+
+// SelectContext scans all rows of the query with placeholders rebound to the dialect
+func (d *sqlDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return d.DB.SelectContext(ctx, dest, d.Rebind(query), args...)
+}
+
+// QueryxContext runs the query with placeholders rebound to the dialect
+func (d *sqlDB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return d.DB.QueryxContext(ctx, d.Rebind(query), args...)
+}
+
+// Beginx begins a transaction rebinding placeholders of its queries
+func (d *sqlDB) Beginx() (*sqlTx, error) {
+	tx, err := d.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: d.dialect}, nil
+}
+
+// BeginTxx begins a transaction rebinding placeholders of its queries
+func (d *sqlDB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlTx, error) {
+	tx, err := d.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: d.dialect}, nil
+}
+
+// insertContext executes the INSERT query and returns id of the inserted row. Postgres drivers
+// don't support LastInsertId, the id is returned by the query instead.
+func (d *sqlDB) insertContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if d.dialect == dialectPostgres {
+		var id int64
+		if err := d.GetContext(ctx, &id, query+` RETURNING id`, args...); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	result, err := d.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// likeOperator returns the case-insensitive LIKE operator, SQLite LIKE ignores case already
+func (d *sqlDB) likeOperator() string {
+	if d.dialect == dialectPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// tableExists checks whether the table exists in the database
+func (d *sqlDB) tableExists(name string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if d.dialect == dialectPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	var count int
+	if err := d.Get(&count, query, name); err != nil {
+		return false, fmt.Errorf("failed to check %s table: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+// sqlTx wraps sqlx.Tx rebinding "?" placeholders of queries to the placeholders of the dialect
+type sqlTx struct {
+	*sqlx.Tx
+	dialect dialect
+}
+
+// Exec executes the query with placeholders rebound to the dialect
+func (t *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.Exec(t.Rebind(query), args...)
+}
+
+// ExecContext executes the query with placeholders rebound to the dialect
+func (t *sqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.Rebind(query), args...)
+}
+
+// Get scans a single row of the query with placeholders rebound to the dialect
+func (t *sqlTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return t.Tx.Get(dest, t.Rebind(query), args...)
+}
+
+// GetContext scans a single row of the query with placeholders rebound to the dialect
+func (t *sqlTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.Tx.GetContext(ctx, dest, t.Rebind(query), args...)
+}
+
+// Select scans all rows of the query with placeholders rebound to the dialect
+func (t *sqlTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return t.Tx.Select(dest, t.Rebind(query), args...)
+}
+
+// SelectContext scans all rows of the query with placeholders rebound to the dialect
+func (t *sqlTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.Tx.SelectContext(ctx, dest, t.Rebind(query), args...)
+}
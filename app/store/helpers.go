@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"modernc.org/sqlite"
 )
 
@@ -20,6 +21,12 @@ func isUniqueViolation(err error) bool {
 		return code == 2067 || code == 1555
 	}
 
+	// postgres: unique_violation = 23505
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+
 	// fallback: check error message
 	return strings.Contains(err.Error(), "UNIQUE constraint")
 }
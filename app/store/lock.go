@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// keys of Postgres advisory locks taken by replicas sharing the database
+const (
+	lockKeyStartup int64 = 0x736d0001 // migrations, seeding and credentials encryption on start
+	lockKeyPurge   int64 = 0x736d0002 // trash purge
+	lockKeySync    int64 = 0x736d0003 // account sync, in high 32 bits of the key with account ID in low ones
+)
+
+// dialectLock serializes queries of a SQLite store, which allows a single writer at a time.
+// Postgres handles concurrent queries itself, the lock does nothing for it.
+type dialectLock struct {
+	rw      sync.RWMutex
+	enabled bool
+}
+
+// Lock locks for writing
+func (l *dialectLock) Lock() {
+	if l.enabled {
+		l.rw.Lock()
+	}
+}
+
+// Unlock unlocks for writing
+func (l *dialectLock) Unlock() {
+	if l.enabled {
+		l.rw.Unlock()
+	}
+}
+
+// RLock locks for reading
+func (l *dialectLock) RLock() {
+	if l.enabled {
+		l.rw.RLock()
+	}
+}
+
+// RUnlock unlocks for reading
+func (l *dialectLock) RUnlock() {
+	if l.enabled {
+		l.rw.RUnlock()
+	}
+}
+
+// withAdvisoryLock runs fn holding the Postgres session advisory lock of the key, waiting for other
+// replicas holding it. SQLite databases are not shared between processes, fn is run right away.
+func (s *DB) withAdvisoryLock(ctx context.Context, key int64, fn func() error) error {
+	if s.db.dialect != dialectPostgres {
+		return fn()
+	}
+
+	// the lock belongs to the session, so it is taken and released on the same connection
+	conn, err := s.db.DB.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		return fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, key) }()
+
+	return fn()
+}
+
+// TryLockAccountSync takes the Postgres advisory lock of the account sync, returns false if another replica
+// sharing the database holds it. The lock is held on a dedicated connection until unlock is called.
+// SQLite databases are not shared between processes, the lock is always taken.
+func (s *DB) TryLockAccountSync(ctx context.Context, accountID int64) (unlock func(), locked bool, err error) {
+	if s.db.dialect != dialectPostgres {
+		return func() {}, true, nil
+	}
+
+	conn, err := s.db.DB.Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	key := lockKeySync<<32 | accountID&0xffffffff
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock($1)`, key); err != nil {
+		_ = conn.Close()
+		return nil, false, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !locked {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		_ = conn.Close()
+	}, true, nil
+}
+
+// tryAdvisoryXactLock takes the Postgres advisory lock of the key for the rest of the transaction,
+// returns false if another replica holds it. Always succeeds for SQLite.
+func tryAdvisoryXactLock(ctx context.Context, tx *sqlTx, key int64) (bool, error) {
+	if tx.dialect != dialectPostgres {
+		return true, nil
+	}
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock(?)`, key); err != nil {
+		return false, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	return locked, nil
+}
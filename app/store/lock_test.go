@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_TryLockAccountSync(t *testing.T) {
+	ctx := context.Background()
+	dsn := testTargetDSN(t)
+	shared := dialectOf(dsn) == dialectPostgres
+
+	replica1, err := New(dsn)
+	require.NoError(t, err)
+	defer replica1.Close()
+	replica2, err := New(dsn)
+	require.NoError(t, err)
+	defer replica2.Close()
+
+	unlock, locked, err := replica1.TryLockAccountSync(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	unlock2, locked, err := replica2.TryLockAccountSync(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, !shared, locked, "account locked by another replica of a Postgres database")
+	if locked {
+		unlock2()
+	}
+
+	unlock2, locked, err = replica2.TryLockAccountSync(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, locked, "other accounts not locked")
+	unlock2()
+
+	unlock()
+	unlock2, locked, err = replica2.TryLockAccountSync(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, locked, "unlocked")
+	unlock2()
+}
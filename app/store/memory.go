@@ -0,0 +1,1729 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+// Memory implements Store interface keeping all data in memory. Data is lost on Close,
+// it is meant for tests and trying the application out without a database.
+type Memory struct {
+	mu     sync.RWMutex
+	lastID map[string]int64 // last assigned id by table
+
+	providers map[int64]Provider
+	accounts  map[int64]Account
+	servers   map[int64]Server
+	addresses map[int64]ServerAddress
+	tags      map[int64]ServerTag
+	resources map[int64]Resource
+	metrics   map[metricKey]ServerMetric
+	logs      map[int64]ServerLog
+	syncRuns  map[int64]SyncRun
+	users     map[int64]User
+	sessions  map[string]Session
+}
+
+// metricKey identifies a sample like the primary key of server_metrics
+type metricKey struct {
+	serverID int64
+	step     int
+	ts       int64
+}
+
+// NewMemory creates an empty in-memory store with default providers
+func NewMemory() *Memory {
+	m := &Memory{
+		lastID:    make(map[string]int64),
+		providers: make(map[int64]Provider),
+		accounts:  make(map[int64]Account),
+		servers:   make(map[int64]Server),
+		addresses: make(map[int64]ServerAddress),
+		tags:      make(map[int64]ServerTag),
+		resources: make(map[int64]Resource),
+		metrics:   make(map[metricKey]ServerMetric),
+		logs:      make(map[int64]ServerLog),
+		syncRuns:  make(map[int64]SyncRun),
+		users:     make(map[int64]User),
+		sessions:  make(map[string]Session),
+	}
+	now := time.Now().UTC()
+	for _, p := range defaultProviders {
+		id := m.nextID("providers")
+		m.providers[id] = Provider{ID: id, Ident: p.Ident, Name: p.Name, Description: p.Description,
+			CreatedAt: now, UpdatedAt: now}
+	}
+	return m
+}
+
+// Close does nothing, data is kept until the store is garbage collected
+func (m *Memory) Close() error {
+	return nil
+}
+
+// nextID returns the next id of the table, caller holds the lock
+func (m *Memory) nextID(table string) int64 {
+	m.lastID[table]++
+	return m.lastID[table]
+}
+
+// sortedIDs returns keys of the map in ascending order
+func sortedIDs[T any](rows map[int64]T) []int64 {
+	ids := make([]int64, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// limitRows truncates rows to the limit, negative limit keeps all rows like SQLite LIMIT -1
+func limitRows[T any](rows []T, limit int) []T {
+	if limit >= 0 && len(rows) > limit {
+		return rows[:limit]
+	}
+	return rows
+}
+
+// CreateProvider creates a new provider
+func (m *Memory) CreateProvider(_ context.Context, p *Provider) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.providerConflict(p.Ident, 0); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	p.ID = m.nextID("providers")
+	m.providers[p.ID] = *p
+	return nil
+}
+
+// GetProvider retrieves a provider by ID
+func (m *Memory) GetProvider(_ context.Context, id int64) (*Provider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.providers[id]
+	if !ok || !p.DeletedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+// GetProviderByName retrieves a provider by name
+func (m *Memory) GetProviderByName(_ context.Context, name string) (*Provider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range sortedIDs(m.providers) {
+		if p := m.providers[id]; p.Name == name && p.DeletedAt.IsZero() {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListProviders lists all providers
+func (m *Memory) ListProviders(_ context.Context) ([]Provider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	providers := make([]Provider, 0, len(m.providers))
+	for _, id := range sortedIDs(m.providers) {
+		if p := m.providers[id]; p.DeletedAt.IsZero() {
+			providers = append(providers, p)
+		}
+	}
+	sort.SliceStable(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers, nil
+}
+
+// UpdateProvider updates an existing provider
+func (m *Memory) UpdateProvider(_ context.Context, p *Provider) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.providers[p.ID]
+	if !ok || !stored.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	if err := m.providerConflict(p.Ident, p.ID); err != nil {
+		return err
+	}
+
+	p.UpdatedAt = time.Now().UTC()
+	stored.Ident, stored.Name, stored.Description, stored.UpdatedAt = p.Ident, p.Name, p.Description, p.UpdatedAt
+	m.providers[p.ID] = stored
+	return nil
+}
+
+// DeleteProvider moves a provider to the trash along with its accounts and their servers,
+// restoring the provider restores them as well
+func (m *Memory) DeleteProvider(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.providers[id]
+	if !ok || !p.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	p.DeletedAt = time.Now().UTC()
+	m.providers[id] = p
+	for _, a := range m.accounts {
+		if a.ProviderID == id && a.DeletedAt.IsZero() {
+			m.deleteAccount(a, p.DeletedAt)
+		}
+	}
+	return nil
+}
+
+// providerConflict returns ErrConflict if a provider other than exceptID has the ident, along with
+// ErrInTrash if that provider is in the trash, caller holds the lock
+func (m *Memory) providerConflict(ident string, exceptID int64) error {
+	for _, p := range m.providers {
+		if p.ID == exceptID || p.Ident != ident {
+			continue
+		}
+		if !p.DeletedAt.IsZero() {
+			return fmt.Errorf("%w: provider %q %w", ErrConflict, ident, ErrInTrash)
+		}
+		return fmt.Errorf("%w: provider with ident %q already exists", ErrConflict, ident)
+	}
+	return nil
+}
+
+// CreateAccount creates a new account
+func (m *Memory) CreateAccount(_ context.Context, a *Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.providers[a.ProviderID]; !ok {
+		return fmt.Errorf("failed to create account: provider %d not found", a.ProviderID)
+	}
+	if err := m.accountConflict(a.ProviderID, a.Name, 0); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	a.CreatedAt = now
+	a.UpdatedAt = now
+	a.ID = m.nextID("accounts")
+	m.accounts[a.ID] = *a
+	return nil
+}
+
+// GetAccount retrieves an account by ID
+func (m *Memory) GetAccount(_ context.Context, id int64) (*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.accounts[id]
+	if !ok || !a.DeletedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+// GetAccountWithProvider retrieves an account with provider info by ID
+func (m *Memory) GetAccountWithProvider(_ context.Context, id int64) (*AccountWithProvider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.accounts[id]
+	if !ok || !a.DeletedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	res := m.accountWithProvider(a)
+	return &res, nil
+}
+
+// ListAccounts lists all accounts
+func (m *Memory) ListAccounts(_ context.Context) ([]Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedAccounts(func(Account) bool { return true }), nil
+}
+
+// ListAccountsWithProviders lists all accounts with provider info
+func (m *Memory) ListAccountsWithProviders(_ context.Context) ([]AccountWithProvider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]AccountWithProvider, 0, len(m.accounts))
+	for _, a := range m.sortedAccounts(func(Account) bool { return true }) {
+		accounts = append(accounts, m.accountWithProvider(a))
+	}
+	sort.SliceStable(accounts, func(i, j int) bool { return accounts[i].ProviderName < accounts[j].ProviderName })
+	return accounts, nil
+}
+
+// ListAccountsByProvider lists accounts by provider ID
+func (m *Memory) ListAccountsByProvider(_ context.Context, providerID int64) ([]Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedAccounts(func(a Account) bool { return a.ProviderID == providerID }), nil
+}
+
+// UpdateAccount updates an existing account
+func (m *Memory) UpdateAccount(_ context.Context, a *Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.accounts[a.ID]
+	if !ok || !stored.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	if err := m.accountConflict(a.ProviderID, a.Name, a.ID); err != nil {
+		return err
+	}
+
+	a.UpdatedAt = time.Now().UTC()
+	a.CreatedAt = stored.CreatedAt
+	m.accounts[a.ID] = *a
+	return nil
+}
+
+// DeleteAccount moves an account to the trash along with its servers, restoring the account restores them.
+// Resources and sync runs of the account are kept until it is purged.
+func (m *Memory) DeleteAccount(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.accounts[id]
+	if !ok || !a.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	m.deleteAccount(a, time.Now().UTC())
+	return nil
+}
+
+// deleteAccount marks the account and its servers not deleted yet as deleted at the time,
+// caller holds the lock
+func (m *Memory) deleteAccount(a Account, deletedAt time.Time) {
+	a.DeletedAt = deletedAt
+	m.accounts[a.ID] = a
+	for id, srv := range m.servers {
+		if srv.AccountID == a.ID && srv.DeletedAt.IsZero() {
+			srv.DeletedAt = deletedAt
+			m.servers[id] = srv
+		}
+	}
+}
+
+// accountConflict returns ErrConflict if an account other than exceptID of the provider has the name,
+// along with ErrInTrash if that account is in the trash, caller holds the lock
+func (m *Memory) accountConflict(providerID int64, name string, exceptID int64) error {
+	for _, a := range m.accounts {
+		if a.ID == exceptID || a.ProviderID != providerID || a.Name != name {
+			continue
+		}
+		if !a.DeletedAt.IsZero() {
+			return fmt.Errorf("%w: account %q %w", ErrConflict, name, ErrInTrash)
+		}
+		return fmt.Errorf("%w: account with name %q already exists for this provider", ErrConflict, name)
+	}
+	return nil
+}
+
+// sortedAccounts returns accounts not in the trash matching the filter ordered by group and name,
+// caller holds the lock
+func (m *Memory) sortedAccounts(match func(Account) bool) []Account {
+	accounts := make([]Account, 0, len(m.accounts))
+	for _, id := range sortedIDs(m.accounts) {
+		if a := m.accounts[id]; a.DeletedAt.IsZero() && match(a) {
+			accounts = append(accounts, a)
+		}
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].GroupName != accounts[j].GroupName {
+			return accounts[i].GroupName < accounts[j].GroupName
+		}
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts
+}
+
+// accountWithProvider joins the account with its provider and server count, caller holds the lock
+func (m *Memory) accountWithProvider(a Account) AccountWithProvider {
+	p := m.providers[a.ProviderID]
+	res := AccountWithProvider{Account: a, ProviderIdent: p.Ident, ProviderName: p.Name}
+	for _, srv := range m.servers {
+		if srv.AccountID == a.ID && srv.DeletedAt.IsZero() {
+			res.ServerCount++
+		}
+	}
+	return res
+}
+
+// CreateServer creates a new server
+func (m *Memory) CreateServer(_ context.Context, srv *Server) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.accounts[srv.AccountID]; !ok {
+		return fmt.Errorf("failed to create server: account %d not found", srv.AccountID)
+	}
+	if m.externalIDTaken(srv.AccountID, srv.ExternalID, 0) {
+		return ErrConflict
+	}
+
+	now := time.Now().UTC()
+	srv.CreatedAt = now
+	srv.UpdatedAt = now
+	srv.ID = m.nextID("servers")
+	m.servers[srv.ID] = cloneServer(*srv)
+	return nil
+}
+
+// GetServer retrieves a server by ID
+func (m *Memory) GetServer(_ context.Context, id int64) (*Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	srv, ok := m.servers[id]
+	if !ok || !srv.DeletedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	res := cloneServer(srv)
+	return &res, nil
+}
+
+// GetServerWithAccount retrieves a server with account info by ID
+func (m *Memory) GetServerWithAccount(_ context.Context, id int64) (*ServerWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	srv, ok := m.servers[id]
+	if !ok || !srv.DeletedAt.IsZero() {
+		return nil, ErrNotFound
+	}
+	res := m.serverWithAccount(srv)
+	return &res, nil
+}
+
+// FindServerByNameAndAccount finds a server by name and account ID
+func (m *Memory) FindServerByNameAndAccount(_ context.Context, name string, accountID int64) (*Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findServer(func(srv Server) bool { return srv.AccountID == accountID && srv.Name == name })
+}
+
+// FindServerByExternalID finds a server by its provider-side identifier within an account
+func (m *Memory) FindServerByExternalID(_ context.Context, externalID string, accountID int64) (*Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findServer(func(srv Server) bool { return srv.AccountID == accountID && srv.ExternalID == externalID })
+}
+
+// FindServerByIPAndAccount finds a server by IP and account ID. The IP is matched against the primary
+// IP and all addresses of the server, servers with a matching primary IP are preferred.
+func (m *Memory) FindServerByIPAndAccount(_ context.Context, ip string, accountID int64) (*Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if srv, err := m.findServer(func(srv Server) bool { return srv.AccountID == accountID && srv.IP == ip }); err == nil {
+		return srv, nil
+	}
+	return m.findServer(func(srv Server) bool {
+		return srv.AccountID == accountID && slices.ContainsFunc(m.serverAddresses(srv.ID),
+			func(a ServerAddress) bool { return a.Address == ip })
+	})
+}
+
+// ListServers lists all servers
+func (m *Memory) ListServers(_ context.Context) ([]Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedServers(func(Server) bool { return true }), nil
+}
+
+// ListServersWithAccounts lists all servers with account info
+func (m *Memory) ListServersWithAccounts(_ context.Context) ([]ServerWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.serversWithAccounts(func(Server) bool { return true }), nil
+}
+
+// ListServersFiltered lists servers with account info matching the filter, in the filter sort order
+func (m *Memory) ListServersFiltered(_ context.Context, f ServerFilter) ([]ServerWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	search := strings.ToLower(f.Search)
+	contains := func(s string) bool { return strings.Contains(strings.ToLower(s), search) }
+	servers := m.serversWithAccounts(func(srv Server) bool {
+		if search != "" && !contains(srv.Name) && !contains(srv.IP) && !contains(srv.Description) &&
+			!slices.ContainsFunc(m.serverAddresses(srv.ID), func(a ServerAddress) bool { return contains(a.Address) }) {
+			return false
+		}
+		if f.CPUCores > 0 && srv.CPUCores != f.CPUCores {
+			return false
+		}
+		textFilters := []struct{ value, filter string }{
+			{srv.OSFlavor, f.OSFlavor},
+			{srv.OSVersion, f.OSVersion},
+			{srv.ServerType, f.ServerType},
+			{srv.Datacenter, f.Datacenter},
+		}
+		for _, tf := range textFilters {
+			if tf.filter != "" && tf.value != tf.filter {
+				return false
+			}
+		}
+		return f.Tag.IsZero() || m.hasTag(srv.ID, f.Tag)
+	})
+	sortServers(servers, f.Sort)
+	return servers, nil
+}
+
+// GetServerSpecOptions returns distinct spec values of stored servers
+func (m *Memory) GetServerSpecOptions(_ context.Context) (*ServerSpecOptions, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var opts ServerSpecOptions
+	for _, srv := range m.servers {
+		if !srv.DeletedAt.IsZero() {
+			continue
+		}
+		if srv.CPUCores > 0 && !slices.Contains(opts.CPUCores, srv.CPUCores) {
+			opts.CPUCores = append(opts.CPUCores, srv.CPUCores)
+		}
+		columns := []struct {
+			value string
+			dst   *[]string
+		}{
+			{srv.OSFlavor, &opts.OSFlavors},
+			{srv.OSVersion, &opts.OSVersions},
+			{srv.ServerType, &opts.ServerTypes},
+			{srv.Datacenter, &opts.Datacenters},
+		}
+		for _, c := range columns {
+			if c.value != "" && !slices.Contains(*c.dst, c.value) {
+				*c.dst = append(*c.dst, c.value)
+			}
+		}
+	}
+	slices.Sort(opts.CPUCores)
+	slices.Sort(opts.OSFlavors)
+	slices.Sort(opts.OSVersions)
+	slices.Sort(opts.ServerTypes)
+	slices.Sort(opts.Datacenters)
+	return &opts, nil
+}
+
+// ListServersByAccount lists servers by account ID
+func (m *Memory) ListServersByAccount(_ context.Context, accountID int64) ([]Server, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedServers(func(srv Server) bool { return srv.AccountID == accountID }), nil
+}
+
+// ListServersByStatus lists servers by status
+func (m *Memory) ListServersByStatus(_ context.Context, status enum.ServerStatus) ([]ServerWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.serversWithAccounts(func(srv Server) bool { return srv.Status == status }), nil
+}
+
+// ListServersWithContractEnding lists not deleted servers whose contract ends or has to be renewed
+// before the given time, ordered by contract end. Servers without contract dates are not listed.
+func (m *Memory) ListServersWithContractEnding(_ context.Context, before time.Time) ([]ServerWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	servers := m.serversWithAccounts(func(srv Server) bool { return contractEndsBefore(srv, before) })
+	sort.SliceStable(servers, func(i, j int) bool {
+		a, b := servers[i].ContractEnd(), servers[j].ContractEnd()
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return servers[i].Name < servers[j].Name
+	})
+	return servers, nil
+}
+
+// UpdateServer updates an existing server
+func (m *Memory) UpdateServer(_ context.Context, srv *Server) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.servers[srv.ID]
+	if !ok || !stored.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	if m.externalIDTaken(srv.AccountID, srv.ExternalID, srv.ID) {
+		return ErrConflict
+	}
+
+	srv.UpdatedAt = time.Now().UTC()
+	updated := cloneServer(*srv)
+	updated.CreatedAt = stored.CreatedAt
+	m.servers[srv.ID] = updated
+	return nil
+}
+
+// UpdateServerStatus updates only the status of a server
+func (m *Memory) UpdateServerStatus(_ context.Context, id int64, status enum.ServerStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	srv, ok := m.servers[id]
+	if !ok || !srv.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	srv.Status = status
+	srv.UpdatedAt = time.Now().UTC()
+	m.servers[id] = srv
+	return nil
+}
+
+// DeleteServer moves a server to the trash, its logs, tags and addresses are kept until it is purged
+func (m *Memory) DeleteServer(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	srv, ok := m.servers[id]
+	if !ok || !srv.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	srv.DeletedAt = time.Now().UTC()
+	m.servers[id] = srv
+	return nil
+}
+
+// purgeServer removes a server with its logs, addresses, tags and metrics,
+// resources attached to it are detached. Caller holds the lock.
+func (m *Memory) purgeServer(id int64) {
+	delete(m.servers, id)
+	for lid, l := range m.logs {
+		if l.ServerID == id {
+			delete(m.logs, lid)
+		}
+	}
+	for aid, a := range m.addresses {
+		if a.ServerID == id {
+			delete(m.addresses, aid)
+		}
+	}
+	for tid, t := range m.tags {
+		if t.ServerID == id {
+			delete(m.tags, tid)
+		}
+	}
+	for k := range m.metrics {
+		if k.serverID == id {
+			delete(m.metrics, k)
+		}
+	}
+	for rid, r := range m.resources {
+		if r.ServerID == id {
+			r.ServerID = 0
+			m.resources[rid] = r
+		}
+	}
+}
+
+// GetDashboardStats returns dashboard statistics
+func (m *Memory) GetDashboardStats(_ context.Context) (*DashboardStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats DashboardStats
+	contractsBefore := time.Now().UTC().AddDate(0, 0, ContractWarningDays)
+	for _, srv := range m.servers {
+		if !srv.DeletedAt.IsZero() {
+			continue
+		}
+		stats.TotalServers++
+		switch srv.Status {
+		case enum.ServerStatusActive:
+			stats.ActiveServers++
+		case enum.ServerStatusPaused:
+			stats.PausedServers++
+		}
+		if srv.Status != enum.ServerStatusDeleted {
+			stats.TotalCost += srv.ApproximateCost
+		}
+		if contractEndsBefore(srv, contractsBefore) {
+			stats.ContractsEnding++
+		}
+	}
+	for _, r := range m.resources {
+		if !m.accounts[r.AccountID].DeletedAt.IsZero() {
+			continue
+		}
+		stats.ResourceCount++
+		stats.ResourceCost += r.Cost
+	}
+	stats.TotalCost += stats.ResourceCost
+	return &stats, nil
+}
+
+// GetServersGroupedByAccount returns servers grouped by account for dashboard
+func (m *Memory) GetServersGroupedByAccount(_ context.Context, status *enum.ServerStatus) ([]AccountGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	servers := m.serversWithAccounts(func(srv Server) bool { return status == nil || srv.Status == *status })
+	var resources []ResourceWithAccount
+	if status == nil {
+		resources = m.resourcesWithAccounts()
+	}
+	return groupByAccount(servers, resources), nil
+}
+
+// GetServersGroupedHierarchically returns servers in hierarchical structure:
+// Provider+GroupName -> Accounts (Projects) -> Servers, optionally filtered by status and tag
+func (m *Memory) GetServersGroupedHierarchically(_ context.Context, status *enum.ServerStatus,
+	tag TagFilter) ([]ProviderAccountGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	servers := m.serversWithAccounts(func(srv Server) bool {
+		return (status == nil || srv.Status == *status) && (tag.IsZero() || m.hasTag(srv.ID, tag))
+	})
+	var resources []ResourceWithAccount
+	if status == nil {
+		resources = m.resourcesWithAccounts()
+	}
+	return groupHierarchically(servers, resources), nil
+}
+
+// externalIDTaken checks whether a server other than exceptID of the account has the external ID,
+// servers without one and servers in the trash are not constrained. Caller holds the lock.
+func (m *Memory) externalIDTaken(accountID int64, externalID string, exceptID int64) bool {
+	if externalID == "" {
+		return false
+	}
+	for _, srv := range m.servers {
+		if srv.ID != exceptID && srv.AccountID == accountID && srv.ExternalID == externalID && srv.DeletedAt.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// findServer returns the server not in the trash with the lowest ID matching the filter,
+// caller holds the lock
+func (m *Memory) findServer(match func(Server) bool) (*Server, error) {
+	for _, id := range sortedIDs(m.servers) {
+		if srv := m.servers[id]; srv.DeletedAt.IsZero() && match(srv) {
+			res := cloneServer(srv)
+			return &res, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// sortedServers returns servers not in the trash matching the filter ordered by name, caller holds the lock
+func (m *Memory) sortedServers(match func(Server) bool) []Server {
+	servers := make([]Server, 0, len(m.servers))
+	for _, id := range sortedIDs(m.servers) {
+		if srv := m.servers[id]; srv.DeletedAt.IsZero() && match(srv) {
+			servers = append(servers, cloneServer(srv))
+		}
+	}
+	sort.SliceStable(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers
+}
+
+// serversWithAccounts returns servers matching the filter joined with their accounts, ordered by
+// provider, group, account and server name. Caller holds the lock.
+func (m *Memory) serversWithAccounts(match func(Server) bool) []ServerWithAccount {
+	servers := make([]ServerWithAccount, 0, len(m.servers))
+	for _, srv := range m.sortedServers(match) {
+		servers = append(servers, m.serverWithAccount(srv))
+	}
+	sortServers(servers, "")
+	return servers
+}
+
+// serverWithAccount joins the server with its account and provider, caller holds the lock
+func (m *Memory) serverWithAccount(srv Server) ServerWithAccount {
+	a := m.accounts[srv.AccountID]
+	return ServerWithAccount{
+		Server:           cloneServer(srv),
+		AccountName:      a.Name,
+		AccountGroupName: a.GroupName,
+		ProviderID:       a.ProviderID,
+		ProviderName:     m.providers[a.ProviderID].Name,
+	}
+}
+
+// sortServers sorts servers by the ServerFilter sort field like serverOrderBy
+func sortServers(servers []ServerWithAccount, sortField string) {
+	desc := strings.HasPrefix(sortField, "-")
+	sortField = strings.TrimPrefix(sortField, "-")
+
+	// compare returns negative, zero or positive number like strings.Compare
+	compare := map[string]func(a, b ServerWithAccount) int{
+		"name":        func(a, b ServerWithAccount) int { return strings.Compare(a.Name, b.Name) },
+		"cpu_cores":   func(a, b ServerWithAccount) int { return a.CPUCores - b.CPUCores },
+		"memory":      func(a, b ServerWithAccount) int { return compareFloats(a.MemoryGB, b.MemoryGB) },
+		"disk":        func(a, b ServerWithAccount) int { return a.DiskGB - b.DiskGB },
+		"cost":        func(a, b ServerWithAccount) int { return compareFloats(a.ApproximateCost, b.ApproximateCost) },
+		"server_type": func(a, b ServerWithAccount) int { return strings.Compare(a.ServerType, b.ServerType) },
+		"os":          func(a, b ServerWithAccount) int { return strings.Compare(a.OS(), b.OS()) },
+		"datacenter":  func(a, b ServerWithAccount) int { return strings.Compare(a.Datacenter, b.Datacenter) },
+	}[sortField]
+
+	if compare == nil {
+		sort.SliceStable(servers, func(i, j int) bool {
+			a, b := servers[i], servers[j]
+			for _, c := range [][2]string{{a.ProviderName, b.ProviderName}, {a.AccountGroupName, b.AccountGroupName},
+				{a.AccountName, b.AccountName}} {
+				if c[0] != c[1] {
+					return c[0] < c[1]
+				}
+			}
+			return a.Name < b.Name
+		})
+		return
+	}
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		c := compare(servers[i], servers[j])
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return servers[i].Name < servers[j].Name
+	})
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// contractEndsBefore checks whether the server is not deleted and its contract ends before the time
+func contractEndsBefore(srv Server, before time.Time) bool {
+	end := srv.ContractEnd()
+	return srv.Status != enum.ServerStatusDeleted && !end.IsZero() && !end.After(before)
+}
+
+// cloneServer copies the server so stored and returned servers don't share locked fields
+func cloneServer(srv Server) Server {
+	srv.LockedFields = slices.Clone(srv.LockedFields)
+	return srv
+}
+
+// ListServerAddresses lists addresses of a server, public first
+func (m *Memory) ListServerAddresses(_ context.Context, serverID int64) ([]ServerAddress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.serverAddresses(serverID), nil
+}
+
+// SetServerAddresses replaces all addresses of a server. Duplicates of the same address and prefix are skipped.
+func (m *Memory) SetServerAddresses(_ context.Context, serverID int64, addrs []ServerAddress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.servers[serverID]; !ok {
+		return fmt.Errorf("failed to add server addresses: server %d not found", serverID)
+	}
+	for id, a := range m.addresses {
+		if a.ServerID == serverID {
+			delete(m.addresses, id)
+		}
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		if seen[a.String()] {
+			continue
+		}
+		seen[a.String()] = true
+		a.ID = m.nextID("server_addresses")
+		a.ServerID = serverID
+		a.CreatedAt = now
+		m.addresses[a.ID] = a
+	}
+	return nil
+}
+
+// serverAddresses returns addresses of the server, public first, caller holds the lock
+func (m *Memory) serverAddresses(serverID int64) []ServerAddress {
+	kindOrder := map[enum.AddressKind]int{enum.AddressKindPublic: 0, enum.AddressKindFloating: 1, enum.AddressKindSubnet: 2}
+	rank := func(k enum.AddressKind) int {
+		if r, ok := kindOrder[k]; ok {
+			return r
+		}
+		return 3
+	}
+
+	addrs := make([]ServerAddress, 0)
+	for _, id := range sortedIDs(m.addresses) {
+		if a := m.addresses[id]; a.ServerID == serverID {
+			addrs = append(addrs, a)
+		}
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		if rank(addrs[i].Kind) != rank(addrs[j].Kind) {
+			return rank(addrs[i].Kind) < rank(addrs[j].Kind)
+		}
+		return addrs[i].Family < addrs[j].Family
+	})
+	return addrs
+}
+
+// ListServerTags lists tags of a server ordered by key
+func (m *Memory) ListServerTags(_ context.Context, serverID int64) ([]ServerTag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.serverTags(func(t ServerTag) bool { return t.ServerID == serverID }), nil
+}
+
+// ListAllServerTags returns tags of all servers by server ID
+func (m *Memory) ListAllServerTags(_ context.Context) (map[int64][]ServerTag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make(map[int64][]ServerTag)
+	for _, t := range m.serverTags(func(ServerTag) bool { return true }) {
+		res[t.ServerID] = append(res[t.ServerID], t)
+	}
+	return res, nil
+}
+
+// ListTagOptions returns distinct key/value pairs of all tags ordered by key and value, for filters
+func (m *Memory) ListTagOptions(_ context.Context) ([]TagFilter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]TagFilter, 0)
+	for _, t := range m.tags {
+		if !m.servers[t.ServerID].DeletedAt.IsZero() {
+			continue
+		}
+		f := TagFilter{Key: t.Key, Value: t.Value}
+		if !slices.Contains(res, f) {
+			res = append(res, f)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Key != res[j].Key {
+			return res[i].Key < res[j].Key
+		}
+		return res[i].Value < res[j].Value
+	})
+	return res, nil
+}
+
+// SetServerTags replaces tags of the source on a server. User tags override provider tags
+// with the same key, provider tags with a key already set by user are skipped.
+func (m *Memory) SetServerTags(_ context.Context, serverID int64, source enum.TagSource, tags []ServerTag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.servers[serverID]; !ok {
+		return fmt.Errorf("failed to add server tags: server %d not found", serverID)
+	}
+	for id, t := range m.tags {
+		if t.ServerID == serverID && t.Source == source {
+			delete(m.tags, id)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, t := range tags {
+		existing := m.serverTags(func(st ServerTag) bool { return st.ServerID == serverID && st.Key == t.Key })
+		if len(existing) > 0 {
+			if source == enum.TagSourceProvider {
+				continue
+			}
+			delete(m.tags, existing[0].ID)
+		}
+		t.ID = m.nextID("server_tags")
+		t.ServerID = serverID
+		t.Source = source
+		t.CreatedAt = now
+		m.tags[t.ID] = t
+	}
+	return nil
+}
+
+// serverTags returns tags matching the filter ordered by server and key, caller holds the lock
+func (m *Memory) serverTags(match func(ServerTag) bool) []ServerTag {
+	tags := make([]ServerTag, 0)
+	for _, t := range m.tags {
+		if match(t) {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].ServerID != tags[j].ServerID {
+			return tags[i].ServerID < tags[j].ServerID
+		}
+		return tags[i].Key < tags[j].Key
+	})
+	return tags
+}
+
+// hasTag checks whether the server has a tag matching the filter, caller holds the lock
+func (m *Memory) hasTag(serverID int64, f TagFilter) bool {
+	for _, t := range m.tags {
+		if t.ServerID == serverID && t.Key == f.Key && (f.Value == "" || t.Value == f.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListResources lists resources of all accounts with account info
+func (m *Memory) ListResources(_ context.Context) ([]ResourceWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.resourcesWithAccounts(), nil
+}
+
+// ListResourcesByAccount lists resources of an account
+func (m *Memory) ListResourcesByAccount(_ context.Context, accountID int64) ([]Resource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedResources(func(r Resource) bool { return r.AccountID == accountID }), nil
+}
+
+// ListResourcesByServer lists resources attached to a server
+func (m *Memory) ListResourcesByServer(_ context.Context, serverID int64) ([]Resource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedResources(func(r Resource) bool { return r.ServerID == serverID }), nil
+}
+
+// ReplaceAccountResources stores resources of the given kinds reported by the account provider.
+// Resources are matched by kind and external ID, stored resources of these kinds missing
+// from the list are removed. Resources of other kinds are not touched.
+func (m *Memory) ReplaceAccountResources(_ context.Context, accountID int64, kinds []enum.ResourceKind,
+	resources []Resource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.accounts[accountID]; !ok {
+		return fmt.Errorf("failed to store resources: account %d not found", accountID)
+	}
+
+	now := time.Now().UTC()
+	keep := make(map[int64]bool, len(resources))
+	for i := range resources {
+		r := &resources[i]
+		r.AccountID = accountID
+		stored := Resource{ID: m.nextID("resources"), CreatedAt: now}
+		for id, existing := range m.resources {
+			if existing.AccountID == accountID && existing.Kind == r.Kind && existing.ExternalID == r.ExternalID {
+				m.lastID["resources"]--
+				stored = Resource{ID: id, CreatedAt: existing.CreatedAt}
+				break
+			}
+		}
+		res := *r
+		res.ID, res.CreatedAt, res.UpdatedAt = stored.ID, stored.CreatedAt, now
+		m.resources[res.ID] = res
+		keep[res.ID] = true
+	}
+
+	for id, r := range m.resources {
+		if r.AccountID == accountID && slices.Contains(kinds, r.Kind) && !keep[id] {
+			delete(m.resources, id)
+		}
+	}
+	return nil
+}
+
+// sortedResources returns resources matching the filter ordered by kind and name, caller holds the lock
+func (m *Memory) sortedResources(match func(Resource) bool) []Resource {
+	resources := make([]Resource, 0)
+	for _, id := range sortedIDs(m.resources) {
+		if r := m.resources[id]; match(r) {
+			resources = append(resources, r)
+		}
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind.String() < resources[j].Kind.String()
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return resources
+}
+
+// resourcesWithAccounts returns resources joined with accounts, providers and servers, ordered by
+// provider, group, account, kind and name. Caller holds the lock.
+func (m *Memory) resourcesWithAccounts() []ResourceWithAccount {
+	resources := make([]ResourceWithAccount, 0, len(m.resources))
+	for _, r := range m.sortedResources(func(r Resource) bool { return m.accounts[r.AccountID].DeletedAt.IsZero() }) {
+		a := m.accounts[r.AccountID]
+		var serverName string
+		if srv, ok := m.servers[r.ServerID]; ok && srv.DeletedAt.IsZero() {
+			serverName = srv.Name
+		}
+		resources = append(resources, ResourceWithAccount{
+			Resource:         r,
+			AccountName:      a.Name,
+			AccountGroupName: a.GroupName,
+			ProviderID:       a.ProviderID,
+			ProviderName:     m.providers[a.ProviderID].Name,
+			ServerName:       serverName,
+		})
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.ProviderName != b.ProviderName {
+			return a.ProviderName < b.ProviderName
+		}
+		if a.AccountGroupName != b.AccountGroupName {
+			return a.AccountGroupName < b.AccountGroupName
+		}
+		return a.AccountName < b.AccountName
+	})
+	return resources
+}
+
+// AddServerMetrics stores utilization samples, replacing samples of the same server, step and time
+func (m *Memory) AddServerMetrics(_ context.Context, metrics []ServerMetric) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, sample := range metrics {
+		if _, ok := m.servers[sample.ServerID]; !ok {
+			return fmt.Errorf("failed to store metric of server %d: server not found", sample.ServerID)
+		}
+	}
+	m.putMetrics(metrics)
+	return nil
+}
+
+// ListServerMetrics lists utilization samples of a server starting at or after since, oldest first
+func (m *Memory) ListServerMetrics(_ context.Context, serverID int64, since time.Time) ([]ServerMetric, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedMetrics(func(s ServerMetric) bool { return s.ServerID == serverID && !s.Time.Before(since) }), nil
+}
+
+// GetLastServerMetricTime returns the end of the latest sample of a server, zero if there are none
+func (m *Memory) GetLastServerMetricTime(_ context.Context, serverID int64) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metrics := m.sortedMetrics(func(s ServerMetric) bool { return s.ServerID == serverID })
+	if len(metrics) == 0 {
+		return time.Time{}, nil
+	}
+	last := metrics[len(metrics)-1]
+	return last.Time.Add(time.Duration(last.Step) * time.Second), nil
+}
+
+// DownsampleServerMetrics rolls hourly samples of whole days before hourlyBefore up into daily
+// samples and removes samples of any step older than purgeBefore
+func (m *Memory) DownsampleServerMetrics(_ context.Context, hourlyBefore, purgeBefore time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hourlyBefore = hourlyBefore.UTC().Truncate(24 * time.Hour)
+	hourly := m.sortedMetrics(func(s ServerMetric) bool {
+		return s.Step == MetricStepHour && s.Time.Before(hourlyBefore)
+	})
+	sort.SliceStable(hourly, func(i, j int) bool { return hourly[i].ServerID < hourly[j].ServerID })
+
+	if len(hourly) > 0 {
+		m.putMetrics(rollupDaily(hourly))
+		for _, s := range hourly {
+			delete(m.metrics, metricKey{serverID: s.ServerID, step: s.Step, ts: s.Time.UnixNano()})
+		}
+	}
+	for k, s := range m.metrics {
+		if s.Time.Before(purgeBefore) {
+			delete(m.metrics, k)
+		}
+	}
+	return nil
+}
+
+// ListServerUtilization lists active servers with samples starting at or after since,
+// with their utilization averaged over the samples, least utilized first
+func (m *Memory) ListServerUtilization(_ context.Context, since time.Time) ([]ServerUtilization, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type agg struct{ cpu, net, maxCPU, steps float64 }
+	aggs := make(map[int64]*agg)
+	for _, s := range m.metrics {
+		if s.Time.Before(since) {
+			continue
+		}
+		a, ok := aggs[s.ServerID]
+		if !ok {
+			a = &agg{maxCPU: s.CPU}
+			aggs[s.ServerID] = a
+		}
+		// samples are weighted by their step, so daily and hourly samples of the period mix correctly
+		w := float64(s.Step)
+		a.cpu += s.CPU * w
+		a.net += (s.NetIn + s.NetOut) * w
+		a.steps += w
+		a.maxCPU = max(a.maxCPU, s.CPU)
+	}
+
+	res := make([]ServerUtilization, 0, len(aggs))
+	for _, id := range sortedIDs(aggs) {
+		srv := m.servers[id]
+		if srv.Status != enum.ServerStatusActive || !srv.DeletedAt.IsZero() {
+			continue
+		}
+		a := aggs[id]
+		res = append(res, ServerUtilization{ServerWithAccount: m.serverWithAccount(srv), AvgCPU: a.cpu / a.steps,
+			MaxCPU: a.maxCPU, AvgNet: a.net / a.steps})
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].AvgCPU < res[j].AvgCPU })
+	return res, nil
+}
+
+// putMetrics upserts samples, caller holds the lock
+func (m *Memory) putMetrics(metrics []ServerMetric) {
+	for _, s := range metrics {
+		s.Time = s.Time.UTC()
+		m.metrics[metricKey{serverID: s.ServerID, step: s.Step, ts: s.Time.UnixNano()}] = s
+	}
+}
+
+// sortedMetrics returns samples matching the filter ordered by time, caller holds the lock
+func (m *Memory) sortedMetrics(match func(ServerMetric) bool) []ServerMetric {
+	metrics := make([]ServerMetric, 0)
+	for _, s := range m.metrics {
+		if match(s) {
+			metrics = append(metrics, s)
+		}
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if !metrics[i].Time.Equal(metrics[j].Time) {
+			return metrics[i].Time.Before(metrics[j].Time)
+		}
+		return metrics[i].ServerID < metrics[j].ServerID
+	})
+	return metrics
+}
+
+// CreateLog creates a new server log entry, at the current time unless its time is set
+func (m *Memory) CreateLog(_ context.Context, l *ServerLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.servers[l.ServerID]; !ok {
+		return fmt.Errorf("failed to create log: server %d not found", l.ServerID)
+	}
+
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	l.CreatedAt = l.CreatedAt.UTC()
+	l.ID = m.nextID("server_logs")
+	m.logs[l.ID] = *l
+	return nil
+}
+
+// ListLogs lists all logs with server info, limited by count
+func (m *Memory) ListLogs(_ context.Context, limit int) ([]ServerLogWithServer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.logsWithServers(func(ServerLog) bool { return true }, limit), nil
+}
+
+// ListLogsByServer lists logs for a specific server
+func (m *Memory) ListLogsByServer(_ context.Context, serverID int64, limit int) ([]ServerLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	logs := make([]ServerLog, 0)
+	for _, l := range m.logsWithServers(func(l ServerLog) bool { return l.ServerID == serverID }, limit) {
+		logs = append(logs, l.ServerLog)
+	}
+	return logs, nil
+}
+
+// ListLogsByAction lists logs filtered by action
+func (m *Memory) ListLogsByAction(_ context.Context, action enum.LogAction, limit int) ([]ServerLogWithServer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.logsWithServers(func(l ServerLog) bool { return l.Action == action }, limit), nil
+}
+
+// ListLogsFiltered lists logs of servers matching the filter
+func (m *Memory) ListLogsFiltered(_ context.Context, f LogFilter, limit int) ([]ServerLogWithServer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.logsWithServers(func(l ServerLog) bool {
+		return (f.Action == nil || l.Action == *f.Action) && (f.Tag.IsZero() || m.hasTag(l.ServerID, f.Tag))
+	}, limit), nil
+}
+
+// logsWithServers returns logs matching the filter joined with their servers, most recent first,
+// caller holds the lock
+func (m *Memory) logsWithServers(match func(ServerLog) bool, limit int) []ServerLogWithServer {
+	logs := make([]ServerLogWithServer, 0)
+	for _, l := range m.logs {
+		if match(l) {
+			srv := m.servers[l.ServerID]
+			logs = append(logs, ServerLogWithServer{ServerLog: l, ServerName: srv.Name, ServerIP: srv.IP})
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].CreatedAt.Equal(logs[j].CreatedAt) {
+			return logs[i].CreatedAt.After(logs[j].CreatedAt)
+		}
+		return logs[i].ID > logs[j].ID
+	})
+	return limitRows(logs, limit)
+}
+
+// CreateSyncRun records the start of an account sync
+func (m *Memory) CreateSyncRun(_ context.Context, r *SyncRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.accounts[r.AccountID]; !ok {
+		return fmt.Errorf("failed to create sync run: account %d not found", r.AccountID)
+	}
+
+	if r.StartedAt.IsZero() {
+		r.StartedAt = time.Now().UTC()
+	}
+	r.ID = m.nextID("sync_runs")
+	m.syncRuns[r.ID] = SyncRun{ID: r.ID, AccountID: r.AccountID, Trigger: r.Trigger, StartedAt: r.StartedAt}
+	return nil
+}
+
+// FinishSyncRun records the outcome of an account sync
+func (m *Memory) FinishSyncRun(_ context.Context, r *SyncRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.syncRuns[r.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.FinishedAt.IsZero() {
+		r.FinishedAt = time.Now().UTC()
+	}
+	stored.FinishedAt, stored.Added, stored.Updated, stored.Deleted = r.FinishedAt, r.Added, r.Updated, r.Deleted
+	stored.Unchanged, stored.Error = r.Unchanged, r.Error
+	m.syncRuns[r.ID] = stored
+	return nil
+}
+
+// ListSyncRuns lists sync runs of all accounts, most recent first
+func (m *Memory) ListSyncRuns(_ context.Context, limit int) ([]SyncRunWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.syncRunsWithAccounts(func(SyncRun) bool { return true }, limit), nil
+}
+
+// ListSyncRunsByAccount lists sync runs of a single account, most recent first
+func (m *Memory) ListSyncRunsByAccount(_ context.Context, accountID int64, limit int) ([]SyncRunWithAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.syncRunsWithAccounts(func(r SyncRun) bool { return r.AccountID == accountID }, limit), nil
+}
+
+// GetLastSyncRuns returns the most recent sync run of each account, keyed by account ID
+func (m *Memory) GetLastSyncRuns(_ context.Context) (map[int64]SyncRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runs := make(map[int64]SyncRun)
+	for _, id := range sortedIDs(m.syncRuns) {
+		r := m.syncRuns[id]
+		runs[r.AccountID] = r
+	}
+	return runs, nil
+}
+
+// syncRunsWithAccounts returns sync runs matching the filter joined with their accounts, most recent first,
+// caller holds the lock
+func (m *Memory) syncRunsWithAccounts(match func(SyncRun) bool, limit int) []SyncRunWithAccount {
+	runs := make([]SyncRunWithAccount, 0)
+	for _, r := range m.syncRuns {
+		if match(r) {
+			a := m.accounts[r.AccountID]
+			runs = append(runs, SyncRunWithAccount{SyncRun: r, AccountName: a.Name,
+				ProviderName: m.providers[a.ProviderID].Name})
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+	return limitRows(runs, limit)
+}
+
+// ListTrash lists deleted providers, accounts and servers, most recently deleted first
+func (m *Memory) ListTrash(_ context.Context) (*Trash, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var trash Trash
+	for _, id := range sortedIDs(m.providers) {
+		if p := m.providers[id]; !p.DeletedAt.IsZero() {
+			trash.Providers = append(trash.Providers, p)
+		}
+	}
+	for _, id := range sortedIDs(m.accounts) {
+		a := m.accounts[id]
+		if a.DeletedAt.IsZero() {
+			continue
+		}
+		// credentials are not listed, server count is the number of servers restored along with the account
+		res := AccountWithProvider{Account: a, ProviderIdent: m.providers[a.ProviderID].Ident,
+			ProviderName: m.providers[a.ProviderID].Name}
+		res.ApiKey = ""
+		for _, srv := range m.servers {
+			if srv.AccountID == a.ID && srv.DeletedAt.Equal(a.DeletedAt) {
+				res.ServerCount++
+			}
+		}
+		trash.Accounts = append(trash.Accounts, res)
+	}
+	for _, id := range sortedIDs(m.servers) {
+		if srv := m.servers[id]; !srv.DeletedAt.IsZero() {
+			trash.Servers = append(trash.Servers, m.serverWithAccount(srv))
+		}
+	}
+
+	sort.SliceStable(trash.Providers, func(i, j int) bool {
+		return trash.Providers[i].DeletedAt.After(trash.Providers[j].DeletedAt)
+	})
+	sort.SliceStable(trash.Accounts, func(i, j int) bool {
+		return trash.Accounts[i].DeletedAt.After(trash.Accounts[j].DeletedAt)
+	})
+	sort.SliceStable(trash.Servers, func(i, j int) bool {
+		return trash.Servers[i].DeletedAt.After(trash.Servers[j].DeletedAt)
+	})
+	return &trash, nil
+}
+
+// RestoreProvider restores a provider from the trash along with accounts and servers deleted with it
+func (m *Memory) RestoreProvider(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.providers[id]
+	if !ok || p.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	var accounts []Account
+	for _, a := range m.accounts {
+		if a.ProviderID == id && a.DeletedAt.Equal(p.DeletedAt) {
+			accounts = append(accounts, a)
+		}
+	}
+	for _, a := range accounts {
+		if err := m.checkRestoredServers(a); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range accounts {
+		m.restoreAccount(a)
+	}
+	p.DeletedAt = time.Time{}
+	m.providers[id] = p
+	return nil
+}
+
+// RestoreAccount restores an account from the trash along with servers deleted with it.
+// Returns ErrParentDeleted if the provider of the account is in the trash.
+func (m *Memory) RestoreAccount(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.accounts[id]
+	if !ok || a.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	if p := m.providers[a.ProviderID]; !p.DeletedAt.IsZero() {
+		return fmt.Errorf("%w: provider %q is deleted, restore it first", ErrParentDeleted, p.Name)
+	}
+	if err := m.checkRestoredServers(a); err != nil {
+		return err
+	}
+	m.restoreAccount(a)
+	return nil
+}
+
+// RestoreServer restores a server from the trash. Returns ErrParentDeleted if its account is in the trash
+// and ErrConflict if another server of the account has the same external ID.
+func (m *Memory) RestoreServer(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	srv, ok := m.servers[id]
+	if !ok || srv.DeletedAt.IsZero() {
+		return ErrNotFound
+	}
+	if a := m.accounts[srv.AccountID]; !a.DeletedAt.IsZero() {
+		return fmt.Errorf("%w: account %q is deleted, restore it first", ErrParentDeleted, a.Name)
+	}
+	if m.externalIDTaken(srv.AccountID, srv.ExternalID, srv.ID) {
+		return fmt.Errorf("%w: a server with the same external id exists", ErrConflict)
+	}
+	srv.DeletedAt = time.Time{}
+	m.servers[id] = srv
+	return nil
+}
+
+// PurgeTrash permanently removes providers, accounts and servers deleted before the given time,
+// along with logs, tags, addresses and metrics of the servers and resources and sync runs of the accounts.
+// Accounts and providers still having records are kept. Returns the number of removed records.
+func (m *Memory) PurgeTrash(_ context.Context, before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := func(t time.Time) bool { return !t.IsZero() && t.Before(before) }
+	total := 0
+	for id, srv := range m.servers {
+		if expired(srv.DeletedAt) {
+			m.purgeServer(id)
+			total++
+		}
+	}
+
+	hasServers := func(accountID int64) bool {
+		for _, srv := range m.servers {
+			if srv.AccountID == accountID {
+				return true
+			}
+		}
+		return false
+	}
+	for id, a := range m.accounts {
+		if !expired(a.DeletedAt) || hasServers(id) {
+			continue
+		}
+		delete(m.accounts, id)
+		for rid, r := range m.syncRuns {
+			if r.AccountID == id {
+				delete(m.syncRuns, rid)
+			}
+		}
+		for rid, r := range m.resources {
+			if r.AccountID == id {
+				delete(m.resources, rid)
+			}
+		}
+		total++
+	}
+
+	hasAccounts := func(providerID int64) bool {
+		for _, a := range m.accounts {
+			if a.ProviderID == providerID {
+				return true
+			}
+		}
+		return false
+	}
+	for id, p := range m.providers {
+		if !expired(p.DeletedAt) || hasAccounts(id) {
+			continue
+		}
+		delete(m.providers, id)
+		total++
+	}
+	return total, nil
+}
+
+// checkRestoredServers returns ErrConflict if a server deleted along with the account has the external ID
+// of another server of the account, caller holds the lock
+func (m *Memory) checkRestoredServers(a Account) error {
+	for _, srv := range m.servers {
+		if srv.AccountID == a.ID && srv.DeletedAt.Equal(a.DeletedAt) && m.externalIDTaken(a.ID, srv.ExternalID, srv.ID) {
+			return fmt.Errorf("%w: a server with the same external id exists", ErrConflict)
+		}
+	}
+	return nil
+}
+
+// restoreAccount clears deletion time of the account and servers deleted along with it, caller holds the lock
+func (m *Memory) restoreAccount(a Account) {
+	for id, srv := range m.servers {
+		if srv.AccountID == a.ID && srv.DeletedAt.Equal(a.DeletedAt) {
+			srv.DeletedAt = time.Time{}
+			m.servers[id] = srv
+		}
+	}
+	a.DeletedAt = time.Time{}
+	m.accounts[a.ID] = a
+}
+
+// CreateUser creates a new user
+func (m *Memory) CreateUser(_ context.Context, u *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Username == u.Username {
+			return fmt.Errorf("%w: user with username %q already exists", ErrConflict, u.Username)
+		}
+	}
+
+	now := time.Now().UTC()
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	u.ID = m.nextID("users")
+	m.users[u.ID] = *u
+	return nil
+}
+
+// GetUserByUsername retrieves a user by username
+func (m *Memory) GetUserByUsername(_ context.Context, username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetUserByID retrieves a user by ID
+func (m *Memory) GetUserByID(_ context.Context, id int64) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// UpdateUserPassword updates a user's password
+func (m *Memory) UpdateUserPassword(_ context.Context, id int64, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	u.UpdatedAt = time.Now().UTC()
+	m.users[id] = u
+	return nil
+}
+
+// CountUsers returns the number of users
+func (m *Memory) CountUsers(_ context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.users), nil
+}
+
+// CreateSession creates a new session
+func (m *Memory) CreateSession(_ context.Context, sess *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[sess.UserID]; !ok {
+		return fmt.Errorf("failed to create session: user %d not found", sess.UserID)
+	}
+	if _, ok := m.sessions[sess.ID]; ok {
+		return fmt.Errorf("failed to create session: session already exists")
+	}
+
+	sess.CreatedAt = time.Now().UTC()
+	m.sessions[sess.ID] = *sess
+	return nil
+}
+
+// GetSession retrieves a session by ID, expired sessions are not returned
+func (m *Memory) GetSession(_ context.Context, id string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sess, ok := m.sessions[id]
+	if !ok || !sess.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &sess, nil
+}
+
+// DeleteSession deletes a session
+func (m *Memory) DeleteSession(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+// DeleteExpiredSessions removes all expired sessions
+func (m *Memory) DeleteExpiredSessions(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, sess := range m.sessions {
+		if !sess.ExpiresAt.After(now) {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+// DeleteUserSessions removes all sessions for a user
+func (m *Memory) DeleteUserSessions(_ context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, sess := range m.sessions {
+		if sess.UserID == userID {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AddServerMetrics stores utilization samples, replacing samples of the same server, step and time
+func (s *DB) AddServerMetrics(ctx context.Context, metrics []ServerMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertServerMetrics(ctx, tx, metrics); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit server metrics: %w", err)
+	}
+	return nil
+}
+
+// ListServerMetrics lists utilization samples of a server starting at or after since, oldest first
+func (s *DB) ListServerMetrics(ctx context.Context, serverID int64, since time.Time) ([]ServerMetric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var metrics []ServerMetric
+	query := `SELECT server_id, ts, step, cpu, disk_read, disk_write, net_in, net_out FROM server_metrics
+		WHERE server_id = ? AND ts >= ? ORDER BY ts`
+	if err := s.db.SelectContext(ctx, &metrics, query, serverID, since.UTC()); err != nil {
+		return nil, fmt.Errorf("failed to list server metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// GetLastServerMetricTime returns the end of the latest sample of a server, zero if there are none
+func (s *DB) GetLastServerMetricTime(ctx context.Context, serverID int64) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var last ServerMetric
+	query := `SELECT ts, step FROM server_metrics WHERE server_id = ? ORDER BY ts DESC LIMIT 1`
+	if err := s.db.GetContext(ctx, &last, query, serverID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get last server metric: %w", err)
+	}
+	return last.Time.Add(time.Duration(last.Step) * time.Second), nil
+}
+
+// DownsampleServerMetrics rolls hourly samples of whole days before hourlyBefore up into daily
+// samples and removes samples of any step older than purgeBefore
+func (s *DB) DownsampleServerMetrics(ctx context.Context, hourlyBefore, purgeBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hourlyBefore = hourlyBefore.UTC().Truncate(24 * time.Hour)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var hourly []ServerMetric
+	query := `SELECT server_id, ts, step, cpu, disk_read, disk_write, net_in, net_out FROM server_metrics
+		WHERE step = ? AND ts < ? ORDER BY server_id, ts`
+	if err := tx.SelectContext(ctx, &hourly, query, MetricStepHour, hourlyBefore); err != nil {
+		return fmt.Errorf("failed to list hourly metrics: %w", err)
+	}
+
+	if len(hourly) > 0 {
+		if err := insertServerMetrics(ctx, tx, rollupDaily(hourly)); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM server_metrics WHERE step = ? AND ts < ?`,
+			MetricStepHour, hourlyBefore); err != nil {
+			return fmt.Errorf("failed to remove hourly metrics: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM server_metrics WHERE ts < ?`, purgeBefore.UTC()); err != nil {
+		return fmt.Errorf("failed to purge metrics: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit downsampled metrics: %w", err)
+	}
+	return nil
+}
+
+// ListServerUtilization lists active servers with samples starting at or after since,
+// with their utilization averaged over the samples, least utilized first
+func (s *DB) ListServerUtilization(ctx context.Context, since time.Time) ([]ServerUtilization, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var aggs []struct {
+		ServerID int64   `db:"server_id"`
+		AvgCPU   float64 `db:"avg_cpu"`
+		MaxCPU   float64 `db:"max_cpu"`
+		AvgNet   float64 `db:"avg_net"`
+	}
+	// samples are weighted by their step, so daily and hourly samples of the period mix correctly
+	query := `SELECT server_id, SUM(cpu * step) / SUM(step) as avg_cpu, MAX(cpu) as max_cpu,
+			SUM((net_in + net_out) * step) / SUM(step) as avg_net
+		FROM server_metrics WHERE ts >= ? GROUP BY server_id`
+	if err := s.db.SelectContext(ctx, &aggs, query, since.UTC()); err != nil {
+		return nil, fmt.Errorf("failed to aggregate server metrics: %w", err)
+	}
+	if len(aggs) == 0 {
+		return []ServerUtilization{}, nil
+	}
+
+	var rows []serverWithAccountRow
+	query = serverWithAccountSelect + ` AND s.status = 'active'`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	servers := make(map[int64]*ServerWithAccount, len(rows))
+	for _, r := range rows {
+		srv, err := r.toServerWithAccount()
+		if err != nil {
+			return nil, err
+		}
+		servers[srv.ID] = srv
+	}
+
+	res := make([]ServerUtilization, 0, len(aggs))
+	for _, a := range aggs {
+		srv, ok := servers[a.ServerID]
+		if !ok {
+			continue
+		}
+		res = append(res, ServerUtilization{ServerWithAccount: *srv, AvgCPU: a.AvgCPU, MaxCPU: a.MaxCPU,
+			AvgNet: a.AvgNet})
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].AvgCPU < res[j].AvgCPU })
+	return res, nil
+}
+
+// insertServerMetrics upserts samples within the transaction
+func insertServerMetrics(ctx context.Context, tx *sqlTx, metrics []ServerMetric) error {
+	query := `INSERT INTO server_metrics (server_id, ts, step, cpu, disk_read, disk_write, net_in, net_out)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(server_id, step, ts) DO UPDATE SET cpu = excluded.cpu, disk_read = excluded.disk_read,
+			disk_write = excluded.disk_write, net_in = excluded.net_in, net_out = excluded.net_out`
+	for _, m := range metrics {
+		_, err := tx.ExecContext(ctx, query, m.ServerID, m.Time.UTC(), m.Step, m.CPU, m.DiskRead, m.DiskWrite,
+			m.NetIn, m.NetOut)
+		if err != nil {
+			return fmt.Errorf("failed to store metric of server %d: %w", m.ServerID, err)
+		}
+	}
+	return nil
+}
+
+// rollupDaily averages samples sorted by server and time into daily samples, weighted by their step
+func rollupDaily(metrics []ServerMetric) []ServerMetric {
+	var res []ServerMetric
+	var covered int
+	for _, m := range metrics {
+		day := m.Time.UTC().Truncate(24 * time.Hour)
+		if len(res) == 0 || res[len(res)-1].ServerID != m.ServerID || !res[len(res)-1].Time.Equal(day) {
+			finishRollup(res, covered)
+			res = append(res, ServerMetric{ServerID: m.ServerID, Time: day, Step: MetricStepDay})
+			covered = 0
+		}
+		d := &res[len(res)-1]
+		w := float64(m.Step)
+		d.CPU += m.CPU * w
+		d.DiskRead += m.DiskRead * w
+		d.DiskWrite += m.DiskWrite * w
+		d.NetIn += m.NetIn * w
+		d.NetOut += m.NetOut * w
+		covered += m.Step
+	}
+	finishRollup(res, covered)
+	return res
+}
+
+// finishRollup turns weighted sums of the last daily sample into averages
+func finishRollup(res []ServerMetric, covered int) {
+	if len(res) == 0 || covered == 0 {
+		return
+	}
+	d := &res[len(res)-1]
+	w := float64(covered)
+	d.CPU /= w
+	d.DiskRead /= w
+	d.DiskWrite /= w
+	d.NetIn /= w
+	d.NetOut /= w
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+//go:embed migrations/*/*.sql
+var migrationsFS embed.FS
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer version of the application
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// Migration is a numbered schema change, applied once in order of versions. Migrations are up-only SQL
+// files named as "<version>_<name>.sql", e.g. "0002_add_server_notes.sql", kept in a directory per dialect.
+// Both dialects have the same versions, a schema change adds a migration of the same version to each.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// String returns the migration file name without extension, e.g. "0001_initial"
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// PendingMigrations returns migrations not yet applied to the database, without applying them.
+// Returns ErrSchemaTooNew if the database has migrations unknown to this version.
+func PendingMigrations(dsn string) ([]Migration, error) {
+	db, err := connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations(migrationsFS, db.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	return pendingMigrations(migrations, applied)
+}
+
+// migrate applies pending migrations, each in its own transaction
+func (s *DB) migrate() error {
+	migrations, err := loadMigrations(migrationsFS, s.db.dialect)
+	if err != nil {
+		return err
+	}
+
+	// databases created before versioned migrations are SQLite only
+	if s.db.dialect == dialectSQLite {
+		if err := s.upgradeLegacySchema(); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingMigrations(migrations, applied)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := s.applyMigration(m); err != nil {
+			return err
+		}
+		log.Printf("[INFO] migration: applied %s", m)
+	}
+	return nil
+}
+
+// applyMigration runs the migration and records it as applied in a single transaction
+func (s *DB) applyMigration(m Migration) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", m, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", m, err)
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m, err)
+	}
+	return nil
+}
+
+// loadMigrations reads migrations of the dialect from the file system ordered by version
+func loadMigrations(fsys fs.FS, d dialect) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/"+string(d)+"/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	seen := make(map[int]string)
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 || name == "" {
+			return nil, fmt.Errorf("invalid migration file name %s, expected <version>_<name>.sql", file)
+		}
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d in %s and %s", version, prev, file)
+		}
+		seen[version] = file
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedMigrations returns versions of applied migrations, none if schema_migrations doesn't exist yet
+func appliedMigrations(db *sqlDB) (map[int]bool, error) {
+	exists, err := db.tableExists("schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool)
+	if !exists {
+		return applied, nil
+	}
+
+	var versions []int
+	if err := db.Select(&versions, `SELECT version FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// pendingMigrations returns migrations not in applied, or ErrSchemaTooNew if a migration newer
+// than all known ones is applied
+func pendingMigrations(migrations []Migration, applied map[int]bool) ([]Migration, error) {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for v := range applied {
+		if v > latest {
+			return nil, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, v, latest)
+		}
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// legacyColumns are columns added to tables before versioned migrations, in order they were added
+var legacyColumns = []struct {
+	table  string
+	column string
+	ddl    string
+}{
+	{"accounts", "group_name", "TEXT DEFAULT ''"},
+	{"servers", "location", "TEXT DEFAULT ''"},
+	{"providers", "ident", "TEXT DEFAULT ''"},
+	{"servers", "backups", "INTEGER DEFAULT 0"},
+	{"accounts", "sync_interval", "INTEGER DEFAULT 0"},
+	{"servers", "external_id", "TEXT DEFAULT ''"},
+	{"servers", "provider_ident", "TEXT DEFAULT ''"},
+	{"servers", "locked_fields", "TEXT DEFAULT ''"},
+	{"servers", "cpu_cores", "INTEGER DEFAULT 0"},
+	{"servers", "memory_gb", "REAL DEFAULT 0"},
+	{"servers", "disk_gb", "INTEGER DEFAULT 0"},
+	{"servers", "server_type", "TEXT DEFAULT ''"},
+	{"servers", "os_flavor", "TEXT DEFAULT ''"},
+	{"servers", "os_version", "TEXT DEFAULT ''"},
+	{"servers", "datacenter", "TEXT DEFAULT ''"},
+	{"servers", "paid_until", "DATETIME"},
+	{"servers", "cancellation_date", "DATETIME"},
+	{"resources", "used_gb", "REAL DEFAULT 0"},
+}
+
+// upgradeLegacySchema adds columns missing from tables of databases created before versioned migrations,
+// so the initial migration can run against them. Does nothing for databases tracked by schema_migrations.
+func (s *DB) upgradeLegacySchema() error {
+	tracked, err := s.db.tableExists("schema_migrations")
+	if err != nil {
+		return err
+	}
+	if tracked {
+		return nil
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin legacy schema upgrade: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var added []string
+	for _, c := range legacyColumns {
+		var columns []string
+		if err := tx.Select(&columns, `SELECT name FROM pragma_table_info(?)`, c.table); err != nil {
+			return fmt.Errorf("failed to check %s schema: %w", c.table, err)
+		}
+		// missing tables are created by the initial migration
+		if len(columns) == 0 || slices.Contains(columns, c.column) {
+			continue
+		}
+		if _, err := tx.Exec(`ALTER TABLE ` + c.table + ` ADD COLUMN ` + c.column + ` ` + c.ddl); err != nil {
+			return fmt.Errorf("failed to add %s column to %s: %w", c.column, c.table, err)
+		}
+		added = append(added, c.table+"."+c.column)
+
+		if c.table == "providers" && c.column == "ident" {
+			if err := setLegacyProviderIdents(tx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit legacy schema upgrade: %w", err)
+	}
+	if len(added) > 0 {
+		log.Printf("[INFO] migration: added legacy columns %s", strings.Join(added, ", "))
+	}
+	return nil
+}
+
+// setLegacyProviderIdents sets idents of providers created before they had one by their names,
+// and adds Hetzner Robot next to Hetzner Cloud
+func setLegacyProviderIdents(tx *sqlTx) error {
+	idents := []struct{ name, ident string }{
+		{"Hetzner", "hetzner_cloud"},
+		{"AWS", "aws"},
+		{"Scaleway", "scaleway"},
+		{"Vsys Host", "vsys_host"},
+	}
+	for _, p := range idents {
+		if _, err := tx.Exec(`UPDATE providers SET ident = ? WHERE name = ?`, p.ident, p.name); err != nil {
+			return fmt.Errorf("failed to set ident of provider %s: %w", p.name, err)
+		}
+	}
+
+	_, err := tx.Exec(`INSERT INTO providers (ident, name, description)
+		SELECT 'hetzner_robot', 'Hetzner Robot', 'Hetzner Dedicated Servers'
+		WHERE EXISTS (SELECT 1 FROM providers WHERE ident = 'hetzner_cloud')`)
+	if err != nil {
+		return fmt.Errorf("failed to add Hetzner Robot provider: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+-- Initial schema, same tables as the SQLite one with native Postgres types
+
+-- Providers
+CREATE TABLE IF NOT EXISTS providers (
+	id BIGSERIAL PRIMARY KEY,
+	ident TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Accounts
+CREATE TABLE IF NOT EXISTS accounts (
+	id BIGSERIAL PRIMARY KEY,
+	provider_id BIGINT NOT NULL REFERENCES providers(id),
+	group_name TEXT DEFAULT '',
+	name TEXT NOT NULL,
+	login TEXT,
+	api_key TEXT,
+	sync_interval INTEGER DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(provider_id, name)
+);
+
+-- Servers
+CREATE TABLE IF NOT EXISTS servers (
+	id BIGSERIAL PRIMARY KEY,
+	account_id BIGINT NOT NULL REFERENCES accounts(id),
+	name TEXT NOT NULL,
+	ip TEXT,
+	location TEXT DEFAULT '',
+	description TEXT,
+	responsible TEXT,
+	approximate_cost DOUBLE PRECISION DEFAULT 0,
+	backups BOOLEAN DEFAULT FALSE,
+	status TEXT NOT NULL DEFAULT 'active',
+	external_id TEXT DEFAULT '',
+	provider_ident TEXT DEFAULT '',
+	locked_fields TEXT DEFAULT '',
+	cpu_cores INTEGER DEFAULT 0,
+	memory_gb DOUBLE PRECISION DEFAULT 0,
+	disk_gb INTEGER DEFAULT 0,
+	server_type TEXT DEFAULT '',
+	os_flavor TEXT DEFAULT '',
+	os_version TEXT DEFAULT '',
+	datacenter TEXT DEFAULT '',
+	paid_until TIMESTAMPTZ,
+	cancellation_date TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Server Logs
+CREATE TABLE IF NOT EXISTS server_logs (
+	id BIGSERIAL PRIMARY KEY,
+	server_id BIGINT NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+	action TEXT NOT NULL,
+	description TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sync Runs
+CREATE TABLE IF NOT EXISTS sync_runs (
+	id BIGSERIAL PRIMARY KEY,
+	account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	trigger_type TEXT NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	added INTEGER DEFAULT 0,
+	updated INTEGER DEFAULT 0,
+	deleted INTEGER DEFAULT 0,
+	unchanged INTEGER DEFAULT 0,
+	error TEXT DEFAULT ''
+);
+
+-- Server Addresses
+CREATE TABLE IF NOT EXISTS server_addresses (
+	id BIGSERIAL PRIMARY KEY,
+	server_id BIGINT NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+	address TEXT NOT NULL,
+	family INTEGER NOT NULL DEFAULT 4,
+	kind TEXT NOT NULL DEFAULT 'public',
+	prefix INTEGER DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(server_id, address, prefix)
+);
+
+-- Server Tags, synced from provider labels or set by user
+CREATE TABLE IF NOT EXISTS server_tags (
+	id BIGSERIAL PRIMARY KEY,
+	server_id BIGINT NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL DEFAULT '',
+	source TEXT NOT NULL DEFAULT 'user',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(server_id, key)
+);
+
+-- Billable resources other than servers
+CREATE TABLE IF NOT EXISTS resources (
+	id BIGSERIAL PRIMARY KEY,
+	account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	server_id BIGINT REFERENCES servers(id) ON DELETE SET NULL,
+	kind TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	name TEXT DEFAULT '',
+	location TEXT DEFAULT '',
+	description TEXT DEFAULT '',
+	size_gb DOUBLE PRECISION DEFAULT 0,
+	used_gb DOUBLE PRECISION DEFAULT 0,
+	cost DOUBLE PRECISION DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(account_id, kind, external_id)
+);
+
+-- Server utilization samples, step is the number of seconds a sample is averaged over
+CREATE TABLE IF NOT EXISTS server_metrics (
+	server_id BIGINT NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+	ts TIMESTAMPTZ NOT NULL,
+	step INTEGER NOT NULL,
+	cpu DOUBLE PRECISION DEFAULT 0,
+	disk_read DOUBLE PRECISION DEFAULT 0,
+	disk_write DOUBLE PRECISION DEFAULT 0,
+	net_in DOUBLE PRECISION DEFAULT 0,
+	net_out DOUBLE PRECISION DEFAULT 0,
+	PRIMARY KEY (server_id, step, ts)
+);
+
+-- Users
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sessions
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_accounts_provider ON accounts(provider_id);
+CREATE INDEX IF NOT EXISTS idx_servers_account ON servers(account_id);
+CREATE INDEX IF NOT EXISTS idx_servers_status ON servers(status);
+CREATE INDEX IF NOT EXISTS idx_server_logs_server ON server_logs(server_id);
+CREATE INDEX IF NOT EXISTS idx_server_logs_created ON server_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_server_addresses_address ON server_addresses(address);
+CREATE INDEX IF NOT EXISTS idx_server_tags_key ON server_tags(key, value);
+CREATE INDEX IF NOT EXISTS idx_resources_server ON resources(server_id);
+CREATE INDEX IF NOT EXISTS idx_server_metrics_ts ON server_metrics(step, ts);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_account ON sync_runs(account_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started ON sync_runs(started_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
+
+-- external IDs are unique within an account, servers without one are not constrained
+CREATE UNIQUE INDEX IF NOT EXISTS idx_servers_external ON servers(account_id, external_id) WHERE external_id != '';
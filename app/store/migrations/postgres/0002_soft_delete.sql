@@ -0,0 +1,12 @@
+-- Soft deletion. Deleted providers, accounts and servers stay in the trash until restored or purged
+-- after the retention period, server logs of deleted servers are kept until then.
+ALTER TABLE providers ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE servers ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_servers_deleted ON servers(deleted_at);
+
+-- a server in the trash doesn't block its external ID, a restored one is checked again
+DROP INDEX IF EXISTS idx_servers_external;
+CREATE UNIQUE INDEX idx_servers_external ON servers(account_id, external_id)
+	WHERE external_id != '' AND deleted_at IS NULL;
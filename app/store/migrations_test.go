@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	dbPath := t.TempDir() + "/test.db"
+
+	pending, err := PendingMigrations(dbPath)
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+	assert.Equal(t, "0001_initial", pending[0].String())
+
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	var versions []int
+	require.NoError(t, db.db.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`))
+	assert.Len(t, versions, len(pending))
+	require.NoError(t, db.Close())
+
+	pending, err = PendingMigrations(dbPath)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// reopening applies nothing and keeps seeded providers
+	db, err = New(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	providers, err := db.ListProviders(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, providers, 5)
+}
+
+func TestMigrate_ConcurrentReplicas(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES_DSN") == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	dsn := testTargetDSN(t)
+
+	// replicas starting at once on an empty database take turns migrating it
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := New(dsn)
+			if err == nil {
+				err = db.Close()
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	db, err := New(dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	providers, err := db.ListProviders(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, providers, len(defaultProviders), "providers seeded once")
+}
+
+func TestMigrate_RefusesNewerSchema(t *testing.T) {
+	dbPath := t.TempDir() + "/test.db"
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	_, err = db.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = New(dbPath)
+	require.ErrorIs(t, err, ErrSchemaTooNew)
+	_, err = PendingMigrations(dbPath)
+	require.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	dbPath := t.TempDir() + "/test.db"
+	conn, err := connectSQLite(dbPath)
+	require.NoError(t, err)
+	// schema of the first release, before providers had idents and servers had addresses
+	_, err = conn.Exec(`
+		CREATE TABLE providers (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, description TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE accounts (id INTEGER PRIMARY KEY AUTOINCREMENT, provider_id INTEGER NOT NULL REFERENCES providers(id),
+			name TEXT NOT NULL, login TEXT, api_key TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE servers (id INTEGER PRIMARY KEY AUTOINCREMENT, account_id INTEGER NOT NULL REFERENCES accounts(id),
+			name TEXT NOT NULL, ip TEXT, description TEXT, responsible TEXT, approximate_cost REAL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'active',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO providers (name, description) VALUES ('Hetzner', 'Hetzner Cloud Servers'), ('AWS', 'Amazon');
+		INSERT INTO accounts (provider_id, name) VALUES (1, 'main');
+		INSERT INTO servers (account_id, name, ip, description, responsible) VALUES (1, 'web-1', '10.0.0.1', '', '');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	providers, err := db.ListProviders(ctx)
+	require.NoError(t, err)
+	idents := make([]string, 0, len(providers))
+	for _, p := range providers {
+		idents = append(idents, p.Ident)
+	}
+	assert.ElementsMatch(t, []string{"hetzner_cloud", "aws", "hetzner_robot"}, idents)
+
+	srv, err := db.GetServer(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1", srv.Name)
+	addrs, err := db.ListServerAddresses(ctx, srv.ID)
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	assert.Equal(t, "10.0.0.1", addrs[0].Address)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"migrations/sqlite/0002_second.sql": {Data: []byte("SELECT 2")},
+		"migrations/sqlite/0001_first.sql":  {Data: []byte("SELECT 1")},
+		"migrations/sqlite/README.md":       {Data: []byte("not a migration")},
+		"migrations/postgres/0001_pg.sql":   {Data: []byte("SELECT 1")},
+	}, dialectSQLite)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "first", SQL: "SELECT 1"}, migrations[0])
+	assert.Equal(t, "0002_second", migrations[1].String())
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/sqlite/first.sql": {}}, dialectSQLite)
+	require.Error(t, err, "no version")
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/sqlite/0001_a.sql": {}, "migrations/sqlite/1_b.sql": {}}, dialectSQLite)
+	require.Error(t, err, "duplicate version")
+}
@@ -1,6 +1,11 @@
 package store
 
 import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nilBora/servers-manager/app/enum"
@@ -31,18 +36,21 @@ type Provider struct {
 	Description string    `db:"description"`
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
+	DeletedAt   time.Time `db:"deleted_at"` // time moved to the trash, zero for live providers
 }
 
 // Account represents an account at a provider
 type Account struct {
-	ID         int64     `db:"id"`
-	ProviderID int64     `db:"provider_id"`
-	GroupName  string    `db:"group_name"`
-	Name       string    `db:"name"`
-	Login      string    `db:"login"`
-	ApiKey     string    `db:"api_key"`
-	CreatedAt  time.Time `db:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at"`
+	ID           int64     `db:"id"`
+	ProviderID   int64     `db:"provider_id"`
+	GroupName    string    `db:"group_name"`
+	Name         string    `db:"name"`
+	Login        string    `db:"login"`
+	ApiKey       string    `db:"api_key"`
+	SyncInterval int       `db:"sync_interval"` // background sync interval in minutes, 0 uses default
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+	DeletedAt    time.Time `db:"deleted_at"` // time moved to the trash, zero for live accounts
 }
 
 // AccountWithProvider extends Account with provider info for display
@@ -73,18 +81,162 @@ type AccountWithServers struct {
 
 // Server represents a server instance
 type Server struct {
-	ID              int64             `db:"id"`
-	AccountID       int64             `db:"account_id"`
-	Name            string            `db:"name"`
-	IP              string            `db:"ip"`
-	Location        string            `db:"location"`
-	Description     string            `db:"description"`
-	Responsible     string            `db:"responsible"`
-	ApproximateCost float64           `db:"approximate_cost"`
-	Backups         bool              `db:"backups"`
-	Status          enum.ServerStatus `db:"status"`
-	CreatedAt       time.Time         `db:"created_at"`
-	UpdatedAt       time.Time         `db:"updated_at"`
+	ID               int64              `db:"id"`
+	AccountID        int64              `db:"account_id"`
+	Name             string             `db:"name"`
+	IP               string             `db:"ip"`
+	Location         string             `db:"location"`
+	Description      string             `db:"description"`
+	Responsible      string             `db:"responsible"`
+	ApproximateCost  float64            `db:"approximate_cost"`
+	Backups          bool               `db:"backups"`
+	Status           enum.ServerStatus  `db:"status"`
+	ExternalID       string             `db:"external_id"`    // provider-side server ID, empty for manually added servers
+	ProviderIdent    string             `db:"provider_ident"` // ident of the provider the external ID belongs to
+	LockedFields     []enum.ServerField `db:"locked_fields"`  // fields kept as edited by user, skipped by sync
+	CPUCores         int                `db:"cpu_cores"`
+	MemoryGB         float64            `db:"memory_gb"`
+	DiskGB           int                `db:"disk_gb"`
+	ServerType       string             `db:"server_type"`       // provider plan or product, e.g. "cx21" or "AX41-NVMe"
+	OSFlavor         string             `db:"os_flavor"`         // e.g. "ubuntu"
+	OSVersion        string             `db:"os_version"`        // e.g. "20.04"
+	Datacenter       string             `db:"datacenter"`        // e.g. "fsn1-dc14"
+	PaidUntil        time.Time          `db:"paid_until"`        // end of the paid period, zero if unknown
+	CancellationDate time.Time          `db:"cancellation_date"` // date the contract ends at, zero if not cancelled
+	CreatedAt        time.Time          `db:"created_at"`
+	UpdatedAt        time.Time          `db:"updated_at"`
+	DeletedAt        time.Time          `db:"deleted_at"` // time moved to the trash, zero for live servers
+}
+
+// IsLocked returns true if the field is locked against sync
+func (s *Server) IsLocked(field enum.ServerField) bool {
+	for _, f := range s.LockedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// OS returns operating system flavor and version, e.g. "ubuntu 20.04"
+func (s *Server) OS() string {
+	return strings.TrimSpace(s.OSFlavor + " " + s.OSVersion)
+}
+
+// ContractEnd returns the date the server contract ends or has to be renewed at: the cancellation date
+// of cancelled servers and the paid-until date of manually tracked ones. Servers synced from a provider
+// are renewed by it until cancelled, their paid-until date is not a contract end. Zero if unknown.
+func (s *Server) ContractEnd() time.Time {
+	switch {
+	case !s.CancellationDate.IsZero():
+		return s.CancellationDate
+	case s.ExternalID == "":
+		return s.PaidUntil
+	}
+	return time.Time{}
+}
+
+// ServerAddress is an IP address or subnet assigned to a server
+type ServerAddress struct {
+	ID        int64            `db:"id"`
+	ServerID  int64            `db:"server_id"`
+	Address   string           `db:"address"`
+	Family    int              `db:"family"` // 4 or 6
+	Kind      enum.AddressKind `db:"kind"`
+	Prefix    int              `db:"prefix"` // prefix length for networks and subnets, 0 for single addresses
+	CreatedAt time.Time        `db:"created_at"`
+}
+
+// ParseServerAddress parses an IP address or a network in CIDR notation, e.g. "10.0.0.2" or
+// "2a01:4f8:c17::/64", into a server address of the given kind
+func ParseServerAddress(s string, kind enum.AddressKind) (ServerAddress, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return ServerAddress{}, fmt.Errorf("invalid network %q: %w", s, err)
+		}
+		return ServerAddress{Address: prefix.Addr().String(), Family: addrFamily(prefix.Addr()),
+			Kind: kind, Prefix: prefix.Bits()}, nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return ServerAddress{}, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	return ServerAddress{Address: addr.String(), Family: addrFamily(addr), Kind: kind}, nil
+}
+
+// String returns the address, in CIDR notation for networks
+func (a ServerAddress) String() string {
+	if a.Prefix > 0 {
+		return a.Address + "/" + strconv.Itoa(a.Prefix)
+	}
+	return a.Address
+}
+
+func addrFamily(addr netip.Addr) int {
+	if addr.Is4() || addr.Is4In6() {
+		return 4
+	}
+	return 6
+}
+
+// ServerTag is a key/value tag of a server, either synced from provider labels or set by user.
+// User tags take precedence over provider tags with the same key.
+type ServerTag struct {
+	ID        int64          `db:"id"`
+	ServerID  int64          `db:"server_id"`
+	Key       string         `db:"key"`
+	Value     string         `db:"value"`
+	Source    enum.TagSource `db:"source"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// ParseServerTag parses a "key=value" or "key" tag
+func ParseServerTag(s string, source enum.TagSource) (ServerTag, error) {
+	key, value, _ := strings.Cut(strings.TrimSpace(s), "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if key == "" {
+		return ServerTag{}, fmt.Errorf("invalid tag %q: empty key", s)
+	}
+	if strings.ContainsAny(key, " \t,") {
+		return ServerTag{}, fmt.Errorf("invalid tag %q: key can't contain spaces or commas", s)
+	}
+	return ServerTag{Key: key, Value: value, Source: source}, nil
+}
+
+// String returns the tag as "key=value", or just the key if the value is empty
+func (t ServerTag) String() string {
+	if t.Value == "" {
+		return t.Key
+	}
+	return t.Key + "=" + t.Value
+}
+
+// TagFilter matches servers having a tag with the key and, if set, the value
+type TagFilter struct {
+	Key   string
+	Value string // any value if empty
+}
+
+// ParseTagFilter parses a "key=value" or "key" filter, zero filter if s is empty
+func ParseTagFilter(s string) TagFilter {
+	key, value, _ := strings.Cut(strings.TrimSpace(s), "=")
+	return TagFilter{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
+}
+
+// IsZero returns true if the filter matches all servers
+func (f TagFilter) IsZero() bool {
+	return f.Key == ""
+}
+
+// String returns the filter as "key=value", or just the key if any value matches
+func (f TagFilter) String() string {
+	if f.Value == "" {
+		return f.Key
+	}
+	return f.Key + "=" + f.Value
 }
 
 // ServerWithAccount extends Server with account and provider info for display
@@ -96,6 +248,65 @@ type ServerWithAccount struct {
 	ProviderName     string `db:"provider_name"`
 }
 
+// Resource is a billable provider resource other than a server, e.g. a volume or a floating IP
+type Resource struct {
+	ID          int64             `db:"id"`
+	AccountID   int64             `db:"account_id"`
+	ServerID    int64             `db:"server_id"` // attached server, 0 if not attached
+	Kind        enum.ResourceKind `db:"kind"`
+	ExternalID  string            `db:"external_id"`
+	Name        string            `db:"name"`
+	Location    string            `db:"location"`
+	Description string            `db:"description"`
+	SizeGB      float64           `db:"size_gb"`
+	UsedGB      float64           `db:"used_gb"` // used space of storage, 0 if not reported
+	Cost        float64           `db:"cost"`    // monthly
+	CreatedAt   time.Time         `db:"created_at"`
+	UpdatedAt   time.Time         `db:"updated_at"`
+}
+
+// ResourceWithAccount extends Resource with account, provider and server info for display
+type ResourceWithAccount struct {
+	Resource
+	AccountName      string `db:"account_name"`
+	AccountGroupName string `db:"account_group_name"`
+	ProviderID       int64  `db:"provider_id"`
+	ProviderName     string `db:"provider_name"`
+	ServerName       string `db:"server_name"` // empty if not attached
+}
+
+// metric sample steps, in seconds. Hourly samples are rolled up into daily ones as they age.
+const (
+	MetricStepHour = 3600
+	MetricStepDay  = 86400
+)
+
+// ServerMetric is a utilization sample of a server averaged over Step seconds starting at Time
+type ServerMetric struct {
+	ServerID  int64     `db:"server_id"`
+	Time      time.Time `db:"ts"`
+	Step      int       `db:"step"`
+	CPU       float64   `db:"cpu"`        // percent of all cores, 0-100
+	DiskRead  float64   `db:"disk_read"`  // bytes/s
+	DiskWrite float64   `db:"disk_write"` // bytes/s
+	NetIn     float64   `db:"net_in"`     // bytes/s
+	NetOut    float64   `db:"net_out"`    // bytes/s
+}
+
+// ServerUtilization is the average utilization of a server over a period
+type ServerUtilization struct {
+	ServerWithAccount
+	AvgCPU float64 `db:"avg_cpu"`
+	MaxCPU float64 `db:"max_cpu"` // highest sample, i.e. hourly or daily average
+	AvgNet float64 `db:"avg_net"` // inbound and outbound, bytes/s
+}
+
+// CostPerCPU returns the monthly cost per percent of average CPU usage, the higher it is the more
+// is paid for unused capacity. Average usage below 0.1% is counted as 0.1%.
+func (u ServerUtilization) CostPerCPU() float64 {
+	return u.ApproximateCost / math.Max(u.AvgCPU, 0.1)
+}
+
 // ServerLog represents a server action log entry
 type ServerLog struct {
 	ID          int64          `db:"id"`
@@ -105,6 +316,12 @@ type ServerLog struct {
 	CreatedAt   time.Time      `db:"created_at"`
 }
 
+// LogFilter selects server logs by action and server tag, zero values match all logs
+type LogFilter struct {
+	Action *enum.LogAction
+	Tag    TagFilter
+}
+
 // ServerLogWithServer extends ServerLog with server info for display
 type ServerLogWithServer struct {
 	ServerLog
@@ -112,14 +329,83 @@ type ServerLogWithServer struct {
 	ServerIP   string `db:"server_ip"`
 }
 
+// SyncRun represents a single sync of an account from its provider
+type SyncRun struct {
+	ID         int64            `db:"id"`
+	AccountID  int64            `db:"account_id"`
+	Trigger    enum.SyncTrigger `db:"trigger_type"`
+	StartedAt  time.Time        `db:"started_at"`
+	FinishedAt time.Time        `db:"finished_at"` // zero while the sync is running
+	Added      int              `db:"added"`
+	Updated    int              `db:"updated"`
+	Deleted    int              `db:"deleted"`
+	Unchanged  int              `db:"unchanged"`
+	Error      string           `db:"error"`
+}
+
+// Running returns true if the sync has not finished yet
+func (r SyncRun) Running() bool {
+	return r.FinishedAt.IsZero()
+}
+
+// SyncRunWithAccount extends SyncRun with account and provider info for display
+type SyncRunWithAccount struct {
+	SyncRun
+	AccountName  string `db:"account_name"`
+	ProviderName string `db:"provider_name"`
+}
+
+// Trash holds deleted providers, accounts and servers. Records deleted along with their parent
+// have the same deletion time as the parent and are restored with it.
+type Trash struct {
+	Providers []Provider
+	Accounts  []AccountWithProvider // server count is the number of servers deleted with the account
+	Servers   []ServerWithAccount
+}
+
+// IsEmpty returns true if nothing is in the trash
+func (t *Trash) IsEmpty() bool {
+	return len(t.Providers) == 0 && len(t.Accounts) == 0 && len(t.Servers) == 0
+}
+
+// ServerFilter selects and orders servers in ListServersFiltered. Zero values match any server.
+type ServerFilter struct {
+	Search     string // substring of name, description, primary IP or any address
+	CPUCores   int
+	OSFlavor   string
+	OSVersion  string
+	ServerType string
+	Datacenter string
+	Tag        TagFilter
+	Sort       string // name, cpu_cores, memory, disk, cost, server_type, os or datacenter, "-" prefix for descending order
+}
+
+// ServerSpecOptions holds distinct spec values of stored servers, used to build filters
+type ServerSpecOptions struct {
+	CPUCores    []int
+	OSFlavors   []string
+	OSVersions  []string
+	ServerTypes []string
+	Datacenters []string
+}
+
 // DashboardStats holds dashboard statistics
 type DashboardStats struct {
 	TotalServers  int     `db:"total_servers"`
 	ActiveServers int     `db:"active_servers"`
 	PausedServers int     `db:"paused_servers"`
-	TotalCost     float64 `db:"total_cost"`
+	TotalCost     float64 `db:"total_cost"` // servers and resources
+	ResourceCount int     `db:"resource_count"`
+	ResourceCost  float64 `db:"resource_cost"`
+
+	// ContractsEnding is the number of servers whose contract ends or has to be renewed
+	// within ContractWarningDays
+	ContractsEnding int `db:"contracts_ending"`
 }
 
+// ContractWarningDays is the default number of days before a contract end it is reported as ending soon
+const ContractWarningDays = 30
+
 // AccountGroup groups servers by account for dashboard display
 type AccountGroup struct {
 	AccountID        int64
@@ -128,17 +414,20 @@ type AccountGroup struct {
 	ProviderID       int64
 	ProviderName     string
 	Servers          []ServerWithAccount
-	TotalCost        float64
+	Resources        []ResourceWithAccount
+	TotalCost        float64 // servers and resources
+	ResourceCost     float64
 }
 
 // ProviderAccountGroup groups accounts by provider and group_name for hierarchical display
 // Structure: Provider + GroupName -> Accounts (Projects) -> Servers
 type ProviderAccountGroup struct {
-	ProviderID   int64
-	ProviderName string
-	GroupName    string
-	GroupKey     string // unique key: "provider_id:group_name"
-	Accounts     []AccountGroup
-	TotalCost    float64
-	ServerCount  int
+	ProviderID    int64
+	ProviderName  string
+	GroupName     string
+	GroupKey      string // unique key: "provider_id:group_name"
+	Accounts      []AccountGroup
+	TotalCost     float64 // servers and resources
+	ServerCount   int
+	ResourceCount int
 }
@@ -26,18 +26,14 @@ func (s *DB) CreateProvider(ctx context.Context, p *Provider) error {
 	query := `INSERT INTO providers (ident, name, description, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query, p.Ident, p.Name, p.Description, p.CreatedAt, p.UpdatedAt)
+	id, err := s.db.insertContext(ctx, query, p.Ident, p.Name, p.Description, p.CreatedAt, p.UpdatedAt)
 	if err != nil {
 		if isUniqueViolation(err) {
-			return fmt.Errorf("%w: provider with ident %q already exists", ErrConflict, p.Ident)
+			return s.providerConflict(ctx, p.Ident)
 		}
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
 	p.ID = id
 
 	return nil
@@ -49,7 +45,8 @@ func (s *DB) GetProvider(ctx context.Context, id int64) (*Provider, error) {
 	defer s.mu.RUnlock()
 
 	var p Provider
-	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers WHERE id = ?`
+	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers
+		WHERE id = ? AND deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &p, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -66,7 +63,8 @@ func (s *DB) GetProviderByName(ctx context.Context, name string) (*Provider, err
 	defer s.mu.RUnlock()
 
 	var p Provider
-	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers WHERE name = ?`
+	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers
+		WHERE name = ? AND deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &p, query, name); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -83,7 +81,8 @@ func (s *DB) ListProviders(ctx context.Context) ([]Provider, error) {
 	defer s.mu.RUnlock()
 
 	var providers []Provider
-	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers ORDER BY name`
+	query := `SELECT id, ident, name, description, created_at, updated_at FROM providers
+		WHERE deleted_at IS NULL ORDER BY name`
 	if err := s.db.SelectContext(ctx, &providers, query); err != nil {
 		return nil, fmt.Errorf("failed to list providers: %w", err)
 	}
@@ -98,11 +97,12 @@ func (s *DB) UpdateProvider(ctx context.Context, p *Provider) error {
 
 	p.UpdatedAt = time.Now().UTC()
 
-	query := `UPDATE providers SET ident = ?, name = ?, description = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE providers SET ident = ?, name = ?, description = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`
 	result, err := s.db.ExecContext(ctx, query, p.Ident, p.Name, p.Description, p.UpdatedAt, p.ID)
 	if err != nil {
 		if isUniqueViolation(err) {
-			return fmt.Errorf("%w: provider with ident %q already exists", ErrConflict, p.Ident)
+			return s.providerConflict(ctx, p.Ident)
 		}
 		return fmt.Errorf("failed to update provider: %w", err)
 	}
@@ -118,13 +118,21 @@ func (s *DB) UpdateProvider(ctx context.Context, p *Provider) error {
 	return nil
 }
 
-// DeleteProvider deletes a provider by ID
+// DeleteProvider moves a provider to the trash along with its accounts and their servers,
+// restoring the provider restores them as well
 func (s *DB) DeleteProvider(ctx context.Context, id int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	query := `DELETE FROM providers WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	query := `UPDATE providers SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, now, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete provider: %w", err)
 	}
@@ -137,5 +145,29 @@ func (s *DB) DeleteProvider(ctx context.Context, id int64) error {
 		return ErrNotFound
 	}
 
+	query = `UPDATE servers SET deleted_at = ? WHERE deleted_at IS NULL
+		AND account_id IN (SELECT id FROM accounts WHERE provider_id = ? AND deleted_at IS NULL)`
+	if _, err := tx.ExecContext(ctx, query, now, id); err != nil {
+		return fmt.Errorf("failed to delete servers of provider: %w", err)
+	}
+	query = `UPDATE accounts SET deleted_at = ? WHERE provider_id = ? AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, now, id); err != nil {
+		return fmt.Errorf("failed to delete accounts of provider: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit provider deletion: %w", err)
+	}
 	return nil
 }
+
+// providerConflict returns ErrConflict for the ident taken by another provider, along with ErrInTrash
+// if that provider is in the trash
+func (s *DB) providerConflict(ctx context.Context, ident string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM providers WHERE ident = ? AND deleted_at IS NOT NULL`
+	if err := s.db.GetContext(ctx, &count, query, ident); err == nil && count > 0 {
+		return fmt.Errorf("%w: provider %q %w", ErrConflict, ident, ErrInTrash)
+	}
+	return fmt.Errorf("%w: provider with ident %q already exists", ErrConflict, ident)
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+const resourceColumns = `r.id, r.account_id, r.server_id, r.kind, r.external_id, r.name, r.location, r.description,
+	r.size_gb, r.used_gb, r.cost, r.created_at, r.updated_at`
+
+const resourceWithAccountSelect = `SELECT ` + resourceColumns + `,
+	a.name as account_name, COALESCE(a.group_name, '') as account_group_name,
+	p.id as provider_id, p.name as provider_name, COALESCE(s.name, '') as server_name
+	FROM resources r
+	JOIN accounts a ON r.account_id = a.id
+	JOIN providers p ON a.provider_id = p.id
+	LEFT JOIN servers s ON r.server_id = s.id AND s.deleted_at IS NULL
+	WHERE a.deleted_at IS NULL`
+
+// ListResources lists resources of all accounts with account info
+func (s *DB) ListResources(ctx context.Context) ([]ResourceWithAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listResourcesWithAccounts(ctx)
+}
+
+// ListResourcesByAccount lists resources of an account
+func (s *DB) ListResourcesByAccount(ctx context.Context, accountID int64) ([]Resource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + resourceColumns + ` FROM resources r WHERE r.account_id = ? ORDER BY r.kind, r.name`
+	return s.selectResources(ctx, query, accountID)
+}
+
+// ListResourcesByServer lists resources attached to a server
+func (s *DB) ListResourcesByServer(ctx context.Context, serverID int64) ([]Resource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + resourceColumns + ` FROM resources r WHERE r.server_id = ? ORDER BY r.kind, r.name`
+	return s.selectResources(ctx, query, serverID)
+}
+
+// ReplaceAccountResources stores resources of the given kinds reported by the account provider.
+// Resources are matched by kind and external ID, stored resources of these kinds missing
+// from the list are removed. Resources of other kinds are not touched.
+func (s *DB) ReplaceAccountResources(ctx context.Context, accountID int64, kinds []enum.ResourceKind,
+	resources []Resource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	keep := make(map[string][]interface{}, len(kinds))
+	for i := range resources {
+		r := &resources[i]
+		r.AccountID = accountID
+		query := `INSERT INTO resources (account_id, server_id, kind, external_id, name, location, description,
+				size_gb, used_gb, cost, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(account_id, kind, external_id) DO UPDATE SET server_id = excluded.server_id,
+				name = excluded.name, location = excluded.location, description = excluded.description,
+				size_gb = excluded.size_gb, used_gb = excluded.used_gb, cost = excluded.cost,
+				updated_at = excluded.updated_at`
+		_, err := tx.ExecContext(ctx, query, accountID, nullID(r.ServerID), r.Kind.String(), r.ExternalID, r.Name,
+			r.Location, r.Description, r.SizeGB, r.UsedGB, r.Cost, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to store %s %s: %w", r.Kind, r.ExternalID, err)
+		}
+		keep[r.Kind.String()] = append(keep[r.Kind.String()], r.ExternalID)
+	}
+
+	for _, kind := range kinds {
+		query := `DELETE FROM resources WHERE account_id = ? AND kind = ?`
+		args := []interface{}{accountID, kind.String()}
+		if ids := keep[kind.String()]; len(ids) > 0 {
+			query += ` AND external_id NOT IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+			args = append(args, ids...)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to remove stale %s resources: %w", kind, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit resources: %w", err)
+	}
+	return nil
+}
+
+// listResourcesWithAccounts lists resources with account info, caller holds the lock
+func (s *DB) listResourcesWithAccounts(ctx context.Context) ([]ResourceWithAccount, error) {
+	var rows []resourceWithAccountRow
+	query := resourceWithAccountSelect + ` ORDER BY p.name, a.group_name, a.name, r.kind, r.name`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	resources := make([]ResourceWithAccount, 0, len(rows))
+	for _, r := range rows {
+		res, err := r.toResource()
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, ResourceWithAccount{
+			Resource:         *res,
+			AccountName:      r.AccountName,
+			AccountGroupName: r.AccountGroupName,
+			ProviderID:       r.ProviderID,
+			ProviderName:     r.ProviderName,
+			ServerName:       r.ServerName,
+		})
+	}
+	return resources, nil
+}
+
+// selectResources runs a query returning resource columns, caller holds the lock
+func (s *DB) selectResources(ctx context.Context, query string, args ...interface{}) ([]Resource, error) {
+	var rows []resourceRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	resources := make([]Resource, 0, len(rows))
+	for _, r := range rows {
+		res, err := r.toResource()
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, *res)
+	}
+	return resources, nil
+}
+
+// nullID converts zero ID to NULL for optional foreign keys
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+type resourceRow struct {
+	ID          int64         `db:"id"`
+	AccountID   int64         `db:"account_id"`
+	ServerID    sql.NullInt64 `db:"server_id"`
+	Kind        string        `db:"kind"`
+	ExternalID  string        `db:"external_id"`
+	Name        string        `db:"name"`
+	Location    string        `db:"location"`
+	Description string        `db:"description"`
+	SizeGB      float64       `db:"size_gb"`
+	UsedGB      float64       `db:"used_gb"`
+	Cost        float64       `db:"cost"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
+}
+
+func (r *resourceRow) toResource() (*Resource, error) {
+	kind, err := enum.ParseResourceKind(r.Kind)
+	if err != nil {
+		return nil, err
+	}
+	return &Resource{
+		ID:          r.ID,
+		AccountID:   r.AccountID,
+		ServerID:    r.ServerID.Int64,
+		Kind:        kind,
+		ExternalID:  r.ExternalID,
+		Name:        r.Name,
+		Location:    r.Location,
+		Description: r.Description,
+		SizeGB:      r.SizeGB,
+		UsedGB:      r.UsedGB,
+		Cost:        r.Cost,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}, nil
+}
+
+type resourceWithAccountRow struct {
+	resourceRow
+	AccountName      string `db:"account_name"`
+	AccountGroupName string `db:"account_group_name"`
+	ProviderID       int64  `db:"provider_id"`
+	ProviderName     string `db:"provider_name"`
+	ServerName       string `db:"server_name"`
+}
@@ -3,30 +3,30 @@ package store
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nilBora/servers-manager/app/enum"
 )
 
-// CreateLog creates a new server log entry
+// CreateLog creates a new server log entry, at the current time unless its time is set
 func (s *DB) CreateLog(ctx context.Context, l *ServerLog) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	l.CreatedAt = time.Now().UTC()
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	l.CreatedAt = l.CreatedAt.UTC()
 
 	query := `INSERT INTO server_logs (server_id, action, description, created_at)
 		VALUES (?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query, l.ServerID, l.Action.String(), l.Description, l.CreatedAt)
+	id, err := s.db.insertContext(ctx, query, l.ServerID, l.Action.String(), l.Description, l.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create log: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
 	l.ID = id
 
 	return nil
@@ -115,6 +115,50 @@ func (s *DB) ListLogsByAction(ctx context.Context, action enum.LogAction, limit
 	return logs, nil
 }
 
+// ListLogsFiltered lists logs of servers matching the filter
+func (s *DB) ListLogsFiltered(ctx context.Context, f LogFilter, limit int) ([]ServerLogWithServer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var conds []string
+	var args []interface{}
+	if f.Action != nil {
+		conds = append(conds, `l.action = ?`)
+		args = append(args, f.Action.String())
+	}
+	if !f.Tag.IsZero() {
+		cond, tagArgs := tagCondition(f.Tag)
+		conds = append(conds, cond)
+		args = append(args, tagArgs...)
+	}
+
+	query := `SELECT l.id, l.server_id, l.action, l.description, l.created_at,
+		s.name as server_name, s.ip as server_ip
+		FROM server_logs l
+		JOIN servers s ON l.server_id = s.id`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY l.created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	var rows []serverLogWithServerRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	logs := make([]ServerLogWithServer, 0, len(rows))
+	for _, r := range rows {
+		l, err := r.toServerLogWithServer()
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, *l)
+	}
+
+	return logs, nil
+}
+
 // serverLogRow is used for scanning database rows
 type serverLogRow struct {
 	ID          int64     `db:"id"`
@@ -5,11 +5,39 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/nilBora/servers-manager/app/enum"
 )
 
+// serverColumns lists columns of the servers table in serverRow order
+const serverColumns = `id, account_id, name, ip, location, description, responsible,
+	approximate_cost, backups, status, external_id, provider_ident, locked_fields,
+	cpu_cores, memory_gb, disk_gb, server_type, os_flavor, os_version, datacenter, paid_until, cancellation_date,
+	created_at, updated_at, deleted_at`
+
+// serverWithAccountSelectAll selects servers joined with their account and provider, deleted ones included
+const serverWithAccountSelectAll = `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
+	s.approximate_cost, s.backups, s.status, s.external_id, s.provider_ident, s.locked_fields,
+	s.cpu_cores, s.memory_gb, s.disk_gb, s.server_type, s.os_flavor, s.os_version, s.datacenter,
+	s.paid_until, s.cancellation_date, s.created_at, s.updated_at, s.deleted_at,
+	a.name as account_name, a.group_name as account_group_name, a.provider_id,
+	p.name as provider_name
+	FROM servers s
+	JOIN accounts a ON s.account_id = a.id
+	JOIN providers p ON a.provider_id = p.id`
+
+// serverWithAccountSelect selects servers not in the trash joined with their account and provider,
+// further conditions are appended with AND
+const serverWithAccountSelect = serverWithAccountSelectAll + `
+	WHERE s.deleted_at IS NULL`
+
+// contractEndExpr is the server contract end in queries, see Server.ContractEnd
+const contractEndExpr = `COALESCE(s.cancellation_date, CASE WHEN COALESCE(s.external_id, '') = '' THEN s.paid_until END)`
+
 // CreateServer creates a new server
 func (s *DB) CreateServer(ctx context.Context, srv *Server) error {
 	s.mu.Lock()
@@ -20,20 +48,23 @@ func (s *DB) CreateServer(ctx context.Context, srv *Server) error {
 	srv.UpdatedAt = now
 
 	query := `INSERT INTO servers (account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		approximate_cost, backups, status, external_id, provider_ident, locked_fields,
+		cpu_cores, memory_gb, disk_gb, server_type, os_flavor, os_version, datacenter, paid_until, cancellation_date,
+		created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query, srv.AccountID, srv.Name, srv.IP, srv.Location,
+	id, err := s.db.insertContext(ctx, query, srv.AccountID, srv.Name, srv.IP, srv.Location,
 		srv.Description, srv.Responsible, srv.ApproximateCost, srv.Backups, srv.Status.String(),
-		srv.CreatedAt, srv.UpdatedAt)
+		srv.ExternalID, srv.ProviderIdent, joinServerFields(srv.LockedFields),
+		srv.CPUCores, srv.MemoryGB, srv.DiskGB, srv.ServerType, srv.OSFlavor, srv.OSVersion, srv.Datacenter,
+		nullTime(srv.PaidUntil), nullTime(srv.CancellationDate), srv.CreatedAt, srv.UpdatedAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
 	srv.ID = id
 
 	return nil
@@ -45,9 +76,7 @@ func (s *DB) GetServer(ctx context.Context, id int64) (*Server, error) {
 	defer s.mu.RUnlock()
 
 	var r serverRow
-	query := `SELECT id, account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at
-		FROM servers WHERE id = ?`
+	query := `SELECT ` + serverColumns + ` FROM servers WHERE id = ? AND deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &r, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -64,14 +93,8 @@ func (s *DB) GetServerWithAccount(ctx context.Context, id int64) (*ServerWithAcc
 	defer s.mu.RUnlock()
 
 	var r serverWithAccountRow
-	query := `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
-		s.approximate_cost, s.backups, s.status, s.created_at, s.updated_at,
-		a.name as account_name, a.group_name as account_group_name, a.provider_id,
-		p.name as provider_name
-		FROM servers s
-		JOIN accounts a ON s.account_id = a.id
-		JOIN providers p ON a.provider_id = p.id
-		WHERE s.id = ?`
+	query := serverWithAccountSelect + `
+		AND s.id = ?`
 	if err := s.db.GetContext(ctx, &r, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -88,9 +111,7 @@ func (s *DB) ListServers(ctx context.Context) ([]Server, error) {
 	defer s.mu.RUnlock()
 
 	var rows []serverRow
-	query := `SELECT id, account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at
-		FROM servers ORDER BY name`
+	query := `SELECT ` + serverColumns + ` FROM servers WHERE deleted_at IS NULL ORDER BY name`
 	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
 	}
@@ -113,13 +134,7 @@ func (s *DB) ListServersWithAccounts(ctx context.Context) ([]ServerWithAccount,
 	defer s.mu.RUnlock()
 
 	var rows []serverWithAccountRow
-	query := `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
-		s.approximate_cost, s.backups, s.status, s.created_at, s.updated_at,
-		a.name as account_name, a.group_name as account_group_name, a.provider_id,
-		p.name as provider_name
-		FROM servers s
-		JOIN accounts a ON s.account_id = a.id
-		JOIN providers p ON a.provider_id = p.id
+	query := serverWithAccountSelect + `
 		ORDER BY p.name, a.group_name, a.name, s.name`
 	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
@@ -137,15 +152,136 @@ func (s *DB) ListServersWithAccounts(ctx context.Context) ([]ServerWithAccount,
 	return servers, nil
 }
 
+// ListServersFiltered lists servers with account info matching the filter, in the filter sort order
+func (s *DB) ListServersFiltered(ctx context.Context, f ServerFilter) ([]ServerWithAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var conds []string
+	var args []interface{}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		op := s.db.likeOperator()
+		conds = append(conds, `(s.name `+op+` ? OR s.ip `+op+` ? OR s.description `+op+` ?
+			OR EXISTS (SELECT 1 FROM server_addresses sa WHERE sa.server_id = s.id AND sa.address `+op+` ?))`)
+		args = append(args, like, like, like, like)
+	}
+	if f.CPUCores > 0 {
+		conds = append(conds, "s.cpu_cores = ?")
+		args = append(args, f.CPUCores)
+	}
+	textFilters := []struct {
+		column string
+		value  string
+	}{
+		{"s.os_flavor", f.OSFlavor},
+		{"s.os_version", f.OSVersion},
+		{"s.server_type", f.ServerType},
+		{"s.datacenter", f.Datacenter},
+	}
+	for _, tf := range textFilters {
+		if tf.value != "" {
+			conds = append(conds, tf.column+" = ?")
+			args = append(args, tf.value)
+		}
+	}
+	if !f.Tag.IsZero() {
+		cond, tagArgs := tagCondition(f.Tag)
+		conds = append(conds, cond)
+		args = append(args, tagArgs...)
+	}
+
+	query := serverWithAccountSelect
+	for _, cond := range conds {
+		query += ` AND ` + cond
+	}
+	query += ` ORDER BY ` + serverOrderBy(f.Sort)
+
+	var rows []serverWithAccountRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+
+	servers := make([]ServerWithAccount, 0, len(rows))
+	for _, r := range rows {
+		srv, err := r.toServerWithAccount()
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, *srv)
+	}
+
+	return servers, nil
+}
+
+// GetServerSpecOptions returns distinct spec values of stored servers
+func (s *DB) GetServerSpecOptions(ctx context.Context) (*ServerSpecOptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var opts ServerSpecOptions
+	if err := s.db.SelectContext(ctx, &opts.CPUCores,
+		`SELECT DISTINCT cpu_cores FROM servers WHERE cpu_cores > 0 AND deleted_at IS NULL ORDER BY cpu_cores`); err != nil {
+		return nil, fmt.Errorf("failed to get cpu cores: %w", err)
+	}
+
+	columns := []struct {
+		name string
+		dst  *[]string
+	}{
+		{"os_flavor", &opts.OSFlavors},
+		{"os_version", &opts.OSVersions},
+		{"server_type", &opts.ServerTypes},
+		{"datacenter", &opts.Datacenters},
+	}
+	for _, c := range columns {
+		query := `SELECT DISTINCT ` + c.name + ` FROM servers WHERE ` + c.name + ` != '' AND deleted_at IS NULL
+			ORDER BY ` + c.name
+		if err := s.db.SelectContext(ctx, c.dst, query); err != nil {
+			return nil, fmt.Errorf("failed to get %s values: %w", c.name, err)
+		}
+	}
+
+	return &opts, nil
+}
+
+// serverOrderBy returns ORDER BY clause for the sort field, prefixed with "-" for descending order.
+// Unknown fields fall back to default provider, group, account, name order.
+func serverOrderBy(sortField string) string {
+	dir := "ASC"
+	if strings.HasPrefix(sortField, "-") {
+		dir = "DESC"
+		sortField = sortField[1:]
+	}
+
+	columns := map[string][]string{
+		"name":        {"s.name"},
+		"cpu_cores":   {"s.cpu_cores"},
+		"memory":      {"s.memory_gb"},
+		"disk":        {"s.disk_gb"},
+		"cost":        {"s.approximate_cost"},
+		"server_type": {"s.server_type"},
+		"os":          {"s.os_flavor", "s.os_version"},
+		"datacenter":  {"s.datacenter"},
+	}[sortField]
+	if len(columns) == 0 {
+		return "p.name, a.group_name, a.name, s.name"
+	}
+
+	res := make([]string, 0, len(columns)+1)
+	for _, c := range columns {
+		res = append(res, c+" "+dir)
+	}
+	return strings.Join(append(res, "s.name"), ", ")
+}
+
 // ListServersByAccount lists servers by account ID
 func (s *DB) ListServersByAccount(ctx context.Context, accountID int64) ([]Server, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var rows []serverRow
-	query := `SELECT id, account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at
-		FROM servers WHERE account_id = ? ORDER BY name`
+	query := `SELECT ` + serverColumns + ` FROM servers WHERE account_id = ? AND deleted_at IS NULL ORDER BY name`
 	if err := s.db.SelectContext(ctx, &rows, query, accountID); err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
 	}
@@ -168,14 +304,8 @@ func (s *DB) ListServersByStatus(ctx context.Context, status enum.ServerStatus)
 	defer s.mu.RUnlock()
 
 	var rows []serverWithAccountRow
-	query := `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
-		s.approximate_cost, s.backups, s.status, s.created_at, s.updated_at,
-		a.name as account_name, a.group_name as account_group_name, a.provider_id,
-		p.name as provider_name
-		FROM servers s
-		JOIN accounts a ON s.account_id = a.id
-		JOIN providers p ON a.provider_id = p.id
-		WHERE s.status = ?
+	query := serverWithAccountSelect + `
+		AND s.status = ?
 		ORDER BY p.name, a.group_name, a.name, s.name`
 	if err := s.db.SelectContext(ctx, &rows, query, status.String()); err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
@@ -193,6 +323,32 @@ func (s *DB) ListServersByStatus(ctx context.Context, status enum.ServerStatus)
 	return servers, nil
 }
 
+// ListServersWithContractEnding lists not deleted servers whose contract ends or has to be renewed
+// before the given time, ordered by contract end. Servers without contract dates are not listed.
+func (s *DB) ListServersWithContractEnding(ctx context.Context, before time.Time) ([]ServerWithAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []serverWithAccountRow
+	query := serverWithAccountSelect + `
+		AND s.status != 'deleted' AND ` + contractEndExpr + ` <= ?
+		ORDER BY ` + contractEndExpr + `, s.name`
+	if err := s.db.SelectContext(ctx, &rows, query, before.UTC()); err != nil {
+		return nil, fmt.Errorf("failed to list servers with contract ending: %w", err)
+	}
+
+	servers := make([]ServerWithAccount, 0, len(rows))
+	for _, r := range rows {
+		srv, err := r.toServerWithAccount()
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, *srv)
+	}
+
+	return servers, nil
+}
+
 // UpdateServer updates an existing server
 func (s *DB) UpdateServer(ctx context.Context, srv *Server) error {
 	s.mu.Lock()
@@ -201,12 +357,19 @@ func (s *DB) UpdateServer(ctx context.Context, srv *Server) error {
 	srv.UpdatedAt = time.Now().UTC()
 
 	query := `UPDATE servers SET account_id = ?, name = ?, ip = ?, location = ?, description = ?,
-		responsible = ?, approximate_cost = ?, backups = ?, status = ?, updated_at = ?
-		WHERE id = ?`
+		responsible = ?, approximate_cost = ?, backups = ?, status = ?, external_id = ?, provider_ident = ?,
+		locked_fields = ?, cpu_cores = ?, memory_gb = ?, disk_gb = ?, server_type = ?, os_flavor = ?,
+		os_version = ?, datacenter = ?, paid_until = ?, cancellation_date = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`
 	result, err := s.db.ExecContext(ctx, query, srv.AccountID, srv.Name, srv.IP, srv.Location,
 		srv.Description, srv.Responsible, srv.ApproximateCost, srv.Backups, srv.Status.String(),
-		srv.UpdatedAt, srv.ID)
+		srv.ExternalID, srv.ProviderIdent, joinServerFields(srv.LockedFields),
+		srv.CPUCores, srv.MemoryGB, srv.DiskGB, srv.ServerType, srv.OSFlavor, srv.OSVersion, srv.Datacenter,
+		nullTime(srv.PaidUntil), nullTime(srv.CancellationDate), srv.UpdatedAt, srv.ID)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
 		return fmt.Errorf("failed to update server: %w", err)
 	}
 
@@ -228,7 +391,7 @@ func (s *DB) UpdateServerStatus(ctx context.Context, id int64, status enum.Serve
 
 	now := time.Now().UTC()
 
-	query := `UPDATE servers SET status = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE servers SET status = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
 	result, err := s.db.ExecContext(ctx, query, status.String(), now, id)
 	if err != nil {
 		return fmt.Errorf("failed to update server status: %w", err)
@@ -251,9 +414,8 @@ func (s *DB) FindServerByNameAndAccount(ctx context.Context, name string, accoun
 	defer s.mu.RUnlock()
 
 	var r serverRow
-	query := `SELECT id, account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at
-		FROM servers WHERE name = ? AND account_id = ?`
+	query := `SELECT ` + serverColumns + ` FROM servers
+		WHERE name = ? AND account_id = ? AND deleted_at IS NULL`
 	if err := s.db.GetContext(ctx, &r, query, name, accountID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -264,16 +426,36 @@ func (s *DB) FindServerByNameAndAccount(ctx context.Context, name string, accoun
 	return r.toServer()
 }
 
-// FindServerByIPAndAccount finds a server by IP and account ID
+// FindServerByExternalID finds a server by its provider-side identifier within an account
+func (s *DB) FindServerByExternalID(ctx context.Context, externalID string, accountID int64) (*Server, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var r serverRow
+	query := `SELECT ` + serverColumns + ` FROM servers
+		WHERE external_id = ? AND account_id = ? AND deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &r, query, externalID, accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to find server by external id: %w", err)
+	}
+
+	return r.toServer()
+}
+
+// FindServerByIPAndAccount finds a server by IP and account ID. The IP is matched against the primary
+// IP and all addresses of the server, servers with a matching primary IP are preferred.
 func (s *DB) FindServerByIPAndAccount(ctx context.Context, ip string, accountID int64) (*Server, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var r serverRow
-	query := `SELECT id, account_id, name, ip, location, description, responsible,
-		approximate_cost, backups, status, created_at, updated_at
-		FROM servers WHERE ip = ? AND account_id = ?`
-	if err := s.db.GetContext(ctx, &r, query, ip, accountID); err != nil {
+	query := `SELECT ` + serverColumns + ` FROM servers
+		WHERE account_id = ? AND deleted_at IS NULL AND (ip = ? OR id IN (SELECT server_id FROM server_addresses WHERE address = ?))
+		ORDER BY ip = ? DESC, id
+		LIMIT 1`
+	if err := s.db.GetContext(ctx, &r, query, accountID, ip, ip, ip); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
@@ -283,13 +465,13 @@ func (s *DB) FindServerByIPAndAccount(ctx context.Context, ip string, accountID
 	return r.toServer()
 }
 
-// DeleteServer deletes a server by ID
+// DeleteServer moves a server to the trash, its logs, tags and addresses are kept until it is purged
 func (s *DB) DeleteServer(ctx context.Context, id int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	query := `DELETE FROM servers WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, id)
+	query := `UPDATE servers SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, time.Now().UTC(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete server: %w", err)
 	}
@@ -315,12 +497,22 @@ func (s *DB) GetDashboardStats(ctx context.Context) (*DashboardStats, error) {
 		COUNT(*) as total_servers,
 		COALESCE(SUM(CASE WHEN status = 'active' THEN 1 ELSE 0 END), 0) as active_servers,
 		COALESCE(SUM(CASE WHEN status = 'paused' THEN 1 ELSE 0 END), 0) as paused_servers,
-		COALESCE(SUM(CASE WHEN status != 'deleted' THEN approximate_cost ELSE 0 END), 0) as total_cost
-		FROM servers`
-	if err := s.db.GetContext(ctx, &stats, query); err != nil {
+		COALESCE(SUM(CASE WHEN status != 'deleted' THEN approximate_cost ELSE 0 END), 0) as total_cost,
+		COALESCE(SUM(CASE WHEN status != 'deleted' AND ` + contractEndExpr + ` <= ?
+			THEN 1 ELSE 0 END), 0) as contracts_ending
+		FROM servers s WHERE deleted_at IS NULL`
+	contractsBefore := time.Now().UTC().AddDate(0, 0, ContractWarningDays)
+	if err := s.db.GetContext(ctx, &stats, query, contractsBefore); err != nil {
 		return nil, fmt.Errorf("failed to get dashboard stats: %w", err)
 	}
 
+	query = `SELECT COUNT(*) as resource_count, COALESCE(SUM(r.cost), 0) as resource_cost
+		FROM resources r JOIN accounts a ON r.account_id = a.id WHERE a.deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &stats, query); err != nil {
+		return nil, fmt.Errorf("failed to get resource stats: %w", err)
+	}
+	stats.TotalCost += stats.ResourceCost
+
 	return &stats, nil
 }
 
@@ -330,36 +522,96 @@ func (s *DB) GetServersGroupedByAccount(ctx context.Context, status *enum.Server
 	defer s.mu.RUnlock()
 
 	// build query with optional status filter
-	query := `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
-		s.approximate_cost, s.backups, s.status, s.created_at, s.updated_at,
-		a.name as account_name, a.group_name as account_group_name, a.provider_id,
-		p.name as provider_name
-		FROM servers s
-		JOIN accounts a ON s.account_id = a.id
-		JOIN providers p ON a.provider_id = p.id`
+	query := serverWithAccountSelect
 
 	var args []interface{}
 	if status != nil {
-		query += ` WHERE s.status = ?`
+		query += ` AND s.status = ?`
 		args = append(args, status.String())
 	}
 	query += ` ORDER BY p.name, a.group_name, a.name, s.name`
 
-	var rows []serverWithAccountRow
-	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+	servers, err := s.selectServersWithAccounts(ctx, query, args...)
+	if err != nil {
 		return nil, fmt.Errorf("failed to get servers grouped: %w", err)
 	}
 
-	// group by account
-	groupMap := make(map[int64]*AccountGroup)
-	var groups []AccountGroup
+	// resources are billed regardless of server status, they are shown in unfiltered view only
+	var resources []ResourceWithAccount
+	if status == nil {
+		if resources, err = s.listResourcesWithAccounts(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return groupByAccount(servers, resources), nil
+}
+
+// GetServersGroupedHierarchically returns servers in hierarchical structure:
+// Provider+GroupName -> Accounts (Projects) -> Servers, optionally filtered by status and tag
+func (s *DB) GetServersGroupedHierarchically(ctx context.Context, status *enum.ServerStatus,
+	tag TagFilter) ([]ProviderAccountGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	// build query with optional status and tag filters
+	query := serverWithAccountSelect
+
+	var conds []string
+	var args []interface{}
+	if status != nil {
+		conds = append(conds, `s.status = ?`)
+		args = append(args, status.String())
+	}
+	if !tag.IsZero() {
+		cond, tagArgs := tagCondition(tag)
+		conds = append(conds, cond)
+		args = append(args, tagArgs...)
+	}
+	for _, cond := range conds {
+		query += ` AND ` + cond
+	}
+	query += ` ORDER BY p.name, a.group_name, a.name, s.name`
+
+	servers, err := s.selectServersWithAccounts(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get servers grouped: %w", err)
+	}
+
+	// resources are billed regardless of server status, they are shown in unfiltered view only
+	var resources []ResourceWithAccount
+	if status == nil {
+		if resources, err = s.listResourcesWithAccounts(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return groupHierarchically(servers, resources), nil
+}
+
+// selectServersWithAccounts runs a query returning serverWithAccountSelect columns, caller holds the lock
+func (s *DB) selectServersWithAccounts(ctx context.Context, query string, args ...interface{}) ([]ServerWithAccount, error) {
+	var rows []serverWithAccountRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	servers := make([]ServerWithAccount, 0, len(rows))
 	for _, r := range rows {
 		srv, err := r.toServerWithAccount()
 		if err != nil {
 			return nil, err
 		}
+		servers = append(servers, *srv)
+	}
+	return servers, nil
+}
 
+// groupByAccount groups servers ordered by provider, group, account and name by account
+// and adds resources to the groups of their accounts
+func groupByAccount(servers []ServerWithAccount, resources []ResourceWithAccount) []AccountGroup {
+	groupMap := make(map[int64]*AccountGroup)
+	var groups []AccountGroup
+
+	for _, srv := range servers {
 		group, exists := groupMap[srv.AccountID]
 		if !exists {
 			group = &AccountGroup{
@@ -377,7 +629,7 @@ func (s *DB) GetServersGroupedByAccount(ctx context.Context, status *enum.Server
 		// find and update group in slice
 		for i := range groups {
 			if groups[i].AccountID == srv.AccountID {
-				groups[i].Servers = append(groups[i].Servers, *srv)
+				groups[i].Servers = append(groups[i].Servers, srv)
 				if srv.Status != enum.ServerStatusDeleted {
 					groups[i].TotalCost += srv.ApproximateCost
 				}
@@ -386,47 +638,37 @@ func (s *DB) GetServersGroupedByAccount(ctx context.Context, status *enum.Server
 		}
 	}
 
-	return groups, nil
-}
-
-// GetServersGroupedHierarchically returns servers in hierarchical structure:
-// Provider+GroupName -> Accounts (Projects) -> Servers
-func (s *DB) GetServersGroupedHierarchically(ctx context.Context, status *enum.ServerStatus) ([]ProviderAccountGroup, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// build query with optional status filter
-	query := `SELECT s.id, s.account_id, s.name, s.ip, s.location, s.description, s.responsible,
-		s.approximate_cost, s.backups, s.status, s.created_at, s.updated_at,
-		a.name as account_name, a.group_name as account_group_name, a.provider_id,
-		p.name as provider_name
-		FROM servers s
-		JOIN accounts a ON s.account_id = a.id
-		JOIN providers p ON a.provider_id = p.id`
-
-	var args []interface{}
-	if status != nil {
-		query += ` WHERE s.status = ?`
-		args = append(args, status.String())
+	if len(resources) == 0 {
+		return groups
 	}
-	query += ` ORDER BY p.name, a.group_name, a.name, s.name`
-
-	var rows []serverWithAccountRow
-	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
-		return nil, fmt.Errorf("failed to get servers grouped: %w", err)
+	for _, res := range resources {
+		i := slices.IndexFunc(groups, func(g AccountGroup) bool { return g.AccountID == res.AccountID })
+		if i < 0 {
+			groups = append(groups, AccountGroup{
+				AccountID:        res.AccountID,
+				AccountName:      res.AccountName,
+				AccountGroupName: res.AccountGroupName,
+				ProviderID:       res.ProviderID,
+				ProviderName:     res.ProviderName,
+				Servers:          make([]ServerWithAccount, 0),
+			})
+			i = len(groups) - 1
+		}
+		groups[i].addResource(res)
 	}
+	sortAccountGroups(groups)
+
+	return groups
+}
 
-	// Build hierarchical structure: ProviderAccountGroup -> AccountGroup -> Servers
+// groupHierarchically groups servers ordered by provider, group, account and name in hierarchical
+// structure: Provider+GroupName -> Accounts (Projects) -> Servers, and adds resources to their accounts
+func groupHierarchically(servers []ServerWithAccount, resources []ResourceWithAccount) []ProviderAccountGroup {
 	providerGroupMap := make(map[string]*ProviderAccountGroup) // key: "provider_id:group_name"
 	accountGroupMap := make(map[int64]*AccountGroup)           // key: account_id
 	var providerGroups []ProviderAccountGroup
 
-	for _, r := range rows {
-		srv, err := r.toServerWithAccount()
-		if err != nil {
-			return nil, err
-		}
-
+	for _, srv := range servers {
 		// Create group key from provider_id and group_name
 		groupKey := fmt.Sprintf("%d:%s", srv.ProviderID, srv.AccountGroupName)
 
@@ -471,7 +713,7 @@ func (s *DB) GetServersGroupedHierarchically(ctx context.Context, status *enum.S
 			if providerGroups[i].GroupKey == groupKey {
 				for j := range providerGroups[i].Accounts {
 					if providerGroups[i].Accounts[j].AccountID == srv.AccountID {
-						providerGroups[i].Accounts[j].Servers = append(providerGroups[i].Accounts[j].Servers, *srv)
+						providerGroups[i].Accounts[j].Servers = append(providerGroups[i].Accounts[j].Servers, srv)
 						if srv.Status != enum.ServerStatusDeleted {
 							providerGroups[i].Accounts[j].TotalCost += srv.ApproximateCost
 							providerGroups[i].TotalCost += srv.ApproximateCost
@@ -485,23 +727,103 @@ func (s *DB) GetServersGroupedHierarchically(ctx context.Context, status *enum.S
 		}
 	}
 
-	return providerGroups, nil
+	if len(resources) == 0 {
+		return providerGroups
+	}
+	for _, res := range resources {
+		groupKey := fmt.Sprintf("%d:%s", res.ProviderID, res.AccountGroupName)
+		i := slices.IndexFunc(providerGroups, func(g ProviderAccountGroup) bool { return g.GroupKey == groupKey })
+		if i < 0 {
+			providerGroups = append(providerGroups, ProviderAccountGroup{
+				ProviderID:   res.ProviderID,
+				ProviderName: res.ProviderName,
+				GroupName:    res.AccountGroupName,
+				GroupKey:     groupKey,
+				Accounts:     make([]AccountGroup, 0),
+			})
+			i = len(providerGroups) - 1
+		}
+
+		pg := &providerGroups[i]
+		j := slices.IndexFunc(pg.Accounts, func(g AccountGroup) bool { return g.AccountID == res.AccountID })
+		if j < 0 {
+			pg.Accounts = append(pg.Accounts, AccountGroup{
+				AccountID:        res.AccountID,
+				AccountName:      res.AccountName,
+				AccountGroupName: res.AccountGroupName,
+				ProviderID:       res.ProviderID,
+				ProviderName:     res.ProviderName,
+				Servers:          make([]ServerWithAccount, 0),
+			})
+			j = len(pg.Accounts) - 1
+		}
+		pg.Accounts[j].addResource(res)
+		pg.TotalCost += res.Cost
+		pg.ResourceCount++
+	}
+
+	// groups and accounts having resources only are appended above, restore the display order
+	sort.SliceStable(providerGroups, func(i, j int) bool {
+		if providerGroups[i].ProviderName != providerGroups[j].ProviderName {
+			return providerGroups[i].ProviderName < providerGroups[j].ProviderName
+		}
+		return providerGroups[i].GroupName < providerGroups[j].GroupName
+	})
+	for i := range providerGroups {
+		sortAccountGroups(providerGroups[i].Accounts)
+	}
+
+	return providerGroups
+}
+
+// addResource adds a resource to the account group and its cost to the totals
+func (g *AccountGroup) addResource(res ResourceWithAccount) {
+	g.Resources = append(g.Resources, res)
+	g.ResourceCost += res.Cost
+	g.TotalCost += res.Cost
+}
+
+// sortAccountGroups sorts account groups in display order, by provider, group and account name
+func sortAccountGroups(groups []AccountGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.ProviderName != b.ProviderName {
+			return a.ProviderName < b.ProviderName
+		}
+		if a.AccountGroupName != b.AccountGroupName {
+			return a.AccountGroupName < b.AccountGroupName
+		}
+		return a.AccountName < b.AccountName
+	})
 }
 
 // serverRow is used for scanning database rows
 type serverRow struct {
-	ID              int64     `db:"id"`
-	AccountID       int64     `db:"account_id"`
-	Name            string    `db:"name"`
-	IP              string    `db:"ip"`
-	Location        string    `db:"location"`
-	Description     string    `db:"description"`
-	Responsible     string    `db:"responsible"`
-	ApproximateCost float64   `db:"approximate_cost"`
-	Backups         bool      `db:"backups"`
-	Status          string    `db:"status"`
-	CreatedAt       time.Time `db:"created_at"`
-	UpdatedAt       time.Time `db:"updated_at"`
+	ID               int64        `db:"id"`
+	AccountID        int64        `db:"account_id"`
+	Name             string       `db:"name"`
+	IP               string       `db:"ip"`
+	Location         string       `db:"location"`
+	Description      string       `db:"description"`
+	Responsible      string       `db:"responsible"`
+	ApproximateCost  float64      `db:"approximate_cost"`
+	Backups          bool         `db:"backups"`
+	Status           string       `db:"status"`
+	ExternalID       string       `db:"external_id"`
+	ProviderIdent    string       `db:"provider_ident"`
+	LockedFields     string       `db:"locked_fields"`
+	CPUCores         int          `db:"cpu_cores"`
+	MemoryGB         float64      `db:"memory_gb"`
+	DiskGB           int          `db:"disk_gb"`
+	ServerType       string       `db:"server_type"`
+	OSFlavor         string       `db:"os_flavor"`
+	OSVersion        string       `db:"os_version"`
+	Datacenter       string       `db:"datacenter"`
+	PaidUntil        sql.NullTime `db:"paid_until"`
+	CancellationDate sql.NullTime `db:"cancellation_date"`
+	CreatedAt        time.Time    `db:"created_at"`
+	UpdatedAt        time.Time    `db:"updated_at"`
+	DeletedAt        sql.NullTime `db:"deleted_at"`
 }
 
 func (r *serverRow) toServer() (*Server, error) {
@@ -509,19 +831,36 @@ func (r *serverRow) toServer() (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	locked, err := parseServerFields(r.LockedFields)
+	if err != nil {
+		return nil, err
+	}
 	return &Server{
-		ID:              r.ID,
-		AccountID:       r.AccountID,
-		Name:            r.Name,
-		IP:              r.IP,
-		Location:        r.Location,
-		Description:     r.Description,
-		Responsible:     r.Responsible,
-		ApproximateCost: r.ApproximateCost,
-		Backups:         r.Backups,
-		Status:          st,
-		CreatedAt:       r.CreatedAt,
-		UpdatedAt:       r.UpdatedAt,
+		ID:               r.ID,
+		AccountID:        r.AccountID,
+		Name:             r.Name,
+		IP:               r.IP,
+		Location:         r.Location,
+		Description:      r.Description,
+		Responsible:      r.Responsible,
+		ApproximateCost:  r.ApproximateCost,
+		Backups:          r.Backups,
+		Status:           st,
+		ExternalID:       r.ExternalID,
+		ProviderIdent:    r.ProviderIdent,
+		LockedFields:     locked,
+		CPUCores:         r.CPUCores,
+		MemoryGB:         r.MemoryGB,
+		DiskGB:           r.DiskGB,
+		ServerType:       r.ServerType,
+		OSFlavor:         r.OSFlavor,
+		OSVersion:        r.OSVersion,
+		Datacenter:       r.Datacenter,
+		PaidUntil:        r.PaidUntil.Time,
+		CancellationDate: r.CancellationDate.Time,
+		CreatedAt:        r.CreatedAt,
+		UpdatedAt:        r.UpdatedAt,
+		DeletedAt:        r.DeletedAt.Time,
 	}, nil
 }
 
@@ -546,3 +885,34 @@ func (r *serverWithAccountRow) toServerWithAccount() (*ServerWithAccount, error)
 		ProviderName:     r.ProviderName,
 	}, nil
 }
+
+// nullTime converts zero time to NULL for optional dates
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// joinServerFields formats server fields as a comma-separated list for storage
+func joinServerFields(fields []enum.ServerField) string {
+	res := make([]string, 0, len(fields))
+	for _, f := range fields {
+		res = append(res, f.String())
+	}
+	return strings.Join(res, ",")
+}
+
+// parseServerFields parses a comma-separated list of server fields
+func parseServerFields(s string) ([]enum.ServerField, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	fields := make([]enum.ServerField, 0, len(parts))
+	for _, p := range parts {
+		f, err := enum.ParseServerField(p)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/nilBora/servers-manager/app/enum"
 )
@@ -35,16 +36,51 @@ type ServerStore interface {
 	GetServerWithAccount(ctx context.Context, id int64) (*ServerWithAccount, error)
 	FindServerByNameAndAccount(ctx context.Context, name string, accountID int64) (*Server, error)
 	FindServerByIPAndAccount(ctx context.Context, ip string, accountID int64) (*Server, error)
+	FindServerByExternalID(ctx context.Context, externalID string, accountID int64) (*Server, error)
 	ListServers(ctx context.Context) ([]Server, error)
 	ListServersWithAccounts(ctx context.Context) ([]ServerWithAccount, error)
+	ListServersFiltered(ctx context.Context, f ServerFilter) ([]ServerWithAccount, error)
+	GetServerSpecOptions(ctx context.Context) (*ServerSpecOptions, error)
 	ListServersByAccount(ctx context.Context, accountID int64) ([]Server, error)
 	ListServersByStatus(ctx context.Context, status enum.ServerStatus) ([]ServerWithAccount, error)
+	ListServersWithContractEnding(ctx context.Context, before time.Time) ([]ServerWithAccount, error)
 	UpdateServer(ctx context.Context, s *Server) error
 	UpdateServerStatus(ctx context.Context, id int64, status enum.ServerStatus) error
 	DeleteServer(ctx context.Context, id int64) error
 	GetDashboardStats(ctx context.Context) (*DashboardStats, error)
 	GetServersGroupedByAccount(ctx context.Context, status *enum.ServerStatus) ([]AccountGroup, error)
-	GetServersGroupedHierarchically(ctx context.Context, status *enum.ServerStatus) ([]ProviderAccountGroup, error)
+	GetServersGroupedHierarchically(ctx context.Context, status *enum.ServerStatus, tag TagFilter) ([]ProviderAccountGroup, error)
+}
+
+// ServerAddressStore defines operations for server IP addresses and subnets
+type ServerAddressStore interface {
+	ListServerAddresses(ctx context.Context, serverID int64) ([]ServerAddress, error)
+	SetServerAddresses(ctx context.Context, serverID int64, addrs []ServerAddress) error
+}
+
+// ResourceStore defines operations for billable provider resources other than servers
+type ResourceStore interface {
+	ListResources(ctx context.Context) ([]ResourceWithAccount, error)
+	ListResourcesByAccount(ctx context.Context, accountID int64) ([]Resource, error)
+	ListResourcesByServer(ctx context.Context, serverID int64) ([]Resource, error)
+	ReplaceAccountResources(ctx context.Context, accountID int64, kinds []enum.ResourceKind, resources []Resource) error
+}
+
+// ServerTagStore defines operations for server tags
+type ServerTagStore interface {
+	ListServerTags(ctx context.Context, serverID int64) ([]ServerTag, error)
+	ListAllServerTags(ctx context.Context) (map[int64][]ServerTag, error)
+	ListTagOptions(ctx context.Context) ([]TagFilter, error)
+	SetServerTags(ctx context.Context, serverID int64, source enum.TagSource, tags []ServerTag) error
+}
+
+// MetricStore defines operations for server utilization samples
+type MetricStore interface {
+	AddServerMetrics(ctx context.Context, metrics []ServerMetric) error
+	ListServerMetrics(ctx context.Context, serverID int64, since time.Time) ([]ServerMetric, error)
+	GetLastServerMetricTime(ctx context.Context, serverID int64) (time.Time, error)
+	DownsampleServerMetrics(ctx context.Context, hourlyBefore, purgeBefore time.Time) error
+	ListServerUtilization(ctx context.Context, since time.Time) ([]ServerUtilization, error)
 }
 
 // ServerLogStore defines operations for server logs
@@ -53,6 +89,16 @@ type ServerLogStore interface {
 	ListLogs(ctx context.Context, limit int) ([]ServerLogWithServer, error)
 	ListLogsByServer(ctx context.Context, serverID int64, limit int) ([]ServerLog, error)
 	ListLogsByAction(ctx context.Context, action enum.LogAction, limit int) ([]ServerLogWithServer, error)
+	ListLogsFiltered(ctx context.Context, f LogFilter, limit int) ([]ServerLogWithServer, error)
+}
+
+// SyncRunStore defines operations for sync run history
+type SyncRunStore interface {
+	CreateSyncRun(ctx context.Context, r *SyncRun) error
+	FinishSyncRun(ctx context.Context, r *SyncRun) error
+	ListSyncRuns(ctx context.Context, limit int) ([]SyncRunWithAccount, error)
+	ListSyncRunsByAccount(ctx context.Context, accountID int64, limit int) ([]SyncRunWithAccount, error)
+	GetLastSyncRuns(ctx context.Context) (map[int64]SyncRun, error)
 }
 
 // UserStore defines operations for users
@@ -73,13 +119,40 @@ type SessionStore interface {
 	DeleteUserSessions(ctx context.Context, userID int64) error
 }
 
+// BackupStore defines online backups, implemented by stores able to copy their database into a file
+type BackupStore interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// SyncLockStore defines locks of account sync shared by processes using the same database,
+// implemented by stores able to be shared
+type SyncLockStore interface {
+	TryLockAccountSync(ctx context.Context, accountID int64) (unlock func(), locked bool, err error)
+}
+
+// TrashStore defines operations for deleted providers, accounts and servers. Deleted records are hidden
+// from other operations and kept in the trash until restored or purged.
+type TrashStore interface {
+	ListTrash(ctx context.Context) (*Trash, error)
+	RestoreProvider(ctx context.Context, id int64) error
+	RestoreAccount(ctx context.Context, id int64) error
+	RestoreServer(ctx context.Context, id int64) error
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
 // Store combines all store interfaces
 type Store interface {
 	ProviderStore
 	AccountStore
 	ServerStore
+	ServerAddressStore
+	ServerTagStore
+	ResourceStore
+	MetricStore
 	ServerLogStore
+	SyncRunStore
 	UserStore
 	SessionStore
+	TrashStore
 	Close() error
 }
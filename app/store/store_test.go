@@ -0,0 +1,976 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+func TestDB_Conformance(t *testing.T) {
+	testStoreConformance(t, func(t *testing.T) Store {
+		db, err := New(testTargetDSN(t))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	})
+}
+
+func TestMemory_Conformance(t *testing.T) {
+	testStoreConformance(t, func(*testing.T) Store { return NewMemory() })
+}
+
+// testStoreConformance runs checks every Store implementation has to pass. newStore returns an empty store
+// with default providers for each subtest.
+func testStoreConformance(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("providers", func(t *testing.T) { testProviderStore(t, newStore(t)) })
+	t.Run("accounts", func(t *testing.T) { testAccountStore(t, newStore(t)) })
+	t.Run("servers", func(t *testing.T) { testServerStore(t, newStore(t)) })
+	t.Run("server filters", func(t *testing.T) { testServerFilters(t, newStore(t)) })
+	t.Run("server groups", func(t *testing.T) { testServerGroups(t, newStore(t)) })
+	t.Run("contracts", func(t *testing.T) { testContracts(t, newStore(t)) })
+	t.Run("addresses and tags", func(t *testing.T) { testAddressesAndTags(t, newStore(t)) })
+	t.Run("resources", func(t *testing.T) { testResourceStore(t, newStore(t)) })
+	t.Run("metrics", func(t *testing.T) { testMetricStore(t, newStore(t)) })
+	t.Run("logs", func(t *testing.T) { testServerLogStore(t, newStore(t)) })
+	t.Run("sync runs", func(t *testing.T) { testSyncRunStore(t, newStore(t)) })
+	t.Run("users", func(t *testing.T) { testUserStore(t, newStore(t)) })
+	t.Run("sessions", func(t *testing.T) { testSessionStore(t, newStore(t)) })
+	t.Run("trash", func(t *testing.T) { testTrashStore(t, newStore(t)) })
+}
+
+func testProviderStore(t *testing.T, st Store) {
+	ctx := context.Background()
+
+	providers, err := st.ListProviders(ctx)
+	require.NoError(t, err)
+	require.Len(t, providers, len(defaultProviders), "default providers seeded")
+	for i := 1; i < len(providers); i++ {
+		assert.LessOrEqual(t, providers[i-1].Name, providers[i].Name, "ordered by name")
+	}
+
+	p := &Provider{Ident: "ovh", Name: "OVH", Description: "OVHcloud"}
+	require.NoError(t, st.CreateProvider(ctx, p))
+	assert.NotZero(t, p.ID)
+	assert.False(t, p.CreatedAt.IsZero())
+
+	got, err := st.GetProvider(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ovh", got.Ident)
+	assert.Equal(t, "OVHcloud", got.Description)
+
+	got, err = st.GetProviderByName(ctx, "OVH")
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, got.ID)
+	_, err = st.GetProviderByName(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = st.CreateProvider(ctx, &Provider{Ident: "ovh", Name: "OVH again"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	p.Name = "OVH Cloud"
+	require.NoError(t, st.UpdateProvider(ctx, p))
+	got, err = st.GetProvider(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "OVH Cloud", got.Name)
+
+	p.Ident = providers[0].Ident
+	require.ErrorIs(t, st.UpdateProvider(ctx, p), ErrConflict)
+	require.ErrorIs(t, st.UpdateProvider(ctx, &Provider{ID: 9999, Ident: "x", Name: "x"}), ErrNotFound)
+
+	acc := &Account{ProviderID: p.ID, Name: "main"}
+	require.NoError(t, st.CreateAccount(ctx, acc))
+	require.NoError(t, st.DeleteProvider(ctx, p.ID), "accounts are moved to the trash with the provider")
+	_, err = st.GetProvider(ctx, p.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = st.GetAccount(ctx, acc.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, st.DeleteProvider(ctx, p.ID), ErrNotFound)
+	require.ErrorIs(t, st.UpdateProvider(ctx, p), ErrNotFound)
+	providers, err = st.ListProviders(ctx)
+	require.NoError(t, err)
+	assert.Len(t, providers, len(defaultProviders))
+	err = st.CreateProvider(ctx, &Provider{Ident: "ovh", Name: "OVH"})
+	require.ErrorIs(t, err, ErrConflict, "ident of a provider in the trash is taken")
+	require.ErrorIs(t, err, ErrInTrash)
+}
+
+func testAccountStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	hetzner := testProvider(t, st, "hetzner_cloud")
+	aws := testProvider(t, st, "aws")
+
+	a1 := &Account{ProviderID: hetzner.ID, GroupName: "prod", Name: "web", Login: "user", ApiKey: "token", SyncInterval: 30}
+	require.NoError(t, st.CreateAccount(ctx, a1))
+	assert.NotZero(t, a1.ID)
+	a2 := &Account{ProviderID: hetzner.ID, GroupName: "dev", Name: "test"}
+	require.NoError(t, st.CreateAccount(ctx, a2))
+	a3 := &Account{ProviderID: aws.ID, Name: "web"}
+	require.NoError(t, st.CreateAccount(ctx, a3), "same name for another provider")
+
+	err := st.CreateAccount(ctx, &Account{ProviderID: hetzner.ID, Name: "web"})
+	require.ErrorIs(t, err, ErrConflict)
+	require.Error(t, st.CreateAccount(ctx, &Account{ProviderID: 9999, Name: "orphan"}), "provider must exist")
+
+	got, err := st.GetAccount(ctx, a1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", got.GroupName)
+	assert.Equal(t, "user", got.Login)
+	assert.Equal(t, "token", got.ApiKey)
+	assert.Equal(t, 30, got.SyncInterval)
+	_, err = st.GetAccount(ctx, 9999)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, st.CreateServer(ctx, &Server{AccountID: a1.ID, Name: "s1", Status: enum.ServerStatusActive}))
+	withProvider, err := st.GetAccountWithProvider(ctx, a1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, hetzner.Ident, withProvider.ProviderIdent)
+	assert.Equal(t, hetzner.Name, withProvider.ProviderName)
+	assert.Equal(t, 1, withProvider.ServerCount)
+	_, err = st.GetAccountWithProvider(ctx, 9999)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	accounts, err := st.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a3.ID, a2.ID, a1.ID}, accountIDs(accounts), "ordered by group and name")
+
+	byProvider, err := st.ListAccountsByProvider(ctx, hetzner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a2.ID, a1.ID}, accountIDs(byProvider))
+
+	withProviders, err := st.ListAccountsWithProviders(ctx)
+	require.NoError(t, err)
+	require.Len(t, withProviders, 3)
+	assert.Equal(t, a3.ID, withProviders[0].ID, "ordered by provider name first")
+	assert.Equal(t, 1, withProviders[2].ServerCount)
+
+	a2.Name = "staging"
+	a2.ApiKey = "new-token"
+	require.NoError(t, st.UpdateAccount(ctx, a2))
+	got, err = st.GetAccount(ctx, a2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", got.Name)
+	assert.Equal(t, "new-token", got.ApiKey)
+
+	a2.Name = "web"
+	err = st.UpdateAccount(ctx, a2)
+	require.ErrorIs(t, err, ErrConflict)
+	assert.NotErrorIs(t, err, ErrInTrash, "taken by a live account")
+	require.ErrorIs(t, st.UpdateAccount(ctx, &Account{ID: 9999, ProviderID: hetzner.ID, Name: "x"}), ErrNotFound)
+
+	require.NoError(t, st.DeleteAccount(ctx, a1.ID), "servers are moved to the trash with the account")
+	servers, err := st.ListServers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+
+	require.NoError(t, st.ReplaceAccountResources(ctx, a2.ID, []enum.ResourceKind{enum.ResourceKindVolume},
+		[]Resource{{Kind: enum.ResourceKindVolume, ExternalID: "1"}}))
+	require.NoError(t, st.DeleteAccount(ctx, a2.ID))
+	_, err = st.GetAccount(ctx, a2.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, st.DeleteAccount(ctx, a2.ID), ErrNotFound)
+	require.ErrorIs(t, st.UpdateAccount(ctx, a2), ErrNotFound)
+	resources, err := st.ListResources(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resources, "resources of accounts in the trash are not listed")
+
+	accounts, err = st.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a3.ID}, accountIDs(accounts))
+	err = st.CreateAccount(ctx, &Account{ProviderID: hetzner.ID, Name: "web"})
+	require.ErrorIs(t, err, ErrConflict, "name of an account in the trash is taken")
+	require.ErrorIs(t, err, ErrInTrash)
+}
+
+func testServerStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+
+	srv := &Server{AccountID: acc.ID, Name: "web-1", IP: "10.0.0.1", Location: "fsn1", Description: "frontend",
+		Responsible: "ops", ApproximateCost: 12.5, Backups: true, Status: enum.ServerStatusActive, ExternalID: "42",
+		ProviderIdent: "hetzner_cloud", LockedFields: []enum.ServerField{enum.ServerFieldName, enum.ServerFieldCost},
+		CPUCores: 2, MemoryGB: 4, DiskGB: 40, ServerType: "cx21", OSFlavor: "ubuntu", OSVersion: "22.04",
+		Datacenter: "fsn1-dc14", PaidUntil: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, st.CreateServer(ctx, srv))
+	assert.NotZero(t, srv.ID)
+	assert.False(t, srv.CreatedAt.IsZero())
+
+	got, err := st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1", got.Name)
+	assert.Equal(t, "10.0.0.1", got.IP)
+	assert.Equal(t, "frontend", got.Description)
+	assert.InDelta(t, 12.5, got.ApproximateCost, 0.001)
+	assert.True(t, got.Backups)
+	assert.Equal(t, enum.ServerStatusActive, got.Status)
+	assert.Equal(t, "42", got.ExternalID)
+	assert.Equal(t, []enum.ServerField{enum.ServerFieldName, enum.ServerFieldCost}, got.LockedFields)
+	assert.Equal(t, 2, got.CPUCores)
+	assert.Equal(t, "22.04", got.OSVersion)
+	assert.True(t, got.PaidUntil.Equal(srv.PaidUntil))
+	assert.True(t, got.CancellationDate.IsZero())
+	_, err = st.GetServer(ctx, 9999)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	got.LockedFields[0] = enum.ServerFieldIP
+	again, err := st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enum.ServerFieldName, again.LockedFields[0], "returned server doesn't share stored data")
+
+	withAccount, err := st.GetServerWithAccount(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "main", withAccount.AccountName)
+	assert.Equal(t, acc.ProviderID, withAccount.ProviderID)
+	assert.NotEmpty(t, withAccount.ProviderName)
+	_, err = st.GetServerWithAccount(ctx, 9999)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// external IDs are unique within an account, empty ones are not constrained
+	err = st.CreateServer(ctx, &Server{AccountID: acc.ID, Name: "dup", ExternalID: "42", Status: enum.ServerStatusActive})
+	require.ErrorIs(t, err, ErrConflict)
+	manual1 := &Server{AccountID: acc.ID, Name: "manual", IP: "10.0.0.2", Status: enum.ServerStatusPaused}
+	require.NoError(t, st.CreateServer(ctx, manual1))
+	manual2 := &Server{AccountID: acc.ID, Name: "another", Status: enum.ServerStatusDeleted}
+	require.NoError(t, st.CreateServer(ctx, manual2))
+	require.Error(t, st.CreateServer(ctx, &Server{AccountID: 9999, Name: "orphan"}), "account must exist")
+
+	found, err := st.FindServerByNameAndAccount(ctx, "manual", acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, manual1.ID, found.ID)
+	_, err = st.FindServerByNameAndAccount(ctx, "manual", acc.ID+1)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	found, err = st.FindServerByExternalID(ctx, "42", acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, srv.ID, found.ID)
+	_, err = st.FindServerByExternalID(ctx, "43", acc.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	found, err = st.FindServerByIPAndAccount(ctx, "10.0.0.2", acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, manual1.ID, found.ID)
+	require.NoError(t, st.SetServerAddresses(ctx, srv.ID, []ServerAddress{
+		{Address: "2a01:4f8::1", Family: 6, Kind: enum.AddressKindPublic}}))
+	found, err = st.FindServerByIPAndAccount(ctx, "2a01:4f8::1", acc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, srv.ID, found.ID, "found by secondary address")
+	_, err = st.FindServerByIPAndAccount(ctx, "10.9.9.9", acc.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	servers, err := st.ListServers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{manual2.ID, manual1.ID, srv.ID}, serverIDs(servers), "ordered by name")
+
+	byAccount, err := st.ListServersByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	assert.Len(t, byAccount, 3)
+	byAccount, err = st.ListServersByAccount(ctx, 9999)
+	require.NoError(t, err)
+	assert.Empty(t, byAccount)
+
+	withAccounts, err := st.ListServersWithAccounts(ctx)
+	require.NoError(t, err)
+	require.Len(t, withAccounts, 3)
+	assert.Equal(t, "main", withAccounts[0].AccountName)
+
+	paused, err := st.ListServersByStatus(ctx, enum.ServerStatusPaused)
+	require.NoError(t, err)
+	require.Len(t, paused, 1)
+	assert.Equal(t, manual1.ID, paused[0].ID)
+
+	srv.Name = "web-1-renamed"
+	srv.LockedFields = nil
+	srv.CancellationDate = time.Date(2029, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, st.UpdateServer(ctx, srv))
+	got, err = st.GetServer(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1-renamed", got.Name)
+	assert.Empty(t, got.LockedFields)
+	assert.True(t, got.CancellationDate.Equal(srv.CancellationDate))
+
+	manual1.ExternalID = "42"
+	require.ErrorIs(t, st.UpdateServer(ctx, manual1), ErrConflict)
+	require.ErrorIs(t, st.UpdateServer(ctx, &Server{ID: 9999, AccountID: acc.ID, Name: "x"}), ErrNotFound)
+
+	require.NoError(t, st.UpdateServerStatus(ctx, manual1.ID, enum.ServerStatusActive))
+	got, err = st.GetServer(ctx, manual1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enum.ServerStatusActive, got.Status)
+	require.ErrorIs(t, st.UpdateServerStatus(ctx, 9999, enum.ServerStatusActive), ErrNotFound)
+
+	// contracts ending: srv is cancelled in 2029, deleted servers are skipped
+	manual2.PaidUntil = time.Date(2028, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, st.UpdateServer(ctx, manual2))
+	ending, err := st.ListServersWithContractEnding(ctx, time.Date(2029, 12, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{srv.ID}, serverWithAccountIDs(ending))
+	ending, err = st.ListServersWithContractEnding(ctx, time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, ending)
+
+	stats, err := st.GetDashboardStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalServers)
+	assert.Equal(t, 2, stats.ActiveServers)
+	assert.Equal(t, 0, stats.PausedServers)
+	assert.InDelta(t, 12.5, stats.TotalCost, 0.001)
+
+	// deleting a server moves it to the trash keeping its logs, purging removes logs, addresses, tags and metrics
+	require.NoError(t, st.CreateLog(ctx, &ServerLog{ServerID: srv.ID, Action: enum.LogActionAdded}))
+	require.NoError(t, st.SetServerTags(ctx, srv.ID, enum.TagSourceUser, []ServerTag{{Key: "env", Value: "prod"}}))
+	require.NoError(t, st.AddServerMetrics(ctx, []ServerMetric{{ServerID: srv.ID, Step: MetricStepHour,
+		Time: time.Now().UTC().Truncate(time.Hour), CPU: 10}}))
+	require.NoError(t, st.DeleteServer(ctx, srv.ID))
+	_, err = st.GetServer(ctx, srv.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = st.GetServerWithAccount(ctx, srv.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, st.DeleteServer(ctx, srv.ID), ErrNotFound)
+	require.ErrorIs(t, st.UpdateServerStatus(ctx, srv.ID, enum.ServerStatusPaused), ErrNotFound)
+	_, err = st.FindServerByExternalID(ctx, "42", acc.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	servers, err = st.ListServers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{manual2.ID, manual1.ID}, serverIDs(servers))
+	stats, err = st.GetDashboardStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalServers)
+	logs, err := st.ListLogsByServer(ctx, srv.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, logs, 1, "logs kept in the trash")
+
+	n, err := st.PurgeTrash(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	logs, err = st.ListLogsByServer(ctx, srv.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+	addrs, err := st.ListServerAddresses(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Empty(t, addrs)
+	tags, err := st.ListServerTags(ctx, srv.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+	metrics, err := st.ListServerMetrics(ctx, srv.ID, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func testServerFilters(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+
+	small := &Server{AccountID: acc.ID, Name: "alpha", IP: "10.0.0.1", Description: "Database primary",
+		Status: enum.ServerStatusActive, CPUCores: 2, MemoryGB: 4, DiskGB: 80, ApproximateCost: 5,
+		ServerType: "cx21", OSFlavor: "ubuntu", OSVersion: "22.04", Datacenter: "fsn1"}
+	big := &Server{AccountID: acc.ID, Name: "beta", IP: "10.0.0.2", Status: enum.ServerStatusActive,
+		CPUCores: 8, MemoryGB: 32, DiskGB: 40, ApproximateCost: 50, ServerType: "ccx33", OSFlavor: "debian",
+		OSVersion: "12", Datacenter: "nbg1"}
+	bare := &Server{AccountID: acc.ID, Name: "gamma", Status: enum.ServerStatusPaused}
+	for _, srv := range []*Server{small, big, bare} {
+		require.NoError(t, st.CreateServer(ctx, srv))
+	}
+	require.NoError(t, st.SetServerAddresses(ctx, bare.ID, []ServerAddress{
+		{Address: "192.168.5.0", Family: 4, Kind: enum.AddressKindSubnet, Prefix: 24}}))
+	require.NoError(t, st.SetServerTags(ctx, big.ID, enum.TagSourceUser, []ServerTag{{Key: "env", Value: "prod"}}))
+
+	tbl := []struct {
+		name   string
+		filter ServerFilter
+		want   []int64
+	}{
+		{"all", ServerFilter{}, []int64{small.ID, big.ID, bare.ID}},
+		{"search by name", ServerFilter{Search: "ALP"}, []int64{small.ID}},
+		{"search by description", ServerFilter{Search: "database"}, []int64{small.ID}},
+		{"search by ip", ServerFilter{Search: "10.0.0.2"}, []int64{big.ID}},
+		{"search by address", ServerFilter{Search: "192.168.5"}, []int64{bare.ID}},
+		{"cpu", ServerFilter{CPUCores: 8}, []int64{big.ID}},
+		{"os", ServerFilter{OSFlavor: "ubuntu", OSVersion: "22.04"}, []int64{small.ID}},
+		{"server type", ServerFilter{ServerType: "ccx33"}, []int64{big.ID}},
+		{"datacenter", ServerFilter{Datacenter: "fsn1"}, []int64{small.ID}},
+		{"tag", ServerFilter{Tag: TagFilter{Key: "env"}}, []int64{big.ID}},
+		{"tag value", ServerFilter{Tag: TagFilter{Key: "env", Value: "dev"}}, []int64{}},
+		{"sort by cost desc", ServerFilter{Sort: "-cost"}, []int64{big.ID, small.ID, bare.ID}},
+		{"sort by disk", ServerFilter{Sort: "disk"}, []int64{bare.ID, big.ID, small.ID}},
+		{"sort by memory", ServerFilter{Sort: "memory"}, []int64{bare.ID, small.ID, big.ID}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			servers, err := st.ListServersFiltered(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, serverWithAccountIDs(servers))
+		})
+	}
+
+	opts, err := st.GetServerSpecOptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 8}, opts.CPUCores)
+	assert.Equal(t, []string{"debian", "ubuntu"}, opts.OSFlavors)
+	assert.Equal(t, []string{"12", "22.04"}, opts.OSVersions)
+	assert.Equal(t, []string{"ccx33", "cx21"}, opts.ServerTypes)
+	assert.Equal(t, []string{"fsn1", "nbg1"}, opts.Datacenters)
+}
+
+func testServerGroups(t *testing.T, st Store) {
+	ctx := context.Background()
+	prod := testAccount(t, st, "hetzner_cloud", "prod")
+	dev := testAccount(t, st, "hetzner_cloud", "dev")
+
+	s1 := &Server{AccountID: prod.ID, Name: "web", Status: enum.ServerStatusActive, ApproximateCost: 10}
+	s2 := &Server{AccountID: prod.ID, Name: "db", Status: enum.ServerStatusPaused, ApproximateCost: 20}
+	s3 := &Server{AccountID: dev.ID, Name: "test", Status: enum.ServerStatusActive, ApproximateCost: 5}
+	for _, srv := range []*Server{s1, s2, s3} {
+		require.NoError(t, st.CreateServer(ctx, srv))
+	}
+	require.NoError(t, st.SetServerTags(ctx, s1.ID, enum.TagSourceUser, []ServerTag{{Key: "role", Value: "web"}}))
+	require.NoError(t, st.ReplaceAccountResources(ctx, prod.ID, []enum.ResourceKind{enum.ResourceKindVolume},
+		[]Resource{{Kind: enum.ResourceKindVolume, ExternalID: "v1", Name: "data", Cost: 3, ServerID: s2.ID}}))
+
+	groups, err := st.GetServersGroupedByAccount(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "dev", groups[0].AccountName)
+	assert.Len(t, groups[0].Servers, 1)
+	assert.Equal(t, "prod", groups[1].AccountName)
+	assert.Len(t, groups[1].Servers, 2)
+	assert.Len(t, groups[1].Resources, 1)
+
+	active := enum.ServerStatusActive
+	groups, err = st.GetServersGroupedByAccount(ctx, &active)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Len(t, groups[1].Servers, 1)
+	assert.Empty(t, groups[1].Resources, "resources are listed without status filter only")
+
+	tree, err := st.GetServersGroupedHierarchically(ctx, nil, TagFilter{})
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	assert.Len(t, tree[0].Accounts, 2)
+	assert.InDelta(t, 38, tree[0].TotalCost, 0.001)
+
+	tree, err = st.GetServersGroupedHierarchically(ctx, &active, TagFilter{Key: "role", Value: "web"})
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	require.Len(t, tree[0].Accounts, 1)
+	require.Len(t, tree[0].Accounts[0].Servers, 1)
+	assert.Equal(t, s1.ID, tree[0].Accounts[0].Servers[0].ID)
+
+	stats, err := st.GetDashboardStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.ResourceCount)
+	assert.InDelta(t, 3, stats.ResourceCost, 0.001)
+	assert.InDelta(t, 38, stats.TotalCost, 0.001)
+}
+
+func testContracts(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_robot", "robot")
+	soon := time.Now().UTC().AddDate(0, 0, 10).Truncate(24 * time.Hour)
+
+	// monthly synced server renewed by the provider, paid until the end of the month
+	monthly := &Server{AccountID: acc.ID, Name: "monthly", ExternalID: "1", Status: enum.ServerStatusActive,
+		PaidUntil: soon}
+	cancelled := &Server{AccountID: acc.ID, Name: "cancelled", ExternalID: "2", Status: enum.ServerStatusActive,
+		PaidUntil: soon, CancellationDate: soon.AddDate(0, 0, 5)}
+	manual := &Server{AccountID: acc.ID, Name: "manual", Status: enum.ServerStatusActive, PaidUntil: soon}
+	for _, srv := range []*Server{monthly, cancelled, manual} {
+		require.NoError(t, st.CreateServer(ctx, srv))
+	}
+
+	ending, err := st.ListServersWithContractEnding(ctx, time.Now().UTC().AddDate(0, 0, ContractWarningDays))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{manual.ID, cancelled.ID}, serverWithAccountIDs(ending),
+		"non-cancelled synced server not listed, ordered by contract end")
+
+	stats, err := st.GetDashboardStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.ContractsEnding)
+}
+
+func testAddressesAndTags(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+	s1 := &Server{AccountID: acc.ID, Name: "s1", Status: enum.ServerStatusActive}
+	s2 := &Server{AccountID: acc.ID, Name: "s2", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, s1))
+	require.NoError(t, st.CreateServer(ctx, s2))
+
+	require.NoError(t, st.SetServerAddresses(ctx, s1.ID, []ServerAddress{
+		{Address: "10.0.0.1", Family: 4, Kind: enum.AddressKindPrivate},
+		{Address: "2a01::", Family: 6, Kind: enum.AddressKindPublic, Prefix: 64},
+		{Address: "1.2.3.4", Family: 4, Kind: enum.AddressKindPublic},
+		{Address: "1.2.3.4", Family: 4, Kind: enum.AddressKindPublic},
+		{Address: "5.6.7.8", Family: 4, Kind: enum.AddressKindFloating},
+	}))
+	addrs, err := st.ListServerAddresses(ctx, s1.ID)
+	require.NoError(t, err)
+	require.Len(t, addrs, 4, "duplicate skipped")
+	assert.Equal(t, []string{"1.2.3.4", "2a01::", "5.6.7.8", "10.0.0.1"},
+		[]string{addrs[0].Address, addrs[1].Address, addrs[2].Address, addrs[3].Address}, "public first")
+	assert.Equal(t, s1.ID, addrs[0].ServerID)
+
+	require.NoError(t, st.SetServerAddresses(ctx, s1.ID, []ServerAddress{{Address: "9.9.9.9", Family: 4}}))
+	addrs, err = st.ListServerAddresses(ctx, s1.ID)
+	require.NoError(t, err)
+	require.Len(t, addrs, 1, "addresses replaced")
+	require.Error(t, st.SetServerAddresses(ctx, 9999, []ServerAddress{{Address: "1.1.1.1", Family: 4}}))
+
+	require.NoError(t, st.SetServerTags(ctx, s1.ID, enum.TagSourceProvider, []ServerTag{
+		{Key: "env", Value: "prod"}, {Key: "app", Value: "shop"}}))
+	require.NoError(t, st.SetServerTags(ctx, s1.ID, enum.TagSourceUser, []ServerTag{{Key: "env", Value: "staging"}}))
+	tags, err := st.ListServerTags(ctx, s1.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "app", tags[0].Key, "ordered by key")
+	assert.Equal(t, enum.TagSourceUser, tags[1].Source, "user tag overrides provider tag")
+	assert.Equal(t, "staging", tags[1].Value)
+
+	// provider tags don't override user tags on the next sync
+	require.NoError(t, st.SetServerTags(ctx, s1.ID, enum.TagSourceProvider, []ServerTag{{Key: "env", Value: "prod"}}))
+	tags, err = st.ListServerTags(ctx, s1.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "staging", tags[0].Value)
+
+	require.NoError(t, st.SetServerTags(ctx, s2.ID, enum.TagSourceUser, []ServerTag{{Key: "env", Value: "staging"},
+		{Key: "team", Value: "core"}}))
+	all, err := st.ListAllServerTags(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Len(t, all[s2.ID], 2)
+
+	opts, err := st.ListTagOptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []TagFilter{{Key: "env", Value: "staging"}, {Key: "team", Value: "core"}}, opts)
+	require.Error(t, st.SetServerTags(ctx, 9999, enum.TagSourceUser, []ServerTag{{Key: "a"}}))
+}
+
+func testResourceStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+	srv := &Server{AccountID: acc.ID, Name: "web", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, srv))
+
+	kinds := []enum.ResourceKind{enum.ResourceKindVolume, enum.ResourceKindFloatingIP}
+	require.NoError(t, st.ReplaceAccountResources(ctx, acc.ID, kinds, []Resource{
+		{Kind: enum.ResourceKindVolume, ExternalID: "v1", Name: "data", SizeGB: 10, Cost: 1, ServerID: srv.ID},
+		{Kind: enum.ResourceKindFloatingIP, ExternalID: "ip1", Name: "1.2.3.4", Cost: 2},
+	}))
+	require.NoError(t, st.ReplaceAccountResources(ctx, acc.ID, []enum.ResourceKind{enum.ResourceKindStorageBox},
+		[]Resource{{Kind: enum.ResourceKindStorageBox, ExternalID: "bx1", Name: "backup", Cost: 5}}))
+
+	resources, err := st.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 3)
+	assert.Equal(t, "main", resources[0].AccountName)
+	byServer, err := st.ListResourcesByServer(ctx, srv.ID)
+	require.NoError(t, err)
+	require.Len(t, byServer, 1)
+	assert.Equal(t, "v1", byServer[0].ExternalID)
+	firstID := byServer[0].ID
+
+	// matched by kind and external ID, missing resources of replaced kinds removed, other kinds kept
+	require.NoError(t, st.ReplaceAccountResources(ctx, acc.ID, kinds, []Resource{
+		{Kind: enum.ResourceKindVolume, ExternalID: "v1", Name: "data-renamed", SizeGB: 20, Cost: 2, ServerID: srv.ID},
+	}))
+	byAccount, err := st.ListResourcesByAccount(ctx, acc.ID)
+	require.NoError(t, err)
+	require.Len(t, byAccount, 2)
+	for _, r := range byAccount {
+		if r.Kind == enum.ResourceKindVolume {
+			assert.Equal(t, firstID, r.ID, "id kept on update")
+			assert.Equal(t, "data-renamed", r.Name)
+			assert.InDelta(t, 20, r.SizeGB, 0.001)
+		}
+	}
+
+	// server names of resources are hidden while the server is in the trash, resources are detached
+	// from purged servers
+	require.NoError(t, st.DeleteServer(ctx, srv.ID))
+	resources, err = st.ListResources(ctx)
+	require.NoError(t, err)
+	for _, r := range resources {
+		assert.Empty(t, r.ServerName, "server in the trash")
+	}
+	_, err = st.PurgeTrash(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	resources, err = st.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	for _, r := range resources {
+		assert.Zero(t, r.ServerID)
+		assert.Empty(t, r.ServerName)
+	}
+	require.Error(t, st.ReplaceAccountResources(ctx, 9999, kinds, []Resource{{Kind: enum.ResourceKindVolume, ExternalID: "x"}}))
+}
+
+func testMetricStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+	idle := &Server{AccountID: acc.ID, Name: "idle", Status: enum.ServerStatusActive}
+	busy := &Server{AccountID: acc.ID, Name: "busy", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, idle))
+	require.NoError(t, st.CreateServer(ctx, busy))
+
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	var samples []ServerMetric
+	for h := range 24 {
+		ts := day.Add(time.Duration(h) * time.Hour)
+		samples = append(samples, ServerMetric{ServerID: idle.ID, Time: ts, Step: MetricStepHour, CPU: 2, NetIn: 100},
+			ServerMetric{ServerID: busy.ID, Time: ts, Step: MetricStepHour, CPU: float64(50 + h), NetOut: 1000})
+	}
+	require.NoError(t, st.AddServerMetrics(ctx, samples))
+	require.Error(t, st.AddServerMetrics(ctx, []ServerMetric{{ServerID: 9999, Time: day, Step: MetricStepHour}}))
+
+	// same server, step and time replace the sample
+	require.NoError(t, st.AddServerMetrics(ctx, []ServerMetric{{ServerID: idle.ID, Time: day, Step: MetricStepHour, CPU: 2, NetIn: 100}}))
+
+	metrics, err := st.ListServerMetrics(ctx, idle.ID, day.Add(12*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, metrics, 12)
+	assert.True(t, metrics[0].Time.Equal(day.Add(12*time.Hour)), "oldest first")
+
+	last, err := st.GetLastServerMetricTime(ctx, busy.ID)
+	require.NoError(t, err)
+	assert.True(t, last.Equal(day.Add(24*time.Hour)), "end of the last sample")
+	last, err = st.GetLastServerMetricTime(ctx, 9999)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	util, err := st.ListServerUtilization(ctx, day)
+	require.NoError(t, err)
+	require.Len(t, util, 2)
+	assert.Equal(t, idle.ID, util[0].ID, "least utilized first")
+	assert.InDelta(t, 2, util[0].AvgCPU, 0.001)
+	assert.InDelta(t, 100, util[0].AvgNet, 0.001)
+	assert.InDelta(t, 61.5, util[1].AvgCPU, 0.001)
+	assert.InDelta(t, 73, util[1].MaxCPU, 0.001)
+
+	require.NoError(t, st.DownsampleServerMetrics(ctx, day.Add(36*time.Hour), day.Add(-24*time.Hour)))
+	metrics, err = st.ListServerMetrics(ctx, busy.ID, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1, "hourly samples of the day rolled up")
+	assert.Equal(t, MetricStepDay, metrics[0].Step)
+	assert.True(t, metrics[0].Time.Equal(day))
+	assert.InDelta(t, 61.5, metrics[0].CPU, 0.001)
+
+	require.NoError(t, st.DownsampleServerMetrics(ctx, day, day.Add(48*time.Hour)))
+	metrics, err = st.ListServerMetrics(ctx, busy.ID, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, metrics, "old samples purged")
+}
+
+func testServerLogStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	acc := testAccount(t, st, "hetzner_cloud", "main")
+	s1 := &Server{AccountID: acc.ID, Name: "web", IP: "10.0.0.1", Status: enum.ServerStatusActive}
+	s2 := &Server{AccountID: acc.ID, Name: "db", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, s1))
+	require.NoError(t, st.CreateServer(ctx, s2))
+	require.NoError(t, st.SetServerTags(ctx, s2.ID, enum.TagSourceUser, []ServerTag{{Key: "role", Value: "db"}}))
+
+	entries := []*ServerLog{
+		{ServerID: s1.ID, Action: enum.LogActionAdded, Description: "added web"},
+		{ServerID: s2.ID, Action: enum.LogActionAdded, Description: "added db"},
+		{ServerID: s1.ID, Action: enum.LogActionUpdated, Description: "updated web"},
+	}
+	for _, l := range entries {
+		require.NoError(t, st.CreateLog(ctx, l))
+		assert.NotZero(t, l.ID)
+		assert.False(t, l.CreatedAt.IsZero())
+		time.Sleep(5 * time.Millisecond) // distinct creation times for ordering
+	}
+	require.Error(t, st.CreateLog(ctx, &ServerLog{ServerID: 9999, Action: enum.LogActionAdded}))
+
+	logs, err := st.ListLogs(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+	assert.Equal(t, entries[2].ID, logs[0].ID, "most recent first")
+	assert.Equal(t, "web", logs[0].ServerName)
+	assert.Equal(t, "10.0.0.1", logs[0].ServerIP)
+
+	logs, err = st.ListLogs(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+
+	byServer, err := st.ListLogsByServer(ctx, s1.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, byServer, 2)
+	assert.Equal(t, "updated web", byServer[0].Description)
+
+	added := enum.LogActionAdded
+	byAction, err := st.ListLogsByAction(ctx, added, 10)
+	require.NoError(t, err)
+	assert.Len(t, byAction, 2)
+
+	filtered, err := st.ListLogsFiltered(ctx, LogFilter{Action: &added, Tag: TagFilter{Key: "role"}}, 10)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, entries[1].ID, filtered[0].ID)
+	filtered, err = st.ListLogsFiltered(ctx, LogFilter{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, filtered, 3)
+	// preset time is kept, e.g. for imported logs
+	past := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, st.CreateLog(ctx, &ServerLog{ServerID: s2.ID, Action: enum.LogActionPaused, CreatedAt: past}))
+	byServer, err = st.ListLogsByServer(ctx, s2.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, byServer, 2)
+	assert.True(t, past.Equal(byServer[1].CreatedAt), "oldest last, got %v", byServer[1].CreatedAt)
+}
+
+func testSyncRunStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	a1 := testAccount(t, st, "hetzner_cloud", "first")
+	a2 := testAccount(t, st, "hetzner_cloud", "second")
+
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	r1 := &SyncRun{AccountID: a1.ID, Trigger: enum.SyncTriggerScheduled, StartedAt: start}
+	r2 := &SyncRun{AccountID: a1.ID, Trigger: enum.SyncTriggerManual, StartedAt: start.Add(time.Hour)}
+	r3 := &SyncRun{AccountID: a2.ID, Trigger: enum.SyncTriggerManual, StartedAt: start.Add(30 * time.Minute)}
+	for _, r := range []*SyncRun{r1, r2, r3} {
+		require.NoError(t, st.CreateSyncRun(ctx, r))
+		assert.NotZero(t, r.ID)
+	}
+	require.Error(t, st.CreateSyncRun(ctx, &SyncRun{AccountID: 9999, Trigger: enum.SyncTriggerManual}))
+
+	r2.Added, r2.Updated, r2.Deleted, r2.Unchanged, r2.Error = 1, 2, 3, 4, "partial failure"
+	require.NoError(t, st.FinishSyncRun(ctx, r2))
+	assert.False(t, r2.FinishedAt.IsZero())
+	require.ErrorIs(t, st.FinishSyncRun(ctx, &SyncRun{ID: 9999}), ErrNotFound)
+
+	runs, err := st.ListSyncRuns(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, []int64{r2.ID, r3.ID, r1.ID}, []int64{runs[0].ID, runs[1].ID, runs[2].ID}, "most recent first")
+	assert.Equal(t, "first", runs[0].AccountName)
+	assert.NotEmpty(t, runs[0].ProviderName)
+	assert.Equal(t, 3, runs[0].Deleted)
+	assert.Equal(t, "partial failure", runs[0].Error)
+	assert.False(t, runs[0].FinishedAt.IsZero())
+	assert.True(t, runs[1].FinishedAt.IsZero(), "running sync has no finish time")
+
+	runs, err = st.ListSyncRuns(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+
+	byAccount, err := st.ListSyncRunsByAccount(ctx, a1.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, byAccount, 2)
+	assert.Equal(t, enum.SyncTriggerManual, byAccount[0].Trigger)
+
+	last, err := st.GetLastSyncRuns(ctx)
+	require.NoError(t, err)
+	require.Len(t, last, 2)
+	assert.Equal(t, r2.ID, last[a1.ID].ID)
+	assert.Equal(t, r3.ID, last[a2.ID].ID)
+}
+
+func testUserStore(t *testing.T, st Store) {
+	ctx := context.Background()
+
+	count, err := st.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	u := &User{Username: "admin", PasswordHash: "hash"}
+	require.NoError(t, st.CreateUser(ctx, u))
+	assert.NotZero(t, u.ID)
+	require.ErrorIs(t, st.CreateUser(ctx, &User{Username: "admin", PasswordHash: "other"}), ErrConflict)
+
+	got, err := st.GetUserByUsername(ctx, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, got.ID)
+	assert.Equal(t, "hash", got.PasswordHash)
+	_, err = st.GetUserByUsername(ctx, "nobody")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, st.UpdateUserPassword(ctx, u.ID, "new-hash"))
+	got, err = st.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.PasswordHash)
+	_, err = st.GetUserByID(ctx, 9999)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, st.UpdateUserPassword(ctx, 9999, "x"), ErrNotFound)
+
+	count, err = st.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testSessionStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	u1 := &User{Username: "first", PasswordHash: "hash"}
+	u2 := &User{Username: "second", PasswordHash: "hash"}
+	require.NoError(t, st.CreateUser(ctx, u1))
+	require.NoError(t, st.CreateUser(ctx, u2))
+
+	now := time.Now().UTC()
+	active := &Session{ID: "active", UserID: u1.ID, ExpiresAt: now.Add(time.Hour)}
+	expired := &Session{ID: "expired", UserID: u1.ID, ExpiresAt: now.Add(-time.Hour)}
+	other := &Session{ID: "other", UserID: u2.ID, ExpiresAt: now.Add(time.Hour)}
+	for _, sess := range []*Session{active, expired, other} {
+		require.NoError(t, st.CreateSession(ctx, sess))
+	}
+	require.Error(t, st.CreateSession(ctx, &Session{ID: "orphan", UserID: 9999, ExpiresAt: now.Add(time.Hour)}))
+
+	got, err := st.GetSession(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, u1.ID, got.UserID)
+	_, err = st.GetSession(ctx, "expired")
+	require.ErrorIs(t, err, ErrNotFound, "expired sessions are not returned")
+	_, err = st.GetSession(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, st.DeleteExpiredSessions(ctx))
+	require.NoError(t, st.DeleteUserSessions(ctx, u1.ID))
+	_, err = st.GetSession(ctx, "active")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = st.GetSession(ctx, "other")
+	require.NoError(t, err)
+
+	require.NoError(t, st.DeleteSession(ctx, "other"))
+	_, err = st.GetSession(ctx, "other")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, st.DeleteSession(ctx, "other"), "deleting missing session is not an error")
+}
+
+func testTrashStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	p := &Provider{Ident: "ovh", Name: "OVH"}
+	require.NoError(t, st.CreateProvider(ctx, p))
+	main := &Account{ProviderID: p.ID, Name: "main", ApiKey: "secret"}
+	require.NoError(t, st.CreateAccount(ctx, main))
+	other := &Account{ProviderID: p.ID, Name: "other"}
+	require.NoError(t, st.CreateAccount(ctx, other))
+	web := &Server{AccountID: main.ID, Name: "web", ExternalID: "1", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, web))
+	db := &Server{AccountID: main.ID, Name: "db", ExternalID: "2", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, db))
+	kept := testAccount(t, st, "hetzner_cloud", "kept")
+	keptSrv := &Server{AccountID: kept.ID, Name: "kept", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, keptSrv))
+
+	trash, err := st.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.True(t, trash.IsEmpty())
+
+	// db is deleted on its own, web along with the account
+	require.NoError(t, st.DeleteServer(ctx, db.ID))
+	require.NoError(t, st.DeleteAccount(ctx, main.ID))
+	trash, err = st.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, trash.Providers)
+	require.Len(t, trash.Accounts, 1)
+	assert.Equal(t, main.ID, trash.Accounts[0].ID)
+	assert.Equal(t, "OVH", trash.Accounts[0].ProviderName)
+	assert.Empty(t, trash.Accounts[0].ApiKey, "credentials are not listed")
+	assert.Equal(t, 1, trash.Accounts[0].ServerCount, "servers deleted with the account")
+	assert.False(t, trash.Accounts[0].DeletedAt.IsZero())
+	assert.ElementsMatch(t, []int64{web.ID, db.ID}, serverWithAccountIDs(trash.Servers))
+	assert.False(t, trash.Servers[0].DeletedAt.IsZero())
+	assert.Equal(t, "main", trash.Servers[0].AccountName)
+
+	require.ErrorIs(t, st.RestoreServer(ctx, web.ID), ErrParentDeleted)
+	require.NoError(t, st.RestoreAccount(ctx, main.ID))
+	servers, err := st.ListServersByAccount(ctx, main.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{web.ID}, serverIDs(servers), "server deleted before the account stays in the trash")
+	require.ErrorIs(t, st.RestoreAccount(ctx, main.ID), ErrNotFound, "not in the trash")
+
+	// deleting the provider moves its accounts and their servers to the trash
+	require.NoError(t, st.DeleteProvider(ctx, p.ID))
+	_, err = st.GetServer(ctx, web.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	trash, err = st.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, trash.Providers, 1)
+	assert.Len(t, trash.Accounts, 2)
+	assert.Len(t, trash.Servers, 2)
+	require.ErrorIs(t, st.RestoreAccount(ctx, other.ID), ErrParentDeleted)
+
+	require.NoError(t, st.RestoreProvider(ctx, p.ID))
+	accounts, err := st.ListAccountsByProvider(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{main.ID, other.ID}, accountIDs(accounts))
+	got, err := st.GetAccount(ctx, main.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", got.ApiKey)
+	_, err = st.GetServer(ctx, web.ID)
+	require.NoError(t, err)
+	require.ErrorIs(t, st.RestoreProvider(ctx, p.ID), ErrNotFound)
+
+	// external ID of a server in the trash can be reused, restoring it then conflicts
+	dup := &Server{AccountID: main.ID, Name: "db-new", ExternalID: "2", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, dup))
+	require.ErrorIs(t, st.RestoreServer(ctx, db.ID), ErrConflict)
+	require.NoError(t, st.DeleteServer(ctx, dup.ID))
+	require.NoError(t, st.RestoreServer(ctx, db.ID))
+	require.ErrorIs(t, st.RestoreServer(ctx, 9999), ErrNotFound)
+
+	// only records deleted before the time are purged, parents with records left are kept
+	require.NoError(t, st.DeleteAccount(ctx, main.ID))
+	n, err := st.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = st.PurgeTrash(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 4, n, "dup, web, db and the account")
+	trash, err = st.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.True(t, trash.IsEmpty())
+	require.ErrorIs(t, st.RestoreAccount(ctx, main.ID), ErrNotFound)
+	require.NoError(t, st.CreateAccount(ctx, &Account{ProviderID: p.ID, Name: "main"}), "name is free after purge")
+
+	servers, err = st.ListServers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{keptSrv.ID}, serverIDs(servers))
+}
+
+// testProvider returns a default provider by ident
+func testProvider(t *testing.T, st Store, ident string) Provider {
+	providers, err := st.ListProviders(context.Background())
+	require.NoError(t, err)
+	for _, p := range providers {
+		if p.Ident == ident {
+			return p
+		}
+	}
+	t.Fatalf("provider %q not found", ident)
+	return Provider{}
+}
+
+// testAccount creates an account of a default provider
+func testAccount(t *testing.T, st Store, providerIdent, name string) *Account {
+	acc := &Account{ProviderID: testProvider(t, st, providerIdent).ID, Name: name}
+	require.NoError(t, st.CreateAccount(context.Background(), acc))
+	return acc
+}
+
+func accountIDs(accounts []Account) []int64 {
+	res := make([]int64, 0, len(accounts))
+	for _, a := range accounts {
+		res = append(res, a.ID)
+	}
+	return res
+}
+
+func serverIDs(servers []Server) []int64 {
+	res := make([]int64, 0, len(servers))
+	for _, s := range servers {
+		res = append(res, s.ID)
+	}
+	return res
+}
+
+func serverWithAccountIDs(servers []ServerWithAccount) []int64 {
+	res := make([]int64, 0, len(servers))
+	for _, s := range servers {
+		res = append(res, s.ID)
+	}
+	return res
+}
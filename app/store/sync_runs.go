@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+// CreateSyncRun records the start of an account sync
+func (s *DB) CreateSyncRun(ctx context.Context, r *SyncRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.StartedAt.IsZero() {
+		r.StartedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO sync_runs (account_id, trigger_type, started_at) VALUES (?, ?, ?)`
+
+	id, err := s.db.insertContext(ctx, query, r.AccountID, r.Trigger.String(), r.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sync run: %w", err)
+	}
+
+	r.ID = id
+
+	return nil
+}
+
+// FinishSyncRun records the outcome of an account sync
+func (s *DB) FinishSyncRun(ctx context.Context, r *SyncRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.FinishedAt.IsZero() {
+		r.FinishedAt = time.Now().UTC()
+	}
+
+	query := `UPDATE sync_runs SET finished_at = ?, added = ?, updated = ?, deleted = ?, unchanged = ?, error = ?
+		WHERE id = ?`
+	result, err := s.db.ExecContext(ctx, query, r.FinishedAt, r.Added, r.Updated, r.Deleted, r.Unchanged,
+		r.Error, r.ID)
+	if err != nil {
+		return fmt.Errorf("failed to finish sync run: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListSyncRuns lists sync runs of all accounts, most recent first
+func (s *DB) ListSyncRuns(ctx context.Context, limit int) ([]SyncRunWithAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []syncRunWithAccountRow
+	query := `SELECT r.id, r.account_id, r.trigger_type, r.started_at, r.finished_at,
+		r.added, r.updated, r.deleted, r.unchanged, r.error,
+		a.name as account_name, p.name as provider_name
+		FROM sync_runs r
+		JOIN accounts a ON r.account_id = a.id
+		JOIN providers p ON a.provider_id = p.id
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT ?`
+	if err := s.db.SelectContext(ctx, &rows, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+
+	return toSyncRunsWithAccount(rows)
+}
+
+// ListSyncRunsByAccount lists sync runs of a single account, most recent first
+func (s *DB) ListSyncRunsByAccount(ctx context.Context, accountID int64, limit int) ([]SyncRunWithAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []syncRunWithAccountRow
+	query := `SELECT r.id, r.account_id, r.trigger_type, r.started_at, r.finished_at,
+		r.added, r.updated, r.deleted, r.unchanged, r.error,
+		a.name as account_name, p.name as provider_name
+		FROM sync_runs r
+		JOIN accounts a ON r.account_id = a.id
+		JOIN providers p ON a.provider_id = p.id
+		WHERE r.account_id = ?
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT ?`
+	if err := s.db.SelectContext(ctx, &rows, query, accountID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+
+	return toSyncRunsWithAccount(rows)
+}
+
+// GetLastSyncRuns returns the most recent sync run of each account, keyed by account ID
+func (s *DB) GetLastSyncRuns(ctx context.Context) (map[int64]SyncRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []syncRunRow
+	query := `SELECT r.id, r.account_id, r.trigger_type, r.started_at, r.finished_at,
+		r.added, r.updated, r.deleted, r.unchanged, r.error
+		FROM sync_runs r
+		WHERE r.id = (SELECT MAX(id) FROM sync_runs WHERE account_id = r.account_id)`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to get last sync runs: %w", err)
+	}
+
+	runs := make(map[int64]SyncRun, len(rows))
+	for _, r := range rows {
+		run, err := r.toSyncRun()
+		if err != nil {
+			return nil, err
+		}
+		runs[run.AccountID] = *run
+	}
+
+	return runs, nil
+}
+
+// syncRunRow is used for scanning database rows
+type syncRunRow struct {
+	ID         int64        `db:"id"`
+	AccountID  int64        `db:"account_id"`
+	Trigger    string       `db:"trigger_type"`
+	StartedAt  time.Time    `db:"started_at"`
+	FinishedAt sql.NullTime `db:"finished_at"`
+	Added      int          `db:"added"`
+	Updated    int          `db:"updated"`
+	Deleted    int          `db:"deleted"`
+	Unchanged  int          `db:"unchanged"`
+	Error      string       `db:"error"`
+}
+
+func (r *syncRunRow) toSyncRun() (*SyncRun, error) {
+	trigger, err := enum.ParseSyncTrigger(r.Trigger)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncRun{
+		ID:         r.ID,
+		AccountID:  r.AccountID,
+		Trigger:    trigger,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt.Time,
+		Added:      r.Added,
+		Updated:    r.Updated,
+		Deleted:    r.Deleted,
+		Unchanged:  r.Unchanged,
+		Error:      r.Error,
+	}, nil
+}
+
+type syncRunWithAccountRow struct {
+	syncRunRow
+	AccountName  string `db:"account_name"`
+	ProviderName string `db:"provider_name"`
+}
+
+func toSyncRunsWithAccount(rows []syncRunWithAccountRow) ([]SyncRunWithAccount, error) {
+	runs := make([]SyncRunWithAccount, 0, len(rows))
+	for _, r := range rows {
+		run, err := r.syncRunRow.toSyncRun()
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, SyncRunWithAccount{
+			SyncRun:      *run,
+			AccountName:  r.AccountName,
+			ProviderName: r.ProviderName,
+		})
+	}
+	return runs, nil
+}
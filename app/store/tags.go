@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+const serverTagColumns = `id, server_id, key, value, source, created_at`
+
+// ListServerTags lists tags of a server ordered by key
+func (s *DB) ListServerTags(ctx context.Context, serverID int64) ([]ServerTag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + serverTagColumns + ` FROM server_tags WHERE server_id = ? ORDER BY key`
+	return s.selectServerTags(ctx, query, serverID)
+}
+
+// ListAllServerTags returns tags of all servers by server ID
+func (s *DB) ListAllServerTags(ctx context.Context) (map[int64][]ServerTag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `SELECT ` + serverTagColumns + ` FROM server_tags ORDER BY server_id, key`
+	tags, err := s.selectServerTags(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[int64][]ServerTag)
+	for _, t := range tags {
+		res[t.ServerID] = append(res[t.ServerID], t)
+	}
+	return res, nil
+}
+
+// ListTagOptions returns distinct key/value pairs of all tags ordered by key and value, for filters
+func (s *DB) ListTagOptions(ctx context.Context) ([]TagFilter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rows []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	query := `SELECT DISTINCT key, value FROM server_tags
+		WHERE server_id IN (SELECT id FROM servers WHERE deleted_at IS NULL)
+		ORDER BY key, value`
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to list tag options: %w", err)
+	}
+
+	res := make([]TagFilter, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, TagFilter{Key: r.Key, Value: r.Value})
+	}
+	return res, nil
+}
+
+// SetServerTags replaces tags of the source on a server. User tags override provider tags
+// with the same key, provider tags with a key already set by user are skipped.
+func (s *DB) SetServerTags(ctx context.Context, serverID int64, source enum.TagSource, tags []ServerTag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM server_tags WHERE server_id = ? AND source = ?`, serverID, source.String())
+	if err != nil {
+		return fmt.Errorf("failed to delete server tags: %w", err)
+	}
+
+	query := `INSERT INTO server_tags (server_id, key, value, source, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(server_id, key) DO UPDATE SET value = excluded.value, source = excluded.source,
+			created_at = excluded.created_at`
+	if source == enum.TagSourceProvider {
+		query = `INSERT INTO server_tags (server_id, key, value, source, created_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(server_id, key) DO NOTHING`
+	}
+
+	now := time.Now().UTC()
+	for _, t := range tags {
+		if _, err := tx.ExecContext(ctx, query, serverID, t.Key, t.Value, source.String(), now); err != nil {
+			return fmt.Errorf("failed to add server tag %s: %w", t, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit server tags: %w", err)
+	}
+	return nil
+}
+
+// selectServerTags runs a query returning server tag columns, caller holds the lock
+func (s *DB) selectServerTags(ctx context.Context, query string, args ...interface{}) ([]ServerTag, error) {
+	var rows []serverTagRow
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list server tags: %w", err)
+	}
+
+	tags := make([]ServerTag, 0, len(rows))
+	for _, r := range rows {
+		t, err := r.toServerTag()
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *t)
+	}
+	return tags, nil
+}
+
+// tagCondition returns an SQL condition matching servers aliased as s having the tag of the filter
+func tagCondition(f TagFilter) (string, []interface{}) {
+	if f.Value == "" {
+		return `EXISTS (SELECT 1 FROM server_tags st WHERE st.server_id = s.id AND st.key = ?)`, []interface{}{f.Key}
+	}
+	return `EXISTS (SELECT 1 FROM server_tags st WHERE st.server_id = s.id AND st.key = ? AND st.value = ?)`,
+		[]interface{}{f.Key, f.Value}
+}
+
+type serverTagRow struct {
+	ID        int64     `db:"id"`
+	ServerID  int64     `db:"server_id"`
+	Key       string    `db:"key"`
+	Value     string    `db:"value"`
+	Source    string    `db:"source"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *serverTagRow) toServerTag() (*ServerTag, error) {
+	source, err := enum.ParseTagSource(r.Source)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerTag{
+		ID:        r.ID,
+		ServerID:  r.ServerID,
+		Key:       r.Key,
+		Value:     r.Value,
+		Source:    source,
+		CreatedAt: r.CreatedAt,
+	}, nil
+}
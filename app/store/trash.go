@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// ErrParentDeleted is returned when restoring a record whose provider or account is still in the trash
+var ErrParentDeleted = errors.New("parent is in the trash")
+
+// ErrInTrash is returned along with ErrConflict when creating or renaming a provider or an account
+// to the ident or name of one in the trash
+var ErrInTrash = errors.New("is in the trash, restore it first")
+
+// trashPurgeInterval is how often the trash is checked for records older than the retention
+const trashPurgeInterval = time.Hour
+
+// ListTrash lists deleted providers, accounts and servers, most recently deleted first
+func (s *DB) ListTrash(ctx context.Context) (*Trash, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var trash Trash
+	query := `SELECT id, ident, name, description, created_at, updated_at, deleted_at FROM providers
+		WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, name`
+	if err := s.db.SelectContext(ctx, &trash.Providers, query); err != nil {
+		return nil, fmt.Errorf("failed to list deleted providers: %w", err)
+	}
+
+	// credentials are not needed to restore an account, they are not decrypted here.
+	// server count is the number of servers restored along with the account.
+	query = `SELECT a.id, a.provider_id, a.group_name, a.name, a.login, a.sync_interval,
+		a.created_at, a.updated_at, a.deleted_at,
+		p.ident as provider_ident, p.name as provider_name,
+		(SELECT COUNT(*) FROM servers WHERE account_id = a.id AND deleted_at = a.deleted_at) as server_count
+		FROM accounts a
+		JOIN providers p ON a.provider_id = p.id
+		WHERE a.deleted_at IS NOT NULL
+		ORDER BY a.deleted_at DESC, p.name, a.name`
+	if err := s.db.SelectContext(ctx, &trash.Accounts, query); err != nil {
+		return nil, fmt.Errorf("failed to list deleted accounts: %w", err)
+	}
+
+	query = serverWithAccountSelectAll + `
+		WHERE s.deleted_at IS NOT NULL
+		ORDER BY s.deleted_at DESC, s.name`
+	servers, err := s.selectServersWithAccounts(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted servers: %w", err)
+	}
+	trash.Servers = servers
+
+	return &trash, nil
+}
+
+// RestoreProvider restores a provider from the trash along with accounts and servers deleted with it
+func (s *DB) RestoreProvider(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var count int
+	query := `SELECT COUNT(*) FROM providers WHERE id = ? AND deleted_at IS NOT NULL`
+	if err := tx.GetContext(ctx, &count, query, id); err != nil {
+		return fmt.Errorf("failed to get deleted provider: %w", err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	// children deleted along with the provider have the same deletion time
+	query = `UPDATE servers SET deleted_at = NULL WHERE deleted_at = (SELECT deleted_at FROM providers WHERE id = ?)
+		AND account_id IN (SELECT id FROM accounts WHERE provider_id = ?
+			AND deleted_at = (SELECT deleted_at FROM providers WHERE id = ?))`
+	if _, err := tx.ExecContext(ctx, query, id, id, id); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a server with the same external id exists", ErrConflict)
+		}
+		return fmt.Errorf("failed to restore servers of provider: %w", err)
+	}
+	query = `UPDATE accounts SET deleted_at = NULL WHERE provider_id = ?
+		AND deleted_at = (SELECT deleted_at FROM providers WHERE id = ?)`
+	if _, err := tx.ExecContext(ctx, query, id, id); err != nil {
+		return fmt.Errorf("failed to restore accounts of provider: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE providers SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to restore provider: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit provider restore: %w", err)
+	}
+	return nil
+}
+
+// RestoreAccount restores an account from the trash along with servers deleted with it.
+// Returns ErrParentDeleted if the provider of the account is in the trash.
+func (s *DB) RestoreAccount(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var r struct {
+		ProviderName      string       `db:"provider_name"`
+		ProviderDeletedAt sql.NullTime `db:"provider_deleted_at"`
+	}
+	query := `SELECT p.name as provider_name, p.deleted_at as provider_deleted_at
+		FROM accounts a JOIN providers p ON a.provider_id = p.id
+		WHERE a.id = ? AND a.deleted_at IS NOT NULL`
+	if err := tx.GetContext(ctx, &r, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get deleted account: %w", err)
+	}
+	if r.ProviderDeletedAt.Valid {
+		return fmt.Errorf("%w: provider %q is deleted, restore it first", ErrParentDeleted, r.ProviderName)
+	}
+
+	// servers deleted along with the account have the same deletion time
+	query = `UPDATE servers SET deleted_at = NULL WHERE account_id = ?
+		AND deleted_at = (SELECT deleted_at FROM accounts WHERE id = ?)`
+	if _, err := tx.ExecContext(ctx, query, id, id); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a server with the same external id exists", ErrConflict)
+		}
+		return fmt.Errorf("failed to restore servers of account: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to restore account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account restore: %w", err)
+	}
+	return nil
+}
+
+// RestoreServer restores a server from the trash. Returns ErrParentDeleted if its account is in the trash
+// and ErrConflict if another server of the account has the same external ID.
+func (s *DB) RestoreServer(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r struct {
+		AccountName      string       `db:"account_name"`
+		AccountDeletedAt sql.NullTime `db:"account_deleted_at"`
+	}
+	query := `SELECT a.name as account_name, a.deleted_at as account_deleted_at
+		FROM servers s JOIN accounts a ON s.account_id = a.id
+		WHERE s.id = ? AND s.deleted_at IS NOT NULL`
+	if err := s.db.GetContext(ctx, &r, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get deleted server: %w", err)
+	}
+	if r.AccountDeletedAt.Valid {
+		return fmt.Errorf("%w: account %q is deleted, restore it first", ErrParentDeleted, r.AccountName)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE servers SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a server with the same external id exists", ErrConflict)
+		}
+		return fmt.Errorf("failed to restore server: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently removes providers, accounts and servers deleted before the given time,
+// along with logs, tags, addresses and metrics of the servers and resources and sync runs of the accounts.
+// Accounts and providers still having records are kept. Returns the number of removed records.
+// Replicas sharing a Postgres database don't purge at the same time, the one finding the purge
+// running elsewhere removes nothing.
+func (s *DB) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	locked, err := tryAdvisoryXactLock(ctx, tx, lockKeyPurge)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	// children first, parents are removed once nothing references them
+	tables := []struct {
+		name  string
+		query string
+	}{
+		{"servers", `DELETE FROM servers WHERE deleted_at < ?`},
+		{"accounts", `DELETE FROM accounts WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM servers s WHERE s.account_id = accounts.id)`},
+		{"providers", `DELETE FROM providers WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM accounts a WHERE a.provider_id = providers.id)`},
+	}
+	total := 0
+	for _, t := range tables {
+		result, err := tx.ExecContext(ctx, t.query, before.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge deleted %s: %w", t.name, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		total += int(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit trash purge: %w", err)
+	}
+	return total, nil
+}
+
+// TrashPurger periodically removes records kept in the trash longer than the retention
+type TrashPurger struct {
+	store     TrashStore
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewTrashPurger creates a purger removing records deleted more than retention ago, checked hourly
+func NewTrashPurger(st TrashStore, retention time.Duration) *TrashPurger {
+	return &TrashPurger{store: st, retention: retention, interval: trashPurgeInterval}
+}
+
+// Run purges the trash on start and every interval until context is canceled.
+// Zero retention disables purging, deleted records are kept in the trash forever.
+func (p *TrashPurger) Run(ctx context.Context) {
+	if p.retention <= 0 {
+		return
+	}
+	log.Printf("[INFO] trash purger started, retention %v", p.retention)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			log.Printf("[INFO] trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge removes records deleted before now minus retention
+func (p *TrashPurger) purge(ctx context.Context, now time.Time) {
+	n, err := p.store.PurgeTrash(ctx, now.Add(-p.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[ERROR] failed to purge trash: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		log.Printf("[INFO] purged %d records deleted more than %v ago", n, p.retention)
+	}
+}
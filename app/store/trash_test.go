@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nilBora/servers-manager/app/enum"
+)
+
+func TestTrashPurger_Purge(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemory()
+	acc := &Account{ProviderID: 1, Name: "main"}
+	require.NoError(t, st.CreateAccount(ctx, acc))
+	srv := &Server{AccountID: acc.ID, Name: "web-1", Status: enum.ServerStatusActive}
+	require.NoError(t, st.CreateServer(ctx, srv))
+	require.NoError(t, st.DeleteServer(ctx, srv.ID))
+
+	p := NewTrashPurger(st, 24*time.Hour)
+	p.purge(ctx, time.Now())
+	trash, err := st.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.Len(t, trash.Servers, 1, "kept within retention")
+
+	p.purge(ctx, time.Now().Add(25*time.Hour))
+	trash, err = st.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.True(t, trash.IsEmpty(), "purged after retention")
+	_, err = st.GetAccount(ctx, acc.ID)
+	require.NoError(t, err, "live account kept")
+}
+
+func TestTrashPurger_RunDisabled(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		NewTrashPurger(NewMemory(), 0).Run(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purger with zero retention should return immediately")
+	}
+}
@@ -20,7 +20,7 @@ func (s *DB) CreateUser(ctx context.Context, u *User) error {
 	query := `INSERT INTO users (username, password_hash, created_at, updated_at)
 		VALUES (?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query, u.Username, u.PasswordHash, u.CreatedAt, u.UpdatedAt)
+	id, err := s.db.insertContext(ctx, query, u.Username, u.PasswordHash, u.CreatedAt, u.UpdatedAt)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return fmt.Errorf("%w: user with username %q already exists", ErrConflict, u.Username)
@@ -28,10 +28,6 @@ func (s *DB) CreateUser(ctx context.Context, u *User) error {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
 	u.ID = id
 
 	return nil